@@ -0,0 +1,135 @@
+// Code generated by protoc-gen-go.
+// source: kedge/config/client/client.proto
+// DO NOT EDIT!
+
+/*
+Package kedge_config_client is a generated protocol buffer package.
+
+It is generated from these files:
+	kedge/config/client/client.proto
+
+It has these top-level messages:
+	ClientConfig
+	Mapping
+*/
+package kedge_config_client
+
+import proto "github.com/golang/protobuf/proto"
+import fmt "fmt"
+import math "math"
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ = proto.Marshal
+var _ = fmt.Errorf
+var _ = math.Inf
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the proto package it is being compiled against.
+// A compilation error at this line likely means your copy of the
+// proto package needs to be updated.
+const _ = proto.ProtoPackageIsVersion2 // please upgrade the proto package
+
+// / Unmatched decides what happens to requests for hosts that no mapping matches.
+type ClientConfig_Unmatched int32
+
+const (
+	// / DIRECT sends the request straight to its destination, bypassing kedges.
+	ClientConfig_DIRECT ClientConfig_Unmatched = 0
+	// / REJECT fails the request with a 403.
+	ClientConfig_REJECT ClientConfig_Unmatched = 1
+)
+
+var ClientConfig_Unmatched_name = map[int32]string{
+	0: "DIRECT",
+	1: "REJECT",
+}
+var ClientConfig_Unmatched_value = map[string]int32{
+	"DIRECT": 0,
+	"REJECT": 1,
+}
+
+func (x ClientConfig_Unmatched) String() string {
+	return proto.EnumName(ClientConfig_Unmatched_name, int32(x))
+}
+func (ClientConfig_Unmatched) EnumDescriptor() ([]byte, []int) { return fileDescriptor0, []int{0, 0} }
+
+// / ClientConfig is the top level configuration message of kedge_local, the local forward proxy to kedges.
+type ClientConfig struct {
+	// / mappings are evaluated in order, the first one matching the host of the request is used.
+	Mappings  []*Mapping             `protobuf:"bytes,1,rep,name=mappings" json:"mappings,omitempty"`
+	Unmatched ClientConfig_Unmatched `protobuf:"varint,2,opt,name=unmatched,enum=kedge.config.client.ClientConfig_Unmatched" json:"unmatched,omitempty"`
+}
+
+func (m *ClientConfig) Reset()                    { *m = ClientConfig{} }
+func (m *ClientConfig) String() string            { return proto.CompactTextString(m) }
+func (*ClientConfig) ProtoMessage()               {}
+func (*ClientConfig) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{0} }
+
+func (m *ClientConfig) GetMappings() []*Mapping {
+	if m != nil {
+		return m.Mappings
+	}
+	return nil
+}
+
+func (m *ClientConfig) GetUnmatched() ClientConfig_Unmatched {
+	if m != nil {
+		return m.Unmatched
+	}
+	return ClientConfig_DIRECT
+}
+
+// / Mapping sends requests for a set of hosts to the kedge serving them.
+type Mapping struct {
+	// / host_matcher matches the host of the request, without the port.
+	// / It is either an exact host name or a wildcard like "*.my_cluster.cluster.local" matching all its subdomains.
+	HostMatcher string `protobuf:"bytes,1,opt,name=host_matcher,json=hostMatcher" json:"host_matcher,omitempty"`
+	// / kedge_address is the host:port of the kedge, e.g. "my_cluster.internalapi.example.com:443".
+	// / If the port is missing, 443 is used.
+	KedgeAddress string `protobuf:"bytes,2,opt,name=kedge_address,json=kedgeAddress" json:"kedge_address,omitempty"`
+}
+
+func (m *Mapping) Reset()                    { *m = Mapping{} }
+func (m *Mapping) String() string            { return proto.CompactTextString(m) }
+func (*Mapping) ProtoMessage()               {}
+func (*Mapping) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{1} }
+
+func (m *Mapping) GetHostMatcher() string {
+	if m != nil {
+		return m.HostMatcher
+	}
+	return ""
+}
+
+func (m *Mapping) GetKedgeAddress() string {
+	if m != nil {
+		return m.KedgeAddress
+	}
+	return ""
+}
+
+func init() {
+	proto.RegisterType((*ClientConfig)(nil), "kedge.config.client.ClientConfig")
+	proto.RegisterType((*Mapping)(nil), "kedge.config.client.Mapping")
+	proto.RegisterEnum("kedge.config.client.ClientConfig_Unmatched", ClientConfig_Unmatched_name, ClientConfig_Unmatched_value)
+}
+
+func init() { proto.RegisterFile("kedge/config/client/client.proto", fileDescriptor0) }
+
+var fileDescriptor0 = []byte{
+	// 222 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x09, 0x6e, 0x88, 0x02, 0xff, 0xe2, 0x52, 0xc8, 0x4e, 0x4d, 0x49,
+	0x4f, 0xd5, 0x4f, 0xce, 0xcf, 0x4b, 0xcb, 0x4c, 0xd7, 0x4f, 0xce, 0xc9, 0x4c, 0xcd, 0x2b, 0x81,
+	0x52, 0x7a, 0x05, 0x45, 0xf9, 0x25, 0xf9, 0x42, 0xc2, 0x60, 0x15, 0x7a, 0x10, 0x15, 0x7a, 0x10,
+	0x29, 0xa5, 0x1d, 0x8c, 0x5c, 0x3c, 0xce, 0x60, 0xa6, 0x33, 0x58, 0x5c, 0xc8, 0x82, 0x8b, 0x23,
+	0x37, 0xb1, 0xa0, 0x20, 0x33, 0x2f, 0xbd, 0x58, 0x82, 0x51, 0x81, 0x59, 0x83, 0xdb, 0x48, 0x46,
+	0x0f, 0x8b, 0x46, 0x3d, 0x5f, 0x88, 0xa2, 0x20, 0xb8, 0x6a, 0x21, 0x4f, 0x2e, 0xce, 0xd2, 0xbc,
+	0xdc, 0xc4, 0x92, 0xe4, 0x8c, 0xd4, 0x14, 0x09, 0x26, 0x05, 0x46, 0x0d, 0x3e, 0x23, 0x6d, 0xac,
+	0x5a, 0x91, 0xed, 0xd3, 0x0b, 0x85, 0x69, 0x09, 0x42, 0xe8, 0x56, 0x52, 0xe6, 0xe2, 0x84, 0x8b,
+	0x0b, 0x71, 0x71, 0xb1, 0xb9, 0x78, 0x06, 0xb9, 0x3a, 0x87, 0x08, 0x30, 0x80, 0xd8, 0x41, 0xae,
+	0x5e, 0x20, 0x36, 0xa3, 0x52, 0x20, 0x17, 0x3b, 0xd4, 0x11, 0x42, 0x8a, 0x5c, 0x3c, 0x19, 0xf9,
+	0xc5, 0x25, 0xf1, 0x10, 0x2d, 0x45, 0x12, 0x8c, 0x0a, 0x8c, 0x1a, 0x9c, 0x41, 0xdc, 0x20, 0x31,
+	0x5f, 0x88, 0x90, 0x90, 0x32, 0x17, 0x2f, 0xd8, 0x2d, 0xf1, 0x89, 0x29, 0x29, 0x45, 0xa9, 0xc5,
+	0xc5, 0x60, 0x17, 0x72, 0x06, 0xf1, 0x80, 0x05, 0x1d, 0x21, 0x62, 0x49, 0x6c, 0xe0, 0x90, 0x32,
+	0x06, 0x04, 0x00, 0x00, 0xff, 0xff, 0x67, 0x44, 0x8a, 0x96, 0x4d, 0x01, 0x00, 0x00,
+}
@@ -0,0 +1,286 @@
+// Code generated by protoc-gen-go.
+// source: kedge/config/common/auth/auth.proto
+// DO NOT EDIT!
+
+/*
+Package kedge_config_common_auth is a generated protocol buffer package.
+
+It is generated from these files:
+	kedge/config/common/auth/auth.proto
+
+It has these top-level messages:
+	ClientAuthorization
+	CertRequirement
+	JwtRequirement
+*/
+package kedge_config_common_auth
+
+import proto "github.com/golang/protobuf/proto"
+import fmt "fmt"
+import math "math"
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ = proto.Marshal
+var _ = fmt.Errorf
+var _ = math.Inf
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the proto package it is being compiled against.
+// A compilation error at this line likely means your copy of the
+// proto package needs to be updated.
+const _ = proto.ProtoPackageIsVersion2 // please upgrade the proto package
+
+// / ClientAuthorization describes the identity a client needs to present for its request to be proxied.
+// / All present requirements need to be met. Requests failing them are rejected with a 403.
+type ClientAuthorization struct {
+	// / cert requires a TLS client certificate with matching attributes.
+	// / The certificate is only verified if the server is configured with client CA files.
+	Cert *CertRequirement `protobuf:"bytes,1,opt,name=cert" json:"cert,omitempty"`
+	// / jwt requires a valid, signed JSON Web Token with matching claims.
+	Jwt *JwtRequirement `protobuf:"bytes,2,opt,name=jwt" json:"jwt,omitempty"`
+}
+
+func (m *ClientAuthorization) Reset()                    { *m = ClientAuthorization{} }
+func (m *ClientAuthorization) String() string            { return proto.CompactTextString(m) }
+func (*ClientAuthorization) ProtoMessage()               {}
+func (*ClientAuthorization) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{0} }
+
+func (m *ClientAuthorization) GetCert() *CertRequirement {
+	if m != nil {
+		return m.Cert
+	}
+	return nil
+}
+
+func (m *ClientAuthorization) GetJwt() *JwtRequirement {
+	if m != nil {
+		return m.Jwt
+	}
+	return nil
+}
+
+// / CertRequirement matches attributes of the subject of the TLS client certificate.
+// / Each non-empty list needs at least one of its values to be present in the certificate.
+type CertRequirement struct {
+	// / common_names are the allowed subject Common Names.
+	CommonNames []string `protobuf:"bytes,1,rep,name=common_names,json=commonNames" json:"common_names,omitempty"`
+	// / organizations are the allowed subject Organizations.
+	Organizations []string `protobuf:"bytes,2,rep,name=organizations" json:"organizations,omitempty"`
+	// / organizational_units are the allowed subject Organizational Units.
+	OrganizationalUnits []string `protobuf:"bytes,3,rep,name=organizational_units,json=organizationalUnits" json:"organizational_units,omitempty"`
+}
+
+func (m *CertRequirement) Reset()                    { *m = CertRequirement{} }
+func (m *CertRequirement) String() string            { return proto.CompactTextString(m) }
+func (*CertRequirement) ProtoMessage()               {}
+func (*CertRequirement) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{1} }
+
+func (m *CertRequirement) GetCommonNames() []string {
+	if m != nil {
+		return m.CommonNames
+	}
+	return nil
+}
+
+func (m *CertRequirement) GetOrganizations() []string {
+	if m != nil {
+		return m.Organizations
+	}
+	return nil
+}
+
+func (m *CertRequirement) GetOrganizationalUnits() []string {
+	if m != nil {
+		return m.OrganizationalUnits
+	}
+	return nil
+}
+
+// / JwtRequirement verifies a JSON Web Token passed in a header of the request.
+type JwtRequirement struct {
+	// / header is the name of the HTTP header carrying the token as "Bearer <token>".
+	// / This defaults to "Authorization". Forward proxy clients may prefer "Proxy-Authorization", which isn't passed on.
+	Header string `protobuf:"bytes,1,opt,name=header" json:"header,omitempty"`
+	// Types that are valid to be assigned to Key:
+	//	*JwtRequirement_PublicKeyFile
+	//	*JwtRequirement_HmacSecretFile
+	Key isJwtRequirement_Key `protobuf_oneof:"key"`
+	// / issuer, if present, needs to match the "iss" claim.
+	Issuer string `protobuf:"bytes,4,opt,name=issuer" json:"issuer,omitempty"`
+	// / audience, if present, needs to be one of the "aud" claim values.
+	Audience string `protobuf:"bytes,5,opt,name=audience" json:"audience,omitempty"`
+	// / claims are required string claims of the token, matched by equality. Claims with a list of strings need to
+	// / contain the value.
+	Claims map[string]string `protobuf:"bytes,6,rep,name=claims" json:"claims,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+}
+
+func (m *JwtRequirement) Reset()                    { *m = JwtRequirement{} }
+func (m *JwtRequirement) String() string            { return proto.CompactTextString(m) }
+func (*JwtRequirement) ProtoMessage()               {}
+func (*JwtRequirement) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{2} }
+
+type isJwtRequirement_Key interface {
+	isJwtRequirement_Key()
+}
+
+type JwtRequirement_PublicKeyFile struct {
+	PublicKeyFile string `protobuf:"bytes,2,opt,name=public_key_file,json=publicKeyFile,oneof"`
+}
+type JwtRequirement_HmacSecretFile struct {
+	HmacSecretFile string `protobuf:"bytes,3,opt,name=hmac_secret_file,json=hmacSecretFile,oneof"`
+}
+
+func (*JwtRequirement_PublicKeyFile) isJwtRequirement_Key()  {}
+func (*JwtRequirement_HmacSecretFile) isJwtRequirement_Key() {}
+
+func (m *JwtRequirement) GetKey() isJwtRequirement_Key {
+	if m != nil {
+		return m.Key
+	}
+	return nil
+}
+
+func (m *JwtRequirement) GetHeader() string {
+	if m != nil {
+		return m.Header
+	}
+	return ""
+}
+
+func (m *JwtRequirement) GetPublicKeyFile() string {
+	if x, ok := m.GetKey().(*JwtRequirement_PublicKeyFile); ok {
+		return x.PublicKeyFile
+	}
+	return ""
+}
+
+func (m *JwtRequirement) GetHmacSecretFile() string {
+	if x, ok := m.GetKey().(*JwtRequirement_HmacSecretFile); ok {
+		return x.HmacSecretFile
+	}
+	return ""
+}
+
+func (m *JwtRequirement) GetIssuer() string {
+	if m != nil {
+		return m.Issuer
+	}
+	return ""
+}
+
+func (m *JwtRequirement) GetAudience() string {
+	if m != nil {
+		return m.Audience
+	}
+	return ""
+}
+
+func (m *JwtRequirement) GetClaims() map[string]string {
+	if m != nil {
+		return m.Claims
+	}
+	return nil
+}
+
+// XXX_OneofFuncs is for the internal use of the proto package.
+func (*JwtRequirement) XXX_OneofFuncs() (func(msg proto.Message, b *proto.Buffer) error, func(msg proto.Message, tag, wire int, b *proto.Buffer) (bool, error), func(msg proto.Message) (n int), []interface{}) {
+	return _JwtRequirement_OneofMarshaler, _JwtRequirement_OneofUnmarshaler, _JwtRequirement_OneofSizer, []interface{}{
+		(*JwtRequirement_PublicKeyFile)(nil),
+		(*JwtRequirement_HmacSecretFile)(nil),
+	}
+}
+
+func _JwtRequirement_OneofMarshaler(msg proto.Message, b *proto.Buffer) error {
+	m := msg.(*JwtRequirement)
+	// key
+	switch x := m.Key.(type) {
+	case *JwtRequirement_PublicKeyFile:
+		b.EncodeVarint(2<<3 | proto.WireBytes)
+		b.EncodeStringBytes(x.PublicKeyFile)
+	case *JwtRequirement_HmacSecretFile:
+		b.EncodeVarint(3<<3 | proto.WireBytes)
+		b.EncodeStringBytes(x.HmacSecretFile)
+	case nil:
+	default:
+		return fmt.Errorf("JwtRequirement.Key has unexpected type %T", x)
+	}
+	return nil
+}
+
+func _JwtRequirement_OneofUnmarshaler(msg proto.Message, tag, wire int, b *proto.Buffer) (bool, error) {
+	m := msg.(*JwtRequirement)
+	switch tag {
+	case 2: // key.public_key_file
+		if wire != proto.WireBytes {
+			return true, proto.ErrInternalBadWireType
+		}
+		x, err := b.DecodeStringBytes()
+		m.Key = &JwtRequirement_PublicKeyFile{x}
+		return true, err
+	case 3: // key.hmac_secret_file
+		if wire != proto.WireBytes {
+			return true, proto.ErrInternalBadWireType
+		}
+		x, err := b.DecodeStringBytes()
+		m.Key = &JwtRequirement_HmacSecretFile{x}
+		return true, err
+	default:
+		return false, nil
+	}
+}
+
+func _JwtRequirement_OneofSizer(msg proto.Message) (n int) {
+	m := msg.(*JwtRequirement)
+	// key
+	switch x := m.Key.(type) {
+	case *JwtRequirement_PublicKeyFile:
+		n += proto.SizeVarint(2<<3 | proto.WireBytes)
+		n += proto.SizeVarint(uint64(len(x.PublicKeyFile)))
+		n += len(x.PublicKeyFile)
+	case *JwtRequirement_HmacSecretFile:
+		n += proto.SizeVarint(3<<3 | proto.WireBytes)
+		n += proto.SizeVarint(uint64(len(x.HmacSecretFile)))
+		n += len(x.HmacSecretFile)
+	case nil:
+	default:
+		panic(fmt.Sprintf("proto: unexpected type %T in oneof", x))
+	}
+	return n
+}
+
+func init() {
+	proto.RegisterType((*ClientAuthorization)(nil), "kedge.config.common.auth.ClientAuthorization")
+	proto.RegisterType((*CertRequirement)(nil), "kedge.config.common.auth.CertRequirement")
+	proto.RegisterType((*JwtRequirement)(nil), "kedge.config.common.auth.JwtRequirement")
+}
+
+func init() { proto.RegisterFile("kedge/config/common/auth/auth.proto", fileDescriptor0) }
+
+var fileDescriptor0 = []byte{
+	// 397 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x09, 0x6e, 0x88, 0x02, 0xff, 0x8c, 0x92, 0x4f, 0xab, 0xd3, 0x40,
+	0x14, 0xc5, 0x4d, 0xf2, 0x1a, 0xec, 0x8d, 0xef, 0x0f, 0xf3, 0x1e, 0x8f, 0xe1, 0xad, 0x6a, 0x75,
+	0x11, 0x5d, 0xa4, 0xf8, 0x74, 0xa1, 0x05, 0x17, 0x5a, 0x14, 0x51, 0x71, 0x11, 0x71, 0x1d, 0xa6,
+	0xd3, 0xdb, 0x66, 0x6c, 0x32, 0x53, 0x27, 0x33, 0x96, 0xf8, 0x1d, 0x04, 0x3f, 0x93, 0x9f, 0x4c,
+	0x32, 0x13, 0xa4, 0x11, 0x0a, 0x6e, 0x42, 0xce, 0x39, 0xbf, 0x73, 0x93, 0x3b, 0x09, 0x3c, 0xd8,
+	0xe2, 0x6a, 0x83, 0x33, 0xae, 0xe4, 0x5a, 0x6c, 0x66, 0x5c, 0xd5, 0xb5, 0x92, 0x33, 0x66, 0x4d,
+	0xe9, 0x2e, 0xd9, 0x4e, 0x2b, 0xa3, 0x08, 0x75, 0x50, 0xe6, 0xa1, 0xcc, 0x43, 0x59, 0x97, 0x4f,
+	0x7f, 0x05, 0x70, 0xb9, 0xa8, 0x04, 0x4a, 0xf3, 0xca, 0x9a, 0x52, 0x69, 0xf1, 0x83, 0x19, 0xa1,
+	0x24, 0x79, 0x09, 0x27, 0x1c, 0xb5, 0xa1, 0xc1, 0x24, 0x48, 0x93, 0xdb, 0x47, 0xd9, 0xb1, 0x01,
+	0xd9, 0x02, 0xb5, 0xc9, 0xf1, 0x9b, 0x15, 0x1a, 0x6b, 0x94, 0x26, 0x77, 0x35, 0x32, 0x87, 0xe8,
+	0xeb, 0xde, 0xd0, 0xd0, 0xb5, 0xd3, 0xe3, 0xed, 0xf7, 0xfb, 0x41, 0xb9, 0x2b, 0x4d, 0x7f, 0x06,
+	0x70, 0xfe, 0xcf, 0x54, 0x72, 0x1f, 0xee, 0xf9, 0x5a, 0x21, 0x59, 0x8d, 0x0d, 0x0d, 0x26, 0x51,
+	0x3a, 0xce, 0x13, 0xef, 0x7d, 0xea, 0x2c, 0xf2, 0x10, 0x4e, 0x95, 0xde, 0x30, 0xd9, 0x6f, 0xd0,
+	0xd0, 0xd0, 0x31, 0x43, 0x93, 0x3c, 0x81, 0xab, 0x43, 0x83, 0x55, 0x85, 0x95, 0xc2, 0x34, 0x34,
+	0x72, 0xf0, 0xe5, 0x30, 0xfb, 0xd2, 0x45, 0xd3, 0xdf, 0x21, 0x9c, 0x0d, 0xdf, 0x93, 0x5c, 0x43,
+	0x5c, 0x22, 0x5b, 0xa1, 0x76, 0xe7, 0x33, 0xce, 0x7b, 0x45, 0x52, 0x38, 0xdf, 0xd9, 0x65, 0x25,
+	0x78, 0xb1, 0xc5, 0xb6, 0x58, 0x8b, 0x0a, 0xdd, 0x11, 0x8c, 0xdf, 0xdd, 0xc9, 0x4f, 0x7d, 0xf0,
+	0x01, 0xdb, 0xb7, 0xa2, 0x42, 0xf2, 0x18, 0x2e, 0xca, 0x9a, 0xf1, 0xa2, 0x41, 0xae, 0xd1, 0x78,
+	0x34, 0xea, 0xd1, 0xb3, 0x2e, 0xf9, 0xec, 0x02, 0xc7, 0x5e, 0x43, 0x2c, 0x9a, 0xc6, 0xa2, 0xa6,
+	0x27, 0xfe, 0x69, 0x5e, 0x91, 0x1b, 0xb8, 0xcb, 0xec, 0x4a, 0xa0, 0xe4, 0x48, 0x47, 0x2e, 0xf9,
+	0xab, 0xc9, 0x47, 0x88, 0x79, 0xc5, 0x44, 0xdd, 0xd0, 0x78, 0x12, 0xa5, 0xc9, 0xed, 0xb3, 0xff,
+	0xfd, 0x06, 0xd9, 0xc2, 0xd5, 0xde, 0x48, 0xa3, 0xdb, 0xbc, 0x9f, 0x71, 0xf3, 0x02, 0x92, 0x03,
+	0x9b, 0x5c, 0x40, 0xb4, 0xc5, 0xb6, 0xdf, 0xbd, 0xbb, 0x25, 0x57, 0x30, 0xfa, 0xce, 0x2a, 0xdb,
+	0xaf, 0x9b, 0x7b, 0x31, 0x0f, 0x9f, 0x07, 0xaf, 0x47, 0x8e, 0x5d, 0xc6, 0xee, 0x47, 0x7c, 0xfa,
+	0x27, 0x00, 0x00, 0xff, 0xff, 0xab, 0x1f, 0x79, 0xcc, 0xaf, 0x02, 0x00, 0x00,
+}
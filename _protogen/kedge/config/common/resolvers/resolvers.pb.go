@@ -11,6 +11,11 @@ It is generated from these files:
 It has these top-level messages:
 	SrvResolver
 	KubeResolver
+	DnsResolver
+	StaticResolver
+	StaticAddress
+	FileResolver
+	ConsulResolver
 */
 package kedge_config_common_resolvers
 
@@ -34,6 +39,9 @@ type SrvResolver struct {
 	// / dns_name specifies the address to look up using DNS SRV. Needs to be a FQDN.
 	// / E.g. "_grpc._tcp.someservice.somenamespace.svc.cluster.local"
 	DnsName string `protobuf:"bytes,2,opt,name=dns_name,json=dnsName" json:"dns_name,omitempty"`
+	// / zone_pattern is a regular expression matched against the host names of SRV targets, whose first group is the
+	// / zone of the target. E.g. `^[^.]+\.([^.]+)\.` for targets like "pod-1.us-east1-b.example.com".
+	ZonePattern string `protobuf:"bytes,3,opt,name=zone_pattern,json=zonePattern" json:"zone_pattern,omitempty"`
 }
 
 func (m *SrvResolver) Reset()                    { *m = SrvResolver{} }
@@ -48,6 +56,13 @@ func (m *SrvResolver) GetDnsName() string {
 	return ""
 }
 
+func (m *SrvResolver) GetZonePattern() string {
+	if m != nil {
+		return m.ZonePattern
+	}
+	return ""
+}
+
 // / KubeResolver uses the Kubernetes Endpoints API to identify the service.
 // / It requires the job to run inside a K8S pod and uses the pod's credentails to fetch the service information.
 type KubeResolver struct {
@@ -58,6 +73,9 @@ type KubeResolver struct {
 	ServiceName string `protobuf:"bytes,2,opt,name=service_name,json=serviceName" json:"service_name,omitempty"`
 	// / port_name is the name of the port to bind in the service.
 	PortName string `protobuf:"bytes,3,opt,name=port_name,json=portName" json:"port_name,omitempty"`
+	// / endpoint_slices resolves the service using the EndpointSlice API instead, which also provides the zones of the
+	// / targets. Only ready endpoints are used.
+	EndpointSlices bool `protobuf:"varint,4,opt,name=endpoint_slices,json=endpointSlices" json:"endpoint_slices,omitempty"`
 }
 
 func (m *KubeResolver) Reset()                    { *m = KubeResolver{} }
@@ -86,25 +104,199 @@ func (m *KubeResolver) GetPortName() string {
 	return ""
 }
 
+func (m *KubeResolver) GetEndpointSlices() bool {
+	if m != nil {
+		return m.EndpointSlices
+	}
+	return false
+}
+
+// / DnsResolver uses the A and AAAA records of a host name, looking them up again when their TTL expires.
+type DnsResolver struct {
+	// / host_name is the name to look up. Needs to be a FQDN, e.g. "api.someservice.example.com".
+	HostName string `protobuf:"bytes,1,opt,name=host_name,json=hostName" json:"host_name,omitempty"`
+	// / port is the port of the targets.
+	Port uint32 `protobuf:"varint,2,opt,name=port" json:"port,omitempty"`
+}
+
+func (m *DnsResolver) Reset()                    { *m = DnsResolver{} }
+func (m *DnsResolver) String() string            { return proto.CompactTextString(m) }
+func (*DnsResolver) ProtoMessage()               {}
+func (*DnsResolver) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{2} }
+
+func (m *DnsResolver) GetHostName() string {
+	if m != nil {
+		return m.HostName
+	}
+	return ""
+}
+
+func (m *DnsResolver) GetPort() uint32 {
+	if m != nil {
+		return m.Port
+	}
+	return 0
+}
+
+// / StaticResolver uses a fixed list of addresses, e.g. for local development or external endpoints without SRV records.
+// / Host names in the addresses are resolved using A and AAAA records, and resolved again periodically.
+type StaticResolver struct {
+	Addresses []*StaticAddress `protobuf:"bytes,1,rep,name=addresses" json:"addresses,omitempty"`
+}
+
+func (m *StaticResolver) Reset()                    { *m = StaticResolver{} }
+func (m *StaticResolver) String() string            { return proto.CompactTextString(m) }
+func (*StaticResolver) ProtoMessage()               {}
+func (*StaticResolver) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{3} }
+
+func (m *StaticResolver) GetAddresses() []*StaticAddress {
+	if m != nil {
+		return m.Addresses
+	}
+	return nil
+}
+
+// / StaticAddress is a single entry of a StaticResolver.
+type StaticAddress struct {
+	// / address is the `host:port` of the target, e.g. "10.0.0.1:8080" or "api.example.com:443".
+	Address string `protobuf:"bytes,1,opt,name=address" json:"address,omitempty"`
+	// / weight is the share of traffic of the target among the others, at most 65535. Targets of weight 0 only get traffic
+	// / if all of them have a weight of 0. Every address a host name resolves to gets its weight.
+	Weight uint32 `protobuf:"varint,2,opt,name=weight" json:"weight,omitempty"`
+	// / zone is the availability zone of the target, see `locality` of backends.
+	Zone string `protobuf:"bytes,3,opt,name=zone" json:"zone,omitempty"`
+}
+
+func (m *StaticAddress) Reset()                    { *m = StaticAddress{} }
+func (m *StaticAddress) String() string            { return proto.CompactTextString(m) }
+func (*StaticAddress) ProtoMessage()               {}
+func (*StaticAddress) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{4} }
+
+func (m *StaticAddress) GetAddress() string {
+	if m != nil {
+		return m.Address
+	}
+	return ""
+}
+
+func (m *StaticAddress) GetWeight() uint32 {
+	if m != nil {
+		return m.Weight
+	}
+	return 0
+}
+
+func (m *StaticAddress) GetZone() string {
+	if m != nil {
+		return m.Zone
+	}
+	return ""
+}
+
+// / FileResolver reads the targets from a JSON file, reloading it when its content changes. The file contains a list of
+// / StaticAddresses, e.g. `[{"address": "10.0.0.1:8080", "weight": 10, "zone": "us-east1-b"}]`. Only JSON is
+// / supported, files in other formats (e.g. YAML) are rejected.
+// / Host names in the addresses are resolved like the ones of a StaticResolver.
+type FileResolver struct {
+	// / path of the file. It is replaced atomically (e.g. by renaming) by whatever writes it.
+	Path string `protobuf:"bytes,1,opt,name=path" json:"path,omitempty"`
+}
+
+func (m *FileResolver) Reset()                    { *m = FileResolver{} }
+func (m *FileResolver) String() string            { return proto.CompactTextString(m) }
+func (*FileResolver) ProtoMessage()               {}
+func (*FileResolver) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{5} }
+
+func (m *FileResolver) GetPath() string {
+	if m != nil {
+		return m.Path
+	}
+	return ""
+}
+
+// / ConsulResolver uses the instances of a service that pass their health checks in Consul, watched using blocking
+// / queries of the health API. Weights of instances come from their passing weight.
+type ConsulResolver struct {
+	// / address is the URL of the Consul agent, e.g. "http://127.0.0.1:8500".
+	Address string `protobuf:"bytes,1,opt,name=address" json:"address,omitempty"`
+	// / service is the name of the service in the catalog.
+	Service string `protobuf:"bytes,2,opt,name=service" json:"service,omitempty"`
+	// / tag limits the instances to the ones with the tag, if set.
+	Tag string `protobuf:"bytes,3,opt,name=tag" json:"tag,omitempty"`
+	// / datacenter to use instead of the one of the agent, if set.
+	Datacenter string `protobuf:"bytes,4,opt,name=datacenter" json:"datacenter,omitempty"`
+}
+
+func (m *ConsulResolver) Reset()                    { *m = ConsulResolver{} }
+func (m *ConsulResolver) String() string            { return proto.CompactTextString(m) }
+func (*ConsulResolver) ProtoMessage()               {}
+func (*ConsulResolver) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{6} }
+
+func (m *ConsulResolver) GetAddress() string {
+	if m != nil {
+		return m.Address
+	}
+	return ""
+}
+
+func (m *ConsulResolver) GetService() string {
+	if m != nil {
+		return m.Service
+	}
+	return ""
+}
+
+func (m *ConsulResolver) GetTag() string {
+	if m != nil {
+		return m.Tag
+	}
+	return ""
+}
+
+func (m *ConsulResolver) GetDatacenter() string {
+	if m != nil {
+		return m.Datacenter
+	}
+	return ""
+}
+
 func init() {
 	proto.RegisterType((*SrvResolver)(nil), "kedge.config.common.resolvers.SrvResolver")
 	proto.RegisterType((*KubeResolver)(nil), "kedge.config.common.resolvers.KubeResolver")
+	proto.RegisterType((*DnsResolver)(nil), "kedge.config.common.resolvers.DnsResolver")
+	proto.RegisterType((*StaticResolver)(nil), "kedge.config.common.resolvers.StaticResolver")
+	proto.RegisterType((*StaticAddress)(nil), "kedge.config.common.resolvers.StaticAddress")
+	proto.RegisterType((*FileResolver)(nil), "kedge.config.common.resolvers.FileResolver")
+	proto.RegisterType((*ConsulResolver)(nil), "kedge.config.common.resolvers.ConsulResolver")
 }
 
 func init() { proto.RegisterFile("kedge/config/common/resolvers/resolvers.proto", fileDescriptor0) }
 
 var fileDescriptor0 = []byte{
-	// 178 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x09, 0x6e, 0x88, 0x02, 0xff, 0x4c, 0xce, 0xb1, 0xce, 0x82, 0x30,
-	0x14, 0x05, 0xe0, 0xf0, 0xff, 0x89, 0xc2, 0x85, 0xa9, 0x13, 0x46, 0x4d, 0x94, 0x89, 0xc5, 0x32,
-	0xf8, 0x18, 0x26, 0x0e, 0xf8, 0x00, 0x06, 0xca, 0x95, 0x10, 0x69, 0x2f, 0x69, 0x91, 0xe7, 0x37,
-	0x6d, 0x09, 0xb8, 0xb5, 0xe7, 0x7c, 0x39, 0xb9, 0x70, 0x79, 0x63, 0xd3, 0x62, 0x21, 0x48, 0xbd,
-	0xba, 0xb6, 0x10, 0x24, 0x25, 0xa9, 0x42, 0xa3, 0xa1, 0x7e, 0x42, 0x6d, 0xd6, 0x17, 0x1f, 0x34,
-	0x8d, 0xc4, 0x8e, 0x8e, 0x73, 0xcf, 0xb9, 0xe7, 0x7c, 0x41, 0x59, 0x0e, 0xf1, 0x43, 0x4f, 0xe5,
-	0xfc, 0x67, 0x3b, 0x08, 0x1b, 0x65, 0x9e, 0xaa, 0x92, 0x98, 0xfe, 0x9d, 0x82, 0x3c, 0x2a, 0xb7,
-	0x8d, 0x32, 0xf7, 0x4a, 0x62, 0xd6, 0x43, 0x72, 0xfb, 0xd4, 0xb8, 0xd0, 0x03, 0x44, 0x96, 0x99,
-	0xa1, 0x12, 0x98, 0x06, 0xce, 0xae, 0x01, 0x3b, 0x43, 0x62, 0x50, 0x4f, 0x9d, 0xc0, 0xdf, 0xb1,
-	0x78, 0xce, 0xec, 0x20, 0xdb, 0x43, 0x34, 0x90, 0x1e, 0x7d, 0xff, 0xef, 0xfa, 0xd0, 0x06, 0xb6,
-	0xac, 0x37, 0xee, 0xfa, 0xeb, 0x37, 0x00, 0x00, 0xff, 0xff, 0xf3, 0x1f, 0xea, 0x5a, 0xee, 0x00,
-	0x00, 0x00,
+	// 391 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x09, 0x6e, 0x88, 0x02, 0xff, 0x7c, 0x52, 0x4d, 0xab, 0xd3, 0x40,
+	0x14, 0x25, 0xb6, 0xb4, 0xc9, 0x4d, 0x5b, 0x65, 0x16, 0x12, 0xa9, 0x4a, 0x9d, 0x8d, 0x5d, 0x68,
+	0x0a, 0xba, 0x17, 0x44, 0x71, 0x61, 0x41, 0x24, 0xc5, 0x9d, 0x50, 0xa6, 0xc9, 0x35, 0x0d, 0x26,
+	0x33, 0x61, 0x66, 0x9a, 0x07, 0xef, 0x7f, 0xbc, 0xff, 0xfb, 0x98, 0x8f, 0x24, 0x7d, 0x8b, 0xf7,
+	0x76, 0xf7, 0x9e, 0x7b, 0xee, 0x39, 0x27, 0xb9, 0x03, 0x1f, 0xff, 0x63, 0x51, 0xe2, 0x2e, 0x17,
+	0xfc, 0x5f, 0x55, 0xee, 0x72, 0xd1, 0x34, 0x82, 0xef, 0x24, 0x2a, 0x51, 0x77, 0x28, 0xd5, 0x58,
+	0xa5, 0xad, 0x14, 0x5a, 0x90, 0x37, 0x96, 0x9e, 0x3a, 0x7a, 0xea, 0xe8, 0xe9, 0x40, 0xa2, 0x7b,
+	0x88, 0x0f, 0xb2, 0xcb, 0x7c, 0x4f, 0x5e, 0x41, 0x58, 0x70, 0x75, 0xe4, 0xac, 0xc1, 0xe4, 0xd9,
+	0x26, 0xd8, 0x46, 0xd9, 0xbc, 0xe0, 0xea, 0x17, 0x6b, 0x90, 0xbc, 0x83, 0xc5, 0xad, 0xe0, 0x78,
+	0x6c, 0x99, 0xd6, 0x28, 0x79, 0x32, 0xb1, 0xe3, 0xd8, 0x60, 0xbf, 0x1d, 0x44, 0xef, 0x02, 0x58,
+	0xec, 0x2f, 0x27, 0x1c, 0xe4, 0x5e, 0x43, 0x64, 0xa4, 0x54, 0xcb, 0x72, 0x4c, 0x02, 0xbb, 0x30,
+	0x02, 0x46, 0x51, 0xa1, 0xec, 0xaa, 0x1c, 0xaf, 0x0d, 0x63, 0x8f, 0x59, 0xd3, 0x35, 0x44, 0xad,
+	0x90, 0xda, 0xcd, 0x9d, 0x63, 0x68, 0x00, 0x3b, 0x7c, 0x0f, 0xcf, 0x91, 0x17, 0xad, 0xa8, 0xb8,
+	0x3e, 0xaa, 0xba, 0xca, 0x51, 0x25, 0xd3, 0x4d, 0xb0, 0x0d, 0xb3, 0x55, 0x0f, 0x1f, 0x2c, 0x4a,
+	0xbf, 0x40, 0xfc, 0x9d, 0xab, 0x21, 0xd5, 0x1a, 0xa2, 0xb3, 0x50, 0x5e, 0xd4, 0xa5, 0x0a, 0x0d,
+	0x60, 0x45, 0x09, 0x4c, 0x8d, 0x81, 0x0d, 0xb3, 0xcc, 0x6c, 0x4d, 0xff, 0xc2, 0xea, 0xa0, 0x99,
+	0xae, 0xf2, 0x41, 0xe2, 0x27, 0x44, 0xac, 0x28, 0x24, 0x2a, 0x85, 0x2a, 0x09, 0x36, 0x93, 0x6d,
+	0xfc, 0xe9, 0x43, 0xfa, 0xe4, 0x9f, 0x4e, 0x9d, 0xc2, 0x57, 0xb7, 0x95, 0x8d, 0xeb, 0xf4, 0x0f,
+	0x2c, 0x1f, 0xcc, 0x48, 0x02, 0x73, 0x3f, 0xf5, 0xe9, 0xfa, 0x96, 0xbc, 0x84, 0xd9, 0x0d, 0x56,
+	0xe5, 0xb9, 0x8f, 0xe7, 0x3b, 0x13, 0xda, 0xdc, 0xc1, 0xff, 0x21, 0x5b, 0x53, 0x0a, 0x8b, 0x1f,
+	0x55, 0x3d, 0xde, 0xc2, 0x7c, 0x18, 0xd3, 0x67, 0x2f, 0x69, 0x6b, 0xda, 0xc1, 0xea, 0x9b, 0xe0,
+	0xea, 0x52, 0x0f, 0xac, 0xc7, 0xbd, 0x13, 0x98, 0xfb, 0xcb, 0xf4, 0x2f, 0xc3, 0xb7, 0xe4, 0x05,
+	0x4c, 0x34, 0x2b, 0xbd, 0xb9, 0x29, 0xc9, 0x5b, 0x80, 0x82, 0x69, 0x96, 0x23, 0xd7, 0x28, 0xed,
+	0x51, 0xa2, 0xec, 0x0a, 0x39, 0xcd, 0xec, 0xdb, 0xfc, 0x7c, 0x1f, 0x00, 0x00, 0xff, 0xff, 0xdf,
+	0x96, 0xa6, 0x75, 0xcc, 0x02, 0x00, 0x00,
 }
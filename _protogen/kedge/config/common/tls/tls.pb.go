@@ -0,0 +1,107 @@
+// Code generated by protoc-gen-go.
+// source: kedge/config/common/tls/tls.proto
+// DO NOT EDIT!
+
+/*
+Package kedge_config_common_tls is a generated protocol buffer package.
+
+It is generated from these files:
+	kedge/config/common/tls/tls.proto
+
+It has these top-level messages:
+	TlsClientConfig
+*/
+package kedge_config_common_tls
+
+import proto "github.com/golang/protobuf/proto"
+import fmt "fmt"
+import math "math"
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ = proto.Marshal
+var _ = fmt.Errorf
+var _ = math.Inf
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the proto package it is being compiled against.
+// A compilation error at this line likely means your copy of the
+// proto package needs to be updated.
+const _ = proto.ProtoPackageIsVersion2 // please upgrade the proto package
+
+// / TlsClientConfig is a named TLS config used by kedge to dial its destinations over TLS.
+type TlsClientConfig struct {
+	// / name is the string identifying the config in other configs.
+	Name string `protobuf:"bytes,1,opt,name=name" json:"name,omitempty"`
+	// / root_ca_files are PEM files with the CAs verifying the destinations. If none are present, the system roots are used.
+	RootCaFiles []string `protobuf:"bytes,2,rep,name=root_ca_files,json=rootCaFiles" json:"root_ca_files,omitempty"`
+	// / cert_file and key_file are PEM files with the client certificate presented to the destinations, if any.
+	CertFile string `protobuf:"bytes,3,opt,name=cert_file,json=certFile" json:"cert_file,omitempty"`
+	KeyFile  string `protobuf:"bytes,4,opt,name=key_file,json=keyFile" json:"key_file,omitempty"`
+	// / insecure_skip_verify skips the server certificate verification completely.
+	// / This should *not* be used in production software.
+	InsecureSkipVerify bool `protobuf:"varint,5,opt,name=insecure_skip_verify,json=insecureSkipVerify" json:"insecure_skip_verify,omitempty"`
+}
+
+func (m *TlsClientConfig) Reset()                    { *m = TlsClientConfig{} }
+func (m *TlsClientConfig) String() string            { return proto.CompactTextString(m) }
+func (*TlsClientConfig) ProtoMessage()               {}
+func (*TlsClientConfig) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{0} }
+
+func (m *TlsClientConfig) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *TlsClientConfig) GetRootCaFiles() []string {
+	if m != nil {
+		return m.RootCaFiles
+	}
+	return nil
+}
+
+func (m *TlsClientConfig) GetCertFile() string {
+	if m != nil {
+		return m.CertFile
+	}
+	return ""
+}
+
+func (m *TlsClientConfig) GetKeyFile() string {
+	if m != nil {
+		return m.KeyFile
+	}
+	return ""
+}
+
+func (m *TlsClientConfig) GetInsecureSkipVerify() bool {
+	if m != nil {
+		return m.InsecureSkipVerify
+	}
+	return false
+}
+
+func init() {
+	proto.RegisterType((*TlsClientConfig)(nil), "kedge.config.common.tls.TlsClientConfig")
+}
+
+func init() { proto.RegisterFile("kedge/config/common/tls/tls.proto", fileDescriptor0) }
+
+var fileDescriptor0 = []byte{
+	// 209 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x09, 0x6e, 0x88, 0x02, 0xff, 0x34, 0xcf, 0xd1, 0x4a, 0xc5, 0x20,
+	0x1c, 0xc7, 0x71, 0x6c, 0xab, 0x36, 0x23, 0x02, 0x09, 0x32, 0xba, 0x59, 0xbb, 0xda, 0x95, 0x0b,
+	0x7a, 0x84, 0x41, 0x0f, 0xb0, 0xa2, 0x5b, 0x59, 0xf6, 0xdf, 0x10, 0x9d, 0x0e, 0xb5, 0x60, 0xcf,
+	0x75, 0x5e, 0xf0, 0xe0, 0x7f, 0x9c, 0x0b, 0x41, 0x7e, 0x9f, 0xaf, 0x17, 0xd2, 0x57, 0x03, 0xbf,
+	0x0b, 0xf4, 0xca, 0xbb, 0x59, 0x2f, 0xbd, 0xf2, 0xeb, 0xea, 0x5d, 0x9f, 0x6c, 0xcc, 0x47, 0x6c,
+	0xc1, 0x27, 0xcf, 0x9e, 0x30, 0x11, 0x47, 0x22, 0x8e, 0x44, 0x24, 0x1b, 0xdb, 0x13, 0xa1, 0x0f,
+	0x5f, 0x36, 0x0e, 0x56, 0x83, 0x4b, 0x03, 0x32, 0x63, 0xb4, 0x74, 0xd3, 0x0a, 0x9c, 0x34, 0xa4,
+	0xab, 0x47, 0xbc, 0xb3, 0x96, 0xde, 0x07, 0xef, 0x93, 0x54, 0x93, 0x9c, 0xb5, 0x85, 0xc8, 0xaf,
+	0x9a, 0xa2, 0xab, 0xc7, 0xbb, 0x3c, 0x0e, 0xd3, 0x47, 0x9e, 0xd8, 0x0b, 0xad, 0x15, 0x84, 0x84,
+	0x01, 0x2f, 0xf0, 0x71, 0x95, 0x87, 0xac, 0xec, 0x99, 0x56, 0x06, 0xf6, 0xc3, 0x4a, 0xb4, 0x5b,
+	0x03, 0x3b, 0xd2, 0x1b, 0x7d, 0xd4, 0x2e, 0x82, 0xfa, 0x0b, 0x20, 0xa3, 0xd1, 0x9b, 0xfc, 0x87,
+	0xa0, 0xe7, 0x9d, 0x5f, 0x37, 0xa4, 0xab, 0x46, 0x76, 0xb1, 0x4f, 0xa3, 0xb7, 0x6f, 0x94, 0x9f,
+	0x1b, 0xfc, 0xd5, 0xfb, 0x39, 0x00, 0x00, 0xff, 0xff, 0xb1, 0x1a, 0x8b, 0x85, 0xfa, 0x00, 0x00,
+	0x00,
+}
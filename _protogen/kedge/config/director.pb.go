@@ -7,6 +7,7 @@ package kedge_config
 import proto "github.com/golang/protobuf/proto"
 import fmt "fmt"
 import math "math"
+import  kedge_config_common_tls "github.com/mwitkow/kedge/_protogen/kedge/config/common/tls"
 import  kedge_config_grpc_routes "github.com/mwitkow/kedge/_protogen/kedge/config/grpc/routes"
 import  kedge_config_http_routes "github.com/mwitkow/kedge/_protogen/kedge/config/http/routes"
 import  kedge_config_http_routes1 "github.com/mwitkow/kedge/_protogen/kedge/config/http/routes"
@@ -43,6 +44,10 @@ func (m *DirectorConfig) GetHttp() *DirectorConfig_Http {
 
 type DirectorConfig_Grpc struct {
 	Routes []*kedge_config_grpc_routes.Route `protobuf:"bytes,1,rep,name=routes" json:"routes,omitempty"`
+	// / adhoc_rules allow calls that match no route to be sent to the host of their `:authority`.
+	// / They are the same rules as the HTTP ones. Note that gRPC clients usually don't send the port in the
+	// / `:authority`, so rules need a default port or SRV ports.
+	AdhocRules []*kedge_config_http_routes.Adhoc `protobuf:"bytes,2,rep,name=adhoc_rules,json=adhocRules" json:"adhoc_rules,omitempty"`
 }
 
 func (m *DirectorConfig_Grpc) Reset()                    { *m = DirectorConfig_Grpc{} }
@@ -57,9 +62,18 @@ func (m *DirectorConfig_Grpc) GetRoutes() []*kedge_config_grpc_routes.Route {
 	return nil
 }
 
+func (m *DirectorConfig_Grpc) GetAdhocRules() []*kedge_config_http_routes.Adhoc {
+	if m != nil {
+		return m.AdhocRules
+	}
+	return nil
+}
+
 type DirectorConfig_Http struct {
 	Routes     []*kedge_config_http_routes1.Route `protobuf:"bytes,1,rep,name=routes" json:"routes,omitempty"`
 	AdhocRules []*kedge_config_http_routes.Adhoc  `protobuf:"bytes,2,rep,name=adhoc_rules,json=adhocRules" json:"adhoc_rules,omitempty"`
+	// / tls_client_configs are the TLS configs that adhoc rules refer to by name.
+	TlsClientConfigs []*kedge_config_common_tls.TlsClientConfig `protobuf:"bytes,3,rep,name=tls_client_configs,json=tlsClientConfigs" json:"tls_client_configs,omitempty"`
 }
 
 func (m *DirectorConfig_Http) Reset()                    { *m = DirectorConfig_Http{} }
@@ -81,6 +95,13 @@ func (m *DirectorConfig_Http) GetAdhocRules() []*kedge_config_http_routes.Adhoc
 	return nil
 }
 
+func (m *DirectorConfig_Http) GetTlsClientConfigs() []*kedge_config_common_tls.TlsClientConfig {
+	if m != nil {
+		return m.TlsClientConfigs
+	}
+	return nil
+}
+
 func init() {
 	proto.RegisterType((*DirectorConfig)(nil), "kedge.config.DirectorConfig")
 	proto.RegisterType((*DirectorConfig_Grpc)(nil), "kedge.config.DirectorConfig.Grpc")
@@ -90,20 +111,24 @@ func init() {
 func init() { proto.RegisterFile("kedge/config/director.proto", fileDescriptor1) }
 
 var fileDescriptor1 = []byte{
-	// 234 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x09, 0x6e, 0x88, 0x02, 0xff, 0x8c, 0x90, 0xb1, 0x4a, 0xc0, 0x30,
-	0x14, 0x45, 0x49, 0x2d, 0x1d, 0x5e, 0xc5, 0x21, 0x53, 0x88, 0x83, 0x55, 0x2c, 0x74, 0x4a, 0xa1,
-	0x22, 0x8e, 0x2a, 0x0a, 0x3a, 0xe7, 0x07, 0x44, 0xd3, 0xd8, 0x16, 0xc5, 0x84, 0x34, 0xfd, 0x00,
-	0xbf, 0xcd, 0x1f, 0x93, 0x97, 0x46, 0x68, 0x40, 0xa9, 0x53, 0x86, 0x9c, 0x7b, 0xee, 0xe5, 0xc1,
-	0xf1, 0x9b, 0xee, 0x07, 0xdd, 0x2a, 0xf3, 0xf1, 0x3a, 0x0d, 0x6d, 0x3f, 0x39, 0xad, 0xbc, 0x71,
-	0xc2, 0x3a, 0xe3, 0x0d, 0x3d, 0x0c, 0x9f, 0x62, 0xfd, 0xe4, 0x75, 0x82, 0x0e, 0xce, 0xaa, 0xd6,
-	0x99, 0xc5, 0xeb, 0x39, 0x3e, 0x6b, 0x88, 0x9f, 0x27, 0xd8, 0xe8, 0xbd, 0xfd, 0xc1, 0x9e, 0xfb,
-	0xd1, 0xa8, 0x48, 0xd5, 0x7f, 0x52, 0x5b, 0xd9, 0xd9, 0x57, 0x06, 0x47, 0xf7, 0x71, 0xd4, 0x5d,
-	0x60, 0xe9, 0x25, 0xe4, 0xd8, 0xcd, 0x48, 0x45, 0x9a, 0xb2, 0x3b, 0x15, 0xdb, 0x8d, 0x22, 0x65,
-	0xc5, 0x83, 0xb3, 0x4a, 0x06, 0x1c, 0x63, 0xd8, 0xc2, 0xb2, 0x7f, 0xc4, 0x1e, 0xbd, 0xb7, 0x32,
-	0xe0, 0xfc, 0x1a, 0x72, 0x94, 0xd0, 0x2b, 0x28, 0xd6, 0x61, 0x8c, 0x54, 0x07, 0x4d, 0xd9, 0x9d,
-	0xa4, 0x02, 0xac, 0x10, 0x71, 0xb9, 0xc4, 0x47, 0x46, 0x9c, 0x7f, 0x12, 0xc8, 0xd1, 0xb7, 0x67,
-	0xc0, 0xb6, 0x5f, 0x0d, 0xf4, 0x06, 0xca, 0x70, 0xb9, 0x27, 0xb7, 0xbc, 0xeb, 0x99, 0x65, 0x7b,
-	0xe9, 0x5b, 0x84, 0x25, 0x84, 0x8c, 0xc4, 0xc8, 0x4b, 0x11, 0x8e, 0x79, 0xf1, 0x1d, 0x00, 0x00,
-	0xff, 0xff, 0xcd, 0x16, 0x20, 0x3b, 0xed, 0x01, 0x00, 0x00,
+	// 295 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x09, 0x6e, 0x88, 0x02, 0xff, 0xac, 0x92, 0x41, 0x4b, 0xc3, 0x30,
+	0x18, 0x86, 0xe9, 0x5a, 0x76, 0x48, 0x45, 0x24, 0xa7, 0x52, 0x0f, 0x6e, 0xa2, 0xd0, 0x53, 0x0a,
+	0x13, 0xf1, 0xaa, 0x4c, 0xd0, 0x73, 0x10, 0xaf, 0x45, 0xd3, 0xd8, 0x15, 0xbb, 0x26, 0x24, 0xdf,
+	0x7e, 0x80, 0x3f, 0xd3, 0x8b, 0xbf, 0x45, 0xbe, 0x24, 0x83, 0x06, 0x06, 0x7a, 0xd8, 0xa1, 0x04,
+	0x9a, 0xe7, 0x79, 0xbf, 0x37, 0x6d, 0xc8, 0xf9, 0xa7, 0x6c, 0x3b, 0x59, 0x0b, 0x35, 0x7e, 0xf4,
+	0x5d, 0xdd, 0xf6, 0x46, 0x0a, 0x50, 0x86, 0x69, 0xa3, 0x40, 0xd1, 0x13, 0xb7, 0xc9, 0xfc, 0x66,
+	0xb9, 0x8c, 0x50, 0xa1, 0xb6, 0x5b, 0x35, 0xd6, 0x30, 0x58, 0x7c, 0xbc, 0x50, 0x5e, 0x47, 0x48,
+	0x67, 0xb4, 0xa8, 0x8d, 0xda, 0x81, 0xb4, 0x61, 0x09, 0xd8, 0x55, 0x84, 0x6d, 0x00, 0xf4, 0x1e,
+	0x7b, 0x6b, 0x37, 0x4a, 0x1c, 0x0c, 0x9b, 0x52, 0xd3, 0xb0, 0xcb, 0x9f, 0x94, 0x9c, 0x3e, 0x86,
+	0xde, 0x6b, 0xc7, 0xd2, 0x5b, 0x92, 0xe1, 0xec, 0x22, 0x59, 0x24, 0x55, 0xbe, 0x5a, 0xb2, 0xe9,
+	0x31, 0x58, 0xcc, 0xb2, 0x27, 0xa3, 0x05, 0x77, 0x38, 0x6a, 0x38, 0xa5, 0x98, 0xfd, 0x43, 0x7b,
+	0x06, 0xd0, 0xdc, 0xe1, 0xe5, 0x57, 0x42, 0x32, 0x4c, 0xa1, 0x77, 0x64, 0xee, 0x9b, 0x15, 0xc9,
+	0x22, 0xad, 0xf2, 0xd5, 0x45, 0x9c, 0x80, 0x33, 0x58, 0xa8, 0xce, 0x71, 0xe1, 0x01, 0xa7, 0xf7,
+	0x24, 0x77, 0x07, 0x6f, 0xcc, 0x6e, 0x90, 0xb6, 0x98, 0x1d, 0xb2, 0x71, 0xd4, 0xde, 0x7e, 0x40,
+	0x98, 0x13, 0xe7, 0x70, 0x54, 0xca, 0xef, 0x84, 0x64, 0x58, 0xe9, 0xaf, 0x0e, 0xd3, 0x94, 0x23,
+	0x77, 0xa0, 0xaf, 0x84, 0xc2, 0x60, 0x1b, 0x31, 0xf4, 0x72, 0x84, 0xc6, 0x2b, 0xb6, 0x48, 0x5d,
+	0x50, 0x15, 0x07, 0xf9, 0xcb, 0xc3, 0xf0, 0xe2, 0xbc, 0x0c, 0x76, 0xed, 0x0c, 0xff, 0x61, 0xf9,
+	0x19, 0xc4, 0x2f, 0xec, 0xfb, 0xdc, 0xfd, 0xe7, 0x9b, 0xdf, 0x00, 0x00, 0x00, 0xff, 0xff, 0xb1,
+	0x4d, 0xd7, 0x69, 0xab, 0x02, 0x00, 0x00,
 }
@@ -10,7 +10,10 @@ It is generated from these files:
 
 It has these top-level messages:
 	Backend
+	Failover
+	Locality
 	Interceptor
+	Deadlines
 	Security
 */
 package kedge_config_grpc_backends
@@ -19,6 +22,7 @@ import proto "github.com/golang/protobuf/proto"
 import fmt "fmt"
 import math "math"
 import  kedge_config_common_resolvers "github.com/mwitkow/kedge/_protogen/kedge/config/common/resolvers"
+import google_protobuf "github.com/golang/protobuf/ptypes/duration"
 
 // Reference imports to suppress errors if they are not otherwise used.
 var _ = proto.Marshal
@@ -63,9 +67,21 @@ type Backend struct {
 	Security *Security `protobuf:"bytes,4,opt,name=security" json:"security,omitempty"`
 	// / interceptors controls what interceptors will be enabled for this backend.
 	Interceptors []*Interceptor `protobuf:"bytes,5,rep,name=interceptors" json:"interceptors,omitempty"`
+	// / deadlines controls the deadlines of calls proxied to this backend.
+	Deadlines *Deadlines `protobuf:"bytes,6,opt,name=deadlines" json:"deadlines,omitempty"`
+	// / locality makes the balancer prefer targets in the zone of kedge.
+	Locality *Locality `protobuf:"bytes,7,opt,name=locality" json:"locality,omitempty"`
+	// / resolver_grace_period is how long the targets resolved last are kept while the resolver fails, e.g. while DNS is
+	// / unavailable. Failing resolvers are restarted with backoff. Defaults to 5 minutes.
+	ResolverGracePeriod *google_protobuf.Duration `protobuf:"bytes,8,opt,name=resolver_grace_period,json=resolverGracePeriod" json:"resolver_grace_period,omitempty"`
 	// Types that are valid to be assigned to Resolver:
 	//	*Backend_Srv
 	//	*Backend_K8S
+	//	*Backend_Static
+	//	*Backend_Dns
+	//	*Backend_File
+	//	*Backend_Consul
+	//	*Backend_Failover
 	Resolver isBackend_Resolver `protobuf_oneof:"resolver"`
 }
 
@@ -84,9 +100,29 @@ type Backend_Srv struct {
 type Backend_K8S struct {
 	K8S *kedge_config_common_resolvers.KubeResolver `protobuf:"bytes,11,opt,name=k8s,oneof"`
 }
+type Backend_Static struct {
+	Static *kedge_config_common_resolvers.StaticResolver `protobuf:"bytes,13,opt,name=static,oneof"`
+}
+type Backend_Dns struct {
+	Dns *kedge_config_common_resolvers.DnsResolver `protobuf:"bytes,14,opt,name=dns,oneof"`
+}
+type Backend_File struct {
+	File *kedge_config_common_resolvers.FileResolver `protobuf:"bytes,15,opt,name=file,oneof"`
+}
+type Backend_Consul struct {
+	Consul *kedge_config_common_resolvers.ConsulResolver `protobuf:"bytes,16,opt,name=consul,oneof"`
+}
+type Backend_Failover struct {
+	Failover *Failover `protobuf:"bytes,12,opt,name=failover,oneof"`
+}
 
-func (*Backend_Srv) isBackend_Resolver() {}
-func (*Backend_K8S) isBackend_Resolver() {}
+func (*Backend_Srv) isBackend_Resolver()      {}
+func (*Backend_K8S) isBackend_Resolver()      {}
+func (*Backend_Static) isBackend_Resolver()   {}
+func (*Backend_Dns) isBackend_Resolver()      {}
+func (*Backend_File) isBackend_Resolver()     {}
+func (*Backend_Consul) isBackend_Resolver()   {}
+func (*Backend_Failover) isBackend_Resolver() {}
 
 func (m *Backend) GetResolver() isBackend_Resolver {
 	if m != nil {
@@ -130,6 +166,27 @@ func (m *Backend) GetInterceptors() []*Interceptor {
 	return nil
 }
 
+func (m *Backend) GetDeadlines() *Deadlines {
+	if m != nil {
+		return m.Deadlines
+	}
+	return nil
+}
+
+func (m *Backend) GetLocality() *Locality {
+	if m != nil {
+		return m.Locality
+	}
+	return nil
+}
+
+func (m *Backend) GetResolverGracePeriod() *google_protobuf.Duration {
+	if m != nil {
+		return m.ResolverGracePeriod
+	}
+	return nil
+}
+
 func (m *Backend) GetSrv() *kedge_config_common_resolvers.SrvResolver {
 	if x, ok := m.GetResolver().(*Backend_Srv); ok {
 		return x.Srv
@@ -144,11 +201,51 @@ func (m *Backend) GetK8S() *kedge_config_common_resolvers.KubeResolver {
 	return nil
 }
 
+func (m *Backend) GetStatic() *kedge_config_common_resolvers.StaticResolver {
+	if x, ok := m.GetResolver().(*Backend_Static); ok {
+		return x.Static
+	}
+	return nil
+}
+
+func (m *Backend) GetDns() *kedge_config_common_resolvers.DnsResolver {
+	if x, ok := m.GetResolver().(*Backend_Dns); ok {
+		return x.Dns
+	}
+	return nil
+}
+
+func (m *Backend) GetFile() *kedge_config_common_resolvers.FileResolver {
+	if x, ok := m.GetResolver().(*Backend_File); ok {
+		return x.File
+	}
+	return nil
+}
+
+func (m *Backend) GetConsul() *kedge_config_common_resolvers.ConsulResolver {
+	if x, ok := m.GetResolver().(*Backend_Consul); ok {
+		return x.Consul
+	}
+	return nil
+}
+
+func (m *Backend) GetFailover() *Failover {
+	if x, ok := m.GetResolver().(*Backend_Failover); ok {
+		return x.Failover
+	}
+	return nil
+}
+
 // XXX_OneofFuncs is for the internal use of the proto package.
 func (*Backend) XXX_OneofFuncs() (func(msg proto.Message, b *proto.Buffer) error, func(msg proto.Message, tag, wire int, b *proto.Buffer) (bool, error), func(msg proto.Message) (n int), []interface{}) {
 	return _Backend_OneofMarshaler, _Backend_OneofUnmarshaler, _Backend_OneofSizer, []interface{}{
 		(*Backend_Srv)(nil),
 		(*Backend_K8S)(nil),
+		(*Backend_Static)(nil),
+		(*Backend_Dns)(nil),
+		(*Backend_File)(nil),
+		(*Backend_Consul)(nil),
+		(*Backend_Failover)(nil),
 	}
 }
 
@@ -166,6 +263,31 @@ func _Backend_OneofMarshaler(msg proto.Message, b *proto.Buffer) error {
 		if err := b.EncodeMessage(x.K8S); err != nil {
 			return err
 		}
+	case *Backend_Static:
+		b.EncodeVarint(13<<3 | proto.WireBytes)
+		if err := b.EncodeMessage(x.Static); err != nil {
+			return err
+		}
+	case *Backend_Dns:
+		b.EncodeVarint(14<<3 | proto.WireBytes)
+		if err := b.EncodeMessage(x.Dns); err != nil {
+			return err
+		}
+	case *Backend_File:
+		b.EncodeVarint(15<<3 | proto.WireBytes)
+		if err := b.EncodeMessage(x.File); err != nil {
+			return err
+		}
+	case *Backend_Consul:
+		b.EncodeVarint(16<<3 | proto.WireBytes)
+		if err := b.EncodeMessage(x.Consul); err != nil {
+			return err
+		}
+	case *Backend_Failover:
+		b.EncodeVarint(12<<3 | proto.WireBytes)
+		if err := b.EncodeMessage(x.Failover); err != nil {
+			return err
+		}
 	case nil:
 	default:
 		return fmt.Errorf("Backend.Resolver has unexpected type %T", x)
@@ -192,6 +314,46 @@ func _Backend_OneofUnmarshaler(msg proto.Message, tag, wire int, b *proto.Buffer
 		err := b.DecodeMessage(msg)
 		m.Resolver = &Backend_K8S{msg}
 		return true, err
+	case 13: // resolver.static
+		if wire != proto.WireBytes {
+			return true, proto.ErrInternalBadWireType
+		}
+		msg := new(kedge_config_common_resolvers.StaticResolver)
+		err := b.DecodeMessage(msg)
+		m.Resolver = &Backend_Static{msg}
+		return true, err
+	case 14: // resolver.dns
+		if wire != proto.WireBytes {
+			return true, proto.ErrInternalBadWireType
+		}
+		msg := new(kedge_config_common_resolvers.DnsResolver)
+		err := b.DecodeMessage(msg)
+		m.Resolver = &Backend_Dns{msg}
+		return true, err
+	case 15: // resolver.file
+		if wire != proto.WireBytes {
+			return true, proto.ErrInternalBadWireType
+		}
+		msg := new(kedge_config_common_resolvers.FileResolver)
+		err := b.DecodeMessage(msg)
+		m.Resolver = &Backend_File{msg}
+		return true, err
+	case 16: // resolver.consul
+		if wire != proto.WireBytes {
+			return true, proto.ErrInternalBadWireType
+		}
+		msg := new(kedge_config_common_resolvers.ConsulResolver)
+		err := b.DecodeMessage(msg)
+		m.Resolver = &Backend_Consul{msg}
+		return true, err
+	case 12: // resolver.failover
+		if wire != proto.WireBytes {
+			return true, proto.ErrInternalBadWireType
+		}
+		msg := new(Failover)
+		err := b.DecodeMessage(msg)
+		m.Resolver = &Backend_Failover{msg}
+		return true, err
 	default:
 		return false, nil
 	}
@@ -211,6 +373,31 @@ func _Backend_OneofSizer(msg proto.Message) (n int) {
 		n += proto.SizeVarint(11<<3 | proto.WireBytes)
 		n += proto.SizeVarint(uint64(s))
 		n += s
+	case *Backend_Static:
+		s := proto.Size(x.Static)
+		n += proto.SizeVarint(13<<3 | proto.WireBytes)
+		n += proto.SizeVarint(uint64(s))
+		n += s
+	case *Backend_Dns:
+		s := proto.Size(x.Dns)
+		n += proto.SizeVarint(14<<3 | proto.WireBytes)
+		n += proto.SizeVarint(uint64(s))
+		n += s
+	case *Backend_File:
+		s := proto.Size(x.File)
+		n += proto.SizeVarint(15<<3 | proto.WireBytes)
+		n += proto.SizeVarint(uint64(s))
+		n += s
+	case *Backend_Consul:
+		s := proto.Size(x.Consul)
+		n += proto.SizeVarint(16<<3 | proto.WireBytes)
+		n += proto.SizeVarint(uint64(s))
+		n += s
+	case *Backend_Failover:
+		s := proto.Size(x.Failover)
+		n += proto.SizeVarint(12<<3 | proto.WireBytes)
+		n += proto.SizeVarint(uint64(s))
+		n += s
 	case nil:
 	default:
 		panic(fmt.Sprintf("proto: unexpected type %T in oneof", x))
@@ -218,6 +405,45 @@ func _Backend_OneofSizer(msg proto.Message) (n int) {
 	return n
 }
 
+// / Failover sends calls to the first of the backends that has healthy targets, e.g. the pool of the local cluster, and then
+// / a remote kedge in another region.
+type Failover struct {
+	// / backends are the names of other backends of the pool, in order of preference. They can't be failovers themselves.
+	Backends []string `protobuf:"bytes,1,rep,name=backends" json:"backends,omitempty"`
+}
+
+func (m *Failover) Reset()                    { *m = Failover{} }
+func (m *Failover) String() string            { return proto.CompactTextString(m) }
+func (*Failover) ProtoMessage()               {}
+func (*Failover) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{1} }
+
+func (m *Failover) GetBackends() []string {
+	if m != nil {
+		return m.Backends
+	}
+	return nil
+}
+
+// / Locality prefers targets in the zone of kedge, set with the `--server_zone` flag, to avoid cross-zone traffic.
+// / Zones of targets come from their resolver, see `zone_pattern` of SrvResolver and `endpoint_slices` of KubeResolver.
+type Locality struct {
+	// / min_healthy_share is the share of the targets of the local zone that need to be healthy for it to get all the
+	// / calls. Below it, calls spill over to targets in all zones. Between 0 and 1, defaults to 0.5.
+	MinHealthyShare float32 `protobuf:"fixed32,1,opt,name=min_healthy_share,json=minHealthyShare" json:"min_healthy_share,omitempty"`
+}
+
+func (m *Locality) Reset()                    { *m = Locality{} }
+func (m *Locality) String() string            { return proto.CompactTextString(m) }
+func (*Locality) ProtoMessage()               {}
+func (*Locality) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{2} }
+
+func (m *Locality) GetMinHealthyShare() float32 {
+	if m != nil {
+		return m.MinHealthyShare
+	}
+	return 0
+}
+
 type Interceptor struct {
 	// Types that are valid to be assigned to Interceptor:
 	//	*Interceptor_Prometheus
@@ -227,7 +453,7 @@ type Interceptor struct {
 func (m *Interceptor) Reset()                    { *m = Interceptor{} }
 func (m *Interceptor) String() string            { return proto.CompactTextString(m) }
 func (*Interceptor) ProtoMessage()               {}
-func (*Interceptor) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{1} }
+func (*Interceptor) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{3} }
 
 type isInterceptor_Interceptor interface {
 	isInterceptor_Interceptor()
@@ -307,6 +533,34 @@ func _Interceptor_OneofSizer(msg proto.Message) (n int) {
 	return n
 }
 
+// / Deadlines of calls to a backend. Calls that exceed them fail with DeadlineExceeded.
+type Deadlines struct {
+	// / default_timeout is the deadline of calls for which the client didn't set one. If not set, there's no limit.
+	DefaultTimeout *google_protobuf.Duration `protobuf:"bytes,1,opt,name=default_timeout,json=defaultTimeout" json:"default_timeout,omitempty"`
+	// / max_timeout caps the deadlines of calls, shortening ones set by clients that are too long.
+	// / If not set, client deadlines are used as they are.
+	MaxTimeout *google_protobuf.Duration `protobuf:"bytes,2,opt,name=max_timeout,json=maxTimeout" json:"max_timeout,omitempty"`
+}
+
+func (m *Deadlines) Reset()                    { *m = Deadlines{} }
+func (m *Deadlines) String() string            { return proto.CompactTextString(m) }
+func (*Deadlines) ProtoMessage()               {}
+func (*Deadlines) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{4} }
+
+func (m *Deadlines) GetDefaultTimeout() *google_protobuf.Duration {
+	if m != nil {
+		return m.DefaultTimeout
+	}
+	return nil
+}
+
+func (m *Deadlines) GetMaxTimeout() *google_protobuf.Duration {
+	if m != nil {
+		return m.MaxTimeout
+	}
+	return nil
+}
+
 // / Security settings for a backend.
 type Security struct {
 	// / insecure_skip_verify skips the server certificate verification completely.
@@ -319,7 +573,7 @@ type Security struct {
 func (m *Security) Reset()                    { *m = Security{} }
 func (m *Security) String() string            { return proto.CompactTextString(m) }
 func (*Security) ProtoMessage()               {}
-func (*Security) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{2} }
+func (*Security) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{5} }
 
 func (m *Security) GetInsecureSkipVerify() bool {
 	if m != nil {
@@ -337,7 +591,10 @@ func (m *Security) GetConfigName() string {
 
 func init() {
 	proto.RegisterType((*Backend)(nil), "kedge.config.grpc.backends.Backend")
+	proto.RegisterType((*Failover)(nil), "kedge.config.grpc.backends.Failover")
+	proto.RegisterType((*Locality)(nil), "kedge.config.grpc.backends.Locality")
 	proto.RegisterType((*Interceptor)(nil), "kedge.config.grpc.backends.Interceptor")
+	proto.RegisterType((*Deadlines)(nil), "kedge.config.grpc.backends.Deadlines")
 	proto.RegisterType((*Security)(nil), "kedge.config.grpc.backends.Security")
 	proto.RegisterEnum("kedge.config.grpc.backends.Balancer", Balancer_name, Balancer_value)
 }
@@ -345,31 +602,49 @@ func init() {
 func init() { proto.RegisterFile("kedge/config/grpc/backends/backend.proto", fileDescriptor0) }
 
 var fileDescriptor0 = []byte{
-	// 404 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x09, 0x6e, 0x88, 0x02, 0xff, 0x84, 0x92, 0xdf, 0x8f, 0x93, 0x40,
-	0x10, 0xc7, 0xcb, 0x71, 0x2a, 0x0e, 0xfe, 0xca, 0x7a, 0x0f, 0xe4, 0x7c, 0x90, 0x34, 0x26, 0x92,
-	0x33, 0x82, 0x9e, 0x2f, 0xf7, 0x74, 0x1a, 0xf4, 0xa1, 0x4d, 0x93, 0x36, 0xd9, 0x46, 0xdf, 0x0c,
-	0x81, 0xed, 0x14, 0x37, 0x94, 0x5d, 0xb2, 0x4b, 0x49, 0xfa, 0x4f, 0xf9, 0x37, 0x9a, 0x2e, 0xd0,
-	0xd2, 0x07, 0x7b, 0x6f, 0xc3, 0xcc, 0xe7, 0xfb, 0x9d, 0x61, 0x76, 0x20, 0x28, 0x70, 0x95, 0x63,
-	0xc4, 0xa4, 0x58, 0xf3, 0x3c, 0xca, 0x55, 0xc5, 0xa2, 0x2c, 0x65, 0x05, 0x8a, 0x95, 0xee, 0x83,
-	0xb0, 0x52, 0xb2, 0x96, 0xe4, 0xda, 0x90, 0x61, 0x4b, 0x86, 0x7b, 0x32, 0xec, 0xc9, 0xeb, 0x8f,
-	0x27, 0x2e, 0x4c, 0x96, 0xa5, 0x14, 0x91, 0x42, 0x2d, 0x37, 0x0d, 0x2a, 0x7d, 0x8c, 0x5a, 0xab,
-	0xf1, 0x5f, 0x1b, 0x9e, 0xc4, 0xad, 0x96, 0x10, 0xb8, 0x14, 0x69, 0x89, 0x9e, 0xe5, 0x5b, 0xc1,
-	0x53, 0x6a, 0x62, 0xf2, 0x0d, 0x9c, 0x2c, 0xdd, 0xa4, 0x82, 0xa1, 0xf2, 0x2e, 0x7c, 0x2b, 0x78,
-	0x71, 0xfb, 0x2e, 0xfc, 0x7f, 0xf7, 0x30, 0xee, 0x58, 0x7a, 0x50, 0x91, 0xcf, 0x70, 0xb5, 0xe2,
-	0x3a, 0xcd, 0x36, 0x98, 0x30, 0x29, 0x44, 0xad, 0x52, 0x56, 0x70, 0x91, 0x7b, 0xb6, 0x6f, 0x05,
-	0x0e, 0x7d, 0xdd, 0xd5, 0xbe, 0x0f, 0x4a, 0xfb, 0xa6, 0x1a, 0xd9, 0x56, 0xf1, 0x7a, 0xe7, 0x5d,
-	0xfa, 0x56, 0xe0, 0x9e, 0x6f, 0xba, 0xec, 0x58, 0x7a, 0x50, 0x91, 0x19, 0x3c, 0xe3, 0xa2, 0x46,
-	0xc5, 0xb0, 0xaa, 0xa5, 0xd2, 0xde, 0x23, 0xdf, 0x0e, 0xdc, 0xdb, 0xf7, 0xe7, 0x5c, 0xa6, 0x47,
-	0x9e, 0x9e, 0x88, 0xc9, 0x3d, 0xd8, 0x5a, 0x35, 0x1e, 0x98, 0x49, 0x6e, 0x4e, 0x3d, 0xda, 0x05,
-	0x87, 0xc7, 0xb5, 0x2e, 0x55, 0x43, 0xbb, 0x8f, 0xc9, 0x88, 0xee, 0x85, 0xe4, 0x2b, 0xd8, 0xc5,
-	0x9d, 0xf6, 0x5c, 0xa3, 0xff, 0xf0, 0x80, 0x7e, 0xb6, 0xcd, 0x70, 0x68, 0x50, 0xdc, 0xe9, 0x18,
-	0xc0, 0xe9, 0x81, 0xf1, 0x3d, 0xb8, 0x83, 0x49, 0x89, 0x0f, 0x50, 0x29, 0x59, 0x62, 0xfd, 0x07,
-	0xb7, 0xda, 0xbc, 0x9c, 0x33, 0x19, 0xd1, 0x41, 0x2e, 0x7e, 0x0e, 0xee, 0xe0, 0x6f, 0xc6, 0xbf,
-	0xc1, 0xe9, 0xf7, 0x45, 0x3e, 0xc1, 0x15, 0x17, 0x66, 0x67, 0x98, 0xe8, 0x82, 0x57, 0x49, 0x83,
-	0x8a, 0xaf, 0x77, 0xad, 0x0d, 0x25, 0x7d, 0x6d, 0x59, 0xf0, 0xea, 0x97, 0xa9, 0x90, 0xb7, 0xe0,
-	0xb6, 0x83, 0x27, 0xe6, 0x52, 0x2e, 0xcc, 0xa5, 0x40, 0x9b, 0x9a, 0xa7, 0x25, 0xde, 0xbc, 0x01,
-	0xa7, 0xbf, 0x01, 0xf2, 0x12, 0x5c, 0xba, 0xf8, 0x39, 0xff, 0x91, 0xd0, 0x45, 0x3c, 0x9d, 0xbf,
-	0x1a, 0x65, 0x8f, 0xcd, 0xcd, 0x7d, 0xf9, 0x17, 0x00, 0x00, 0xff, 0xff, 0x1e, 0x81, 0x99, 0xa4,
-	0xea, 0x02, 0x00, 0x00,
+	// 691 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x09, 0x6e, 0x88, 0x02, 0xff, 0x8c, 0x94, 0x6d, 0x4f, 0xdb, 0x3a,
+	0x14, 0xc7, 0x9b, 0x96, 0x0b, 0xe9, 0x09, 0x50, 0xae, 0xe1, 0x4a, 0xbe, 0xbd, 0xd2, 0x5d, 0x55,
+	0xed, 0xa1, 0x62, 0x22, 0xdd, 0x98, 0x34, 0xa1, 0xbd, 0x60, 0x5b, 0xa9, 0xa0, 0x88, 0x0d, 0x26,
+	0x77, 0xdb, 0xbb, 0x29, 0x72, 0x13, 0xb7, 0xb5, 0x9a, 0xd8, 0x95, 0x9d, 0x54, 0xf0, 0x19, 0xf6,
+	0x19, 0xf7, 0x5d, 0xa6, 0x38, 0x49, 0x1b, 0x5e, 0x8c, 0xf2, 0xce, 0x0f, 0xff, 0xdf, 0xf1, 0xf9,
+	0xeb, 0x1c, 0x1f, 0xe8, 0xcc, 0x58, 0x30, 0x61, 0x5d, 0x5f, 0x8a, 0x31, 0x9f, 0x74, 0x27, 0x6a,
+	0xee, 0x77, 0x47, 0xd4, 0x9f, 0x31, 0x11, 0xe8, 0x62, 0xe1, 0xce, 0x95, 0x8c, 0x25, 0x6a, 0x1a,
+	0xa5, 0x9b, 0x29, 0xdd, 0x54, 0xe9, 0x16, 0xca, 0xe6, 0xd1, 0xbd, 0x28, 0xbe, 0x8c, 0x22, 0x29,
+	0xba, 0x8a, 0x69, 0x19, 0x2e, 0x98, 0xd2, 0xab, 0x55, 0x16, 0xaa, 0xf9, 0xff, 0x44, 0xca, 0x49,
+	0xc8, 0xba, 0x66, 0x37, 0x4a, 0xc6, 0xdd, 0x20, 0x51, 0x34, 0xe6, 0x52, 0x64, 0xf7, 0xed, 0x5f,
+	0x5b, 0xb0, 0xd5, 0xcb, 0x62, 0x23, 0x04, 0x1b, 0x82, 0x46, 0x0c, 0x5b, 0x2d, 0xab, 0x53, 0x27,
+	0x66, 0x8d, 0x3e, 0x80, 0x3d, 0xa2, 0x21, 0x15, 0x3e, 0x53, 0xb8, 0xda, 0xb2, 0x3a, 0xbb, 0xc7,
+	0x4f, 0xdd, 0x3f, 0x67, 0xe7, 0xf6, 0x72, 0x2d, 0x59, 0x52, 0xe8, 0x35, 0x1c, 0x04, 0x5c, 0xd3,
+	0x51, 0xc8, 0x3c, 0x5f, 0x0a, 0x11, 0x2b, 0xea, 0xcf, 0xb8, 0x98, 0xe0, 0x5a, 0xcb, 0xea, 0xd8,
+	0x64, 0x3f, 0xbf, 0x3b, 0x2b, 0x5d, 0xa5, 0x8f, 0x6a, 0xe6, 0x27, 0x8a, 0xc7, 0x77, 0x78, 0xa3,
+	0x65, 0x75, 0x9c, 0x87, 0x1f, 0x1d, 0xe6, 0x5a, 0xb2, 0xa4, 0xd0, 0x15, 0x6c, 0x73, 0x11, 0x33,
+	0xe5, 0xb3, 0x79, 0x2c, 0x95, 0xc6, 0x7f, 0xb5, 0x6a, 0x1d, 0xe7, 0xf8, 0xc5, 0x43, 0x51, 0x2e,
+	0x57, 0x7a, 0x72, 0x0f, 0x46, 0x67, 0x50, 0x0f, 0x18, 0x0d, 0x42, 0x2e, 0x98, 0xc6, 0x9b, 0x26,
+	0x9f, 0x67, 0x0f, 0x45, 0xea, 0x17, 0x62, 0xb2, 0xe2, 0x52, 0x4f, 0xa1, 0xf4, 0x69, 0x98, 0x7a,
+	0xda, 0x5a, 0xef, 0xe9, 0x53, 0xae, 0x25, 0x4b, 0x0a, 0x7d, 0x86, 0x7f, 0x8a, 0xea, 0x7a, 0x13,
+	0x45, 0x7d, 0xe6, 0xcd, 0x99, 0xe2, 0x32, 0xc0, 0xb6, 0x09, 0xf7, 0xaf, 0x9b, 0x95, 0xda, 0x2d,
+	0x4a, 0xed, 0xf6, 0xf3, 0x52, 0x93, 0xfd, 0x82, 0xbb, 0x48, 0xb1, 0x2f, 0x86, 0x42, 0xa7, 0x50,
+	0xd3, 0x6a, 0x81, 0xc1, 0xc0, 0x87, 0xf7, 0x73, 0xc9, 0xda, 0xca, 0x5d, 0x35, 0xd3, 0x50, 0x2d,
+	0x48, 0xbe, 0x19, 0x54, 0x48, 0x0a, 0xa2, 0xf7, 0x50, 0x9b, 0x9d, 0x68, 0xec, 0x18, 0xfe, 0xe5,
+	0x1a, 0xfe, 0x2a, 0x19, 0xb1, 0x72, 0x80, 0xd9, 0x89, 0x46, 0x17, 0xb0, 0xa9, 0x63, 0x1a, 0x73,
+	0x1f, 0xef, 0x98, 0x18, 0x47, 0xeb, 0x72, 0x30, 0xe2, 0x52, 0x94, 0x1c, 0x4f, 0x9d, 0x04, 0x42,
+	0xe3, 0xdd, 0x47, 0x39, 0xe9, 0x0b, 0x5d, 0x4e, 0x24, 0x10, 0x1a, 0x7d, 0x84, 0x8d, 0x31, 0x0f,
+	0x19, 0x6e, 0x3c, 0xca, 0xca, 0x39, 0x0f, 0xcb, 0x56, 0x0c, 0x9a, 0x7a, 0xf1, 0xa5, 0xd0, 0x49,
+	0x88, 0xf7, 0x1e, 0xe5, 0xe5, 0xcc, 0x88, 0xcb, 0x5e, 0x32, 0x1c, 0xf5, 0xc0, 0x1e, 0x53, 0x1e,
+	0xca, 0x05, 0x53, 0x78, 0x7b, 0x7d, 0x9b, 0x9c, 0xe7, 0xda, 0x41, 0x85, 0x2c, 0xb9, 0x1e, 0x80,
+	0x5d, 0xbc, 0xd4, 0x7e, 0x0e, 0x76, 0xa1, 0x41, 0x4d, 0xb0, 0x0b, 0x10, 0x5b, 0xad, 0x5a, 0xa7,
+	0x4e, 0x96, 0xfb, 0xf6, 0x5b, 0xb0, 0x8b, 0x96, 0x43, 0x87, 0xf0, 0x77, 0xc4, 0x85, 0x37, 0x65,
+	0x34, 0x8c, 0xa7, 0x77, 0x9e, 0x9e, 0x52, 0x95, 0x0d, 0x85, 0x2a, 0x69, 0x44, 0x5c, 0x0c, 0xb2,
+	0xf3, 0x61, 0x7a, 0xdc, 0x3e, 0x05, 0xa7, 0xf4, 0x71, 0x50, 0x0b, 0x60, 0xae, 0x64, 0xc4, 0xe2,
+	0x29, 0x4b, 0xb4, 0x61, 0xec, 0x41, 0x85, 0x94, 0xce, 0x7a, 0x3b, 0xe0, 0x94, 0x3e, 0x57, 0xfb,
+	0xa7, 0x05, 0xf5, 0xe5, 0x7f, 0x41, 0x3d, 0x68, 0x04, 0x6c, 0x4c, 0x93, 0x30, 0xf6, 0x62, 0x1e,
+	0x31, 0x99, 0xc4, 0xd8, 0x5a, 0xd7, 0xdc, 0xbb, 0x39, 0xf1, 0x35, 0x03, 0xd0, 0x3b, 0x70, 0x22,
+	0x7a, 0xbb, 0xe4, 0xab, 0xeb, 0x78, 0x88, 0xe8, 0x6d, 0xce, 0xb6, 0x7f, 0x80, 0x5d, 0x0c, 0x13,
+	0xf4, 0x0a, 0x0e, 0xb8, 0x30, 0x03, 0x85, 0x79, 0x7a, 0xc6, 0xe7, 0xde, 0x82, 0x29, 0x3e, 0xbe,
+	0xcb, 0x4c, 0x11, 0x54, 0xdc, 0x0d, 0x67, 0x7c, 0xfe, 0xdd, 0xdc, 0xa0, 0x27, 0xe0, 0x64, 0x45,
+	0xf2, 0xcc, 0x18, 0xad, 0x9a, 0x31, 0x0a, 0xd9, 0xd1, 0x35, 0x8d, 0xd8, 0xe1, 0x7f, 0x60, 0x17,
+	0x03, 0x12, 0x35, 0xc0, 0x21, 0x37, 0xdf, 0xae, 0xfb, 0x1e, 0xb9, 0xe9, 0x5d, 0x5e, 0xef, 0x55,
+	0x46, 0x9b, 0x26, 0xb5, 0x37, 0xbf, 0x03, 0x00, 0x00, 0xff, 0xff, 0x68, 0x17, 0xd6, 0x03, 0x27,
+	0x06, 0x00, 0x00,
 }
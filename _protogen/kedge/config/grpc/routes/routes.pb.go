@@ -10,6 +10,10 @@ It is generated from these files:
 
 It has these top-level messages:
 	Route
+	WeightedBackend
+	Sticky
+	Mirror
+	MetadataRule
 */
 package kedge_config_grpc_routes
 
@@ -31,6 +35,7 @@ const _ = proto.ProtoPackageIsVersion2 // please upgrade the proto package
 // / Route is a mapping between invoked gRPC requests and backends that should serve it.
 type Route struct {
 	// / backend_name is the string identifying the backend to send data to.
+	// / It must not be set if weighted `backends` are present.
 	BackendName string `protobuf:"bytes,1,opt,name=backend_name,json=backendName" json:"backend_name,omitempty"`
 	// / service_name_matcher is a globbing expression that matches a full gRPC service name.
 	// / For example a method call to 'com.example.MyService/Create' would be matched by:
@@ -38,10 +43,14 @@ type Route struct {
 	// /  - com.example.*
 	// /  - com.*
 	// /  - *
+	// / Specific methods can be matched by including the method name, e.g. 'com.example.MyService/Create' or
+	// / 'com.example.MyService/Get*'.
 	// / If not present, '*' is default.
 	ServiceNameMatcher string `protobuf:"bytes,2,opt,name=service_name_matcher,json=serviceNameMatcher" json:"service_name_matcher,omitempty"`
 	// / authority_matcher matches on the ':authority' header (a.k.a. Host header) enabling Virtual Host-like proxying.
 	// / The matching is done through lower-case string-equality.
+	// / A matcher starting with '*' is a wildcard, e.g. '*.example.com', which matches any authority with that suffix,
+	// / regardless of the port used in the ':authority' header.
 	// / If none are present, the route skips ':authority' checks.
 	AuthorityMatcher string `protobuf:"bytes,3,opt,name=authority_matcher,json=authorityMatcher" json:"authority_matcher,omitempty"`
 	// / metadata_matcher matches any gRPC inbound request metadata.
@@ -50,6 +59,24 @@ type Route struct {
 	// / If a given metadata entry has more than one string value, at least one of them needs to match.
 	// / If none are present, the route skips metadata checks.
 	MetadataMatcher map[string]string `protobuf:"bytes,4,rep,name=metadata_matcher,json=metadataMatcher" json:"metadata_matcher,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	// / service_name_regex is an RE2 regular expression that needs to match the whole full gRPC method name, e.g.
+	// / 'com.example.MyService/Create'. It is checked in addition to service_name_matcher.
+	// / For example 'com\.example\.[^/]+/(Get|List).*' would match only read methods of example services.
+	// / If not present, the route skips regex checks.
+	ServiceNameRegex string `protobuf:"bytes,5,opt,name=service_name_regex,json=serviceNameRegex" json:"service_name_regex,omitempty"`
+	// / metadata_rules are additional checks on gRPC inbound request metadata.
+	// / All of the rules need to pass for the route to match.
+	MetadataRules []*MetadataRule `protobuf:"bytes,6,rep,name=metadata_rules,json=metadataRules" json:"metadata_rules,omitempty"`
+	// / backends splits the traffic of this route between multiple backends according to their weights.
+	// / For example, a canary release would send 95% of calls to 'controller' and 5% to 'controller-canary'.
+	// / If present, backend_name must not be set, and every backend needs a name.
+	Backends []*WeightedBackend `protobuf:"bytes,7,rep,name=backends" json:"backends,omitempty"`
+	// / sticky makes sure that the same client is consistently sent to the same weighted backend.
+	// / If not present, or the sticky key is missing from the call, the backend is chosen at random.
+	Sticky *Sticky `protobuf:"bytes,8,opt,name=sticky" json:"sticky,omitempty"`
+	// / mirror sends copies of a share of this route's unary calls to another backend, e.g. to test a new version with
+	// / production traffic. Responses of the mirror are discarded and never affect the original call.
+	Mirror *Mirror `protobuf:"bytes,9,opt,name=mirror" json:"mirror,omitempty"`
 }
 
 func (m *Route) Reset()                    { *m = Route{} }
@@ -85,28 +112,304 @@ func (m *Route) GetMetadataMatcher() map[string]string {
 	return nil
 }
 
+func (m *Route) GetServiceNameRegex() string {
+	if m != nil {
+		return m.ServiceNameRegex
+	}
+	return ""
+}
+
+func (m *Route) GetMetadataRules() []*MetadataRule {
+	if m != nil {
+		return m.MetadataRules
+	}
+	return nil
+}
+
+func (m *Route) GetBackends() []*WeightedBackend {
+	if m != nil {
+		return m.Backends
+	}
+	return nil
+}
+
+func (m *Route) GetSticky() *Sticky {
+	if m != nil {
+		return m.Sticky
+	}
+	return nil
+}
+
+func (m *Route) GetMirror() *Mirror {
+	if m != nil {
+		return m.Mirror
+	}
+	return nil
+}
+
+// / WeightedBackend is a backend that receives a share of a route's traffic.
+type WeightedBackend struct {
+	// / name is the string identifying the backend to send data to.
+	Name string `protobuf:"bytes,1,opt,name=name" json:"name,omitempty"`
+	// / weight is the share of traffic relative to the sum of weights of all backends of the route.
+	// / If all weights of a route are zero, the traffic is split equally.
+	Weight uint32 `protobuf:"varint,2,opt,name=weight" json:"weight,omitempty"`
+}
+
+func (m *WeightedBackend) Reset()                    { *m = WeightedBackend{} }
+func (m *WeightedBackend) String() string            { return proto.CompactTextString(m) }
+func (*WeightedBackend) ProtoMessage()               {}
+func (*WeightedBackend) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{1} }
+
+func (m *WeightedBackend) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *WeightedBackend) GetWeight() uint32 {
+	if m != nil {
+		return m.Weight
+	}
+	return 0
+}
+
+// / Sticky controls what part of the call is hashed to choose a weighted backend.
+type Sticky struct {
+	// Types that are valid to be assigned to Key:
+	//	*Sticky_MetadataKey
+	//	*Sticky_ClientIdentity
+	Key isSticky_Key `protobuf_oneof:"key"`
+}
+
+func (m *Sticky) Reset()                    { *m = Sticky{} }
+func (m *Sticky) String() string            { return proto.CompactTextString(m) }
+func (*Sticky) ProtoMessage()               {}
+func (*Sticky) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{2} }
+
+type isSticky_Key interface {
+	isSticky_Key()
+}
+
+type Sticky_MetadataKey struct {
+	MetadataKey string `protobuf:"bytes,1,opt,name=metadata_key,json=metadataKey,oneof"`
+}
+type Sticky_ClientIdentity struct {
+	ClientIdentity bool `protobuf:"varint,2,opt,name=client_identity,json=clientIdentity,oneof"`
+}
+
+func (*Sticky_MetadataKey) isSticky_Key()    {}
+func (*Sticky_ClientIdentity) isSticky_Key() {}
+
+func (m *Sticky) GetKey() isSticky_Key {
+	if m != nil {
+		return m.Key
+	}
+	return nil
+}
+
+func (m *Sticky) GetMetadataKey() string {
+	if x, ok := m.GetKey().(*Sticky_MetadataKey); ok {
+		return x.MetadataKey
+	}
+	return ""
+}
+
+func (m *Sticky) GetClientIdentity() bool {
+	if x, ok := m.GetKey().(*Sticky_ClientIdentity); ok {
+		return x.ClientIdentity
+	}
+	return false
+}
+
+// XXX_OneofFuncs is for the internal use of the proto package.
+func (*Sticky) XXX_OneofFuncs() (func(msg proto.Message, b *proto.Buffer) error, func(msg proto.Message, tag, wire int, b *proto.Buffer) (bool, error), func(msg proto.Message) (n int), []interface{}) {
+	return _Sticky_OneofMarshaler, _Sticky_OneofUnmarshaler, _Sticky_OneofSizer, []interface{}{
+		(*Sticky_MetadataKey)(nil),
+		(*Sticky_ClientIdentity)(nil),
+	}
+}
+
+func _Sticky_OneofMarshaler(msg proto.Message, b *proto.Buffer) error {
+	m := msg.(*Sticky)
+	// key
+	switch x := m.Key.(type) {
+	case *Sticky_MetadataKey:
+		b.EncodeVarint(1<<3 | proto.WireBytes)
+		b.EncodeStringBytes(x.MetadataKey)
+	case *Sticky_ClientIdentity:
+		t := uint64(0)
+		if x.ClientIdentity {
+			t = 1
+		}
+		b.EncodeVarint(2<<3 | proto.WireVarint)
+		b.EncodeVarint(t)
+	case nil:
+	default:
+		return fmt.Errorf("Sticky.Key has unexpected type %T", x)
+	}
+	return nil
+}
+
+func _Sticky_OneofUnmarshaler(msg proto.Message, tag, wire int, b *proto.Buffer) (bool, error) {
+	m := msg.(*Sticky)
+	switch tag {
+	case 1: // key.metadata_key
+		if wire != proto.WireBytes {
+			return true, proto.ErrInternalBadWireType
+		}
+		x, err := b.DecodeStringBytes()
+		m.Key = &Sticky_MetadataKey{x}
+		return true, err
+	case 2: // key.client_identity
+		if wire != proto.WireVarint {
+			return true, proto.ErrInternalBadWireType
+		}
+		x, err := b.DecodeVarint()
+		m.Key = &Sticky_ClientIdentity{x != 0}
+		return true, err
+	default:
+		return false, nil
+	}
+}
+
+func _Sticky_OneofSizer(msg proto.Message) (n int) {
+	m := msg.(*Sticky)
+	// key
+	switch x := m.Key.(type) {
+	case *Sticky_MetadataKey:
+		n += proto.SizeVarint(1<<3 | proto.WireBytes)
+		n += proto.SizeVarint(uint64(len(x.MetadataKey)))
+		n += len(x.MetadataKey)
+	case *Sticky_ClientIdentity:
+		n += proto.SizeVarint(2<<3 | proto.WireVarint)
+		n += 1
+	case nil:
+	default:
+		panic(fmt.Sprintf("proto: unexpected type %T in oneof", x))
+	}
+	return n
+}
+
+// / Mirror is a backend that receives copies of calls.
+type Mirror struct {
+	// / backend_name is the string identifying the backend to send copies of calls to.
+	BackendName string `protobuf:"bytes,1,opt,name=backend_name,json=backendName" json:"backend_name,omitempty"`
+	// / percentage of the route's calls that are mirrored, between 0 and 100. If 0, no calls are mirrored.
+	Percentage float32 `protobuf:"fixed32,2,opt,name=percentage" json:"percentage,omitempty"`
+	// / max_message_bytes is the largest request message that is copied. Calls with larger messages are not mirrored.
+	// / Defaults to 64KiB.
+	MaxMessageBytes uint32 `protobuf:"varint,3,opt,name=max_message_bytes,json=maxMessageBytes" json:"max_message_bytes,omitempty"`
+}
+
+func (m *Mirror) Reset()                    { *m = Mirror{} }
+func (m *Mirror) String() string            { return proto.CompactTextString(m) }
+func (*Mirror) ProtoMessage()               {}
+func (*Mirror) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{3} }
+
+func (m *Mirror) GetBackendName() string {
+	if m != nil {
+		return m.BackendName
+	}
+	return ""
+}
+
+func (m *Mirror) GetPercentage() float32 {
+	if m != nil {
+		return m.Percentage
+	}
+	return 0
+}
+
+func (m *Mirror) GetMaxMessageBytes() uint32 {
+	if m != nil {
+		return m.MaxMessageBytes
+	}
+	return 0
+}
+
+// / MetadataRule checks presence, absence or values of an inbound gRPC metadata entry.
+type MetadataRule struct {
+	// / key is the metadata key to check, matched in lower-case.
+	Key string `protobuf:"bytes,1,opt,name=key" json:"key,omitempty"`
+	// / value_regex is an RE2 regular expression that needs to match a whole value of the metadata entry.
+	// / If a given metadata entry has more than one string value, at least one of them needs to match.
+	// / If not present, the rule only checks that the key is present.
+	ValueRegex string `protobuf:"bytes,2,opt,name=value_regex,json=valueRegex" json:"value_regex,omitempty"`
+	// / negate inverts the result of the rule.
+	// / For example, a rule with only a key and negate set requires the metadata key to be absent.
+	Negate bool `protobuf:"varint,3,opt,name=negate" json:"negate,omitempty"`
+}
+
+func (m *MetadataRule) Reset()                    { *m = MetadataRule{} }
+func (m *MetadataRule) String() string            { return proto.CompactTextString(m) }
+func (*MetadataRule) ProtoMessage()               {}
+func (*MetadataRule) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{4} }
+
+func (m *MetadataRule) GetKey() string {
+	if m != nil {
+		return m.Key
+	}
+	return ""
+}
+
+func (m *MetadataRule) GetValueRegex() string {
+	if m != nil {
+		return m.ValueRegex
+	}
+	return ""
+}
+
+func (m *MetadataRule) GetNegate() bool {
+	if m != nil {
+		return m.Negate
+	}
+	return false
+}
+
 func init() {
 	proto.RegisterType((*Route)(nil), "kedge.config.grpc.routes.Route")
+	proto.RegisterType((*WeightedBackend)(nil), "kedge.config.grpc.routes.WeightedBackend")
+	proto.RegisterType((*Sticky)(nil), "kedge.config.grpc.routes.Sticky")
+	proto.RegisterType((*Mirror)(nil), "kedge.config.grpc.routes.Mirror")
+	proto.RegisterType((*MetadataRule)(nil), "kedge.config.grpc.routes.MetadataRule")
 }
 
 func init() { proto.RegisterFile("kedge/config/grpc/routes/routes.proto", fileDescriptor0) }
 
 var fileDescriptor0 = []byte{
-	// 243 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x09, 0x6e, 0x88, 0x02, 0xff, 0x74, 0xd0, 0xc1, 0x4a, 0xc4, 0x30,
-	0x10, 0x06, 0x60, 0xda, 0xba, 0x82, 0xb3, 0x82, 0x35, 0xf4, 0x50, 0x3c, 0xad, 0x82, 0xb0, 0x20,
-	0xa4, 0xa2, 0x1e, 0xc4, 0xa3, 0xe0, 0x71, 0x3d, 0xf4, 0x05, 0xca, 0x6c, 0x3a, 0x76, 0x4b, 0x4d,
-	0xb3, 0x64, 0xa7, 0x0b, 0x7d, 0x29, 0x9f, 0x51, 0x9a, 0xc4, 0x82, 0xa0, 0xa7, 0x4c, 0xe6, 0xff,
-	0x12, 0x32, 0x81, 0xdb, 0x8e, 0xea, 0x86, 0x0a, 0x65, 0xfa, 0x8f, 0xb6, 0x29, 0x1a, 0xbb, 0x57,
-	0x85, 0x35, 0x03, 0xd3, 0x21, 0x2c, 0x72, 0x6f, 0x0d, 0x1b, 0x91, 0x3b, 0x26, 0x3d, 0x93, 0x13,
-	0x93, 0x3e, 0xbf, 0xf9, 0x8a, 0x61, 0x51, 0x4e, 0xa5, 0xb8, 0x86, 0xf3, 0x2d, 0xaa, 0x8e, 0xfa,
-	0xba, 0xea, 0x51, 0x53, 0x1e, 0xad, 0xa2, 0xf5, 0x59, 0xb9, 0x0c, 0xbd, 0x77, 0xd4, 0x24, 0xee,
-	0x21, 0x3b, 0x90, 0x3d, 0xb6, 0x8a, 0x1c, 0xa9, 0x34, 0xb2, 0xda, 0x91, 0xcd, 0x63, 0x47, 0x45,
-	0xc8, 0x26, 0xba, 0xf1, 0x89, 0xb8, 0x83, 0x4b, 0x1c, 0x78, 0x67, 0x6c, 0xcb, 0xe3, 0xcc, 0x13,
-	0xc7, 0xd3, 0x39, 0xf8, 0xc1, 0x15, 0xa4, 0x9a, 0x18, 0x6b, 0x64, 0x9c, 0xed, 0xc9, 0x2a, 0x59,
-	0x2f, 0x1f, 0x9e, 0xe4, 0x7f, 0x03, 0x48, 0xf7, 0x78, 0xb9, 0x09, 0xe7, 0xc2, 0x55, 0x6f, 0x3d,
-	0xdb, 0xb1, 0xbc, 0xd0, 0xbf, 0xbb, 0x57, 0xaf, 0x90, 0xfd, 0x05, 0x45, 0x0a, 0x49, 0x47, 0x63,
-	0x98, 0x78, 0x2a, 0x45, 0x06, 0x8b, 0x23, 0x7e, 0x0e, 0x14, 0x46, 0xf3, 0x9b, 0x97, 0xf8, 0x39,
-	0xda, 0x9e, 0xba, 0x1f, 0x7d, 0xfc, 0x0e, 0x00, 0x00, 0xff, 0xff, 0x1b, 0xa1, 0x67, 0x69, 0x7a,
-	0x01, 0x00, 0x00,
+	// 510 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x09, 0x6e, 0x88, 0x02, 0xff, 0x8c, 0x93, 0xcf, 0x6e, 0xd3, 0x40,
+	0x10, 0xc6, 0x9b, 0x7f, 0x26, 0x9d, 0x24, 0x4d, 0xba, 0x8a, 0x2a, 0x8b, 0x03, 0x84, 0x20, 0x50,
+	0x0a, 0xc8, 0x41, 0x85, 0x43, 0x85, 0xc4, 0x25, 0x52, 0xa5, 0x22, 0x14, 0x0e, 0xcb, 0x01, 0x71,
+	0xb2, 0x36, 0xf6, 0xe0, 0x58, 0xc9, 0xda, 0xd1, 0x7a, 0xdd, 0xc6, 0x2f, 0xcc, 0x73, 0x20, 0xcf,
+	0x6e, 0x2c, 0x17, 0x35, 0x82, 0x53, 0x76, 0xbf, 0xf9, 0xcd, 0xf8, 0x9b, 0xc9, 0x2c, 0xbc, 0xda,
+	0x60, 0x18, 0xe1, 0x3c, 0x48, 0x93, 0x5f, 0x71, 0x34, 0x8f, 0xd4, 0x2e, 0x98, 0xab, 0x34, 0xd7,
+	0x98, 0xd9, 0x1f, 0x6f, 0xa7, 0x52, 0x9d, 0x32, 0x97, 0x30, 0xcf, 0x60, 0x5e, 0x89, 0x79, 0x26,
+	0x3e, 0xfd, 0xdd, 0x86, 0x0e, 0x2f, 0x8f, 0xec, 0x05, 0xf4, 0x57, 0x22, 0xd8, 0x60, 0x12, 0xfa,
+	0x89, 0x90, 0xe8, 0x36, 0x26, 0x8d, 0xd9, 0x29, 0xef, 0x59, 0xed, 0x9b, 0x90, 0xc8, 0xde, 0xc3,
+	0x38, 0x43, 0x75, 0x17, 0x07, 0x48, 0x88, 0x2f, 0x85, 0x0e, 0xd6, 0xa8, 0xdc, 0x26, 0xa1, 0xcc,
+	0xc6, 0x4a, 0x74, 0x69, 0x22, 0xec, 0x2d, 0x9c, 0x8b, 0x5c, 0xaf, 0x53, 0x15, 0xeb, 0xa2, 0xc2,
+	0x5b, 0x84, 0x8f, 0xaa, 0xc0, 0x01, 0xf6, 0x61, 0x24, 0x51, 0x8b, 0x50, 0x68, 0x51, 0xb1, 0xed,
+	0x49, 0x6b, 0xd6, 0xbb, 0xfa, 0xe8, 0x1d, 0x6b, 0xc0, 0x23, 0xf3, 0xde, 0xd2, 0xe6, 0xd9, 0x52,
+	0x37, 0x89, 0x56, 0x05, 0x1f, 0xca, 0x87, 0x2a, 0x7b, 0x07, 0xec, 0x81, 0x7f, 0x85, 0x11, 0xee,
+	0xdd, 0x8e, 0xb1, 0x53, 0x73, 0xcf, 0x4b, 0x9d, 0x2d, 0xe1, 0xac, 0xb2, 0xa3, 0xf2, 0x2d, 0x66,
+	0xae, 0x43, 0x66, 0x5e, 0x1f, 0x37, 0x73, 0xb0, 0xc1, 0xf3, 0x2d, 0xf2, 0x81, 0xac, 0xdd, 0x32,
+	0x76, 0x03, 0x5d, 0x3b, 0xcb, 0xcc, 0x7d, 0x42, 0x85, 0x2e, 0x8f, 0x17, 0xfa, 0x81, 0x71, 0xb4,
+	0xd6, 0x18, 0x2e, 0x4c, 0x06, 0xaf, 0x52, 0xd9, 0x35, 0x38, 0x99, 0x8e, 0x83, 0x4d, 0xe1, 0x76,
+	0x27, 0x8d, 0x59, 0xef, 0x6a, 0x72, 0xbc, 0xc8, 0x77, 0xe2, 0xb8, 0xe5, 0xcb, 0x4c, 0x19, 0x2b,
+	0x95, 0x2a, 0xf7, 0xf4, 0x5f, 0x99, 0x4b, 0xe2, 0xb8, 0xe5, 0x9f, 0x2e, 0x60, 0xfc, 0xd8, 0x80,
+	0xd9, 0x08, 0x5a, 0x1b, 0x2c, 0xec, 0xa6, 0x94, 0x47, 0x36, 0x86, 0xce, 0x9d, 0xd8, 0xe6, 0x68,
+	0x57, 0xc2, 0x5c, 0x3e, 0x35, 0xaf, 0x1b, 0xd3, 0xcf, 0x30, 0xfc, 0xab, 0x29, 0xc6, 0xa0, 0x5d,
+	0xdb, 0x34, 0x3a, 0xb3, 0x0b, 0x70, 0xee, 0x09, 0xa3, 0x0a, 0x03, 0x6e, 0x6f, 0x53, 0x1f, 0x1c,
+	0xd3, 0x0e, 0x7b, 0x09, 0xfd, 0xea, 0x6f, 0xa9, 0xbe, 0x7e, 0x7b, 0xc2, 0x7b, 0x07, 0xf5, 0x2b,
+	0x16, 0xec, 0x12, 0x86, 0xc1, 0x36, 0xc6, 0x44, 0xfb, 0x71, 0x88, 0x89, 0x8e, 0x75, 0x41, 0xf5,
+	0xba, 0xb7, 0x27, 0xfc, 0xcc, 0x04, 0xbe, 0x58, 0x7d, 0xd1, 0xa1, 0x26, 0xa6, 0xf7, 0xe0, 0x98,
+	0xae, 0xff, 0xe7, 0x21, 0x3c, 0x03, 0xd8, 0xa1, 0x0a, 0x30, 0xd1, 0x22, 0x32, 0xbd, 0x36, 0x79,
+	0x4d, 0x61, 0x6f, 0xe0, 0x5c, 0x8a, 0xbd, 0x2f, 0x31, 0xcb, 0x44, 0x84, 0xfe, 0xaa, 0xd0, 0x98,
+	0xd1, 0xda, 0x0f, 0xf8, 0x50, 0x8a, 0xfd, 0xd2, 0xe8, 0x8b, 0x52, 0x9e, 0xfe, 0x84, 0x7e, 0x7d,
+	0x6d, 0x1e, 0x19, 0xea, 0x73, 0xe8, 0xd1, 0x1c, 0xed, 0xbe, 0x9a, 0xd1, 0x02, 0x49, 0x66, 0x53,
+	0x2f, 0xc0, 0x49, 0x30, 0x12, 0x1a, 0xe9, 0x1b, 0x5d, 0x6e, 0x6f, 0x2b, 0x87, 0x5e, 0xff, 0x87,
+	0x3f, 0x01, 0x00, 0x00, 0xff, 0xff, 0x2e, 0xd8, 0xf6, 0xf4, 0x26, 0x04, 0x00, 0x00,
 }
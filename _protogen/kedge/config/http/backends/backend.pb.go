@@ -10,7 +10,11 @@ It is generated from these files:
 
 It has these top-level messages:
 	Backend
+	Failover
+	Locality
 	Middleware
+	Timeouts
+	ConnectionPool
 	Security
 */
 package kedge_config_http_backends
@@ -19,6 +23,7 @@ import proto "github.com/golang/protobuf/proto"
 import fmt "fmt"
 import math "math"
 import  kedge_config_common_resolvers "github.com/mwitkow/kedge/_protogen/kedge/config/common/resolvers"
+import google_protobuf "github.com/golang/protobuf/ptypes/duration"
 
 // Reference imports to suppress errors if they are not otherwise used.
 var _ = proto.Marshal
@@ -64,9 +69,23 @@ type Backend struct {
 	// / interceptors controls what middleware will be available on every call made to this backend.
 	// / These will be executed in order from left to right.
 	Middlewares []*Middleware `protobuf:"bytes,5,rep,name=middlewares" json:"middlewares,omitempty"`
+	// / timeouts controls how long connections and requests to this backend can take.
+	Timeouts *Timeouts `protobuf:"bytes,6,opt,name=timeouts" json:"timeouts,omitempty"`
+	// / connection_pool controls how many connections are kept open to the targets of this backend.
+	ConnectionPool *ConnectionPool `protobuf:"bytes,7,opt,name=connection_pool,json=connectionPool" json:"connection_pool,omitempty"`
+	// / locality makes the balancer prefer targets in the zone of kedge.
+	Locality *Locality `protobuf:"bytes,8,opt,name=locality" json:"locality,omitempty"`
+	// / resolver_grace_period is how long the targets resolved last are kept while the resolver fails, e.g. while DNS is
+	// / unavailable. Failing resolvers are restarted with backoff. Defaults to 5 minutes.
+	ResolverGracePeriod *google_protobuf.Duration `protobuf:"bytes,9,opt,name=resolver_grace_period,json=resolverGracePeriod" json:"resolver_grace_period,omitempty"`
 	// Types that are valid to be assigned to Resolver:
 	//	*Backend_Srv
 	//	*Backend_K8S
+	//	*Backend_Static
+	//	*Backend_Dns
+	//	*Backend_File
+	//	*Backend_Consul
+	//	*Backend_Failover
 	Resolver isBackend_Resolver `protobuf_oneof:"resolver"`
 }
 
@@ -85,9 +104,29 @@ type Backend_Srv struct {
 type Backend_K8S struct {
 	K8S *kedge_config_common_resolvers.KubeResolver `protobuf:"bytes,11,opt,name=k8s,oneof"`
 }
+type Backend_Static struct {
+	Static *kedge_config_common_resolvers.StaticResolver `protobuf:"bytes,13,opt,name=static,oneof"`
+}
+type Backend_Dns struct {
+	Dns *kedge_config_common_resolvers.DnsResolver `protobuf:"bytes,14,opt,name=dns,oneof"`
+}
+type Backend_File struct {
+	File *kedge_config_common_resolvers.FileResolver `protobuf:"bytes,15,opt,name=file,oneof"`
+}
+type Backend_Consul struct {
+	Consul *kedge_config_common_resolvers.ConsulResolver `protobuf:"bytes,16,opt,name=consul,oneof"`
+}
+type Backend_Failover struct {
+	Failover *Failover `protobuf:"bytes,12,opt,name=failover,oneof"`
+}
 
-func (*Backend_Srv) isBackend_Resolver() {}
-func (*Backend_K8S) isBackend_Resolver() {}
+func (*Backend_Srv) isBackend_Resolver()      {}
+func (*Backend_K8S) isBackend_Resolver()      {}
+func (*Backend_Static) isBackend_Resolver()   {}
+func (*Backend_Dns) isBackend_Resolver()      {}
+func (*Backend_File) isBackend_Resolver()     {}
+func (*Backend_Consul) isBackend_Resolver()   {}
+func (*Backend_Failover) isBackend_Resolver() {}
 
 func (m *Backend) GetResolver() isBackend_Resolver {
 	if m != nil {
@@ -131,6 +170,34 @@ func (m *Backend) GetMiddlewares() []*Middleware {
 	return nil
 }
 
+func (m *Backend) GetTimeouts() *Timeouts {
+	if m != nil {
+		return m.Timeouts
+	}
+	return nil
+}
+
+func (m *Backend) GetConnectionPool() *ConnectionPool {
+	if m != nil {
+		return m.ConnectionPool
+	}
+	return nil
+}
+
+func (m *Backend) GetLocality() *Locality {
+	if m != nil {
+		return m.Locality
+	}
+	return nil
+}
+
+func (m *Backend) GetResolverGracePeriod() *google_protobuf.Duration {
+	if m != nil {
+		return m.ResolverGracePeriod
+	}
+	return nil
+}
+
 func (m *Backend) GetSrv() *kedge_config_common_resolvers.SrvResolver {
 	if x, ok := m.GetResolver().(*Backend_Srv); ok {
 		return x.Srv
@@ -145,11 +212,51 @@ func (m *Backend) GetK8S() *kedge_config_common_resolvers.KubeResolver {
 	return nil
 }
 
+func (m *Backend) GetStatic() *kedge_config_common_resolvers.StaticResolver {
+	if x, ok := m.GetResolver().(*Backend_Static); ok {
+		return x.Static
+	}
+	return nil
+}
+
+func (m *Backend) GetDns() *kedge_config_common_resolvers.DnsResolver {
+	if x, ok := m.GetResolver().(*Backend_Dns); ok {
+		return x.Dns
+	}
+	return nil
+}
+
+func (m *Backend) GetFile() *kedge_config_common_resolvers.FileResolver {
+	if x, ok := m.GetResolver().(*Backend_File); ok {
+		return x.File
+	}
+	return nil
+}
+
+func (m *Backend) GetConsul() *kedge_config_common_resolvers.ConsulResolver {
+	if x, ok := m.GetResolver().(*Backend_Consul); ok {
+		return x.Consul
+	}
+	return nil
+}
+
+func (m *Backend) GetFailover() *Failover {
+	if x, ok := m.GetResolver().(*Backend_Failover); ok {
+		return x.Failover
+	}
+	return nil
+}
+
 // XXX_OneofFuncs is for the internal use of the proto package.
 func (*Backend) XXX_OneofFuncs() (func(msg proto.Message, b *proto.Buffer) error, func(msg proto.Message, tag, wire int, b *proto.Buffer) (bool, error), func(msg proto.Message) (n int), []interface{}) {
 	return _Backend_OneofMarshaler, _Backend_OneofUnmarshaler, _Backend_OneofSizer, []interface{}{
 		(*Backend_Srv)(nil),
 		(*Backend_K8S)(nil),
+		(*Backend_Static)(nil),
+		(*Backend_Dns)(nil),
+		(*Backend_File)(nil),
+		(*Backend_Consul)(nil),
+		(*Backend_Failover)(nil),
 	}
 }
 
@@ -167,6 +274,31 @@ func _Backend_OneofMarshaler(msg proto.Message, b *proto.Buffer) error {
 		if err := b.EncodeMessage(x.K8S); err != nil {
 			return err
 		}
+	case *Backend_Static:
+		b.EncodeVarint(13<<3 | proto.WireBytes)
+		if err := b.EncodeMessage(x.Static); err != nil {
+			return err
+		}
+	case *Backend_Dns:
+		b.EncodeVarint(14<<3 | proto.WireBytes)
+		if err := b.EncodeMessage(x.Dns); err != nil {
+			return err
+		}
+	case *Backend_File:
+		b.EncodeVarint(15<<3 | proto.WireBytes)
+		if err := b.EncodeMessage(x.File); err != nil {
+			return err
+		}
+	case *Backend_Consul:
+		b.EncodeVarint(16<<3 | proto.WireBytes)
+		if err := b.EncodeMessage(x.Consul); err != nil {
+			return err
+		}
+	case *Backend_Failover:
+		b.EncodeVarint(12<<3 | proto.WireBytes)
+		if err := b.EncodeMessage(x.Failover); err != nil {
+			return err
+		}
 	case nil:
 	default:
 		return fmt.Errorf("Backend.Resolver has unexpected type %T", x)
@@ -193,6 +325,46 @@ func _Backend_OneofUnmarshaler(msg proto.Message, tag, wire int, b *proto.Buffer
 		err := b.DecodeMessage(msg)
 		m.Resolver = &Backend_K8S{msg}
 		return true, err
+	case 13: // resolver.static
+		if wire != proto.WireBytes {
+			return true, proto.ErrInternalBadWireType
+		}
+		msg := new(kedge_config_common_resolvers.StaticResolver)
+		err := b.DecodeMessage(msg)
+		m.Resolver = &Backend_Static{msg}
+		return true, err
+	case 14: // resolver.dns
+		if wire != proto.WireBytes {
+			return true, proto.ErrInternalBadWireType
+		}
+		msg := new(kedge_config_common_resolvers.DnsResolver)
+		err := b.DecodeMessage(msg)
+		m.Resolver = &Backend_Dns{msg}
+		return true, err
+	case 15: // resolver.file
+		if wire != proto.WireBytes {
+			return true, proto.ErrInternalBadWireType
+		}
+		msg := new(kedge_config_common_resolvers.FileResolver)
+		err := b.DecodeMessage(msg)
+		m.Resolver = &Backend_File{msg}
+		return true, err
+	case 16: // resolver.consul
+		if wire != proto.WireBytes {
+			return true, proto.ErrInternalBadWireType
+		}
+		msg := new(kedge_config_common_resolvers.ConsulResolver)
+		err := b.DecodeMessage(msg)
+		m.Resolver = &Backend_Consul{msg}
+		return true, err
+	case 12: // resolver.failover
+		if wire != proto.WireBytes {
+			return true, proto.ErrInternalBadWireType
+		}
+		msg := new(Failover)
+		err := b.DecodeMessage(msg)
+		m.Resolver = &Backend_Failover{msg}
+		return true, err
 	default:
 		return false, nil
 	}
@@ -212,6 +384,31 @@ func _Backend_OneofSizer(msg proto.Message) (n int) {
 		n += proto.SizeVarint(11<<3 | proto.WireBytes)
 		n += proto.SizeVarint(uint64(s))
 		n += s
+	case *Backend_Static:
+		s := proto.Size(x.Static)
+		n += proto.SizeVarint(13<<3 | proto.WireBytes)
+		n += proto.SizeVarint(uint64(s))
+		n += s
+	case *Backend_Dns:
+		s := proto.Size(x.Dns)
+		n += proto.SizeVarint(14<<3 | proto.WireBytes)
+		n += proto.SizeVarint(uint64(s))
+		n += s
+	case *Backend_File:
+		s := proto.Size(x.File)
+		n += proto.SizeVarint(15<<3 | proto.WireBytes)
+		n += proto.SizeVarint(uint64(s))
+		n += s
+	case *Backend_Consul:
+		s := proto.Size(x.Consul)
+		n += proto.SizeVarint(16<<3 | proto.WireBytes)
+		n += proto.SizeVarint(uint64(s))
+		n += s
+	case *Backend_Failover:
+		s := proto.Size(x.Failover)
+		n += proto.SizeVarint(12<<3 | proto.WireBytes)
+		n += proto.SizeVarint(uint64(s))
+		n += s
 	case nil:
 	default:
 		panic(fmt.Sprintf("proto: unexpected type %T in oneof", x))
@@ -219,6 +416,45 @@ func _Backend_OneofSizer(msg proto.Message) (n int) {
 	return n
 }
 
+// / Failover sends requests to the first of the backends that has healthy targets, e.g. the pool of the local cluster, and then
+// / a remote kedge in another region.
+type Failover struct {
+	// / backends are the names of other backends of the pool, in order of preference. They can't be failovers themselves.
+	Backends []string `protobuf:"bytes,1,rep,name=backends" json:"backends,omitempty"`
+}
+
+func (m *Failover) Reset()                    { *m = Failover{} }
+func (m *Failover) String() string            { return proto.CompactTextString(m) }
+func (*Failover) ProtoMessage()               {}
+func (*Failover) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{1} }
+
+func (m *Failover) GetBackends() []string {
+	if m != nil {
+		return m.Backends
+	}
+	return nil
+}
+
+// / Locality prefers targets in the zone of kedge, set with the `--server_zone` flag, to avoid cross-zone traffic.
+// / Zones of targets come from their resolver, see `zone_pattern` of SrvResolver and `endpoint_slices` of KubeResolver.
+type Locality struct {
+	// / min_healthy_share is the share of the targets of the local zone that need to be healthy for it to get all the
+	// / requests. Below it, requests spill over to targets in all zones. Between 0 and 1, defaults to 0.5.
+	MinHealthyShare float32 `protobuf:"fixed32,1,opt,name=min_healthy_share,json=minHealthyShare" json:"min_healthy_share,omitempty"`
+}
+
+func (m *Locality) Reset()                    { *m = Locality{} }
+func (m *Locality) String() string            { return proto.CompactTextString(m) }
+func (*Locality) ProtoMessage()               {}
+func (*Locality) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{2} }
+
+func (m *Locality) GetMinHealthyShare() float32 {
+	if m != nil {
+		return m.MinHealthyShare
+	}
+	return 0
+}
+
 type Middleware struct {
 	// Types that are valid to be assigned to Middleware:
 	//	*Middleware_Prometheus
@@ -228,7 +464,7 @@ type Middleware struct {
 func (m *Middleware) Reset()                    { *m = Middleware{} }
 func (m *Middleware) String() string            { return proto.CompactTextString(m) }
 func (*Middleware) ProtoMessage()               {}
-func (*Middleware) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{1} }
+func (*Middleware) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{3} }
 
 type isMiddleware_Middleware interface {
 	isMiddleware_Middleware()
@@ -319,7 +555,7 @@ type Middleware_Retry struct {
 func (m *Middleware_Retry) Reset()                    { *m = Middleware_Retry{} }
 func (m *Middleware_Retry) String() string            { return proto.CompactTextString(m) }
 func (*Middleware_Retry) ProtoMessage()               {}
-func (*Middleware_Retry) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{1, 0} }
+func (*Middleware_Retry) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{3, 0} }
 
 func (m *Middleware_Retry) GetRetryCount() uint32 {
 	if m != nil {
@@ -335,6 +571,82 @@ func (m *Middleware_Retry) GetOnCodes() []uint32 {
 	return nil
 }
 
+// / Timeouts of a backend. Requests that time out return a 504 Gateway Timeout with an `x-kedge-error` header.
+type Timeouts struct {
+	// / connect is the maximum duration of dialing a target, including the TLS handshake. Defaults to 1s.
+	Connect *google_protobuf.Duration `protobuf:"bytes,1,opt,name=connect" json:"connect,omitempty"`
+	// / response_header is the maximum duration between writing the request and receiving the response headers.
+	// / It doesn't limit reading the response body, so it is safe to use with streaming. If not set, there's no limit.
+	ResponseHeader *google_protobuf.Duration `protobuf:"bytes,2,opt,name=response_header,json=responseHeader" json:"response_header,omitempty"`
+	// / idle is how long a connection is kept open without any requests. Defaults to 90s.
+	Idle *google_protobuf.Duration `protobuf:"bytes,3,opt,name=idle" json:"idle,omitempty"`
+}
+
+func (m *Timeouts) Reset()                    { *m = Timeouts{} }
+func (m *Timeouts) String() string            { return proto.CompactTextString(m) }
+func (*Timeouts) ProtoMessage()               {}
+func (*Timeouts) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{4} }
+
+func (m *Timeouts) GetConnect() *google_protobuf.Duration {
+	if m != nil {
+		return m.Connect
+	}
+	return nil
+}
+
+func (m *Timeouts) GetResponseHeader() *google_protobuf.Duration {
+	if m != nil {
+		return m.ResponseHeader
+	}
+	return nil
+}
+
+func (m *Timeouts) GetIdle() *google_protobuf.Duration {
+	if m != nil {
+		return m.Idle
+	}
+	return nil
+}
+
+// / ConnectionPool limits the connections kept open to the targets of a backend.
+type ConnectionPool struct {
+	// / max_idle_per_target is the maximum number of idle connections kept to each target. Defaults to 2.
+	MaxIdlePerTarget uint32 `protobuf:"varint,1,opt,name=max_idle_per_target,json=maxIdlePerTarget" json:"max_idle_per_target,omitempty"`
+	// / max_idle is the maximum number of idle connections kept to all targets. If not set, there's no limit.
+	MaxIdle uint32 `protobuf:"varint,2,opt,name=max_idle,json=maxIdle" json:"max_idle,omitempty"`
+	// / warm_per_target is the number of spare connections kept open to each target, including their TLS (and HTTP2)
+	// / handshakes, which requests use instead of dialing. Spare connections are replaced once used or closed by their
+	// / targets, and kept for up to the idle timeout otherwise. Requests to HTTP2 targets share the connection they
+	// / took, so these only take spares again once it's gone. If not set, connections are only dialed by requests.
+	WarmPerTarget uint32 `protobuf:"varint,3,opt,name=warm_per_target,json=warmPerTarget" json:"warm_per_target,omitempty"`
+}
+
+func (m *ConnectionPool) Reset()                    { *m = ConnectionPool{} }
+func (m *ConnectionPool) String() string            { return proto.CompactTextString(m) }
+func (*ConnectionPool) ProtoMessage()               {}
+func (*ConnectionPool) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{5} }
+
+func (m *ConnectionPool) GetMaxIdlePerTarget() uint32 {
+	if m != nil {
+		return m.MaxIdlePerTarget
+	}
+	return 0
+}
+
+func (m *ConnectionPool) GetMaxIdle() uint32 {
+	if m != nil {
+		return m.MaxIdle
+	}
+	return 0
+}
+
+func (m *ConnectionPool) GetWarmPerTarget() uint32 {
+	if m != nil {
+		return m.WarmPerTarget
+	}
+	return 0
+}
+
 // / Security settings for a backend.
 type Security struct {
 	// / insecure_skip_verify skips the server certificate verification completely.
@@ -347,7 +659,7 @@ type Security struct {
 func (m *Security) Reset()                    { *m = Security{} }
 func (m *Security) String() string            { return proto.CompactTextString(m) }
 func (*Security) ProtoMessage()               {}
-func (*Security) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{2} }
+func (*Security) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{6} }
 
 func (m *Security) GetInsecureSkipVerify() bool {
 	if m != nil {
@@ -365,8 +677,12 @@ func (m *Security) GetConfigName() string {
 
 func init() {
 	proto.RegisterType((*Backend)(nil), "kedge.config.http.backends.Backend")
+	proto.RegisterType((*Failover)(nil), "kedge.config.http.backends.Failover")
+	proto.RegisterType((*Locality)(nil), "kedge.config.http.backends.Locality")
 	proto.RegisterType((*Middleware)(nil), "kedge.config.http.backends.Middleware")
 	proto.RegisterType((*Middleware_Retry)(nil), "kedge.config.http.backends.Middleware.Retry")
+	proto.RegisterType((*Timeouts)(nil), "kedge.config.http.backends.Timeouts")
+	proto.RegisterType((*ConnectionPool)(nil), "kedge.config.http.backends.ConnectionPool")
 	proto.RegisterType((*Security)(nil), "kedge.config.http.backends.Security")
 	proto.RegisterEnum("kedge.config.http.backends.Balancer", Balancer_name, Balancer_value)
 }
@@ -374,34 +690,57 @@ func init() {
 func init() { proto.RegisterFile("kedge/config/http/backends/backend.proto", fileDescriptor0) }
 
 var fileDescriptor0 = []byte{
-	// 455 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x09, 0x6e, 0x88, 0x02, 0xff, 0x8c, 0x52, 0xc1, 0x6e, 0xd3, 0x40,
-	0x10, 0x8d, 0xe3, 0x96, 0x9a, 0x31, 0x05, 0xb4, 0xf4, 0x60, 0xc2, 0x01, 0x2b, 0x42, 0xc8, 0x2a,
-	0xe0, 0x40, 0xb8, 0xf4, 0x04, 0xc8, 0xe1, 0x10, 0x84, 0x48, 0xa5, 0x8d, 0xe0, 0x86, 0x2c, 0x7b,
-	0x3d, 0x4d, 0x57, 0x8e, 0x77, 0xad, 0x5d, 0x27, 0x28, 0xdf, 0xc4, 0x81, 0x5f, 0x44, 0x5e, 0xdb,
-	0x89, 0x7b, 0xa0, 0xf4, 0x36, 0x3b, 0xf3, 0xde, 0x9b, 0xd9, 0x99, 0x07, 0x41, 0x8e, 0xd9, 0x0a,
-	0x27, 0x4c, 0x8a, 0x2b, 0xbe, 0x9a, 0x5c, 0x57, 0x55, 0x39, 0x49, 0x13, 0x96, 0xa3, 0xc8, 0x74,
-	0x17, 0x84, 0xa5, 0x92, 0x95, 0x24, 0x23, 0x83, 0x0c, 0x1b, 0x64, 0x58, 0x23, 0xc3, 0x0e, 0x39,
-	0x7a, 0x73, 0x43, 0x85, 0xc9, 0xa2, 0x90, 0x62, 0xa2, 0x50, 0xcb, 0xf5, 0x16, 0x95, 0x3e, 0x44,
-	0x8d, 0xd4, 0xf8, 0xb7, 0x0d, 0x27, 0x51, 0xc3, 0x25, 0x04, 0x8e, 0x44, 0x52, 0xa0, 0x67, 0xf9,
-	0x56, 0x70, 0x9f, 0x9a, 0x98, 0x7c, 0x02, 0x27, 0x4d, 0xd6, 0x89, 0x60, 0xa8, 0xbc, 0xa1, 0x6f,
-	0x05, 0x0f, 0xa7, 0x2f, 0xc2, 0x7f, 0x77, 0x0f, 0xa3, 0x16, 0x4b, 0xf7, 0x2c, 0xf2, 0x0e, 0xce,
-	0x32, 0xae, 0x93, 0x74, 0x8d, 0x31, 0x93, 0x42, 0x54, 0x2a, 0x61, 0x39, 0x17, 0x2b, 0xcf, 0xf6,
-	0xad, 0xc0, 0xa1, 0x4f, 0xda, 0xda, 0xac, 0x57, 0xaa, 0x9b, 0x6a, 0x64, 0x1b, 0xc5, 0xab, 0x9d,
-	0x77, 0xe4, 0x5b, 0x81, 0x7b, 0x7b, 0xd3, 0x65, 0x8b, 0xa5, 0x7b, 0x16, 0x99, 0x83, 0x5b, 0xf0,
-	0x2c, 0x5b, 0xe3, 0xaf, 0x44, 0xa1, 0xf6, 0x8e, 0x7d, 0x3b, 0x70, 0xa7, 0x2f, 0x6f, 0x13, 0xf9,
-	0xb6, 0x87, 0xd3, 0x3e, 0x95, 0x7c, 0x00, 0x5b, 0xab, 0xad, 0x07, 0x66, 0x8c, 0xf3, 0x9b, 0x0a,
-	0xcd, 0x76, 0xc3, 0xc3, 0x4e, 0x97, 0x6a, 0x4b, 0xdb, 0xc7, 0x7c, 0x40, 0x6b, 0x22, 0xf9, 0x08,
-	0x76, 0x7e, 0xa1, 0x3d, 0xd7, 0xf0, 0x5f, 0xfd, 0x87, 0xff, 0x75, 0x93, 0x62, 0x5f, 0x20, 0xbf,
-	0xd0, 0x11, 0x80, 0xd3, 0x01, 0xc6, 0x7f, 0x2c, 0x80, 0xc3, 0xa0, 0x64, 0x01, 0x50, 0x2a, 0x59,
-	0x60, 0x75, 0x8d, 0x1b, 0x6d, 0xce, 0xe6, 0x4e, 0x5f, 0xdf, 0xed, 0x93, 0x21, 0xc5, 0x4a, 0xed,
-	0xe6, 0x03, 0xda, 0x53, 0x18, 0xcd, 0xe0, 0xd8, 0xa4, 0xc9, 0x73, 0x70, 0x55, 0x1d, 0xc4, 0x4c,
-	0x6e, 0x44, 0x65, 0x94, 0x4f, 0x29, 0x98, 0xd4, 0xac, 0xce, 0x90, 0xa7, 0xe0, 0x48, 0x11, 0x33,
-	0x99, 0xa1, 0xf6, 0x86, 0xbe, 0x1d, 0x9c, 0xd2, 0x13, 0x29, 0x66, 0xf5, 0x33, 0x7a, 0xd0, 0x1f,
-	0x71, 0xfc, 0x13, 0x9c, 0xee, 0x3c, 0xe4, 0x2d, 0x9c, 0x71, 0x61, 0x4e, 0x84, 0xb1, 0xce, 0x79,
-	0x19, 0x6f, 0x51, 0xf1, 0xab, 0x9d, 0x91, 0x77, 0x28, 0xe9, 0x6a, 0xcb, 0x9c, 0x97, 0x3f, 0x4c,
-	0xa5, 0x9e, 0xa3, 0xf9, 0x47, 0x6c, 0x8c, 0x39, 0x34, 0xc6, 0x84, 0x26, 0xb5, 0x48, 0x0a, 0x3c,
-	0x7f, 0x06, 0x4e, 0x67, 0x39, 0xf2, 0x08, 0x5c, 0x7a, 0xf9, 0x7d, 0xf1, 0x39, 0xa6, 0x97, 0xd1,
-	0x97, 0xc5, 0xe3, 0x41, 0x7a, 0xcf, 0x58, 0xfc, 0xfd, 0xdf, 0x00, 0x00, 0x00, 0xff, 0xff, 0x4a,
-	0xe6, 0xac, 0x82, 0x59, 0x03, 0x00, 0x00,
+	// 829 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x09, 0x6e, 0x88, 0x02, 0xff, 0x8c, 0x55, 0xdf, 0x6f, 0xdb, 0x36,
+	0x10, 0x8e, 0xe2, 0x34, 0x56, 0xce, 0xb5, 0x9d, 0x31, 0x1d, 0xc0, 0x7a, 0xc0, 0x66, 0x18, 0x43,
+	0x60, 0x64, 0x8b, 0xbc, 0xa5, 0xc0, 0xd0, 0xa7, 0xfd, 0xb0, 0x83, 0xd6, 0xc5, 0xd6, 0xb4, 0xa0,
+	0xbb, 0xbd, 0x0d, 0x02, 0x2d, 0xd1, 0x36, 0x61, 0x89, 0x34, 0x48, 0xc9, 0x4d, 0x5e, 0xf7, 0xf7,
+	0x0c, 0x18, 0xb0, 0xbf, 0x70, 0x20, 0x25, 0x2a, 0x0a, 0xb0, 0xc6, 0x7e, 0x23, 0xef, 0xbe, 0xef,
+	0xe3, 0xdd, 0xe9, 0xee, 0x04, 0xc3, 0x35, 0x8b, 0x97, 0x6c, 0x14, 0x49, 0xb1, 0xe0, 0xcb, 0xd1,
+	0x2a, 0xcb, 0x36, 0xa3, 0x39, 0x8d, 0xd6, 0x4c, 0xc4, 0xda, 0x1d, 0x82, 0x8d, 0x92, 0x99, 0x44,
+	0x3d, 0x8b, 0x0c, 0x0a, 0x64, 0x60, 0x90, 0x81, 0x43, 0xf6, 0x2e, 0x1f, 0xa8, 0x44, 0x32, 0x4d,
+	0xa5, 0x18, 0x29, 0xa6, 0x65, 0xb2, 0x65, 0x4a, 0xdf, 0x9f, 0x0a, 0xa9, 0xde, 0x97, 0x4b, 0x29,
+	0x97, 0x09, 0x1b, 0xd9, 0xdb, 0x3c, 0x5f, 0x8c, 0xe2, 0x5c, 0xd1, 0x8c, 0x4b, 0x51, 0xf8, 0x07,
+	0x7f, 0xfb, 0xd0, 0x1c, 0x17, 0xda, 0x08, 0xc1, 0x91, 0xa0, 0x29, 0xc3, 0x5e, 0xdf, 0x1b, 0x9e,
+	0x10, 0x7b, 0x46, 0x3f, 0x83, 0x3f, 0xa7, 0x09, 0x15, 0x11, 0x53, 0xf8, 0xb0, 0xef, 0x0d, 0x3b,
+	0x57, 0x5f, 0x07, 0x9f, 0x8e, 0x2e, 0x18, 0x97, 0x58, 0x52, 0xb1, 0xd0, 0xf7, 0xf0, 0x2c, 0xe6,
+	0x9a, 0xce, 0x13, 0x16, 0x46, 0x52, 0x88, 0x4c, 0xd1, 0x68, 0xcd, 0xc5, 0x12, 0x37, 0xfa, 0xde,
+	0xd0, 0x27, 0x67, 0xa5, 0x6f, 0x52, 0x73, 0x99, 0x47, 0x35, 0x8b, 0x72, 0xc5, 0xb3, 0x3b, 0x7c,
+	0xd4, 0xf7, 0x86, 0xad, 0xc7, 0x1f, 0x9d, 0x95, 0x58, 0x52, 0xb1, 0xd0, 0x14, 0x5a, 0x29, 0x8f,
+	0xe3, 0x84, 0x7d, 0xa4, 0x8a, 0x69, 0xfc, 0xa4, 0xdf, 0x18, 0xb6, 0xae, 0xce, 0x1f, 0x13, 0x79,
+	0x5b, 0xc1, 0x49, 0x9d, 0x6a, 0x62, 0xc9, 0x78, 0xca, 0x64, 0x9e, 0x69, 0x7c, 0xbc, 0x3b, 0x96,
+	0x0f, 0x25, 0x96, 0x54, 0x2c, 0x34, 0x83, 0xae, 0x49, 0x9c, 0x45, 0xa6, 0xec, 0xe1, 0x46, 0xca,
+	0x04, 0x37, 0xad, 0xd0, 0xc5, 0x63, 0x42, 0x93, 0x8a, 0xf2, 0x5e, 0xca, 0x84, 0x74, 0xa2, 0x07,
+	0x77, 0x13, 0x56, 0x22, 0x23, 0x9a, 0x98, 0x12, 0xf9, 0xbb, 0xc3, 0xfa, 0xad, 0xc4, 0x92, 0x8a,
+	0x85, 0xde, 0xc2, 0xe7, 0xae, 0x59, 0xc2, 0xa5, 0xa2, 0x11, 0x0b, 0x37, 0x4c, 0x71, 0x19, 0xe3,
+	0x13, 0x2b, 0xf7, 0x3c, 0x28, 0x3a, 0x27, 0x70, 0x9d, 0x13, 0x5c, 0x97, 0x9d, 0x43, 0xce, 0x1c,
+	0xef, 0xb5, 0xa1, 0xbd, 0xb7, 0x2c, 0xf4, 0x23, 0x34, 0xb4, 0xda, 0x62, 0xf8, 0xbf, 0xcc, 0x8a,
+	0x2e, 0x0d, 0xee, 0x7b, 0x73, 0xa6, 0xb6, 0xa4, 0xbc, 0x4c, 0x0f, 0x88, 0x21, 0xa2, 0x9f, 0xa0,
+	0xb1, 0x7e, 0xa9, 0x71, 0xcb, 0xf2, 0xbf, 0xd9, 0xc1, 0xff, 0x35, 0x9f, 0xb3, 0xba, 0xc0, 0xfa,
+	0xa5, 0x46, 0xaf, 0xe1, 0x58, 0x67, 0x34, 0xe3, 0x11, 0x6e, 0x5b, 0x8d, 0xcb, 0x5d, 0x31, 0x58,
+	0x70, 0x4d, 0xa5, 0xa4, 0x9b, 0x4c, 0x62, 0xa1, 0x71, 0x67, 0xaf, 0x4c, 0xae, 0x85, 0xae, 0x07,
+	0x12, 0x0b, 0x8d, 0x7e, 0x81, 0xa3, 0x05, 0x4f, 0x18, 0xee, 0xee, 0x95, 0xca, 0x2b, 0x9e, 0xd4,
+	0x53, 0xb1, 0x54, 0x93, 0x4b, 0x24, 0x85, 0xce, 0x13, 0x7c, 0xba, 0x57, 0x2e, 0x13, 0x0b, 0xae,
+	0xe7, 0x52, 0xd0, 0xd1, 0x18, 0xfc, 0x05, 0xe5, 0x89, 0xdc, 0x32, 0x85, 0x9f, 0xee, 0x6e, 0x93,
+	0x57, 0x25, 0x76, 0x7a, 0x40, 0x2a, 0xde, 0x18, 0xc0, 0x77, 0x2f, 0x0d, 0xce, 0xc1, 0x77, 0x18,
+	0xd4, 0x03, 0xdf, 0x11, 0xb1, 0xd7, 0x6f, 0x0c, 0x4f, 0x48, 0x75, 0x1f, 0xfc, 0x00, 0xbe, 0x6b,
+	0x39, 0x74, 0x01, 0x9f, 0xa5, 0x5c, 0x84, 0x2b, 0x46, 0x93, 0x6c, 0x75, 0x17, 0xea, 0x15, 0x55,
+	0xc5, 0x8e, 0x39, 0x24, 0xdd, 0x94, 0x8b, 0x69, 0x61, 0x9f, 0x19, 0xf3, 0xe0, 0x1f, 0x0f, 0xe0,
+	0x7e, 0x12, 0xd1, 0x0d, 0xc0, 0x46, 0xc9, 0x94, 0x65, 0x2b, 0x96, 0x6b, 0xcb, 0x69, 0x5d, 0x7d,
+	0xbb, 0xdf, 0x14, 0x07, 0x84, 0x65, 0xea, 0x6e, 0x7a, 0x40, 0x6a, 0x0a, 0xbd, 0x09, 0x3c, 0xb1,
+	0x66, 0xf4, 0x15, 0xb4, 0x94, 0x39, 0x84, 0x91, 0xcc, 0x45, 0x66, 0x95, 0xdb, 0x04, 0xac, 0x69,
+	0x62, 0x2c, 0xe8, 0x39, 0xf8, 0x52, 0x84, 0x91, 0x8c, 0x99, 0xc6, 0x87, 0xfd, 0xc6, 0xb0, 0x4d,
+	0x9a, 0x52, 0x4c, 0xcc, 0x75, 0xfc, 0xb4, 0x1e, 0xe2, 0xe0, 0x5f, 0x0f, 0x7c, 0x37, 0xf4, 0xe8,
+	0x05, 0x34, 0xcb, 0x39, 0xc5, 0xde, 0xae, 0x29, 0x72, 0x48, 0x34, 0x86, 0xae, 0x62, 0x7a, 0x23,
+	0x85, 0x66, 0xa6, 0x48, 0x71, 0xb9, 0x69, 0x1f, 0x25, 0x77, 0x1c, 0x63, 0x6a, 0x09, 0xe8, 0x12,
+	0x8e, 0x78, 0x9c, 0x30, 0xdc, 0xd8, 0x45, 0xb4, 0xb0, 0xc1, 0x5f, 0x1e, 0x74, 0x1e, 0x2e, 0x18,
+	0x74, 0x09, 0x67, 0x29, 0xbd, 0x0d, 0x8d, 0xdb, 0x2c, 0x82, 0x30, 0xa3, 0x6a, 0xc9, 0x5c, 0x65,
+	0x4e, 0x53, 0x7a, 0xfb, 0x26, 0x4e, 0xcc, 0xac, 0x7f, 0xb0, 0x76, 0x53, 0x1f, 0x07, 0xb7, 0xd1,
+	0xb6, 0x49, 0xb3, 0xc4, 0xa0, 0x73, 0xe8, 0x7e, 0xa4, 0x2a, 0xad, 0xab, 0x34, 0x2c, 0xa2, 0x6d,
+	0xcc, 0x95, 0xc4, 0xe0, 0x4f, 0xf0, 0xdd, 0xe6, 0x46, 0xdf, 0xc1, 0x33, 0x2e, 0xec, 0xf6, 0x66,
+	0xa1, 0x5e, 0xf3, 0x4d, 0xb8, 0x65, 0x8a, 0x2f, 0xee, 0xec, 0xf3, 0x3e, 0x41, 0xce, 0x37, 0x5b,
+	0xf3, 0xcd, 0x1f, 0xd6, 0x63, 0xbe, 0x60, 0xd1, 0x01, 0xa1, 0xfd, 0x67, 0x1d, 0xda, 0x7f, 0x16,
+	0x14, 0xa6, 0x1b, 0x9a, 0xb2, 0x8b, 0x2f, 0xc0, 0x77, 0x7f, 0x23, 0xd4, 0x85, 0x16, 0x79, 0xf7,
+	0xfb, 0xcd, 0x75, 0x48, 0xde, 0x8d, 0xdf, 0xdc, 0x9c, 0x1e, 0xcc, 0x8f, 0x6d, 0x65, 0x5e, 0xfc,
+	0x17, 0x00, 0x00, 0xff, 0xff, 0xa0, 0x89, 0x1f, 0x6c, 0x94, 0x07, 0x00, 0x00,
 }
@@ -12,12 +12,17 @@ It is generated from these files:
 It has these top-level messages:
 	Adhoc
 	Route
+	WeightedBackend
+	Sticky
+	Mirror
 */
 package kedge_config_http_routes
 
 import proto "github.com/golang/protobuf/proto"
 import fmt "fmt"
 import math "math"
+import google_protobuf "github.com/golang/protobuf/ptypes/duration"
+import  kedge_config_common_auth "github.com/mwitkow/kedge/_protogen/kedge/config/common/auth"
 
 // Reference imports to suppress errors if they are not otherwise used.
 var _ = proto.Marshal
@@ -41,6 +46,26 @@ type Adhoc struct {
 	DnsNameMatcher string `protobuf:"bytes,1,opt,name=dns_name_matcher,json=dnsNameMatcher" json:"dns_name_matcher,omitempty"`
 	// / Port controls the :port behaviour of the URI requested.
 	Port *Adhoc_Port `protobuf:"bytes,2,opt,name=port" json:"port,omitempty"`
+	// / authorization is the identity clients need to present to use this rule.
+	// / If not present, any client can use the rule.
+	Authorization *kedge_config_common_auth.ClientAuthorization `protobuf:"bytes,3,opt,name=authorization" json:"authorization,omitempty"`
+	// / allowed_cidrs restricts the IP addresses the DNS name may resolve to, e.g. "10.0.0.0/8" for in-cluster pods.
+	// / The check is done after resolution, so that a DNS name can't be pointed at other destinations.
+	// / If not present, all addresses are allowed, except denied_cidrs.
+	AllowedCidrs []string `protobuf:"bytes,4,rep,name=allowed_cidrs,json=allowedCidrs" json:"allowed_cidrs,omitempty"`
+	// / denied_cidrs are IP addresses the DNS name may never resolve to, e.g. "127.0.0.0/8", "169.254.169.254/32" for
+	// / the metadata endpoint or the IP of the API server. It takes precedence over allowed_cidrs.
+	// / Resolved addresses that are denied are skipped, and requests that have no allowed addresses are rejected.
+	DeniedCidrs []string `protobuf:"bytes,5,rep,name=denied_cidrs,json=deniedCidrs" json:"denied_cidrs,omitempty"`
+	// / security makes requests to the destinations use HTTPS. If not present, plain HTTP is used.
+	// / It is not supported for gRPC adhoc rules.
+	Security *Adhoc_Security `protobuf:"bytes,6,opt,name=security" json:"security,omitempty"`
+	// / timeouts controls how long connections and requests to the destinations can take.
+	// / It is not supported for gRPC adhoc rules.
+	Timeouts *Adhoc_Timeouts `protobuf:"bytes,7,opt,name=timeouts" json:"timeouts,omitempty"`
+	// / connection_pool limits the connections kept open to the destinations of this rule.
+	// / It is not supported for gRPC adhoc rules.
+	ConnectionPool *Adhoc_ConnectionPool `protobuf:"bytes,8,opt,name=connection_pool,json=connectionPool" json:"connection_pool,omitempty"`
 }
 
 func (m *Adhoc) Reset()                    { *m = Adhoc{} }
@@ -62,11 +87,56 @@ func (m *Adhoc) GetPort() *Adhoc_Port {
 	return nil
 }
 
+func (m *Adhoc) GetAuthorization() *kedge_config_common_auth.ClientAuthorization {
+	if m != nil {
+		return m.Authorization
+	}
+	return nil
+}
+
+func (m *Adhoc) GetAllowedCidrs() []string {
+	if m != nil {
+		return m.AllowedCidrs
+	}
+	return nil
+}
+
+func (m *Adhoc) GetDeniedCidrs() []string {
+	if m != nil {
+		return m.DeniedCidrs
+	}
+	return nil
+}
+
+func (m *Adhoc) GetSecurity() *Adhoc_Security {
+	if m != nil {
+		return m.Security
+	}
+	return nil
+}
+
+func (m *Adhoc) GetTimeouts() *Adhoc_Timeouts {
+	if m != nil {
+		return m.Timeouts
+	}
+	return nil
+}
+
+func (m *Adhoc) GetConnectionPool() *Adhoc_ConnectionPool {
+	if m != nil {
+		return m.ConnectionPool
+	}
+	return nil
+}
+
 // / Port controls how the :port part of the URI is processed.
 type Adhoc_Port struct {
 	// / default is the default port used if no entry is present.
 	// / This defaults to 80.
 	Default uint32 `protobuf:"varint,1,opt,name=default" json:"default,omitempty"`
+	// / srv makes requests without a port use the port of a DNS SRV record of the host, overriding default.
+	// / If the SRV lookup fails, default is used.
+	Srv *Adhoc_Port_Srv `protobuf:"bytes,2,opt,name=srv" json:"srv,omitempty"`
 	// / allowed ports is a list of whitelisted ports that this Adhoc rule will allow.
 	Allowed []uint32 `protobuf:"varint,3,rep,packed,name=allowed" json:"allowed,omitempty"`
 	// / allowed_ranges is a list of whitelisted port ranges that this Adhoc rule will allow.
@@ -85,6 +155,13 @@ func (m *Adhoc_Port) GetDefault() uint32 {
 	return 0
 }
 
+func (m *Adhoc_Port) GetSrv() *Adhoc_Port_Srv {
+	if m != nil {
+		return m.Srv
+	}
+	return nil
+}
+
 func (m *Adhoc_Port) GetAllowed() []uint32 {
 	if m != nil {
 		return m.Allowed
@@ -99,6 +176,43 @@ func (m *Adhoc_Port) GetAllowedRanges() []*Adhoc_Port_Range {
 	return nil
 }
 
+type Adhoc_Port_Srv struct {
+	// / service is the service part of the looked up name, e.g. "http" for "_http._tcp.<host>".
+	// / This defaults to "http".
+	Service string `protobuf:"bytes,1,opt,name=service" json:"service,omitempty"`
+	// / protocol is the protocol part of the looked up name, e.g. "tcp" for "_http._tcp.<host>".
+	// / This defaults to "tcp".
+	Protocol string `protobuf:"bytes,2,opt,name=protocol" json:"protocol,omitempty"`
+	// / use_target makes the request be sent to the target of the SRV record, instead of the requested host.
+	UseTarget bool `protobuf:"varint,3,opt,name=use_target,json=useTarget" json:"use_target,omitempty"`
+}
+
+func (m *Adhoc_Port_Srv) Reset()                    { *m = Adhoc_Port_Srv{} }
+func (m *Adhoc_Port_Srv) String() string            { return proto.CompactTextString(m) }
+func (*Adhoc_Port_Srv) ProtoMessage()               {}
+func (*Adhoc_Port_Srv) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{0, 0, 0} }
+
+func (m *Adhoc_Port_Srv) GetService() string {
+	if m != nil {
+		return m.Service
+	}
+	return ""
+}
+
+func (m *Adhoc_Port_Srv) GetProtocol() string {
+	if m != nil {
+		return m.Protocol
+	}
+	return ""
+}
+
+func (m *Adhoc_Port_Srv) GetUseTarget() bool {
+	if m != nil {
+		return m.UseTarget
+	}
+	return false
+}
+
 type Adhoc_Port_Range struct {
 	// / from is an inclusive lower bound for the port range
 	From uint32 `protobuf:"varint,1,opt,name=from" json:"from,omitempty"`
@@ -109,7 +223,7 @@ type Adhoc_Port_Range struct {
 func (m *Adhoc_Port_Range) Reset()                    { *m = Adhoc_Port_Range{} }
 func (m *Adhoc_Port_Range) String() string            { return proto.CompactTextString(m) }
 func (*Adhoc_Port_Range) ProtoMessage()               {}
-func (*Adhoc_Port_Range) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{0, 0, 0} }
+func (*Adhoc_Port_Range) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{0, 0, 1} }
 
 func (m *Adhoc_Port_Range) GetFrom() uint32 {
 	if m != nil {
@@ -125,30 +239,160 @@ func (m *Adhoc_Port_Range) GetTo() uint32 {
 	return 0
 }
 
+type Adhoc_Security struct {
+	// / insecure_skip_verify skips the server certificate verification completely.
+	// / This should *not* be used in production software.
+	InsecureSkipVerify bool `protobuf:"varint,1,opt,name=insecure_skip_verify,json=insecureSkipVerify" json:"insecure_skip_verify,omitempty"`
+	// / config_name is the name of the TLS client config used to verify destinations and present client certificates.
+	// / If not set, destinations are verified using the system roots.
+	ConfigName string `protobuf:"bytes,2,opt,name=config_name,json=configName" json:"config_name,omitempty"`
+}
+
+func (m *Adhoc_Security) Reset()                    { *m = Adhoc_Security{} }
+func (m *Adhoc_Security) String() string            { return proto.CompactTextString(m) }
+func (*Adhoc_Security) ProtoMessage()               {}
+func (*Adhoc_Security) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{0, 1} }
+
+func (m *Adhoc_Security) GetInsecureSkipVerify() bool {
+	if m != nil {
+		return m.InsecureSkipVerify
+	}
+	return false
+}
+
+func (m *Adhoc_Security) GetConfigName() string {
+	if m != nil {
+		return m.ConfigName
+	}
+	return ""
+}
+
+type Adhoc_Timeouts struct {
+	// / connect is the maximum duration of dialing a destination, including the TLS handshake. Defaults to 10s.
+	Connect *google_protobuf.Duration `protobuf:"bytes,1,opt,name=connect" json:"connect,omitempty"`
+	// / response_header is the maximum duration between writing the request and receiving the response headers.
+	// / If not set, there's no limit.
+	ResponseHeader *google_protobuf.Duration `protobuf:"bytes,2,opt,name=response_header,json=responseHeader" json:"response_header,omitempty"`
+	// / idle is how long a connection is kept open without any requests. Defaults to 90s.
+	Idle *google_protobuf.Duration `protobuf:"bytes,3,opt,name=idle" json:"idle,omitempty"`
+}
+
+func (m *Adhoc_Timeouts) Reset()                    { *m = Adhoc_Timeouts{} }
+func (m *Adhoc_Timeouts) String() string            { return proto.CompactTextString(m) }
+func (*Adhoc_Timeouts) ProtoMessage()               {}
+func (*Adhoc_Timeouts) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{0, 2} }
+
+func (m *Adhoc_Timeouts) GetConnect() *google_protobuf.Duration {
+	if m != nil {
+		return m.Connect
+	}
+	return nil
+}
+
+func (m *Adhoc_Timeouts) GetResponseHeader() *google_protobuf.Duration {
+	if m != nil {
+		return m.ResponseHeader
+	}
+	return nil
+}
+
+func (m *Adhoc_Timeouts) GetIdle() *google_protobuf.Duration {
+	if m != nil {
+		return m.Idle
+	}
+	return nil
+}
+
+type Adhoc_ConnectionPool struct {
+	// / max_idle_per_host is the maximum number of idle connections kept to each destination. Defaults to 2.
+	MaxIdlePerHost uint32 `protobuf:"varint,1,opt,name=max_idle_per_host,json=maxIdlePerHost" json:"max_idle_per_host,omitempty"`
+	// / max_idle is the maximum number of idle connections kept to all destinations. If not set, there's no limit.
+	MaxIdle uint32 `protobuf:"varint,2,opt,name=max_idle,json=maxIdle" json:"max_idle,omitempty"`
+	// / max_conns is the maximum number of connections open to all destinations. When the limit is reached, idle
+	// / connections are closed, and requests wait for the ones in use. If not set, there's no limit.
+	MaxConns uint32 `protobuf:"varint,3,opt,name=max_conns,json=maxConns" json:"max_conns,omitempty"`
+}
+
+func (m *Adhoc_ConnectionPool) Reset()                    { *m = Adhoc_ConnectionPool{} }
+func (m *Adhoc_ConnectionPool) String() string            { return proto.CompactTextString(m) }
+func (*Adhoc_ConnectionPool) ProtoMessage()               {}
+func (*Adhoc_ConnectionPool) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{0, 3} }
+
+func (m *Adhoc_ConnectionPool) GetMaxIdlePerHost() uint32 {
+	if m != nil {
+		return m.MaxIdlePerHost
+	}
+	return 0
+}
+
+func (m *Adhoc_ConnectionPool) GetMaxIdle() uint32 {
+	if m != nil {
+		return m.MaxIdle
+	}
+	return 0
+}
+
+func (m *Adhoc_ConnectionPool) GetMaxConns() uint32 {
+	if m != nil {
+		return m.MaxConns
+	}
+	return 0
+}
+
 func init() {
 	proto.RegisterType((*Adhoc)(nil), "kedge.config.http.routes.Adhoc")
 	proto.RegisterType((*Adhoc_Port)(nil), "kedge.config.http.routes.Adhoc.Port")
+	proto.RegisterType((*Adhoc_Port_Srv)(nil), "kedge.config.http.routes.Adhoc.Port.Srv")
 	proto.RegisterType((*Adhoc_Port_Range)(nil), "kedge.config.http.routes.Adhoc.Port.Range")
+	proto.RegisterType((*Adhoc_Security)(nil), "kedge.config.http.routes.Adhoc.Security")
+	proto.RegisterType((*Adhoc_Timeouts)(nil), "kedge.config.http.routes.Adhoc.Timeouts")
+	proto.RegisterType((*Adhoc_ConnectionPool)(nil), "kedge.config.http.routes.Adhoc.ConnectionPool")
 }
 
 func init() { proto.RegisterFile("kedge/config/http/routes/adhoc.proto", fileDescriptor0) }
 
 var fileDescriptor0 = []byte{
-	// 251 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x09, 0x6e, 0x88, 0x02, 0xff, 0x8c, 0x90, 0xcd, 0x4a, 0xc4, 0x30,
-	0x14, 0x85, 0xe9, 0xcf, 0x28, 0xde, 0xa1, 0x45, 0xb2, 0x0a, 0xb3, 0x2a, 0x32, 0x8b, 0xa2, 0x90,
-	0xc2, 0xb8, 0x71, 0xeb, 0x03, 0x28, 0x9a, 0x17, 0x28, 0xb1, 0x49, 0x5b, 0xb1, 0xcd, 0x2d, 0xc9,
-	0x1d, 0x7c, 0x25, 0x9f, 0xc1, 0xa7, 0x93, 0xa4, 0x75, 0x29, 0xb8, 0x3b, 0x27, 0xf7, 0xbb, 0x27,
-	0x27, 0x81, 0xe3, 0x87, 0xd1, 0x83, 0x69, 0x3a, 0xb4, 0xfd, 0xfb, 0xd0, 0x8c, 0x44, 0x4b, 0xe3,
-	0xf0, 0x4c, 0xc6, 0x37, 0x4a, 0x8f, 0xd8, 0x89, 0xc5, 0x21, 0x21, 0xe3, 0x91, 0x12, 0x2b, 0x25,
-	0x02, 0x25, 0x56, 0xea, 0xe6, 0x2b, 0x85, 0xdd, 0x63, 0x20, 0x59, 0x0d, 0xd7, 0xda, 0xfa, 0xd6,
-	0xaa, 0xd9, 0xb4, 0xb3, 0xa2, 0x6e, 0x34, 0x8e, 0x27, 0x55, 0x52, 0x5f, 0xc9, 0x52, 0x5b, 0xff,
-	0xac, 0x66, 0xf3, 0xb4, 0x9e, 0xb2, 0x07, 0xc8, 0x17, 0x74, 0xc4, 0xd3, 0x2a, 0xa9, 0xf7, 0xa7,
-	0xa3, 0xf8, 0x2b, 0x5c, 0xc4, 0x60, 0xf1, 0x82, 0x8e, 0x64, 0xdc, 0x38, 0x7c, 0x27, 0x90, 0x07,
-	0xcb, 0x38, 0x5c, 0x6a, 0xd3, 0xab, 0xf3, 0x44, 0xf1, 0x8e, 0x42, 0xfe, 0xda, 0x30, 0x51, 0xd3,
-	0x84, 0x9f, 0x46, 0xf3, 0xac, 0xca, 0xc2, 0x64, 0xb3, 0xec, 0x15, 0xca, 0x4d, 0xb6, 0x4e, 0xd9,
-	0xc1, 0x78, 0x9e, 0x57, 0x59, 0xbd, 0x3f, 0xdd, 0xfe, 0xa7, 0x80, 0x90, 0x61, 0x45, 0x16, 0x5b,
-	0x42, 0x74, 0xfe, 0x70, 0x07, 0xbb, 0xa8, 0x18, 0x83, 0xbc, 0x77, 0x38, 0x6f, 0x65, 0xa2, 0x66,
-	0x25, 0xa4, 0x84, 0xf1, 0x91, 0x85, 0x4c, 0x09, 0xdf, 0x2e, 0xe2, 0x5f, 0xde, 0xff, 0x04, 0x00,
-	0x00, 0xff, 0xff, 0xc7, 0x58, 0xa5, 0xc7, 0x73, 0x01, 0x00, 0x00,
+	// 662 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x09, 0x6e, 0x88, 0x02, 0xff, 0x8c, 0x54, 0xcf, 0x6f, 0xd3, 0x4a,
+	0x10, 0x56, 0x7e, 0xb4, 0x71, 0x26, 0x8d, 0xfb, 0xde, 0xea, 0x1d, 0x5c, 0x3f, 0x01, 0x81, 0xf6,
+	0x10, 0x40, 0xb5, 0x51, 0x7b, 0x41, 0xdc, 0x4a, 0x7b, 0x28, 0x07, 0x50, 0xd9, 0x54, 0x20, 0x21,
+	0x21, 0xcb, 0xb5, 0x27, 0xc9, 0xaa, 0xb6, 0xd7, 0xda, 0x5d, 0x87, 0x96, 0x3f, 0x8b, 0x7f, 0x83,
+	0x1b, 0x7f, 0x11, 0xda, 0xb1, 0x1d, 0x91, 0x03, 0x4a, 0x2f, 0x51, 0x66, 0xf6, 0xfb, 0xbe, 0xf9,
+	0x66, 0x67, 0xbc, 0x70, 0x74, 0x8b, 0xe9, 0x02, 0xc3, 0x44, 0x16, 0x73, 0xb1, 0x08, 0x97, 0xc6,
+	0x94, 0xa1, 0x92, 0x95, 0x41, 0x1d, 0xc6, 0xe9, 0x52, 0x26, 0x41, 0xa9, 0xa4, 0x91, 0xcc, 0x23,
+	0x54, 0x50, 0xa3, 0x02, 0x8b, 0x0a, 0x6a, 0x94, 0xff, 0x78, 0x21, 0xe5, 0x22, 0xc3, 0x90, 0x70,
+	0x37, 0xd5, 0x3c, 0x4c, 0x2b, 0x15, 0x1b, 0x21, 0x8b, 0x9a, 0xe9, 0x1f, 0x6e, 0xe8, 0x27, 0x32,
+	0xcf, 0x65, 0x11, 0xc6, 0x95, 0x59, 0xd2, 0x4f, 0x0d, 0x7a, 0xf6, 0x73, 0x08, 0x3b, 0x67, 0xb6,
+	0x1c, 0x9b, 0xc2, 0x3f, 0x69, 0xa1, 0xa3, 0x22, 0xce, 0x31, 0xca, 0x63, 0x93, 0x2c, 0x51, 0x79,
+	0x9d, 0x49, 0x67, 0x3a, 0xe4, 0x6e, 0x5a, 0xe8, 0x0f, 0x71, 0x8e, 0xef, 0xeb, 0x2c, 0x7b, 0x0d,
+	0xfd, 0x52, 0x2a, 0xe3, 0x75, 0x27, 0x9d, 0xe9, 0xe8, 0xe4, 0x28, 0xf8, 0x9b, 0xc3, 0x80, 0x84,
+	0x83, 0x2b, 0xa9, 0x0c, 0x27, 0x06, 0x9b, 0xc1, 0xd8, 0xd6, 0x96, 0x4a, 0x7c, 0x27, 0xa7, 0x5e,
+	0x8f, 0x24, 0x8e, 0x37, 0x25, 0x6a, 0xab, 0x01, 0xb9, 0x3c, 0xcf, 0x04, 0x16, 0xe6, 0xec, 0x4f,
+	0x12, 0xdf, 0xd4, 0x60, 0x87, 0x30, 0x8e, 0xb3, 0x4c, 0x7e, 0xc3, 0x34, 0x4a, 0x44, 0xaa, 0xb4,
+	0xd7, 0x9f, 0xf4, 0xa6, 0x43, 0xbe, 0xd7, 0x24, 0xcf, 0x6d, 0x8e, 0x3d, 0x85, 0xbd, 0x14, 0x0b,
+	0xb1, 0xc6, 0xec, 0x10, 0x66, 0x54, 0xe7, 0x6a, 0xc8, 0x05, 0x38, 0x1a, 0x93, 0x4a, 0x09, 0x73,
+	0xef, 0xed, 0x92, 0xaf, 0xe9, 0xb6, 0xd6, 0x66, 0x0d, 0x9e, 0xaf, 0x99, 0x56, 0xc5, 0x88, 0x1c,
+	0x65, 0x65, 0xb4, 0x37, 0x78, 0x98, 0xca, 0x75, 0x83, 0xe7, 0x6b, 0x26, 0xfb, 0x0c, 0xfb, 0x89,
+	0x2c, 0x0a, 0x4c, 0x6c, 0x87, 0x51, 0x29, 0x65, 0xe6, 0x39, 0x24, 0x16, 0x6c, 0x13, 0x3b, 0x5f,
+	0xd3, 0xae, 0xa4, 0xcc, 0xb8, 0x9b, 0x6c, 0xc4, 0xfe, 0xaf, 0x2e, 0xf4, 0xed, 0x40, 0x98, 0x07,
+	0x83, 0x14, 0xe7, 0x71, 0x95, 0x19, 0x9a, 0xf2, 0x98, 0xb7, 0x21, 0x7b, 0x03, 0x3d, 0xad, 0x56,
+	0x5e, 0xf7, 0x61, 0xe6, 0xad, 0x58, 0x30, 0x53, 0x2b, 0x6e, 0x49, 0x56, 0xb5, 0xb9, 0x76, 0xaf,
+	0x37, 0xe9, 0x59, 0xd5, 0x26, 0x64, 0x1f, 0xc1, 0x6d, 0xa7, 0xa4, 0xe2, 0x62, 0x81, 0xf5, 0x98,
+	0x46, 0x27, 0x2f, 0x1e, 0x54, 0x80, 0x5b, 0x0a, 0x6f, 0xe7, 0x4c, 0x91, 0xf6, 0xbf, 0x40, 0x6f,
+	0x56, 0xd7, 0xd4, 0xa8, 0x56, 0x22, 0xc1, 0x66, 0x5f, 0xdb, 0x90, 0xf9, 0xe0, 0xd0, 0x96, 0x27,
+	0x32, 0xa3, 0x76, 0x86, 0x7c, 0x1d, 0xb3, 0x47, 0x00, 0x95, 0xc6, 0xc8, 0xc4, 0x6a, 0x81, 0x86,
+	0xf6, 0xd0, 0xe1, 0xc3, 0x4a, 0xe3, 0x35, 0x25, 0xfc, 0x97, 0xb0, 0x43, 0x55, 0x18, 0x83, 0xfe,
+	0x5c, 0xc9, 0xbc, 0xb9, 0x24, 0xfa, 0xcf, 0x5c, 0xe8, 0x1a, 0x49, 0x8a, 0x63, 0xde, 0x35, 0xd2,
+	0xff, 0x0a, 0x4e, 0xbb, 0x09, 0xec, 0x15, 0xfc, 0x27, 0x0a, 0xda, 0x06, 0x8c, 0xf4, 0xad, 0x28,
+	0xa3, 0x15, 0x2a, 0x31, 0xbf, 0x27, 0xbe, 0xc3, 0x59, 0x7b, 0x36, 0xbb, 0x15, 0xe5, 0x27, 0x3a,
+	0x61, 0x4f, 0x60, 0x54, 0x37, 0x4f, 0xdf, 0x5e, 0x63, 0x14, 0xea, 0x94, 0xfd, 0xec, 0xfc, 0x1f,
+	0x1d, 0x70, 0xda, 0x1d, 0x61, 0xa7, 0x30, 0x68, 0x46, 0x4a, 0x92, 0xa3, 0x93, 0x83, 0xa0, 0x7e,
+	0x07, 0x82, 0xf6, 0x1d, 0x08, 0x2e, 0x9a, 0x77, 0x80, 0xb7, 0x48, 0xf6, 0x16, 0xf6, 0x15, 0xea,
+	0x52, 0x16, 0x1a, 0xa3, 0x25, 0xc6, 0x29, 0x2a, 0xaf, 0xbb, 0x8d, 0xec, 0xb6, 0x8c, 0x4b, 0x22,
+	0xb0, 0x63, 0xe8, 0x8b, 0x34, 0x43, 0xaf, 0xb7, 0x8d, 0x48, 0x30, 0x5f, 0x83, 0xbb, 0xb9, 0x8a,
+	0xec, 0x39, 0xfc, 0x9b, 0xc7, 0x77, 0x91, 0x3d, 0x8d, 0x4a, 0x54, 0xd1, 0x52, 0xea, 0x76, 0xf7,
+	0xdc, 0x3c, 0xbe, 0x7b, 0x97, 0x66, 0x78, 0x85, 0xea, 0x52, 0x6a, 0xc3, 0x0e, 0xc0, 0x69, 0xa1,
+	0xcd, 0x35, 0x0f, 0x1a, 0x04, 0xfb, 0x1f, 0x86, 0xf6, 0xc8, 0x76, 0xa6, 0xc9, 0xcb, 0x98, 0x5b,
+	0xac, 0xad, 0xa5, 0x6f, 0x76, 0xc9, 0xcd, 0xe9, 0xef, 0x00, 0x00, 0x00, 0xff, 0xff, 0xd7, 0x9b,
+	0x61, 0xcb, 0x5b, 0x05, 0x00, 0x00,
 }
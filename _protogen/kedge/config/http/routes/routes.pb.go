@@ -7,6 +7,7 @@ package kedge_config_http_routes
 import proto "github.com/golang/protobuf/proto"
 import fmt "fmt"
 import math "math"
+import google_protobuf "github.com/golang/protobuf/ptypes/duration"
 
 // Reference imports to suppress errors if they are not otherwise used.
 var _ = proto.Marshal
@@ -48,6 +49,7 @@ func (ProxyMode) EnumDescriptor() ([]byte, []int) { return fileDescriptor1, []in
 // / Route describes a mapping between a stable proxying endpoint and a pre-defined backend.
 type Route struct {
 	// / backend_name is the string identifying the HTTP backend pool to send data to.
+	// / It must not be set if weighted `backends` are present.
 	BackendName string `protobuf:"bytes,1,opt,name=backend_name,json=backendName" json:"backend_name,omitempty"`
 	// / path_rules is a globbing expression that matches a URL path of the request.
 	// / See: https://cloud.google.com/compute/docs/load-balancing/http/url-map
@@ -64,6 +66,19 @@ type Route struct {
 	HeaderMatcher map[string]string `protobuf:"bytes,4,rep,name=header_matcher,json=headerMatcher" json:"header_matcher,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
 	// / proxy_mode controlls what kind of inbound requests this route matches. See
 	ProxyMode ProxyMode `protobuf:"varint,5,opt,name=proxy_mode,json=proxyMode,enum=kedge.config.http.routes.ProxyMode" json:"proxy_mode,omitempty"`
+	// / backends splits the traffic of this route between multiple HTTP backends according to their weights.
+	// / For example, a canary release would send 95% of traffic to 'controller' and 5% to 'controller-canary'.
+	// / If present, backend_name must not be set, and every backend needs a name.
+	Backends []*WeightedBackend `protobuf:"bytes,6,rep,name=backends" json:"backends,omitempty"`
+	// / sticky makes sure that the same client is consistently sent to the same weighted backend.
+	// / If not present, or the sticky key is missing from the request, the backend is chosen at random.
+	Sticky *Sticky `protobuf:"bytes,7,opt,name=sticky" json:"sticky,omitempty"`
+	// / mirror sends copies of a share of this route's requests to another backend, e.g. to test a new version with
+	// / production traffic. Responses of the mirror are discarded and never affect the original request.
+	Mirror *Mirror `protobuf:"bytes,8,opt,name=mirror" json:"mirror,omitempty"`
+	// / timeout is the maximum duration of proxying a request, including reading the response body.
+	// / Requests that time out return a 504 Gateway Timeout with an `x-kedge-error` header. If not set, there's no limit.
+	Timeout *google_protobuf.Duration `protobuf:"bytes,9,opt,name=timeout" json:"timeout,omitempty"`
 }
 
 func (m *Route) Reset()                    { *m = Route{} }
@@ -106,33 +121,288 @@ func (m *Route) GetProxyMode() ProxyMode {
 	return ProxyMode_ANY
 }
 
+func (m *Route) GetBackends() []*WeightedBackend {
+	if m != nil {
+		return m.Backends
+	}
+	return nil
+}
+
+func (m *Route) GetSticky() *Sticky {
+	if m != nil {
+		return m.Sticky
+	}
+	return nil
+}
+
+func (m *Route) GetMirror() *Mirror {
+	if m != nil {
+		return m.Mirror
+	}
+	return nil
+}
+
+func (m *Route) GetTimeout() *google_protobuf.Duration {
+	if m != nil {
+		return m.Timeout
+	}
+	return nil
+}
+
+// / WeightedBackend is a backend that receives a share of a route's traffic.
+type WeightedBackend struct {
+	// / name is the string identifying the HTTP backend pool to send data to.
+	Name string `protobuf:"bytes,1,opt,name=name" json:"name,omitempty"`
+	// / weight is the share of traffic relative to the sum of weights of all backends of the route.
+	// / If all weights of a route are zero, the traffic is split equally.
+	Weight uint32 `protobuf:"varint,2,opt,name=weight" json:"weight,omitempty"`
+}
+
+func (m *WeightedBackend) Reset()                    { *m = WeightedBackend{} }
+func (m *WeightedBackend) String() string            { return proto.CompactTextString(m) }
+func (*WeightedBackend) ProtoMessage()               {}
+func (*WeightedBackend) Descriptor() ([]byte, []int) { return fileDescriptor1, []int{1} }
+
+func (m *WeightedBackend) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *WeightedBackend) GetWeight() uint32 {
+	if m != nil {
+		return m.Weight
+	}
+	return 0
+}
+
+// / Sticky controls what part of the request is hashed to choose a weighted backend.
+type Sticky struct {
+	// Types that are valid to be assigned to Key:
+	//	*Sticky_Header
+	//	*Sticky_Cookie
+	//	*Sticky_ClientIdentity
+	Key isSticky_Key `protobuf_oneof:"key"`
+}
+
+func (m *Sticky) Reset()                    { *m = Sticky{} }
+func (m *Sticky) String() string            { return proto.CompactTextString(m) }
+func (*Sticky) ProtoMessage()               {}
+func (*Sticky) Descriptor() ([]byte, []int) { return fileDescriptor1, []int{2} }
+
+type isSticky_Key interface {
+	isSticky_Key()
+}
+
+type Sticky_Header struct {
+	Header string `protobuf:"bytes,1,opt,name=header,oneof"`
+}
+type Sticky_Cookie struct {
+	Cookie string `protobuf:"bytes,2,opt,name=cookie,oneof"`
+}
+type Sticky_ClientIdentity struct {
+	ClientIdentity bool `protobuf:"varint,3,opt,name=client_identity,json=clientIdentity,oneof"`
+}
+
+func (*Sticky_Header) isSticky_Key()         {}
+func (*Sticky_Cookie) isSticky_Key()         {}
+func (*Sticky_ClientIdentity) isSticky_Key() {}
+
+func (m *Sticky) GetKey() isSticky_Key {
+	if m != nil {
+		return m.Key
+	}
+	return nil
+}
+
+func (m *Sticky) GetHeader() string {
+	if x, ok := m.GetKey().(*Sticky_Header); ok {
+		return x.Header
+	}
+	return ""
+}
+
+func (m *Sticky) GetCookie() string {
+	if x, ok := m.GetKey().(*Sticky_Cookie); ok {
+		return x.Cookie
+	}
+	return ""
+}
+
+func (m *Sticky) GetClientIdentity() bool {
+	if x, ok := m.GetKey().(*Sticky_ClientIdentity); ok {
+		return x.ClientIdentity
+	}
+	return false
+}
+
+// XXX_OneofFuncs is for the internal use of the proto package.
+func (*Sticky) XXX_OneofFuncs() (func(msg proto.Message, b *proto.Buffer) error, func(msg proto.Message, tag, wire int, b *proto.Buffer) (bool, error), func(msg proto.Message) (n int), []interface{}) {
+	return _Sticky_OneofMarshaler, _Sticky_OneofUnmarshaler, _Sticky_OneofSizer, []interface{}{
+		(*Sticky_Header)(nil),
+		(*Sticky_Cookie)(nil),
+		(*Sticky_ClientIdentity)(nil),
+	}
+}
+
+func _Sticky_OneofMarshaler(msg proto.Message, b *proto.Buffer) error {
+	m := msg.(*Sticky)
+	// key
+	switch x := m.Key.(type) {
+	case *Sticky_Header:
+		b.EncodeVarint(1<<3 | proto.WireBytes)
+		b.EncodeStringBytes(x.Header)
+	case *Sticky_Cookie:
+		b.EncodeVarint(2<<3 | proto.WireBytes)
+		b.EncodeStringBytes(x.Cookie)
+	case *Sticky_ClientIdentity:
+		t := uint64(0)
+		if x.ClientIdentity {
+			t = 1
+		}
+		b.EncodeVarint(3<<3 | proto.WireVarint)
+		b.EncodeVarint(t)
+	case nil:
+	default:
+		return fmt.Errorf("Sticky.Key has unexpected type %T", x)
+	}
+	return nil
+}
+
+func _Sticky_OneofUnmarshaler(msg proto.Message, tag, wire int, b *proto.Buffer) (bool, error) {
+	m := msg.(*Sticky)
+	switch tag {
+	case 1: // key.header
+		if wire != proto.WireBytes {
+			return true, proto.ErrInternalBadWireType
+		}
+		x, err := b.DecodeStringBytes()
+		m.Key = &Sticky_Header{x}
+		return true, err
+	case 2: // key.cookie
+		if wire != proto.WireBytes {
+			return true, proto.ErrInternalBadWireType
+		}
+		x, err := b.DecodeStringBytes()
+		m.Key = &Sticky_Cookie{x}
+		return true, err
+	case 3: // key.client_identity
+		if wire != proto.WireVarint {
+			return true, proto.ErrInternalBadWireType
+		}
+		x, err := b.DecodeVarint()
+		m.Key = &Sticky_ClientIdentity{x != 0}
+		return true, err
+	default:
+		return false, nil
+	}
+}
+
+func _Sticky_OneofSizer(msg proto.Message) (n int) {
+	m := msg.(*Sticky)
+	// key
+	switch x := m.Key.(type) {
+	case *Sticky_Header:
+		n += proto.SizeVarint(1<<3 | proto.WireBytes)
+		n += proto.SizeVarint(uint64(len(x.Header)))
+		n += len(x.Header)
+	case *Sticky_Cookie:
+		n += proto.SizeVarint(2<<3 | proto.WireBytes)
+		n += proto.SizeVarint(uint64(len(x.Cookie)))
+		n += len(x.Cookie)
+	case *Sticky_ClientIdentity:
+		n += proto.SizeVarint(3<<3 | proto.WireVarint)
+		n += 1
+	case nil:
+	default:
+		panic(fmt.Sprintf("proto: unexpected type %T in oneof", x))
+	}
+	return n
+}
+
+// / Mirror is a backend that receives copies of requests.
+type Mirror struct {
+	// / backend_name is the string identifying the HTTP backend pool to send copies of requests to.
+	BackendName string `protobuf:"bytes,1,opt,name=backend_name,json=backendName" json:"backend_name,omitempty"`
+	// / percentage of the route's requests that are mirrored, between 0 and 100. If 0, no requests are mirrored.
+	Percentage float32 `protobuf:"fixed32,2,opt,name=percentage" json:"percentage,omitempty"`
+	// / max_body_bytes is the largest request body that is copied. Requests with larger bodies are not mirrored.
+	// / Defaults to 64KiB.
+	MaxBodyBytes uint32 `protobuf:"varint,3,opt,name=max_body_bytes,json=maxBodyBytes" json:"max_body_bytes,omitempty"`
+}
+
+func (m *Mirror) Reset()                    { *m = Mirror{} }
+func (m *Mirror) String() string            { return proto.CompactTextString(m) }
+func (*Mirror) ProtoMessage()               {}
+func (*Mirror) Descriptor() ([]byte, []int) { return fileDescriptor1, []int{3} }
+
+func (m *Mirror) GetBackendName() string {
+	if m != nil {
+		return m.BackendName
+	}
+	return ""
+}
+
+func (m *Mirror) GetPercentage() float32 {
+	if m != nil {
+		return m.Percentage
+	}
+	return 0
+}
+
+func (m *Mirror) GetMaxBodyBytes() uint32 {
+	if m != nil {
+		return m.MaxBodyBytes
+	}
+	return 0
+}
+
 func init() {
 	proto.RegisterType((*Route)(nil), "kedge.config.http.routes.Route")
+	proto.RegisterType((*WeightedBackend)(nil), "kedge.config.http.routes.WeightedBackend")
+	proto.RegisterType((*Sticky)(nil), "kedge.config.http.routes.Sticky")
+	proto.RegisterType((*Mirror)(nil), "kedge.config.http.routes.Mirror")
 	proto.RegisterEnum("kedge.config.http.routes.ProxyMode", ProxyMode_name, ProxyMode_value)
 }
 
 func init() { proto.RegisterFile("kedge/config/http/routes/routes.proto", fileDescriptor1) }
 
 var fileDescriptor1 = []byte{
-	// 311 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x09, 0x6e, 0x88, 0x02, 0xff, 0x74, 0x91, 0x4f, 0x4b, 0xc3, 0x30,
-	0x18, 0xc6, 0x6d, 0xeb, 0x94, 0xbe, 0x73, 0x63, 0x06, 0x0f, 0x41, 0x10, 0xaa, 0x22, 0x14, 0x0f,
-	0x19, 0xcc, 0x8b, 0xec, 0xe4, 0xc4, 0x8a, 0x97, 0xfd, 0x21, 0x82, 0xba, 0x53, 0xc9, 0xd6, 0xd7,
-	0x45, 0xb6, 0x36, 0x25, 0xcb, 0xc4, 0x7e, 0x48, 0xbf, 0x93, 0xa4, 0xad, 0x43, 0x90, 0x9d, 0xfa,
-	0xf4, 0x97, 0x27, 0xbf, 0xbc, 0x24, 0x70, 0xb5, 0xc4, 0x64, 0x81, 0xdd, 0xb9, 0xca, 0xde, 0x3f,
-	0x16, 0x5d, 0x69, 0x4c, 0xde, 0xd5, 0x6a, 0x63, 0x70, 0x5d, 0x7f, 0x58, 0xae, 0x95, 0x51, 0x84,
-	0x96, 0x35, 0x56, 0xd5, 0x98, 0xad, 0xb1, 0x6a, 0xfd, 0xe2, 0xdb, 0x85, 0x06, 0xb7, 0x91, 0x9c,
-	0xc3, 0xd1, 0x4c, 0xcc, 0x97, 0x98, 0x25, 0x71, 0x26, 0x52, 0xa4, 0x4e, 0xe0, 0x84, 0x3e, 0x6f,
-	0xd6, 0x6c, 0x24, 0x52, 0x24, 0x67, 0x00, 0xb9, 0x30, 0x32, 0xd6, 0x9b, 0x15, 0xae, 0xa9, 0x1b,
-	0x78, 0xa1, 0xcf, 0x7d, 0x4b, 0xb8, 0x05, 0xd6, 0x20, 0xd5, 0xda, 0xc4, 0xa9, 0x30, 0x73, 0x89,
-	0x9a, 0x7a, 0x95, 0xc1, 0xb2, 0x61, 0x85, 0xc8, 0x14, 0xda, 0x12, 0x45, 0x82, 0x7a, 0x5b, 0xda,
-	0x0f, 0xbc, 0xb0, 0xd9, 0xeb, 0xb1, 0x5d, 0x13, 0xb2, 0x72, 0x3a, 0xf6, 0x54, 0xee, 0xaa, 0x35,
-	0x51, 0x66, 0x74, 0xc1, 0x5b, 0xf2, 0x2f, 0x23, 0xf7, 0x00, 0xb9, 0x56, 0x5f, 0x45, 0x9c, 0xaa,
-	0x04, 0x69, 0x23, 0x70, 0xc2, 0x76, 0xef, 0x72, 0xb7, 0x76, 0x62, 0xbb, 0x43, 0x95, 0x20, 0xf7,
-	0xf3, 0xdf, 0x78, 0x7a, 0x07, 0xe4, 0xff, 0x41, 0xa4, 0x03, 0xde, 0x12, 0x8b, 0xfa, 0x42, 0x6c,
-	0x24, 0x27, 0xd0, 0xf8, 0x14, 0xab, 0x0d, 0x52, 0xb7, 0x64, 0xd5, 0x4f, 0xdf, 0xbd, 0x75, 0xae,
-	0xfb, 0xe0, 0x6f, 0xcd, 0xe4, 0x10, 0xbc, 0xc1, 0x68, 0xda, 0xd9, 0x23, 0xc7, 0xd0, 0xe2, 0xd1,
-	0x4b, 0xc4, 0x9f, 0xa3, 0x78, 0xc2, 0xc7, 0x6f, 0xd3, 0x8e, 0x63, 0xd1, 0xe3, 0x98, 0xbf, 0x0e,
-	0xf8, 0x43, 0x8d, 0xdc, 0xd9, 0x41, 0xf9, 0x58, 0x37, 0x3f, 0x01, 0x00, 0x00, 0xff, 0xff, 0xd2,
-	0x4c, 0x9b, 0x82, 0xd5, 0x01, 0x00, 0x00,
+	// 551 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x09, 0x6e, 0x88, 0x02, 0xff, 0x8c, 0x92, 0x4d, 0x6b, 0xdb, 0x4c,
+	0x10, 0xc7, 0x2d, 0xbf, 0xc8, 0xd6, 0x38, 0x76, 0xfc, 0x2c, 0x0f, 0x45, 0x0d, 0x34, 0xa8, 0x6e,
+	0x0b, 0x4e, 0x0f, 0x32, 0x38, 0x97, 0x10, 0x28, 0x34, 0x26, 0x2e, 0xee, 0xc1, 0x49, 0xd8, 0x40,
+	0x53, 0x9f, 0x84, 0x2c, 0x6d, 0x24, 0x61, 0x4b, 0xab, 0xae, 0x56, 0xad, 0xf5, 0xe1, 0xfa, 0xdd,
+	0xca, 0xbe, 0xd8, 0x98, 0x16, 0xd3, 0x9e, 0xb4, 0xf3, 0x9f, 0xdf, 0x7f, 0x56, 0x3b, 0x33, 0xf0,
+	0x6e, 0x4d, 0xc2, 0x88, 0x8c, 0x03, 0x9a, 0x3d, 0x27, 0xd1, 0x38, 0xe6, 0x3c, 0x1f, 0x33, 0x5a,
+	0x72, 0x52, 0xe8, 0x8f, 0x9b, 0x33, 0xca, 0x29, 0xb2, 0x25, 0xe6, 0x2a, 0xcc, 0x15, 0x98, 0xab,
+	0xf2, 0x67, 0xe7, 0x11, 0xa5, 0xd1, 0x86, 0x8c, 0x25, 0xb7, 0x2a, 0x9f, 0xc7, 0x61, 0xc9, 0x7c,
+	0x9e, 0xd0, 0x4c, 0x39, 0x87, 0x3f, 0x9b, 0xd0, 0xc2, 0x02, 0x45, 0xaf, 0xe1, 0x64, 0xe5, 0x07,
+	0x6b, 0x92, 0x85, 0x5e, 0xe6, 0xa7, 0xc4, 0x36, 0x1c, 0x63, 0x64, 0xe1, 0xae, 0xd6, 0xee, 0xfc,
+	0x94, 0xa0, 0x57, 0x00, 0xb9, 0xcf, 0x63, 0x8f, 0x95, 0x1b, 0x52, 0xd8, 0x75, 0xa7, 0x31, 0xb2,
+	0xb0, 0x25, 0x14, 0x2c, 0x04, 0x51, 0x21, 0xa6, 0x05, 0xf7, 0x52, 0x9f, 0x07, 0x31, 0x61, 0x76,
+	0x43, 0x55, 0x10, 0xda, 0x42, 0x49, 0x68, 0x09, 0xfd, 0x98, 0xf8, 0x21, 0x61, 0x7b, 0xa8, 0xe9,
+	0x34, 0x46, 0xdd, 0xc9, 0xc4, 0x3d, 0xf6, 0x02, 0x57, 0xfe, 0x9d, 0x3b, 0x97, 0x2e, 0x5d, 0x66,
+	0x96, 0x71, 0x56, 0xe1, 0x5e, 0x7c, 0xa8, 0xa1, 0x29, 0x40, 0xce, 0xe8, 0xb6, 0xf2, 0x52, 0x1a,
+	0x12, 0xbb, 0xe5, 0x18, 0xa3, 0xfe, 0xe4, 0xcd, 0xf1, 0xb2, 0x0f, 0x82, 0x5d, 0xd0, 0x90, 0x60,
+	0x2b, 0xdf, 0x1d, 0xd1, 0x0c, 0x3a, 0xfa, 0xbd, 0x85, 0x6d, 0xca, 0x1f, 0xbb, 0x38, 0x5e, 0xe1,
+	0x89, 0x24, 0x51, 0xcc, 0x49, 0x38, 0x55, 0x0e, 0xbc, 0xb7, 0xa2, 0x2b, 0x30, 0x0b, 0x9e, 0x04,
+	0xeb, 0xca, 0x6e, 0x3b, 0xc6, 0xa8, 0x3b, 0x71, 0x8e, 0x17, 0x79, 0x94, 0x1c, 0xd6, 0xbc, 0x70,
+	0xa6, 0x09, 0x63, 0x94, 0xd9, 0x9d, 0xbf, 0x39, 0x17, 0x92, 0xc3, 0x9a, 0x47, 0x97, 0xd0, 0xe6,
+	0x49, 0x4a, 0x68, 0xc9, 0x6d, 0x4b, 0x5a, 0x5f, 0xba, 0x6a, 0xf4, 0xee, 0x6e, 0xf4, 0xee, 0xad,
+	0x1e, 0x3d, 0xde, 0x91, 0x67, 0x1f, 0x01, 0xfd, 0xd9, 0x58, 0x34, 0x80, 0xc6, 0x9a, 0x54, 0x7a,
+	0x01, 0xc4, 0x11, 0xfd, 0x0f, 0xad, 0xef, 0xfe, 0xa6, 0x24, 0x76, 0x5d, 0x6a, 0x2a, 0xb8, 0xae,
+	0x5f, 0x19, 0xc3, 0x0f, 0x70, 0xfa, 0x5b, 0x1f, 0x10, 0x82, 0xe6, 0xc1, 0x02, 0xc9, 0x33, 0x7a,
+	0x01, 0xe6, 0x0f, 0x89, 0xc9, 0x0a, 0x3d, 0xac, 0xa3, 0x61, 0x06, 0xa6, 0xea, 0x00, 0xb2, 0xc1,
+	0x54, 0xf3, 0x54, 0xbe, 0x79, 0x0d, 0xeb, 0x58, 0x64, 0x02, 0x4a, 0xd7, 0x89, 0xbe, 0x5d, 0x64,
+	0x54, 0x8c, 0x2e, 0xe0, 0x34, 0xd8, 0x24, 0x24, 0xe3, 0x5e, 0x12, 0x92, 0x8c, 0x27, 0xbc, 0x92,
+	0x3b, 0xd7, 0x99, 0xd7, 0x70, 0x5f, 0x25, 0x3e, 0x6b, 0x7d, 0xda, 0x92, 0x6f, 0x1a, 0x7e, 0x03,
+	0x53, 0xf5, 0xed, 0x5f, 0xd6, 0xfd, 0x1c, 0x20, 0x27, 0x2c, 0x20, 0x19, 0xf7, 0x23, 0x75, 0x79,
+	0x1d, 0x1f, 0x28, 0xe8, 0x2d, 0xf4, 0x53, 0x7f, 0xeb, 0xad, 0x68, 0x58, 0x79, 0xab, 0x8a, 0x93,
+	0x42, 0xde, 0xde, 0xc3, 0x27, 0xa9, 0xbf, 0x9d, 0xd2, 0xb0, 0x9a, 0x0a, 0xed, 0xfd, 0x35, 0x58,
+	0xfb, 0x5d, 0x43, 0x6d, 0x68, 0xdc, 0xdc, 0x2d, 0x07, 0x35, 0xf4, 0x1f, 0xf4, 0xf0, 0xec, 0xcb,
+	0x0c, 0x3f, 0xce, 0xbc, 0x07, 0x7c, 0xff, 0x75, 0x39, 0x30, 0x84, 0xf4, 0xe9, 0x1e, 0x3f, 0xdd,
+	0xe0, 0x5b, 0x2d, 0xd5, 0x57, 0xa6, 0x9c, 0xdd, 0xe5, 0xaf, 0x00, 0x00, 0x00, 0xff, 0xff, 0x53,
+	0x01, 0x25, 0x9c, 0x07, 0x04, 0x00, 0x00,
 }
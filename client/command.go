@@ -0,0 +1,159 @@
+package main
+
+import (
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/mwitkow/kedge/client/localproxy"
+)
+
+var (
+	// noProxyHosts are always reached directly by the command.
+	noProxyHosts = []string{"localhost", "127.0.0.1", "::1"}
+
+	// forwardedSignals are passed on to the command.
+	forwardedSignals = []os.Signal{syscall.SIGTERM, syscall.SIGHUP}
+	// terminalSignals are sent by terminals to the whole process group, which the command is in, so they are only kept
+	// from stopping kedge_local before the command. They aren't ignored, as commands would inherit that.
+	terminalSignals = []os.Signal{syscall.SIGINT, syscall.SIGQUIT}
+
+	// caBundleEnvKeys are the variables, of common TLS libraries, pointing at the CA bundle trusted by the command.
+	caBundleEnvKeys = []string{"SSL_CERT_FILE", "CURL_CA_BUNDLE", "REQUESTS_CA_BUNDLE", "NODE_EXTRA_CA_CERTS"}
+	// systemCABundles are where Linux distributions keep their CA bundles, which the one of the command starts with.
+	systemCABundles = []string{
+		"/etc/ssl/certs/ca-certificates.crt",
+		"/etc/pki/tls/certs/ca-bundle.crt",
+		"/etc/ssl/ca-bundle.pem",
+		"/etc/pki/tls/cacert.pem",
+		"/etc/pki/ca-trust/extracted/pem/tls-ca-bundle.pem",
+		"/etc/ssl/cert.pem",
+	}
+)
+
+// runCommand runs the command with its proxy environment pointing at the handler, served on an ephemeral port for
+// the lifetime of the command. The command trusts the tunnel CA of the handler on top of the system roots. It returns
+// the exit code of the command.
+func runCommand(handler http.Handler, tunnelCA *localproxy.TunnelCA, args []string) int {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		log.Fatalf("failed listening for the command's proxy: %v", err)
+	}
+	defer listener.Close()
+	go func() {
+		(&http.Server{Handler: handler}).Serve(listener)
+	}()
+	caBundle, err := writeCABundle(tunnelCA)
+	if err != nil {
+		log.Fatalf("failed writing the command's CA bundle: %v", err)
+	}
+	defer os.Remove(caBundle)
+
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.Env = proxyEnv(os.Environ(), "http://"+listener.Addr().String(), caBundle)
+
+	caught := make(chan os.Signal, 1)
+	signal.Notify(caught, terminalSignals...)
+	defer signal.Stop(caught)
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, forwardedSignals...)
+	defer signal.Stop(signals)
+	if err := cmd.Start(); err != nil {
+		log.Errorf("failed starting '%v': %v", args[0], err)
+		return 127
+	}
+	go func() {
+		for sig := range signals {
+			cmd.Process.Signal(sig)
+		}
+	}()
+	return exitCode(cmd.Wait())
+}
+
+// writeCABundle writes a temporary file with the CAs trusted by the command: the ones it would trust anyway, and the
+// tunnel CA.
+func writeCABundle(tunnelCA *localproxy.TunnelCA) (string, error) {
+	bundle := []byte{}
+	candidates := systemCABundles
+	if existing := os.Getenv("SSL_CERT_FILE"); existing != "" {
+		candidates = []string{existing}
+	}
+	for _, path := range candidates {
+		if data, err := ioutil.ReadFile(path); err == nil {
+			bundle = append(data, '\n')
+			break
+		}
+	}
+	bundle = append(bundle, tunnelCA.CertPEM()...)
+	f, err := ioutil.TempFile("", "kedge_local_ca_")
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	if _, err := f.Write(bundle); err != nil {
+		os.Remove(f.Name())
+		return "", err
+	}
+	return f.Name(), nil
+}
+
+// proxyEnv returns the environment with all the proxy variables, in their common spellings, set to proxyUrl, and the
+// CA bundle variables set to caBundle.
+func proxyEnv(environ []string, proxyUrl string, caBundle string) []string {
+	noProxy := noProxyHosts
+	env := []string{}
+	for _, kv := range environ {
+		key := strings.SplitN(kv, "=", 2)[0]
+		switch key {
+		case "HTTP_PROXY", "http_proxy", "HTTPS_PROXY", "https_proxy", "grpc_proxy":
+			continue
+		case "SSL_CERT_FILE", "CURL_CA_BUNDLE", "REQUESTS_CA_BUNDLE", "NODE_EXTRA_CA_CERTS":
+			continue
+		case "NO_PROXY", "no_proxy":
+			if existing := strings.TrimPrefix(kv, key+"="); existing != "" {
+				noProxy = append([]string{existing}, noProxyHosts...)
+			}
+			continue
+		}
+		env = append(env, kv)
+	}
+	for _, key := range []string{"HTTP_PROXY", "http_proxy", "HTTPS_PROXY", "https_proxy", "grpc_proxy"} {
+		env = append(env, key+"="+proxyUrl)
+	}
+	for _, key := range []string{"NO_PROXY", "no_proxy"} {
+		env = append(env, key+"="+strings.Join(noProxy, ","))
+	}
+	for _, key := range caBundleEnvKeys {
+		env = append(env, key+"="+caBundle)
+	}
+	return env
+}
+
+// exitCode follows the shell convention of 128+N for commands killed by signal N.
+func exitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+	exitErr, ok := err.(*exec.ExitError)
+	if !ok {
+		log.Errorf("failed running command: %v", err)
+		return 1
+	}
+	status, ok := exitErr.Sys().(syscall.WaitStatus)
+	if !ok {
+		return 1
+	}
+	if status.Signaled() {
+		return 128 + int(status.Signal())
+	}
+	return status.ExitStatus()
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"crypto/tls"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"testing"
+
+	pb "github.com/mwitkow/kedge/_protogen/kedge/config/client"
+	"github.com/mwitkow/kedge/client/localproxy"
+	"github.com/mwitkow/kedge/lib/mapper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProxyEnv(t *testing.T) {
+	env := proxyEnv([]string{"PATH=/bin", "http_proxy=http://old:3128", "NO_PROXY=.internal.example.com", "SSL_CERT_FILE=/old.crt"}, "http://127.0.0.1:1234", "/tmp/ca.crt")
+	assert.Equal(t, []string{
+		"PATH=/bin",
+		"HTTP_PROXY=http://127.0.0.1:1234",
+		"http_proxy=http://127.0.0.1:1234",
+		"HTTPS_PROXY=http://127.0.0.1:1234",
+		"https_proxy=http://127.0.0.1:1234",
+		"grpc_proxy=http://127.0.0.1:1234",
+		"NO_PROXY=.internal.example.com,localhost,127.0.0.1,::1",
+		"no_proxy=.internal.example.com,localhost,127.0.0.1,::1",
+		"SSL_CERT_FILE=/tmp/ca.crt",
+		"CURL_CA_BUNDLE=/tmp/ca.crt",
+		"REQUESTS_CA_BUNDLE=/tmp/ca.crt",
+		"NODE_EXTRA_CA_CERTS=/tmp/ca.crt",
+	}, env)
+}
+
+func newTestTunnelCA(t *testing.T) *localproxy.TunnelCA {
+	ca, err := localproxy.NewTunnelCA()
+	require.NoError(t, err, "tunnel CA creation must not fail")
+	return ca
+}
+
+func TestRunCommandSetsEnvAndPropagatesExitCode(t *testing.T) {
+	handler := http.NotFoundHandler()
+	ca := newTestTunnelCA(t)
+	assert.Equal(t, 3, runCommand(handler, ca, []string{"sh", "-c", `case "$http_proxy" in http://127.0.0.1:*) exit 3;; esac; exit 1`}))
+	assert.Equal(t, 0, runCommand(handler, ca, []string{"sh", "-c", `grep -q "BEGIN CERTIFICATE" "$SSL_CERT_FILE"`}))
+	assert.Equal(t, 0, runCommand(handler, ca, []string{"true"}))
+	assert.Equal(t, 128+15, runCommand(handler, ca, []string{"sh", "-c", "kill -TERM $$"}))
+	assert.Equal(t, 127, runCommand(handler, ca, []string{"/nonexistent/command"}))
+}
+
+func TestRunCommandDoesNotForwardTerminalSignals(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kedge-command")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	received := path.Join(dir, "received")
+	// SIGINT sent to kedge_local alone mustn't reach the command, as terminals send it to the command already.
+	script := fmt.Sprintf(`trap 'echo INT > %s' INT; kill -INT $PPID; sleep 0.3; test ! -e %s`, received, received)
+	assert.Equal(t, 0, runCommand(http.NotFoundHandler(), newTestTunnelCA(t), []string{"sh", "-c", script}), "command must not get SIGINT")
+}
+
+// TestRunCommandMakesHttpsRequestsThroughKedge runs this test binary as the command, which makes an https:// request
+// to a mapped host through the ephemeral proxy, see TestHelperHttpsGet.
+func TestRunCommandMakesHttpsRequestsThroughKedge(t *testing.T) {
+	kedge := httptest.NewTLSServer(http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		fmt.Fprintf(resp, "kedge:%s%s", req.Host, req.URL.Path)
+	}))
+	defer kedge.Close()
+	m, err := mapper.NewStatic([]*pb.Mapping{{HostMatcher: "*.my_cluster.cluster.local", KedgeAddress: kedge.Listener.Addr().String()}})
+	require.NoError(t, err, "mapper creation must not fail")
+	kedgeTransport := &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}} // the test server cert is self-signed.
+	ca := newTestTunnelCA(t)
+	proxy := localproxy.New(m, kedgeTransport, http.DefaultTransport, pb.ClientConfig_REJECT, ca)
+
+	os.Setenv("KEDGE_TEST_HELPER_URL", "https://svc.my_cluster.cluster.local/some/path")
+	defer os.Unsetenv("KEDGE_TEST_HELPER_URL")
+	os.Setenv("KEDGE_TEST_HELPER_EXPECTED", "kedge:svc.my_cluster.cluster.local/some/path")
+	defer os.Unsetenv("KEDGE_TEST_HELPER_EXPECTED")
+	assert.Equal(t, 0, runCommand(proxy, ca, []string{os.Args[0], "-test.run=^TestHelperHttpsGet$"}), "command must get the response of the kedge")
+}
+
+// TestHelperHttpsGet is the command run by TestRunCommandMakesHttpsRequestsThroughKedge, and does nothing otherwise.
+func TestHelperHttpsGet(t *testing.T) {
+	url := os.Getenv("KEDGE_TEST_HELPER_URL")
+	if url == "" {
+		return
+	}
+	resp, err := http.Get(url)
+	require.NoError(t, err, "https:// request through the proxy must not fail")
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Equal(t, os.Getenv("KEDGE_TEST_HELPER_EXPECTED"), string(body))
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/golang/protobuf/proto"
+	"github.com/mwitkow/go-nicejsonpb"
+	pb "github.com/mwitkow/kedge/_protogen/kedge/config/client"
+	"github.com/mwitkow/kedge/lib/mapper"
+	"github.com/mwitkow/kedge/lib/pac"
+	"github.com/mwitkow/kedge/lib/tlsconfig"
+)
+
+var (
+	flagConfigPath = flagSet.String(
+		"client_config_path",
+		"~/.config/kedge/config.json",
+		"Path to the jsonPB file configuring the mappings of hosts to kedges.")
+)
+
+func readConfigOrFail() *pb.ClientConfig {
+	cnf := &pb.ClientConfig{}
+	if err := readAsJson(tlsconfig.ExpandHome(*flagConfigPath), cnf); err != nil {
+		log.Fatalf("failed reading client config: %v", err)
+	}
+	return cnf
+}
+
+func buildMapperOrFail(cnf *pb.ClientConfig) mapper.Mapper {
+	m, err := mapper.NewStatic(cnf.Mappings)
+	if err != nil {
+		log.Fatalf("failed creating mapper: %v", err)
+	}
+	return m
+}
+
+// buildPacFile sends browsers to kedge_local for all mapped hosts, https:// URLs included as kedge_local tunnels them.
+func buildPacFile(cnf *pb.ClientConfig, proxyAddr string) *pac.File {
+	f := &pac.File{ProxyHttps: true}
+	for _, m := range cnf.Mappings {
+		f.Rules = append(f.Rules, pac.Rule{HostMatcher: m.HostMatcher, Proxy: "PROXY " + proxyAddr})
+	}
+	return f
+}
+
+func readAsJson(filePath string, destination proto.Message) error {
+	data, err := ioutil.ReadFile(filePath)
+	if err != nil {
+		return err
+	}
+	um := &nicejsonpb.Unmarshaler{AllowUnknownFields: false}
+	return um.Unmarshal(bytes.NewReader(data), destination)
+}
@@ -0,0 +1,73 @@
+// Package kedge_grpc dials gRPC services of remote clusters through their kedges.
+//
+// The connections are made to the kedge that the Mapper maps the target to, and secured with the client certificate.
+// The :authority of calls remains the target, which is what the kedge routes on.
+package kedge_grpc
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/mwitkow/kedge/lib/mapper"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+)
+
+var (
+	// ParentDialFunc is used for dialing kedges, it can be replaced to resolve kedges differently, e.g. in tests.
+	ParentDialFunc = (&net.Dialer{
+		KeepAlive: 30 * time.Second,
+	}).DialContext
+)
+
+// Dial creates a client connection to the target, e.g. "controller.eu1-prod.improbable.local:443", through its kedge.
+//
+// The tlsConfig needs to contain the client certificate, see tlsconfig.ForClient.
+func Dial(target string, m mapper.Mapper, tlsConfig *tls.Config, opts ...grpc.DialOption) (*grpc.ClientConn, error) {
+	if _, err := m.Map(target); err != nil {
+		return nil, fmt.Errorf("kedge: cannot dial '%v': %v", target, err)
+	}
+	return grpc.Dial(target, append(DialOptions(m, tlsConfig), opts...)...)
+}
+
+// DialOptions makes grpc.Dial connect through the kedges the mapper maps targets to.
+//
+// Targets not mapped to kedges fail to connect. They can't be used with grpc.WithInsecure or grpc.WithBalancer.
+func DialOptions(m mapper.Mapper, tlsConfig *tls.Config) []grpc.DialOption {
+	return []grpc.DialOption{
+		grpc.WithDialer(func(addr string, t time.Duration) (net.Conn, error) {
+			kedgeUrl, err := m.Map(addr)
+			if err != nil {
+				return nil, fmt.Errorf("kedge: cannot dial '%v': %v", addr, err)
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), t)
+			defer cancel()
+			return ParentDialFunc(ctx, "tcp", kedgeUrl.Host)
+		}),
+		grpc.WithTransportCredentials(&kedgeCredentials{
+			TransportCredentials: credentials.NewTLS(tlsConfig),
+			mapper:               m,
+		}),
+	}
+}
+
+// kedgeCredentials verifies the TLS certificate of the kedge instead of the one of the target.
+type kedgeCredentials struct {
+	credentials.TransportCredentials
+	mapper mapper.Mapper
+}
+
+func (c *kedgeCredentials) ClientHandshake(ctx context.Context, addr string, rawConn net.Conn) (net.Conn, credentials.AuthInfo, error) {
+	kedgeUrl, err := c.mapper.Map(addr)
+	if err != nil {
+		return nil, nil, fmt.Errorf("kedge: cannot dial '%v': %v", addr, err)
+	}
+	return c.TransportCredentials.ClientHandshake(ctx, kedgeUrl.Host, rawConn)
+}
+
+func (c *kedgeCredentials) Clone() credentials.TransportCredentials {
+	return &kedgeCredentials{TransportCredentials: c.TransportCredentials.Clone(), mapper: c.mapper}
+}
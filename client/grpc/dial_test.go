@@ -0,0 +1,204 @@
+package kedge_grpc
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"path"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/mwitkow/go-conntrack/connhelpers"
+	"github.com/mwitkow/go-grpc-middleware/testing"
+	pb_testproto "github.com/mwitkow/go-grpc-middleware/testing/testproto"
+	"github.com/mwitkow/go-srvlb/srv"
+	"github.com/mwitkow/grpc-proxy/proxy"
+	pb "github.com/mwitkow/kedge/_protogen/kedge/config/client"
+	pb_res "github.com/mwitkow/kedge/_protogen/kedge/config/common/resolvers"
+	pb_be "github.com/mwitkow/kedge/_protogen/kedge/config/grpc/backends"
+	pb_route "github.com/mwitkow/kedge/_protogen/kedge/config/grpc/routes"
+	"github.com/mwitkow/kedge/grpc/backendpool"
+	"github.com/mwitkow/kedge/grpc/director"
+	"github.com/mwitkow/kedge/grpc/director/router"
+	"github.com/mwitkow/kedge/lib/mapper"
+	"github.com/mwitkow/kedge/lib/resolvers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+)
+
+var backendConfigs = []*pb_be.Backend{
+	{
+		Name: "controller",
+		Resolver: &pb_be.Backend_Srv{
+			Srv: &pb_res.SrvResolver{
+				DnsName: "_grpc._tcp.controller.backends.test.local",
+			},
+		},
+	},
+}
+
+var routeConfigs = []*pb_route.Route{
+	{
+		BackendName:        "controller",
+		ServiceNameMatcher: "*",
+		AuthorityMatcher:   "controller.eu1-prod.improbable.local",
+	},
+}
+
+var mappings = []*pb.Mapping{
+	{
+		HostMatcher:  "*.eu1-prod.improbable.local",
+		KedgeAddress: "kedge.eu1-prod.example.com:443",
+	},
+}
+
+type DialTestSuite struct {
+	suite.Suite
+
+	backend             *grpc.Server
+	backendListener     net.Listener
+	kedge               *grpc.Server
+	kedgeListener       net.Listener
+	kedgeServerNames    chan string
+	originalSrvResolver srv.Resolver
+	originalDialFunc    func(ctx context.Context, network, address string) (net.Conn, error)
+
+	mapper mapper.Mapper
+}
+
+func TestDialTestSuite(t *testing.T) {
+	suite.Run(t, &DialTestSuite{})
+}
+
+// implements srv resolver.
+func (s *DialTestSuite) Lookup(domainName string) ([]*srv.Target, error) {
+	if domainName != "_grpc._tcp.controller.backends.test.local" {
+		return nil, fmt.Errorf("Unknown local backend '%v' in testing", domainName)
+	}
+	return []*srv.Target{{DialAddr: s.backendListener.Addr().String(), Ttl: 10 * time.Second}}, nil
+}
+
+// dialLocalKedge resolves the kedge of the mappings to the in-process one.
+func (s *DialTestSuite) dialLocalKedge(ctx context.Context, network string, addr string) (net.Conn, error) {
+	if addr != "kedge.eu1-prod.example.com:443" {
+		return nil, fmt.Errorf("Unknown kedge '%v' in testing", addr)
+	}
+	return (&net.Dialer{}).DialContext(ctx, network, s.kedgeListener.Addr().String())
+}
+
+func (s *DialTestSuite) SetupSuite() {
+	var err error
+	s.backendListener, err = net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(s.T(), err, "must be able to allocate a port for the backend")
+	s.backend = grpc.NewServer()
+	pb_testproto.RegisterTestServiceServer(s.backend, &grpc_testing.TestPingService{T: s.T()})
+	go func() {
+		s.backend.Serve(s.backendListener)
+	}()
+
+	s.originalSrvResolver = resolvers.ParentSrvResolver
+	resolvers.ParentSrvResolver = s
+	s.originalDialFunc = ParentDialFunc
+	ParentDialFunc = s.dialLocalKedge
+	s.startKedge()
+
+	s.mapper, err = mapper.NewStatic(mappings)
+	require.NoError(s.T(), err, "mapper creation must not fail")
+}
+
+func (s *DialTestSuite) startKedge() {
+	pool, err := backendpool.NewStatic(backendConfigs)
+	require.NoError(s.T(), err, "backend pool creation must not fail")
+	r, err := router.NewStatic(routeConfigs)
+	require.NoError(s.T(), err, "router creation must not fail")
+
+	tlsConfig, err := connhelpers.TlsConfigForServerCerts(
+		path.Join(getTestingCertsPath(), "localhost.crt"),
+		path.Join(getTestingCertsPath(), "localhost.key"))
+	require.NoError(s.T(), err, "failed reading server certs")
+	// The testing certs are expired, so only their presence is checked.
+	tlsConfig.ClientAuth = tls.RequireAnyClientCert
+	s.kedgeServerNames = make(chan string, 100)
+	cert := tlsConfig.Certificates[0]
+	tlsConfig.Certificates = nil
+	tlsConfig.GetCertificate = func(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+		s.kedgeServerNames <- hello.ServerName
+		return &cert, nil
+	}
+
+	s.kedgeListener, err = net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(s.T(), err, "must be able to allocate a port for kedge")
+	s.kedge = grpc.NewServer(
+		grpc.CustomCodec(proxy.Codec()),
+		grpc.UnknownServiceHandler(proxy.TransparentHandler(director.New(pool, r, nil))),
+		grpc.Creds(credentials.NewTLS(tlsConfig)),
+	)
+	go func() {
+		s.kedge.Serve(s.kedgeListener)
+	}()
+}
+
+func (s *DialTestSuite) clientTls() *tls.Config {
+	cert, err := tls.LoadX509KeyPair(
+		path.Join(getTestingCertsPath(), "client.crt"),
+		path.Join(getTestingCertsPath(), "client.key"))
+	require.NoError(s.T(), err, "failed reading client certs")
+	return &tls.Config{
+		Certificates:       []tls.Certificate{cert},
+		InsecureSkipVerify: true, // the testing certs are expired.
+	}
+}
+
+func (s *DialTestSuite) TestDialCallsThroughKedge() {
+	conn, err := Dial("controller.eu1-prod.improbable.local:443", s.mapper, s.clientTls(), grpc.WithBlock(), grpc.WithTimeout(2*time.Second))
+	require.NoError(s.T(), err, "dialing through kedge must not fail")
+	defer conn.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	resp, err := pb_testproto.NewTestServiceClient(conn).Ping(ctx, &pb_testproto.PingRequest{Value: "something"})
+	require.NoError(s.T(), err, "call routed by :authority must not fail")
+	assert.Equal(s.T(), "something", resp.Value)
+	assert.Equal(s.T(), "kedge.eu1-prod.example.com", <-s.kedgeServerNames, "TLS must be established to the kedge")
+}
+
+func (s *DialTestSuite) TestDialToTargetWithoutRouteFailsCalls() {
+	conn, err := Dial("other.eu1-prod.improbable.local:443", s.mapper, s.clientTls(), grpc.WithBlock(), grpc.WithTimeout(2*time.Second))
+	require.NoError(s.T(), err, "dialing through kedge must not fail")
+	defer conn.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	_, err = pb_testproto.NewTestServiceClient(conn).Ping(ctx, &pb_testproto.PingRequest{Value: "something"})
+	require.Error(s.T(), err, "kedge must reject calls to unrouted authorities")
+	<-s.kedgeServerNames
+}
+
+func (s *DialTestSuite) TestDialFailsForUnmappedTargets() {
+	_, err := Dial("controller.example.com:443", s.mapper, s.clientTls())
+	require.Error(s.T(), err, "dialing of unmapped targets must fail")
+	assert.Contains(s.T(), err.Error(), mapper.ErrNotKedgeDestination.Error())
+}
+
+func (s *DialTestSuite) TearDownSuite() {
+	if s.originalSrvResolver != nil {
+		resolvers.ParentSrvResolver = s.originalSrvResolver
+	}
+	if s.originalDialFunc != nil {
+		ParentDialFunc = s.originalDialFunc
+	}
+	if s.kedge != nil {
+		s.kedge.Stop()
+	}
+	if s.backend != nil {
+		s.backend.Stop()
+	}
+}
+
+func getTestingCertsPath() string {
+	_, callerPath, _, _ := runtime.Caller(0)
+	return path.Join(path.Dir(callerPath), "..", "..", "misc")
+}
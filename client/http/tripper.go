@@ -0,0 +1,160 @@
+// Package kedge_http sends HTTP requests of Go clients for hosts of remote clusters through their kedges.
+package kedge_http
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/mwitkow/kedge/lib/mapper"
+	"golang.org/x/net/http2"
+)
+
+const (
+	// KedgeAddressHeader is set on responses that went through a kedge, to the host:port of the kedge.
+	KedgeAddressHeader = "x-kedge-address"
+)
+
+var (
+	// ParentDialFunc is used for dialing kedges, it can be replaced to resolve kedges differently, e.g. in tests.
+	ParentDialFunc = (&net.Dialer{
+		Timeout:   10 * time.Second,
+		KeepAlive: 30 * time.Second,
+	}).DialContext
+)
+
+// Mode decides how requests are sent to kedges, which route them based on the proxy mode of routes.
+type Mode int
+
+const (
+	// REVERSE_PROXY sends requests over HTTP/2 to the kedge, with the destination in the Host header.
+	// They match the REVERSE_PROXY routes of the kedge.
+	REVERSE_PROXY Mode = iota
+	// FORWARD_PROXY sends requests over HTTP/1.1 to the kedge, with the destination in an absolute request URI.
+	// They match the FORWARD_PROXY routes of the kedge, and are only possible for http:// URLs.
+	FORWARD_PROXY
+)
+
+type tripper struct {
+	mapper         mapper.Mapper
+	mode           Mode
+	kedgeTransport *http.Transport
+	parent         http.RoundTripper
+}
+
+// New creates a RoundTripper sending requests for hosts mapped by the mapper to their kedges, and all other ones
+// using the parent, e.g. http.DefaultTransport.
+//
+// The tlsConfig needs to contain the client certificate, see tlsconfig.ForClient.
+func New(m mapper.Mapper, tlsConfig *tls.Config, mode Mode, parent http.RoundTripper) (http.RoundTripper, error) {
+	t := &tripper{mapper: m, mode: mode, parent: parent}
+	switch mode {
+	case REVERSE_PROXY:
+		t.kedgeTransport = &http.Transport{
+			DialContext:         ParentDialFunc,
+			TLSClientConfig:     tlsConfig,
+			TLSHandshakeTimeout: 10 * time.Second,
+			IdleConnTimeout:     90 * time.Second,
+		}
+		if err := http2.ConfigureTransport(t.kedgeTransport); err != nil {
+			return nil, fmt.Errorf("failed setting up HTTP2 for kedges: %v", err)
+		}
+	case FORWARD_PROXY:
+		t.kedgeTransport = &http.Transport{
+			// The proxy URL is plain HTTP, as the TLS to the kedge is done when dialing.
+			Proxy: func(req *http.Request) (*url.URL, error) {
+				kedgeUrl, err := m.Map(req.URL.Host)
+				if err != nil {
+					return nil, err
+				}
+				return &url.URL{Scheme: "http", Host: kedgeUrl.Host}, nil
+			},
+			DialContext: func(ctx context.Context, network string, addr string) (net.Conn, error) {
+				return dialTls(ctx, network, addr, tlsConfig)
+			},
+			IdleConnTimeout: 90 * time.Second,
+		}
+	default:
+		return nil, fmt.Errorf("unknown mode %v", mode)
+	}
+	return t, nil
+}
+
+func (t *tripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	kedgeUrl, err := t.mapper.Map(req.URL.Host)
+	if err == mapper.ErrNotKedgeDestination {
+		return t.parent.RoundTrip(req)
+	} else if err != nil {
+		return nil, err
+	}
+	outReq := new(http.Request)
+	*outReq = *req // shallow copy, RoundTrippers mustn't modify requests.
+	outUrl := *req.URL
+	outReq.URL = &outUrl
+	if t.mode == FORWARD_PROXY {
+		if req.URL.Scheme != "http" {
+			return nil, fmt.Errorf("kedge: forward proxy mode only supports http:// URLs, not '%v'", req.URL.String())
+		}
+	} else {
+		// The kedge routes on the Host, while the connection is made to the kedge itself.
+		if outReq.Host == "" {
+			outReq.Host = req.URL.Host
+		}
+		outReq.URL.Scheme = kedgeUrl.Scheme
+		outReq.URL.Host = kedgeUrl.Host
+	}
+	resp, err := t.kedgeTransport.RoundTrip(outReq)
+	if err != nil {
+		return nil, fmt.Errorf("kedge '%v': %v", kedgeUrl.Host, err)
+	}
+	resp.Header.Set(KedgeAddressHeader, kedgeUrl.Host)
+	return resp, nil
+}
+
+func dialTls(ctx context.Context, network string, addr string, tlsConfig *tls.Config) (net.Conn, error) {
+	conn, err := ParentDialFunc(ctx, network, addr)
+	if err != nil {
+		return nil, err
+	}
+	cfg := tlsConfig
+	if cfg.ServerName == "" {
+		host, _, err := net.SplitHostPort(addr)
+		if err != nil {
+			conn.Close()
+			return nil, err
+		}
+		cfg = withServerName(tlsConfig, host)
+	}
+	tlsConn := tls.Client(conn, cfg)
+	errChan := make(chan error, 1)
+	go func() {
+		errChan <- tlsConn.Handshake()
+	}()
+	select {
+	case err = <-errChan:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return tlsConn, nil
+}
+
+// withServerName copies the fields of a client TLS config relevant to kedges, as tls.Config.Clone needs Go 1.8.
+func withServerName(c *tls.Config, serverName string) *tls.Config {
+	return &tls.Config{
+		Certificates:       c.Certificates,
+		RootCAs:            c.RootCAs,
+		InsecureSkipVerify: c.InsecureSkipVerify,
+		MinVersion:         c.MinVersion,
+		MaxVersion:         c.MaxVersion,
+		CipherSuites:       c.CipherSuites,
+		ServerName:         serverName,
+	}
+}
@@ -0,0 +1,226 @@
+package kedge_http
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/mwitkow/go-conntrack/connhelpers"
+	"github.com/mwitkow/go-srvlb/srv"
+	pb "github.com/mwitkow/kedge/_protogen/kedge/config/client"
+	pb_res "github.com/mwitkow/kedge/_protogen/kedge/config/common/resolvers"
+	pb_be "github.com/mwitkow/kedge/_protogen/kedge/config/http/backends"
+	pb_route "github.com/mwitkow/kedge/_protogen/kedge/config/http/routes"
+	"github.com/mwitkow/kedge/http/backendpool"
+	"github.com/mwitkow/kedge/http/director"
+	"github.com/mwitkow/kedge/http/director/router"
+	"github.com/mwitkow/kedge/lib/mapper"
+	"github.com/mwitkow/kedge/lib/resolvers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+var backendConfigs = []*pb_be.Backend{
+	{
+		Name: "cluster_svc",
+		Resolver: &pb_be.Backend_Srv{
+			Srv: &pb_res.SrvResolver{
+				DnsName: "_http._tcp.svc.backends.test.local",
+			},
+		},
+		Balancer: pb_be.Balancer_ROUND_ROBIN,
+	},
+}
+
+var routeConfigs = []*pb_route.Route{
+	{
+		BackendName: "cluster_svc",
+		HostMatcher: "reverse.my_cluster.cluster.local",
+		ProxyMode:   pb_route.ProxyMode_REVERSE_PROXY,
+	},
+	{
+		BackendName: "cluster_svc",
+		HostMatcher: "forward.my_cluster.cluster.local",
+		ProxyMode:   pb_route.ProxyMode_FORWARD_PROXY,
+	},
+}
+
+var mappings = []*pb.Mapping{
+	{
+		HostMatcher:  "*.my_cluster.cluster.local",
+		KedgeAddress: "kedge.my_cluster.example.com:443",
+	},
+}
+
+type TripperTestSuite struct {
+	suite.Suite
+
+	backend             *httptest.Server
+	direct              *httptest.Server
+	kedgeListener       net.Listener
+	kedgeRequests       chan *http.Request
+	originalSrvResolver srv.Resolver
+	originalDialFunc    func(ctx context.Context, network, address string) (net.Conn, error)
+
+	mapper mapper.Mapper
+}
+
+func TestTripperTestSuite(t *testing.T) {
+	suite.Run(t, &TripperTestSuite{})
+}
+
+// implements srv resolver.
+func (s *TripperTestSuite) Lookup(domainName string) ([]*srv.Target, error) {
+	if domainName != "_http._tcp.svc.backends.test.local" {
+		return nil, fmt.Errorf("Unknown local backend '%v' in testing", domainName)
+	}
+	return []*srv.Target{{DialAddr: s.backend.Listener.Addr().String(), Ttl: 10 * time.Second}}, nil
+}
+
+// dialLocalKedge resolves the kedge of the mappings to the in-process one.
+func (s *TripperTestSuite) dialLocalKedge(ctx context.Context, network string, addr string) (net.Conn, error) {
+	if addr != "kedge.my_cluster.example.com:443" {
+		return nil, fmt.Errorf("Unknown kedge '%v' in testing", addr)
+	}
+	return (&net.Dialer{}).DialContext(ctx, network, s.kedgeListener.Addr().String())
+}
+
+func (s *TripperTestSuite) SetupSuite() {
+	s.backend = httptest.NewServer(http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		fmt.Fprintf(resp, "backend:%s%s", req.Host, req.URL.Path)
+	}))
+	s.direct = httptest.NewServer(http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		fmt.Fprintf(resp, "direct%s", req.URL.Path)
+	}))
+
+	s.originalSrvResolver = resolvers.ParentSrvResolver
+	resolvers.ParentSrvResolver = s
+	s.originalDialFunc = ParentDialFunc
+	ParentDialFunc = s.dialLocalKedge
+	s.startKedge()
+
+	var err error
+	s.mapper, err = mapper.NewStatic(mappings)
+	require.NoError(s.T(), err, "mapper creation must not fail")
+}
+
+func (s *TripperTestSuite) startKedge() {
+	pool, err := backendpool.NewStatic(backendConfigs)
+	require.NoError(s.T(), err, "backend pool creation must not fail")
+	staticRouter, err := router.NewStatic(routeConfigs)
+	require.NoError(s.T(), err, "router creation must not fail")
+	addresser, err := router.NewAddresser(nil, nil)
+	require.NoError(s.T(), err, "addresser creation must not fail")
+	kedge := director.New(pool, staticRouter, addresser)
+	s.kedgeRequests = make(chan *http.Request, 100)
+
+	tlsConfig, err := connhelpers.TlsConfigForServerCerts(
+		path.Join(getTestingCertsPath(), "localhost.crt"),
+		path.Join(getTestingCertsPath(), "localhost.key"))
+	require.NoError(s.T(), err, "failed reading server certs")
+	// The testing certs are expired, so only their presence is checked.
+	tlsConfig.ClientAuth = tls.RequireAnyClientCert
+	tlsConfig, err = connhelpers.TlsConfigWithHttp2Enabled(tlsConfig)
+	require.NoError(s.T(), err, "cannot configure the tls config for http2")
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(s.T(), err, "must be able to allocate a port for kedge")
+	s.kedgeListener = tls.NewListener(listener, tlsConfig)
+	server := &http.Server{
+		Handler: http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+			s.kedgeRequests <- req
+			kedge.ServeHTTP(resp, req)
+		}),
+	}
+	go func() {
+		server.Serve(s.kedgeListener)
+	}()
+}
+
+func (s *TripperTestSuite) clientFor(mode Mode) *http.Client {
+	cert, err := tls.LoadX509KeyPair(
+		path.Join(getTestingCertsPath(), "client.crt"),
+		path.Join(getTestingCertsPath(), "client.key"))
+	require.NoError(s.T(), err, "failed reading client certs")
+	tlsConfig := &tls.Config{
+		Certificates:       []tls.Certificate{cert},
+		InsecureSkipVerify: true, // the testing certs are expired.
+	}
+	tripper, err := New(s.mapper, tlsConfig, mode, http.DefaultTransport)
+	require.NoError(s.T(), err, "tripper creation must not fail")
+	return &http.Client{Transport: tripper}
+}
+
+func (s *TripperTestSuite) assertResponse(resp *http.Response, err error, expectedCode int, expectedBody string) {
+	require.NoError(s.T(), err, "call must not fail")
+	defer resp.Body.Close()
+	body, _ := ioutil.ReadAll(resp.Body)
+	require.Equal(s.T(), expectedCode, resp.StatusCode, "body: %s", body)
+	if expectedBody != "" {
+		assert.Equal(s.T(), expectedBody, string(body))
+	}
+}
+
+func (s *TripperTestSuite) TestReverseProxyModeMatchesReverseProxyRoutes() {
+	resp, err := s.clientFor(REVERSE_PROXY).Get("http://reverse.my_cluster.cluster.local/some/path")
+	s.assertResponse(resp, err, http.StatusOK, "backend:reverse.my_cluster.cluster.local/some/path")
+	assert.Equal(s.T(), "kedge.my_cluster.example.com:443", resp.Header.Get(KedgeAddressHeader))
+	kedgeReq := <-s.kedgeRequests
+	assert.Equal(s.T(), "HTTP/2.0", kedgeReq.Proto, "kedge must be called over HTTP2")
+	assert.NotEmpty(s.T(), kedgeReq.TLS.PeerCertificates, "kedge must get the client certificate")
+}
+
+func (s *TripperTestSuite) TestReverseProxyModeDoesntMatchForwardProxyRoutes() {
+	resp, err := s.clientFor(REVERSE_PROXY).Get("http://forward.my_cluster.cluster.local/some/path")
+	s.assertResponse(resp, err, http.StatusBadGateway, "")
+	assert.NotEmpty(s.T(), resp.Header.Get("x-kedge-error"))
+	<-s.kedgeRequests
+}
+
+func (s *TripperTestSuite) TestForwardProxyModeMatchesForwardProxyRoutes() {
+	resp, err := s.clientFor(FORWARD_PROXY).Get("http://forward.my_cluster.cluster.local/some/path")
+	s.assertResponse(resp, err, http.StatusOK, "backend:forward.my_cluster.cluster.local/some/path")
+	assert.Equal(s.T(), "kedge.my_cluster.example.com:443", resp.Header.Get(KedgeAddressHeader))
+	kedgeReq := <-s.kedgeRequests
+	assert.Equal(s.T(), "http://forward.my_cluster.cluster.local/some/path", kedgeReq.RequestURI, "request must be a forward proxy one")
+	assert.NotEmpty(s.T(), kedgeReq.TLS.PeerCertificates, "kedge must get the client certificate")
+}
+
+func (s *TripperTestSuite) TestForwardProxyModeFailsForHttpsUrls() {
+	_, err := s.clientFor(FORWARD_PROXY).Get("https://forward.my_cluster.cluster.local/some/path")
+	require.Error(s.T(), err, "https:// URLs can't be forward proxied")
+}
+
+func (s *TripperTestSuite) TestUnmappedHostsUseParent() {
+	for _, mode := range []Mode{REVERSE_PROXY, FORWARD_PROXY} {
+		resp, err := s.clientFor(mode).Get(s.direct.URL + "/some/path")
+		s.assertResponse(resp, err, http.StatusOK, "direct/some/path")
+		assert.Empty(s.T(), resp.Header.Get(KedgeAddressHeader))
+	}
+}
+
+func (s *TripperTestSuite) TearDownSuite() {
+	if s.originalSrvResolver != nil {
+		resolvers.ParentSrvResolver = s.originalSrvResolver
+	}
+	if s.originalDialFunc != nil {
+		ParentDialFunc = s.originalDialFunc
+	}
+	s.kedgeListener.Close()
+	s.backend.Close()
+	s.direct.Close()
+}
+
+func getTestingCertsPath() string {
+	_, callerPath, _, _ := runtime.Caller(0)
+	return path.Join(path.Dir(callerPath), "..", "..", "misc")
+}
@@ -0,0 +1,356 @@
+package localproxy
+
+import (
+	"bufio"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mwitkow/go-conntrack/connhelpers"
+	"github.com/mwitkow/go-srvlb/srv"
+	pb "github.com/mwitkow/kedge/_protogen/kedge/config/client"
+	pb_res "github.com/mwitkow/kedge/_protogen/kedge/config/common/resolvers"
+	pb_be "github.com/mwitkow/kedge/_protogen/kedge/config/http/backends"
+	pb_route "github.com/mwitkow/kedge/_protogen/kedge/config/http/routes"
+	"github.com/mwitkow/kedge/http/backendpool"
+	"github.com/mwitkow/kedge/http/director"
+	"github.com/mwitkow/kedge/http/director/router"
+	"github.com/mwitkow/kedge/lib/mapper"
+	"github.com/mwitkow/kedge/lib/resolvers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	"golang.org/x/net/http2"
+)
+
+// clientCommonName is the CN of misc/client.crt.
+const clientCommonName = "someone@example.com"
+
+var backendConfigs = []*pb_be.Backend{
+	{
+		Name: "cluster_svc",
+		Resolver: &pb_be.Backend_Srv{
+			Srv: &pb_res.SrvResolver{
+				DnsName: "_http._tcp.svc.backends.test.local",
+			},
+		},
+		Balancer: pb_be.Balancer_ROUND_ROBIN,
+	},
+}
+
+var routeConfigs = []*pb_route.Route{
+	{
+		BackendName: "cluster_svc",
+		HostMatcher: "svc.my_cluster.cluster.local",
+	},
+	{
+		// CONNECT tunnels always carry the port.
+		BackendName: "cluster_svc",
+		HostMatcher: "svc.my_cluster.cluster.local:81",
+	},
+}
+
+type LocalProxyIntegrationTestSuite struct {
+	suite.Suite
+
+	backend             *httptest.Server
+	direct              *httptest.Server
+	directTls           *httptest.Server
+	kedgeListener       net.Listener
+	originalSrvResolver srv.Resolver
+	clientCertSubjects  chan string
+	tunnelCA            *TunnelCA
+
+	localProxy          *httptest.Server
+	rejectingLocalProxy *httptest.Server
+}
+
+func TestLocalProxyIntegrationTestSuite(t *testing.T) {
+	suite.Run(t, &LocalProxyIntegrationTestSuite{})
+}
+
+// implements srv resolver.
+func (s *LocalProxyIntegrationTestSuite) Lookup(domainName string) ([]*srv.Target, error) {
+	if domainName != "_http._tcp.svc.backends.test.local" {
+		return nil, fmt.Errorf("Unknown local backend '%v' in testing", domainName)
+	}
+	return []*srv.Target{{DialAddr: s.backend.Listener.Addr().String(), Ttl: 10 * time.Second}}, nil
+}
+
+func (s *LocalProxyIntegrationTestSuite) SetupSuite() {
+	s.backend = httptest.NewServer(http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		resp.Header().Set("x-test-backend", "cluster_svc")
+		fmt.Fprintf(resp, "%s%s", req.Host, req.URL.Path)
+	}))
+	directHandler := http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		resp.Header().Set("x-test-backend", "direct")
+		fmt.Fprintf(resp, "direct%s", req.URL.Path)
+	})
+	s.direct = httptest.NewServer(directHandler)
+	s.directTls = httptest.NewTLSServer(directHandler)
+
+	s.originalSrvResolver = resolvers.ParentSrvResolver
+	resolvers.ParentSrvResolver = s
+	s.startKedge()
+
+	m, err := mapper.NewStatic([]*pb.Mapping{
+		{HostMatcher: "*.my_cluster.cluster.local", KedgeAddress: s.kedgeListener.Addr().String()},
+		{HostMatcher: "*.other_cluster.cluster.local", KedgeAddress: "other-kedge.example.com"},
+	})
+	require.NoError(s.T(), err, "mapper creation must not fail")
+	s.tunnelCA, err = NewTunnelCA()
+	require.NoError(s.T(), err, "tunnel CA creation must not fail")
+	s.localProxy = httptest.NewServer(New(m, s.kedgeTransport(), http.DefaultTransport, pb.ClientConfig_DIRECT, s.tunnelCA))
+	s.rejectingLocalProxy = httptest.NewServer(New(m, s.kedgeTransport(), http.DefaultTransport, pb.ClientConfig_REJECT, s.tunnelCA))
+}
+
+func (s *LocalProxyIntegrationTestSuite) startKedge() {
+	pool, err := backendpool.NewStatic(backendConfigs)
+	require.NoError(s.T(), err, "backend pool creation must not fail")
+	staticRouter, err := router.NewStatic(routeConfigs)
+	require.NoError(s.T(), err, "router creation must not fail")
+	addresser, err := router.NewAddresser(nil, nil)
+	require.NoError(s.T(), err, "addresser creation must not fail")
+	kedge := director.New(pool, staticRouter, addresser)
+	s.clientCertSubjects = make(chan string, 100)
+
+	tlsConfig, err := connhelpers.TlsConfigForServerCerts(
+		path.Join(getTestingCertsPath(), "localhost.crt"),
+		path.Join(getTestingCertsPath(), "localhost.key"))
+	require.NoError(s.T(), err, "failed reading server certs")
+	// The testing certs are expired, so only their presence is checked.
+	tlsConfig.ClientAuth = tls.RequireAnyClientCert
+	tlsConfig, err = connhelpers.TlsConfigWithHttp2Enabled(tlsConfig)
+	require.NoError(s.T(), err, "cannot configure the tls config for http2")
+
+	listener, err := net.Listen("tcp", "localhost:0")
+	require.NoError(s.T(), err, "must be able to allocate a port for kedge")
+	s.kedgeListener = tls.NewListener(listener, tlsConfig)
+	server := &http.Server{
+		Handler: http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+			s.clientCertSubjects <- req.TLS.PeerCertificates[0].Subject.CommonName
+			resp.Header().Set("x-test-kedge-proto", req.Proto)
+			kedge.ServeHTTP(resp, req)
+		}),
+	}
+	go func() {
+		server.Serve(s.kedgeListener)
+	}()
+}
+
+func (s *LocalProxyIntegrationTestSuite) kedgeTransport() http.RoundTripper {
+	cert, err := tls.LoadX509KeyPair(
+		path.Join(getTestingCertsPath(), "client.crt"),
+		path.Join(getTestingCertsPath(), "client.key"))
+	require.NoError(s.T(), err, "failed reading client certs")
+	transport := &http.Transport{
+		TLSClientConfig: &tls.Config{
+			Certificates:       []tls.Certificate{cert},
+			InsecureSkipVerify: true, // the testing certs are expired.
+		},
+	}
+	require.NoError(s.T(), http2.ConfigureTransport(transport), "configuring http2 must not fail")
+	return transport
+}
+
+func (s *LocalProxyIntegrationTestSuite) clientFor(localProxy *httptest.Server) *http.Client {
+	return &http.Client{
+		Transport: &http.Transport{
+			Proxy:           http.ProxyURL(urlMustParse(localProxy.URL)),
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+		},
+	}
+}
+
+func (s *LocalProxyIntegrationTestSuite) assertSuccessfulResponse(resp *http.Response, err error, expectedBackend string, expectedBody string) {
+	require.NoError(s.T(), err, "call must not fail")
+	defer resp.Body.Close()
+	body, _ := ioutil.ReadAll(resp.Body)
+	require.Equal(s.T(), http.StatusOK, resp.StatusCode, "call must succeed, body: %s", body)
+	assert.Equal(s.T(), expectedBackend, resp.Header.Get("x-test-backend"), "call must reach the right backend")
+	assert.Equal(s.T(), expectedBody, string(body))
+}
+
+func (s *LocalProxyIntegrationTestSuite) TestMappedHostGoesThroughKedge() {
+	resp, err := s.clientFor(s.localProxy).Get("http://svc.my_cluster.cluster.local/some/path")
+	s.assertSuccessfulResponse(resp, err, "cluster_svc", "svc.my_cluster.cluster.local/some/path")
+	assert.Equal(s.T(), "cluster_svc", resp.Header.Get("x-kedge-backend-name"), "kedge must route the call")
+	assert.Equal(s.T(), "HTTP/2.0", resp.Header.Get("x-test-kedge-proto"), "kedge must be called over HTTP2")
+	assert.Equal(s.T(), clientCommonName, <-s.clientCertSubjects, "kedge must get the client certificate")
+}
+
+func (s *LocalProxyIntegrationTestSuite) TestMappedHostWithoutKedgeRouteReturnsKedgeError() {
+	resp, err := s.clientFor(s.localProxy).Get("http://unknown.my_cluster.cluster.local/some/path")
+	require.NoError(s.T(), err, "call must not fail")
+	resp.Body.Close()
+	assert.NotEqual(s.T(), http.StatusOK, resp.StatusCode)
+	assert.NotEmpty(s.T(), resp.Header.Get("x-kedge-error"), "kedge error must be passed on")
+	<-s.clientCertSubjects
+}
+
+// h2cTunnelClientFor makes HTTP/2 calls in cleartext through CONNECT tunnels, like gRPC clients using `grpc_proxy`.
+func (s *LocalProxyIntegrationTestSuite) h2cTunnelClientFor(localProxy *httptest.Server) *http.Client {
+	return &http.Client{
+		Transport: &http2.Transport{
+			AllowHTTP: true,
+			DialTLS: func(network, addr string, cfg *tls.Config) (net.Conn, error) {
+				conn, err := net.Dial("tcp", localProxy.Listener.Addr().String())
+				if err != nil {
+					return nil, err
+				}
+				fmt.Fprintf(conn, "CONNECT %s HTTP/1.1\r\nHost: %s\r\n\r\n", addr, addr)
+				resp, err := http.ReadResponse(bufio.NewReader(conn), nil)
+				if err != nil {
+					conn.Close()
+					return nil, err
+				}
+				if resp.StatusCode != http.StatusOK {
+					conn.Close()
+					return nil, fmt.Errorf("CONNECT failed: %v", resp.Status)
+				}
+				return conn, nil
+			},
+		},
+	}
+}
+
+func (s *LocalProxyIntegrationTestSuite) TestMappedHostGoesThroughKedgeOverHttp2Tunnel() {
+	resp, err := s.h2cTunnelClientFor(s.localProxy).Get("http://svc.my_cluster.cluster.local:81/some/path")
+	s.assertSuccessfulResponse(resp, err, "cluster_svc", "svc.my_cluster.cluster.local:81/some/path")
+	assert.Equal(s.T(), "cluster_svc", resp.Header.Get("x-kedge-backend-name"), "kedge must route the call")
+	assert.Equal(s.T(), clientCommonName, <-s.clientCertSubjects, "kedge must get the client certificate")
+}
+
+// tlsTunnelClientFor makes https:// calls through CONNECT tunnels, trusting the tunnel CA.
+func (s *LocalProxyIntegrationTestSuite) tlsTunnelClientFor(localProxy *httptest.Server, useHttp2 bool) *http.Client {
+	roots := x509.NewCertPool()
+	require.True(s.T(), roots.AppendCertsFromPEM(s.tunnelCA.CertPEM()), "tunnel CA must be a PEM certificate")
+	transport := &http.Transport{
+		Proxy:           http.ProxyURL(urlMustParse(localProxy.URL)),
+		TLSClientConfig: &tls.Config{RootCAs: roots},
+	}
+	if useHttp2 {
+		require.NoError(s.T(), http2.ConfigureTransport(transport), "configuring http2 must not fail")
+	}
+	return &http.Client{Transport: transport}
+}
+
+func (s *LocalProxyIntegrationTestSuite) TestMappedHostGoesThroughKedgeOverTlsTunnel() {
+	resp, err := s.tlsTunnelClientFor(s.localProxy, false).Get("https://svc.my_cluster.cluster.local/some/path")
+	s.assertSuccessfulResponse(resp, err, "cluster_svc", "svc.my_cluster.cluster.local/some/path")
+	assert.Equal(s.T(), "HTTP/1.1", resp.Proto, "tunnel must be served over HTTP/1.1")
+	assert.Equal(s.T(), "cluster_svc", resp.Header.Get("x-kedge-backend-name"), "kedge must route the call")
+	assert.Equal(s.T(), clientCommonName, <-s.clientCertSubjects, "kedge must get the client certificate")
+}
+
+func (s *LocalProxyIntegrationTestSuite) TestMappedHostGoesThroughKedgeOverHttp2TlsTunnel() {
+	resp, err := s.tlsTunnelClientFor(s.localProxy, true).Get("https://svc.my_cluster.cluster.local/some/path")
+	s.assertSuccessfulResponse(resp, err, "cluster_svc", "svc.my_cluster.cluster.local/some/path")
+	assert.Equal(s.T(), "HTTP/2.0", resp.Proto, "tunnel must be served over HTTP/2")
+	assert.Equal(s.T(), clientCommonName, <-s.clientCertSubjects, "kedge must get the client certificate")
+}
+
+// tlsTunnelTo opens a CONNECT tunnel to the host, and a TLS connection inside it for the server name.
+func (s *LocalProxyIntegrationTestSuite) tlsTunnelTo(connectHost string, serverName string) (*tls.Conn, error) {
+	conn, err := net.Dial("tcp", s.localProxy.Listener.Addr().String())
+	require.NoError(s.T(), err, "must be able to dial the local proxy")
+	fmt.Fprintf(conn, "CONNECT %s HTTP/1.1\r\nHost: %s\r\n\r\n", connectHost, connectHost)
+	resp, err := http.ReadResponse(bufio.NewReader(conn), nil)
+	require.NoError(s.T(), err, "CONNECT must not fail")
+	require.Equal(s.T(), http.StatusOK, resp.StatusCode, "CONNECT must succeed")
+	roots := x509.NewCertPool()
+	require.True(s.T(), roots.AppendCertsFromPEM(s.tunnelCA.CertPEM()), "tunnel CA must be a PEM certificate")
+	tlsConn := tls.Client(conn, &tls.Config{ServerName: serverName, RootCAs: roots})
+	if err := tlsConn.Handshake(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return tlsConn, nil
+}
+
+func (s *LocalProxyIntegrationTestSuite) TestMappedHostTlsTunnelRejectsServerNamesOfOtherKedges() {
+	_, err := s.tlsTunnelTo("svc.my_cluster.cluster.local:443", "svc.other_cluster.cluster.local")
+	assert.Error(s.T(), err, "tunnels must not carry TLS for hosts of other kedges")
+	_, err = s.tlsTunnelTo("svc.my_cluster.cluster.local:443", "unmapped.example.com")
+	assert.Error(s.T(), err, "tunnels must not carry TLS for unmapped hosts")
+}
+
+func (s *LocalProxyIntegrationTestSuite) TestMappedHostTlsTunnelRejectsHostsOfOtherKedges() {
+	for _, host := range []string{"svc.other_cluster.cluster.local", "unmapped.example.com"} {
+		conn, err := s.tlsTunnelTo("svc.my_cluster.cluster.local:443", "svc.my_cluster.cluster.local")
+		require.NoError(s.T(), err, "TLS in the tunnel must not fail")
+		fmt.Fprintf(conn, "GET /some/path HTTP/1.1\r\nHost: %s\r\n\r\n", host)
+		resp, err := http.ReadResponse(bufio.NewReader(conn), nil)
+		require.NoError(s.T(), err, "call must not fail")
+		resp.Body.Close()
+		conn.Close()
+		assert.Equal(s.T(), http.StatusForbidden, resp.StatusCode, "tunnels must not carry requests for host '%v'", host)
+	}
+}
+
+func (s *LocalProxyIntegrationTestSuite) TestMappedHostTlsTunnelFailsForClientsNotTrustingTunnelCA() {
+	client := s.clientFor(s.localProxy)
+	client.Transport.(*http.Transport).TLSClientConfig = &tls.Config{}
+	_, err := client.Get("https://svc.my_cluster.cluster.local/some/path")
+	require.Error(s.T(), err, "https:// calls must fail verification of the tunnel certificate")
+	assert.Contains(s.T(), err.Error(), "certificate", "failure must be about the certificate")
+}
+
+func (s *LocalProxyIntegrationTestSuite) TestUnmappedHostGoesDirect() {
+	resp, err := s.clientFor(s.localProxy).Get(s.direct.URL + "/some/path")
+	s.assertSuccessfulResponse(resp, err, "direct", "direct/some/path")
+}
+
+func (s *LocalProxyIntegrationTestSuite) TestUnmappedHostGoesDirectOverConnect() {
+	resp, err := s.clientFor(s.localProxy).Get(s.directTls.URL + "/some/path")
+	s.assertSuccessfulResponse(resp, err, "direct", "direct/some/path")
+}
+
+func (s *LocalProxyIntegrationTestSuite) TestUnmappedHostIsRejected() {
+	resp, err := s.clientFor(s.rejectingLocalProxy).Get(s.direct.URL + "/some/path")
+	require.NoError(s.T(), err, "call must not fail")
+	resp.Body.Close()
+	assert.Equal(s.T(), http.StatusForbidden, resp.StatusCode)
+	assert.True(s.T(), strings.Contains(resp.Header.Get("x-kedge-error"), "not a kedge destination"))
+}
+
+func (s *LocalProxyIntegrationTestSuite) TestUnmappedHostIsRejectedOverConnect() {
+	_, err := s.clientFor(s.rejectingLocalProxy).Get(s.directTls.URL + "/some/path")
+	require.Error(s.T(), err, "CONNECT must fail")
+	assert.Contains(s.T(), err.Error(), "Forbidden")
+}
+
+func (s *LocalProxyIntegrationTestSuite) TearDownSuite() {
+	if s.originalSrvResolver != nil {
+		resolvers.ParentSrvResolver = s.originalSrvResolver
+	}
+	s.localProxy.Close()
+	s.rejectingLocalProxy.Close()
+	s.kedgeListener.Close()
+	s.backend.Close()
+	s.direct.Close()
+	s.directTls.Close()
+}
+
+func urlMustParse(uStr string) *url.URL {
+	u, err := url.Parse(uStr)
+	if err != nil {
+		panic(err)
+	}
+	return u
+}
+
+func getTestingCertsPath() string {
+	_, callerPath, _, _ := runtime.Caller(0)
+	return path.Join(path.Dir(callerPath), "..", "..", "misc")
+}
@@ -0,0 +1,311 @@
+// Package localproxy implements the forward proxy run by kedge_local on the machines of users.
+//
+// Requests for hosts matched by the Mapper are sent to their kedge, over HTTPS with the user's client certificate.
+// Other requests either go straight to their destination or are rejected. CONNECT tunnels to matched hosts are
+// terminated by the proxy, see Proxy.serveKedgeTunnel.
+package localproxy
+
+import (
+	"bufio"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"sync"
+	"time"
+
+	pb "github.com/mwitkow/kedge/_protogen/kedge/config/client"
+	"github.com/mwitkow/kedge/lib/mapper"
+	"golang.org/x/net/http2"
+)
+
+var (
+	// DirectDialTimeout limits dialing of destinations of CONNECT requests that bypass kedges.
+	DirectDialTimeout = 10 * time.Second
+	// TunnelHandshakeTimeout limits the TLS handshakes of CONNECT tunnels to kedge destinations.
+	TunnelHandshakeTimeout = 10 * time.Second
+)
+
+// Proxy is an http.Handler serving forward proxy requests.
+type Proxy struct {
+	mapper    mapper.Mapper
+	unmatched pb.ClientConfig_Unmatched
+	tunnelCA  *TunnelCA
+
+	kedgeReverseProxy  *httputil.ReverseProxy
+	directReverseProxy *httputil.ReverseProxy
+}
+
+// New creates a forward proxy sending the requests matched by the mapper to kedges using kedgeTripper.
+//
+// The kedgeTripper is expected to present the client certificate to the kedges, and unmatched requests are sent using
+// directTripper, usually http.DefaultTransport. The tunnelCA signs the certificates of CONNECT tunnels carrying TLS.
+func New(m mapper.Mapper, kedgeTripper http.RoundTripper, directTripper http.RoundTripper, unmatched pb.ClientConfig_Unmatched, tunnelCA *TunnelCA) *Proxy {
+	return &Proxy{
+		mapper:    m,
+		unmatched: unmatched,
+		tunnelCA:  tunnelCA,
+		kedgeReverseProxy: &httputil.ReverseProxy{
+			Director:  func(r *http.Request) {},
+			Transport: kedgeTripper,
+			// Streaming responses, e.g. of gRPC calls, mustn't wait in buffers.
+			FlushInterval: 10 * time.Millisecond,
+		},
+		directReverseProxy: &httputil.ReverseProxy{
+			Director:  func(r *http.Request) {},
+			Transport: directTripper,
+		},
+	}
+}
+
+func (p *Proxy) ServeHTTP(resp http.ResponseWriter, req *http.Request) {
+	if req.Method == "CONNECT" {
+		p.serveConnect(resp, req)
+		return
+	}
+	if req.URL.Host == "" {
+		respondWithError(http.StatusBadRequest, fmt.Errorf("'%v' is not a forward proxy request", req.RequestURI), resp)
+		return
+	}
+	kedgeUrl, err := p.mapper.Map(req.URL.Host)
+	if err == mapper.ErrNotKedgeDestination {
+		if p.unmatched == pb.ClientConfig_REJECT {
+			respondWithError(http.StatusForbidden, fmt.Errorf("host '%v' is not a kedge destination", req.URL.Host), resp)
+			return
+		}
+		p.directReverseProxy.ServeHTTP(resp, req)
+		return
+	} else if err != nil {
+		respondWithError(http.StatusBadGateway, err, resp)
+		return
+	}
+	p.forwardToKedge(resp, req, req.URL.Host, kedgeUrl)
+}
+
+// forwardToKedge sends the request to the kedge, which routes it based on the host.
+func (p *Proxy) forwardToKedge(resp http.ResponseWriter, req *http.Request, host string, kedgeUrl *url.URL) {
+	outReq := new(http.Request)
+	*outReq = *req // shallow copy
+	outUrl := *req.URL
+	outReq.URL = &outUrl
+	// The kedge routes on the Host, while the connection is made to the kedge itself.
+	outReq.Host = host
+	outReq.URL.Scheme = kedgeUrl.Scheme
+	outReq.URL.Host = kedgeUrl.Host
+	p.kedgeReverseProxy.ServeHTTP(resp, outReq)
+}
+
+// serveConnect tunnels CONNECT requests.
+//
+// Tunnels of hosts that bypass kedges are made straight to the destination. Kedges don't accept CONNECT, so tunnels of
+// kedge destinations are terminated locally, see serveKedgeTunnel.
+func (p *Proxy) serveConnect(resp http.ResponseWriter, req *http.Request) {
+	kedgeUrl, err := p.mapper.Map(req.Host)
+	if err == nil {
+		p.serveKedgeTunnel(resp, req, kedgeUrl)
+		return
+	} else if err != mapper.ErrNotKedgeDestination {
+		respondWithError(http.StatusBadGateway, err, resp)
+		return
+	}
+	if p.unmatched == pb.ClientConfig_REJECT {
+		respondWithError(http.StatusForbidden, fmt.Errorf("host '%v' is not a kedge destination", req.Host), resp)
+		return
+	}
+	if _, ok := resp.(http.Hijacker); !ok {
+		respondWithError(http.StatusInternalServerError, fmt.Errorf("connection doesn't support CONNECT"), resp)
+		return
+	}
+	destConn, err := net.DialTimeout("tcp", req.Host, DirectDialTimeout)
+	if err != nil {
+		respondWithError(http.StatusBadGateway, fmt.Errorf("failed dialing '%v': %v", req.Host, err), resp)
+		return
+	}
+	clientConn, err := establishTunnel(resp)
+	if err != nil {
+		destConn.Close()
+		return
+	}
+	splice(clientConn, destConn)
+}
+
+// serveKedgeTunnel serves CONNECT tunnels to kedge destinations, which carry either cleartext HTTP/2, like the ones of
+// gRPC clients using `grpc_proxy`, or TLS, like the ones of https:// URLs.
+//
+// The requests inside the tunnel are forwarded to the kedge one by one, secured by the TLS to the kedge. The TLS of
+// tunnels is terminated with a certificate for the host signed by the tunnel CA, which clients need to trust. Tunnels
+// carrying anything else are closed.
+func (p *Proxy) serveKedgeTunnel(resp http.ResponseWriter, req *http.Request, kedgeUrl *url.URL) {
+	if _, ok := resp.(http.Hijacker); !ok {
+		respondWithError(http.StatusInternalServerError, fmt.Errorf("connection doesn't support CONNECT"), resp)
+		return
+	}
+	clientConn, err := establishTunnel(resp)
+	if err != nil {
+		return
+	}
+	first, err := clientConn.reader.Peek(1)
+	if err != nil {
+		clientConn.Close()
+		return
+	}
+	if first[0] == tlsHandshakeRecord {
+		p.serveTlsTunnel(clientConn, req.Host, kedgeUrl)
+		return
+	}
+	preface, err := clientConn.reader.Peek(len(http2.ClientPreface))
+	if err != nil || string(preface) != http2.ClientPreface {
+		clientConn.Close()
+		return
+	}
+	targetHost := req.Host
+	h2Server := &http2.Server{}
+	h2Server.ServeConn(clientConn, &http2.ServeConnOpts{
+		Handler: http.HandlerFunc(func(tunnelResp http.ResponseWriter, tunnelReq *http.Request) {
+			p.forwardToKedge(tunnelResp, tunnelReq, targetHost, kedgeUrl)
+		}),
+	})
+}
+
+// tlsHandshakeRecord is the first byte of TLS connections.
+const tlsHandshakeRecord = 0x16
+
+// serveTlsTunnel terminates the TLS of the tunnel, serving the requests in it over HTTP/2 or HTTP/1.1.
+//
+// Unlike cleartext tunnels, the requests are forwarded with their own Host, which leaves out the default port. Both the
+// SNI and the Host of the requests need to map to the kedge of the tunnel, as the tunnel CA would otherwise sign
+// certificates for, and the kedge get requests for, any host.
+func (p *Proxy) serveTlsTunnel(clientConn net.Conn, connectHost string, kedgeUrl *url.URL) {
+	defaultHost, _, err := net.SplitHostPort(connectHost)
+	if err != nil {
+		defaultHost = connectHost
+	}
+	tlsConn := tls.Server(clientConn, &tls.Config{
+		GetCertificate: func(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+			if hello.ServerName == "" {
+				return p.tunnelCA.certificateFor(defaultHost)
+			}
+			if err := p.checkTunnelHost(hello.ServerName, kedgeUrl); err != nil {
+				return nil, err
+			}
+			return p.tunnelCA.certificateFor(hello.ServerName)
+		},
+		NextProtos: []string{"h2", "http/1.1"},
+		MinVersion: tls.VersionTLS12,
+	})
+	clientConn.SetDeadline(time.Now().Add(TunnelHandshakeTimeout))
+	if err := tlsConn.Handshake(); err != nil {
+		clientConn.Close()
+		return
+	}
+	clientConn.SetDeadline(time.Time{})
+	handler := http.HandlerFunc(func(tunnelResp http.ResponseWriter, tunnelReq *http.Request) {
+		if err := p.checkTunnelHost(tunnelReq.Host, kedgeUrl); err != nil {
+			respondWithError(http.StatusForbidden, err, tunnelResp)
+			return
+		}
+		p.forwardToKedge(tunnelResp, tunnelReq, tunnelReq.Host, kedgeUrl)
+	})
+	if tlsConn.ConnectionState().NegotiatedProtocol == "h2" {
+		(&http2.Server{}).ServeConn(tlsConn, &http2.ServeConnOpts{Handler: handler})
+		return
+	}
+	(&http.Server{Handler: handler}).Serve(&singleConnListener{conn: tlsConn})
+}
+
+// checkTunnelHost returns an error unless the host maps to the kedge of the tunnel.
+func (p *Proxy) checkTunnelHost(host string, kedgeUrl *url.URL) error {
+	hostKedgeUrl, err := p.mapper.Map(host)
+	if err == mapper.ErrNotKedgeDestination {
+		return fmt.Errorf("host '%v' is not a kedge destination", host)
+	} else if err != nil {
+		return err
+	}
+	if hostKedgeUrl.String() != kedgeUrl.String() {
+		return fmt.Errorf("host '%v' is not served by the kedge of the tunnel", host)
+	}
+	return nil
+}
+
+// singleConnListener accepts a single connection, which lets http.Server serve connections that are already open.
+type singleConnListener struct {
+	conn net.Conn
+	once sync.Once
+}
+
+func (l *singleConnListener) Accept() (net.Conn, error) {
+	var conn net.Conn
+	l.once.Do(func() {
+		conn = l.conn
+	})
+	if conn == nil {
+		return nil, errSingleConnAccepted
+	}
+	return conn, nil
+}
+
+func (l *singleConnListener) Close() error {
+	return nil
+}
+
+func (l *singleConnListener) Addr() net.Addr {
+	return l.conn.LocalAddr()
+}
+
+var errSingleConnAccepted = errors.New("the connection was accepted already")
+
+// tunnelConn is the client connection of a tunnel, reading through the buffer of the hijacked HTTP connection.
+type tunnelConn struct {
+	net.Conn
+	reader *bufio.Reader
+}
+
+func (c *tunnelConn) Read(b []byte) (int, error) {
+	return c.reader.Read(b)
+}
+
+// establishTunnel hijacks the connection of a CONNECT request and confirms the tunnel to the client.
+func establishTunnel(resp http.ResponseWriter) (*tunnelConn, error) {
+	conn, rw, err := resp.(http.Hijacker).Hijack()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := io.WriteString(conn, "HTTP/1.1 200 Connection established\r\n\r\n"); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return &tunnelConn{Conn: conn, reader: rw.Reader}, nil
+}
+
+// splice copies data both ways between the connections, closing both when either side is done.
+func splice(a net.Conn, b net.Conn) {
+	var once sync.Once
+	closeBoth := func() {
+		a.Close()
+		b.Close()
+	}
+	wg := &sync.WaitGroup{}
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		io.Copy(a, b)
+		once.Do(closeBoth)
+	}()
+	go func() {
+		defer wg.Done()
+		io.Copy(b, a)
+		once.Do(closeBoth)
+	}()
+	wg.Wait()
+}
+
+func respondWithError(status int, err error, resp http.ResponseWriter) {
+	resp.Header().Set("x-kedge-error", err.Error())
+	resp.Header().Set("content-type", "text/plain")
+	resp.WriteHeader(status)
+	fmt.Fprintf(resp, "kedge_local error: %v", err.Error())
+}
@@ -0,0 +1,135 @@
+package localproxy
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net"
+	"sync"
+	"time"
+)
+
+var (
+	// TunnelCertValidity is how long the certificates signed for hosts of TLS tunnels are valid for.
+	TunnelCertValidity = 24 * time.Hour
+)
+
+// TunnelCA signs the certificates presented to clients of CONNECT tunnels carrying TLS to kedge destinations, e.g. of
+// https:// URLs. Clients need to trust it, as the TLS of these tunnels is terminated by kedge_local.
+type TunnelCA struct {
+	cert    *x509.Certificate
+	key     crypto.Signer
+	certPEM []byte
+
+	mu    sync.Mutex
+	hosts map[string]*tls.Certificate
+}
+
+// NewTunnelCA generates a CA that lives as long as the process.
+func NewTunnelCA() (*TunnelCA, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	serial, err := randomSerial()
+	if err != nil {
+		return nil, err
+	}
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: "kedge_local tunnel CA"},
+		NotBefore:             time.Now().Add(-1 * time.Hour),
+		NotAfter:              time.Now().Add(10 * 365 * 24 * time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		MaxPathLenZero:        true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+	if err != nil {
+		return nil, err
+	}
+	return newTunnelCA(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), key)
+}
+
+// LoadTunnelCA reads a CA from PEM files, which lets clients trust it across restarts of kedge_local.
+func LoadTunnelCA(certFile string, keyFile string) (*TunnelCA, error) {
+	pair, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed reading tunnel CA: %v", err)
+	}
+	key, ok := pair.PrivateKey.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("tunnel CA key can't sign")
+	}
+	return newTunnelCA(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: pair.Certificate[0]}), key)
+}
+
+func newTunnelCA(certPEM []byte, key crypto.Signer) (*TunnelCA, error) {
+	block, _ := pem.Decode(certPEM)
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	if !cert.IsCA {
+		return nil, fmt.Errorf("tunnel CA certificate '%v' isn't a CA", cert.Subject.CommonName)
+	}
+	return &TunnelCA{cert: cert, key: key, certPEM: certPEM, hosts: make(map[string]*tls.Certificate)}, nil
+}
+
+// CertPEM returns the PEM certificate of the CA, for clients to trust.
+func (ca *TunnelCA) CertPEM() []byte {
+	return ca.certPEM
+}
+
+// certificateFor returns a certificate for the host, signing a new one if there is none or it's about to expire.
+func (ca *TunnelCA) certificateFor(host string) (*tls.Certificate, error) {
+	ca.mu.Lock()
+	defer ca.mu.Unlock()
+	if cert, ok := ca.hosts[host]; ok && time.Now().Add(TunnelCertValidity/4).Before(cert.Leaf.NotAfter) {
+		return cert, nil
+	}
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	serial, err := randomSerial()
+	if err != nil {
+		return nil, err
+	}
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: host},
+		NotBefore:    time.Now().Add(-1 * time.Hour),
+		NotAfter:     time.Now().Add(TunnelCertValidity),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	if ip := net.ParseIP(host); ip != nil {
+		template.IPAddresses = []net.IP{ip}
+	} else {
+		template.DNSNames = []string{host}
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, key.Public(), ca.key)
+	if err != nil {
+		return nil, err
+	}
+	leaf, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, err
+	}
+	cert := &tls.Certificate{Certificate: [][]byte{der, ca.cert.Raw}, PrivateKey: key, Leaf: leaf}
+	ca.hosts[host] = cert
+	return cert, nil
+}
+
+func randomSerial() (*big.Int, error) {
+	return rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+}
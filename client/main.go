@@ -0,0 +1,100 @@
+// kedge_local is a forward proxy running on the machines of users, sending requests for remote clusters to their
+// kedges using the user's TLS client certificate.
+//
+// Run as `kedge_local [flags]` it is a daemon, while `kedge_local [flags] <cmd> [args]` runs a single command with an
+// ephemeral proxy set in its environment.
+package main
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/mwitkow/go-conntrack"
+	"github.com/mwitkow/kedge/client/localproxy"
+	"github.com/spf13/pflag"
+	"golang.org/x/net/http2"
+)
+
+var (
+	flagSet = pflag.NewFlagSet("kedge_local", pflag.ExitOnError)
+
+	flagBindAddr  = flagSet.String("client_bind_address", "127.0.0.1", "address to bind the forward proxy to")
+	flagProxyPort = flagSet.Int("client_proxy_port", 8070, "TCP port to listen on for HTTP forward proxy requests.")
+
+	flagKedgeDialTimeout = flagSet.Duration("client_kedge_dial_timeout", 10*time.Second, "Timeout of dialing (including the TLS handshake) of kedges.")
+)
+
+func main() {
+	// Flags after the command belong to it.
+	flagSet.SetInterspersed(false)
+	if err := flagSet.Parse(os.Args[1:]); err != nil {
+		log.Fatalf("failed parsing flags: %v", err)
+	}
+	log.SetOutput(os.Stdout)
+
+	cnf := readConfigOrFail()
+	m := buildMapperOrFail(cnf)
+	tunnelCA := buildTunnelCAOrFail()
+	proxy := localproxy.New(m, buildKedgeTransportOrFail(), http.DefaultTransport, cnf.Unmatched, tunnelCA)
+
+	if flagSet.NArg() > 0 {
+		// The output belongs to the command.
+		log.SetOutput(os.Stderr)
+		log.SetLevel(log.WarnLevel)
+		os.Exit(runCommand(proxy, tunnelCA, flagSet.Args()))
+	}
+
+	listener := buildListenerOrFail("proxy", *flagProxyPort)
+	log.Infof("listening for forward proxy requests on: %v", listener.Addr().String())
+	pacFile := buildPacFile(cnf, listener.Addr().String())
+	log.Infof("serving PAC file on: http://%v/proxy.pac", listener.Addr().String())
+	log.Infof("serving tunnel CA certificate on: http://%v/tunnel_ca.crt", listener.Addr().String())
+	server := &http.Server{
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			if req.URL.Path == "/proxy.pac" && req.URL.Host == "" {
+				pacFile.ServeHTTP(w, req)
+				return
+			}
+			if req.URL.Path == "/tunnel_ca.crt" && req.URL.Host == "" {
+				w.Header().Set("Content-Type", "application/x-pem-file")
+				w.Write(tunnelCA.CertPEM())
+				return
+			}
+			proxy.ServeHTTP(w, req)
+		}),
+	}
+	if err := server.Serve(listener); err != nil {
+		log.Fatalf("proxy server error: %v", err)
+	}
+}
+
+func buildKedgeTransportOrFail() *http.Transport {
+	transport := &http.Transport{
+		DialContext: conntrack.NewDialContextFunc(
+			conntrack.DialWithName("kedge"),
+			conntrack.DialWithTracing(),
+			conntrack.DialWithDialer(&net.Dialer{Timeout: *flagKedgeDialTimeout, KeepAlive: 30 * time.Second}),
+		),
+		TLSClientConfig:     buildClientTlsOrFail(),
+		TLSHandshakeTimeout: *flagKedgeDialTimeout,
+		MaxIdleConnsPerHost: 4,
+		IdleConnTimeout:     90 * time.Second,
+	}
+	if err := http2.ConfigureTransport(transport); err != nil {
+		log.Fatalf("failed setting up HTTP2 for kedges: %v", err)
+	}
+	return transport
+}
+
+func buildListenerOrFail(name string, port int) net.Listener {
+	addr := fmt.Sprintf("%s:%d", *flagBindAddr, port)
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		log.Fatalf("failed listening for '%v' on %v: %v", name, port, err)
+	}
+	return conntrack.NewListener(listener, conntrack.TrackWithName(name))
+}
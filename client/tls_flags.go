@@ -0,0 +1,55 @@
+package main
+
+import (
+	"crypto/tls"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/mwitkow/kedge/client/localproxy"
+	"github.com/mwitkow/kedge/lib/tlsconfig"
+)
+
+var (
+	flagTlsClientCert = flagSet.String(
+		"client_tls_cert_file",
+		tlsconfig.DefaultCertFile,
+		"Path to the PEM client certificate presented to kedges.")
+	flagTlsClientKey = flagSet.String(
+		"client_tls_key_file",
+		tlsconfig.DefaultKeyFile,
+		"Path to the PEM key of the client certificate presented to kedges.")
+	flagTlsRootCAFiles = flagSet.StringSlice(
+		"client_tls_root_ca_files",
+		[]string{},
+		"Paths (comma separated) to PEM certificate chains used for verifying kedges. If empty, the system roots are used.",
+	)
+	flagTunnelCACert = flagSet.String(
+		"client_tunnel_ca_cert_file",
+		"",
+		"Path to the PEM CA certificate signing the certificates of CONNECT tunnels carrying TLS (e.g. of https:// URLs) to mapped hosts. If empty, a CA is generated on every start.")
+	flagTunnelCAKey = flagSet.String(
+		"client_tunnel_ca_key_file",
+		"",
+		"Path to the PEM key of the tunnel CA certificate.")
+)
+
+func buildClientTlsOrFail() *tls.Config {
+	tlsConfig, err := tlsconfig.ForClient(*flagTlsClientCert, *flagTlsClientKey, *flagTlsRootCAFiles)
+	if err != nil {
+		log.Fatalf("failed building client TLS: %v", err)
+	}
+	return tlsConfig
+}
+
+func buildTunnelCAOrFail() *localproxy.TunnelCA {
+	var ca *localproxy.TunnelCA
+	var err error
+	if *flagTunnelCACert != "" || *flagTunnelCAKey != "" {
+		ca, err = localproxy.LoadTunnelCA(tlsconfig.ExpandHome(*flagTunnelCACert), tlsconfig.ExpandHome(*flagTunnelCAKey))
+	} else {
+		ca, err = localproxy.NewTunnelCA()
+	}
+	if err != nil {
+		log.Fatalf("failed building tunnel CA: %v", err)
+	}
+	return ca
+}
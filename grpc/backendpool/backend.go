@@ -14,6 +14,7 @@ import (
 	"github.com/mwitkow/go-grpc-middleware"
 	"github.com/mwitkow/grpc-proxy/proxy"
 	pb "github.com/mwitkow/kedge/_protogen/kedge/config/grpc/backends"
+	http_backendpool "github.com/mwitkow/kedge/http/backendpool"
 	"github.com/mwitkow/kedge/lib/resolvers"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials"
@@ -25,12 +26,26 @@ var (
 		Timeout:   1 * time.Second,
 		KeepAlive: 30 * time.Second,
 	}).DialContext
+
+	// InitialResolveTimeout limits how long creating a backend waits for its targets to be resolved. Backends that
+	// have none by then are connected lazily.
+	InitialResolveTimeout = 1 * time.Second
+
+	// LocalZone is the availability zone kedge runs in, which backends with `locality` prefer targets in.
+	LocalZone = ""
+
+	defaultMinHealthyShare = 0.5
 )
 
 type backend struct {
 	mu     sync.RWMutex
 	conn   *grpc.ClientConn
 	config *pb.Backend
+	health *targetHealth
+
+	// connErr is the error of the last lazy connect, at connFailed, which isn't retried for InitialResolveTimeout.
+	connErr    error
+	connFailed time.Time
 }
 
 func (b *backend) Conn() (*grpc.ClientConn, error) {
@@ -47,45 +62,80 @@ func (b *backend) Conn() (*grpc.ClientConn, error) {
 	if b.conn != nil {
 		return b.conn, nil
 	}
-	cc, err := buildClientConn(b.config)
+	if b.connErr != nil && time.Now().Sub(b.connFailed) < InitialResolveTimeout {
+		return nil, b.connErr
+	}
+	cc, err := buildClientConn(b.config, b.health)
 	if err != nil {
+		b.connErr, b.connFailed = err, time.Now()
 		return nil, err
 	}
 	b.conn = cc
 	return cc, nil
 }
 
+// Healthy returns true if the backend has resolved targets, and it isn't failing to connect to all of them. Backends
+// that aren't connected yet are connected first, waiting up to InitialResolveTimeout for their targets.
+func (b *backend) Healthy() bool {
+	if _, err := b.Conn(); err != nil {
+		return false
+	}
+	return b.health.healthy()
+}
+
 func (b *backend) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.conn == nil {
+		return nil
+	}
 	return b.conn.Close()
 }
 
 func newBackend(cnf *pb.Backend) (*backend, error) {
-
-	cc, err := buildClientConn(cnf)
-	if err != nil && err.Error() == "grpc: there is no address available to dial" {
-		return &backend{conn: nil, config: cnf}, nil // make this lazy
+	health := newTargetHealth()
+	cc, err := buildClientConn(cnf, health)
+	if err != nil && (err.Error() == "grpc: there is no address available to dial" || err == context.DeadlineExceeded) {
+		return &backend{conn: nil, config: cnf, health: health}, nil // make this lazy
 	} else if err != nil {
 		return nil, fmt.Errorf("backend '%v' dial error: %v", cnf.Name, err)
 	}
-	return &backend{conn: cc, config: cnf}, nil
+	return &backend{conn: cc, config: cnf, health: health}, nil
 }
 
-func buildClientConn(cnf *pb.Backend) (*grpc.ClientConn, error) {
+func buildClientConn(cnf *pb.Backend, health *targetHealth) (*grpc.ClientConn, error) {
 	opts := []grpc.DialOption{}
 	target, resolver, err := chooseNamingResolver(cnf)
 	if err != nil {
 		return nil, err
 	}
-	opts = append(opts, chooseDialFuncOpt(cnf))
+	gracePeriod, err := http_backendpool.DurationOrDefault(cnf.ResolverGracePeriod, resolvers.DefaultGracePeriod)
+	if err != nil {
+		return nil, fmt.Errorf("bad resolver grace period: %v", err)
+	} else if gracePeriod < 0 {
+		return nil, fmt.Errorf("negative resolver grace period")
+	}
+	resolver = resolvers.Resilient("grpc", cnf.Name, resolver, gracePeriod)
+	resolver = &healthResolver{Resolver: resolver, health: health}
+	opts = append(opts, chooseDialFuncOpt(cnf, health))
 	opts = append(opts, chooseSecurityOpt(cnf))
 	opts = append(opts, grpc.WithCodec(proxy.Codec())) // needed for the director to function at all.
-	opts = append(opts, chooseInterceptors(cnf)...)
-	opts = append(opts, grpc.WithBalancer(chooseBalancerPolicy(cnf, resolver)))
+	interceptorOpts, err := chooseInterceptors(cnf)
+	if err != nil {
+		return nil, err
+	}
+	opts = append(opts, interceptorOpts...)
+	balancer, err := chooseBalancerPolicy(cnf, resolver, health)
+	if err != nil {
+		return nil, err
+	}
+	opts = append(opts, grpc.WithBalancer(balancer))
+	opts = append(opts, grpc.WithTimeout(InitialResolveTimeout))
 	return grpc.Dial(target, opts...)
 
 }
 
-func chooseDialFuncOpt(cnf *pb.Backend) grpc.DialOption {
+func chooseDialFuncOpt(cnf *pb.Backend, health *targetHealth) grpc.DialOption {
 	dialFunc := ParentDialFunc
 	if !cnf.DisableConntracking {
 		dialFunc = conntrack.NewDialContextFunc(
@@ -96,7 +146,7 @@ func chooseDialFuncOpt(cnf *pb.Backend) grpc.DialOption {
 	}
 	return grpc.WithDialer(func(addr string, t time.Duration) (net.Conn, error) {
 		ctx, _ := context.WithTimeout(context.Background(), t)
-		return dialFunc(ctx, "tcp", addr)
+		return health.dialed(dialFunc(ctx, "tcp", addr))
 	})
 }
 
@@ -112,9 +162,17 @@ func chooseSecurityOpt(cnf *pb.Backend) grpc.DialOption {
 	}
 }
 
-func chooseInterceptors(cnf *pb.Backend) []grpc.DialOption {
+func chooseInterceptors(cnf *pb.Backend) ([]grpc.DialOption, error) {
 	unary := []grpc.UnaryClientInterceptor{}
 	stream := []grpc.StreamClientInterceptor{}
+	if d := cnf.GetDeadlines(); d != nil {
+		dl, err := newDeadlines(cnf.Name, d)
+		if err != nil {
+			return nil, err
+		}
+		unary = append(unary, dl.unaryInterceptor)
+		stream = append(stream, dl.streamInterceptor)
+	}
 	for _, i := range cnf.GetInterceptors() {
 		if prom := i.GetPrometheus(); prom {
 			unary = append(unary, grpc_prometheus.UnaryClientInterceptor)
@@ -125,7 +183,7 @@ func chooseInterceptors(cnf *pb.Backend) []grpc.DialOption {
 	return []grpc.DialOption{
 		grpc.WithUnaryInterceptor(grpc_middleware.ChainUnaryClient(unary...)),
 		grpc.WithStreamInterceptor(grpc_middleware.ChainStreamClient(stream...)),
-	}
+	}, nil
 }
 
 func chooseNamingResolver(cnf *pb.Backend) (string, naming.Resolver, error) {
@@ -133,15 +191,37 @@ func chooseNamingResolver(cnf *pb.Backend) (string, naming.Resolver, error) {
 		return resolvers.NewSrvFromConfig(s)
 	} else if k := cnf.GetK8S(); k != nil {
 		return resolvers.NewK8sFromConfig(k)
+	} else if st := cnf.GetStatic(); st != nil {
+		return resolvers.NewStaticFromConfig(st)
+	} else if d := cnf.GetDns(); d != nil {
+		return resolvers.NewDnsFromConfig(d)
+	} else if f := cnf.GetFile(); f != nil {
+		return resolvers.NewFileFromConfig(f)
+	} else if c := cnf.GetConsul(); c != nil {
+		return resolvers.NewConsulFromConfig(c)
 	}
 	return "", nil, fmt.Errorf("unspecified naming resolver for %v", cnf.Name)
 }
 
-func chooseBalancerPolicy(cnf *pb.Backend, resolver naming.Resolver) grpc.Balancer {
+func chooseBalancerPolicy(cnf *pb.Backend, resolver naming.Resolver, health *targetHealth) (grpc.Balancer, error) {
+	var balancer grpc.Balancer
 	switch cnf.GetBalancer() {
 	case pb.Balancer_ROUND_ROBIN:
-		return grpc.RoundRobin(resolver)
+		balancer = grpc.RoundRobin(resolver)
 	default:
-		return grpc.RoundRobin(resolver)
+		balancer = grpc.RoundRobin(resolver)
+	}
+	zone, share := "", 0.0
+	if loc := cnf.GetLocality(); loc != nil {
+		if LocalZone == "" {
+			return nil, fmt.Errorf("locality needs the zone of kedge to be set")
+		}
+		zone, share = LocalZone, float64(loc.MinHealthyShare)
+		if share < 0 || share > 1 {
+			return nil, fmt.Errorf("locality min_healthy_share must be between 0 and 1")
+		} else if share == 0 {
+			share = defaultMinHealthyShare
+		}
 	}
+	return newAttributesBalancer(balancer, zone, share, health), nil
 }
@@ -0,0 +1,72 @@
+package backendpool
+
+import (
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/ptypes/duration"
+	"github.com/mwitkow/go-srvlb/srv"
+	pb_res "github.com/mwitkow/kedge/_protogen/kedge/config/common/resolvers"
+	pb "github.com/mwitkow/kedge/_protogen/kedge/config/grpc/backends"
+	"github.com/mwitkow/kedge/lib/resolvers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// changingSrvResolver resolves all names to its targets, which tests change.
+type changingSrvResolver struct {
+	mu      sync.Mutex
+	targets []*srv.Target
+}
+
+func (r *changingSrvResolver) Lookup(domainName string) ([]*srv.Target, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.targets, nil
+}
+
+func (r *changingSrvResolver) set(targets ...*srv.Target) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.targets = targets
+}
+
+func TestBackendResolvedAfterCreationIsHealthyOnFirstCheck(t *testing.T) {
+	resolver := &changingSrvResolver{}
+	defer func(parent srv.Resolver, timeout time.Duration) {
+		resolvers.ParentSrvResolver = parent
+		InitialResolveTimeout = timeout
+	}(resolvers.ParentSrvResolver, InitialResolveTimeout)
+	resolvers.ParentSrvResolver = resolver
+	InitialResolveTimeout = 200 * time.Millisecond
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+
+	be, err := newBackend(&pb.Backend{
+		Name:     "lazy",
+		Resolver: &pb.Backend_Srv{Srv: &pb_res.SrvResolver{DnsName: "_grpc._tcp.lazy.test.local"}},
+	})
+	require.NoError(t, err, "backends without targets must be created")
+	defer be.Close()
+
+	start := time.Now()
+	assert.False(t, be.Healthy(), "backends without targets must be unhealthy")
+	assert.False(t, be.Healthy(), "backends without targets must be unhealthy")
+	assert.True(t, time.Now().Sub(start) < 2*InitialResolveTimeout, "failed connects must not be retried right away")
+
+	time.Sleep(InitialResolveTimeout * 3 / 2)
+	resolver.set(&srv.Target{DialAddr: listener.Addr().String(), Ttl: time.Second})
+	assert.True(t, be.Healthy(), "backends must be connected by the health check once their targets are resolved")
+}
+
+func TestBackendRejectsNegativeResolverGracePeriod(t *testing.T) {
+	_, err := newBackend(&pb.Backend{
+		Name:                "negative",
+		Resolver:            &pb.Backend_Srv{Srv: &pb_res.SrvResolver{DnsName: "_grpc._tcp.negative.test.local"}},
+		ResolverGracePeriod: &duration.Duration{Seconds: -1},
+	})
+	assert.Error(t, err, "negative grace periods must be rejected")
+}
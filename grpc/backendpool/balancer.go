@@ -0,0 +1,89 @@
+package backendpool
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/mwitkow/kedge/lib/resolvers"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+)
+
+// attributesBalancer sends calls to the connected addresses of the lowest priority, weighted by the priorities and
+// weights their resolver set as resolvers.Attributes. Calls wait in the parent balancer while no address is connected.
+//
+// If zone is set, calls go to the connected addresses in the zone, as long as at least minHealthyShare of the
+// addresses in the zone are connected. Otherwise, calls spill over to connected addresses in all zones.
+type attributesBalancer struct {
+	grpc.Balancer
+	zone            string
+	minHealthyShare float64
+	health          *targetHealth
+
+	mu        sync.Mutex
+	connected map[string]grpc.Address
+	next      uint64
+}
+
+func newAttributesBalancer(parent grpc.Balancer, zone string, minHealthyShare float64, health *targetHealth) *attributesBalancer {
+	return &attributesBalancer{
+		Balancer:        parent,
+		zone:            zone,
+		minHealthyShare: minHealthyShare,
+		health:          health,
+		connected:       make(map[string]grpc.Address),
+	}
+}
+
+func (b *attributesBalancer) Up(addr grpc.Address) func(error) {
+	down := b.Balancer.Up(addr)
+	b.mu.Lock()
+	b.connected[addr.Addr] = addr
+	b.mu.Unlock()
+	return func(err error) {
+		b.mu.Lock()
+		delete(b.connected, addr.Addr)
+		b.mu.Unlock()
+		if down != nil {
+			down(err)
+		}
+	}
+}
+
+func (b *attributesBalancer) Get(ctx context.Context, opts grpc.BalancerGetOptions) (grpc.Address, func(), error) {
+	if addr, ok := b.pick(); ok {
+		return addr, nil, nil
+	}
+	return b.Balancer.Get(ctx, opts)
+}
+
+// pick chooses the next connected address, preferring the local zone if it is healthy.
+func (b *attributesBalancer) pick() (grpc.Address, bool) {
+	var local map[string]bool
+	if b.zone != "" {
+		local = b.health.addrsInZone(b.zone)
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	var connected, connectedLocal []string
+	for addr := range b.connected {
+		connected = append(connected, addr)
+		if local[addr] {
+			connectedLocal = append(connectedLocal, addr)
+		}
+	}
+	candidates := connected
+	if len(connectedLocal) > 0 && float64(len(connectedLocal)) >= b.minHealthyShare*float64(len(local)) {
+		candidates = connectedLocal
+	}
+	if len(candidates) == 0 {
+		return grpc.Address{}, false
+	}
+	sort.Strings(candidates)
+	attrs := make([]resolvers.Attributes, len(candidates))
+	for i, addr := range candidates {
+		attrs[i] = resolvers.AttributesOf(b.connected[addr].Metadata)
+	}
+	b.next++
+	return b.connected[candidates[resolvers.PickByPriority(b.next, attrs)]], true
+}
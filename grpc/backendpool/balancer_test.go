@@ -0,0 +1,81 @@
+package backendpool
+
+import (
+	"testing"
+
+	"github.com/mwitkow/kedge/lib/resolvers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/naming"
+)
+
+// parentBalancer always returns the same address, standing in for the round robin balancer waiting for connections.
+type parentBalancer struct {
+	grpc.Balancer
+}
+
+func (parentBalancer) Up(addr grpc.Address) func(error) {
+	return func(error) {}
+}
+
+func (parentBalancer) Get(ctx context.Context, opts grpc.BalancerGetOptions) (grpc.Address, func(), error) {
+	return grpc.Address{Addr: "parent:80"}, nil, nil
+}
+
+func gotAddrs(t *testing.T, b grpc.Balancer) map[string]bool {
+	got := map[string]bool{}
+	for i := 0; i < 10; i++ {
+		addr, _, err := b.Get(context.Background(), grpc.BalancerGetOptions{})
+		require.NoError(t, err, "getting an address must not fail")
+		got[addr.Addr] = true
+	}
+	return got
+}
+
+func TestAttributesBalancerPrefersConnectedLocalAddresses(t *testing.T) {
+	health := newTargetHealth()
+	health.update([]*naming.Update{
+		{Op: naming.Add, Addr: "a1:80", Metadata: resolvers.Attributes{Zone: "zone-a"}},
+		{Op: naming.Add, Addr: "a2:80", Metadata: resolvers.Attributes{Zone: "zone-a"}},
+		{Op: naming.Add, Addr: "b1:80", Metadata: resolvers.Attributes{Zone: "zone-b"}},
+	})
+	b := newAttributesBalancer(parentBalancer{}, "zone-a", 0.5, health)
+	strict := newAttributesBalancer(parentBalancer{}, "zone-a", 1, health)
+	assert.Equal(t, map[string]bool{"parent:80": true}, gotAddrs(t, b), "calls must spill over without connected local addresses")
+
+	b.Up(grpc.Address{Addr: "b1:80"})
+	downA1 := b.Up(grpc.Address{Addr: "a1:80"})
+	strict.Up(grpc.Address{Addr: "b1:80"})
+	strict.Up(grpc.Address{Addr: "a1:80"})
+	assert.Equal(t, map[string]bool{"a1:80": true}, gotAddrs(t, b), "connected local addresses must be preferred")
+	assert.Equal(t, map[string]bool{"a1:80": true, "b1:80": true}, gotAddrs(t, strict), "calls must spill over to all connected addresses below the healthy share")
+
+	b.Up(grpc.Address{Addr: "a2:80"})
+	assert.Equal(t, map[string]bool{"a1:80": true, "a2:80": true}, gotAddrs(t, b), "connected local addresses must be balanced")
+
+	downA1(nil)
+	assert.Equal(t, map[string]bool{"a2:80": true}, gotAddrs(t, b), "disconnected addresses must not be used")
+}
+
+func TestAttributesBalancerPicksLowestPriorityByWeight(t *testing.T) {
+	b := newAttributesBalancer(parentBalancer{}, "", 0, nil)
+	b.Up(grpc.Address{Addr: "heavy:80", Metadata: resolvers.Attributes{Priority: 1, Weight: 3}})
+	b.Up(grpc.Address{Addr: "light:80", Metadata: resolvers.Attributes{Priority: 1, Weight: 1}})
+	downBackup := b.Up(grpc.Address{Addr: "backup:80", Metadata: resolvers.Attributes{Priority: 2, Weight: 1}})
+	downPrimary := b.Up(grpc.Address{Addr: "primary:80", Metadata: resolvers.Attributes{Priority: 0, Weight: 1}})
+	assert.Equal(t, map[string]bool{"primary:80": true}, gotAddrs(t, b), "connected addresses of the lowest priority must be used")
+
+	downPrimary(nil)
+	counts := map[string]int{}
+	for i := 0; i < 8; i++ {
+		addr, _, err := b.Get(context.Background(), grpc.BalancerGetOptions{})
+		require.NoError(t, err, "getting an address must not fail")
+		counts[addr.Addr]++
+	}
+	assert.Equal(t, map[string]int{"heavy:80": 6, "light:80": 2}, counts, "addresses must be used in proportion to their weights")
+
+	downBackup(nil)
+	assert.NotContains(t, gotAddrs(t, b), "backup:80", "disconnected backups must not be used")
+}
@@ -0,0 +1,108 @@
+package backendpool
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/golang/protobuf/ptypes"
+	pb "github.com/mwitkow/kedge/_protogen/kedge/config/grpc/backends"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+)
+
+// deadlines applies the default and maximum deadlines of a backend to calls made to it.
+type deadlines struct {
+	backendName    string
+	defaultTimeout time.Duration
+	maxTimeout     time.Duration
+}
+
+func newDeadlines(backendName string, cnf *pb.Deadlines) (*deadlines, error) {
+	d := &deadlines{backendName: backendName}
+	var err error
+	if cnf.DefaultTimeout != nil {
+		if d.defaultTimeout, err = ptypes.Duration(cnf.DefaultTimeout); err != nil {
+			return nil, fmt.Errorf("bad default timeout: %v", err)
+		} else if d.defaultTimeout < 0 {
+			return nil, fmt.Errorf("negative default timeout")
+		}
+	}
+	if cnf.MaxTimeout != nil {
+		if d.maxTimeout, err = ptypes.Duration(cnf.MaxTimeout); err != nil {
+			return nil, fmt.Errorf("bad max timeout: %v", err)
+		} else if d.maxTimeout < 0 {
+			return nil, fmt.Errorf("negative max timeout")
+		}
+	}
+	return d, nil
+}
+
+// apply returns a context with the deadline of the call, and the timeout that kedge set (or 0 if it didn't).
+func (d *deadlines) apply(ctx context.Context) (context.Context, context.CancelFunc, time.Duration) {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		timeout := d.defaultTimeout
+		if d.maxTimeout > 0 && (timeout == 0 || timeout > d.maxTimeout) {
+			timeout = d.maxTimeout
+		}
+		if timeout > 0 {
+			ctx, cancel := context.WithTimeout(ctx, timeout)
+			return ctx, cancel, timeout
+		}
+	} else if d.maxTimeout > 0 && deadline.Sub(time.Now()) > d.maxTimeout {
+		ctx, cancel := context.WithTimeout(ctx, d.maxTimeout)
+		return ctx, cancel, d.maxTimeout
+	}
+	return ctx, func() {}, 0
+}
+
+// wrapErr makes deadlines set by kedge clear to the client.
+func (d *deadlines) wrapErr(err error, timeout time.Duration) error {
+	if err != nil && timeout > 0 && grpc.Code(err) == codes.DeadlineExceeded {
+		return grpc.Errorf(codes.DeadlineExceeded, "kedge: call to backend '%v' exceeded its deadline of %v", d.backendName, timeout)
+	}
+	return err
+}
+
+func (d *deadlines) unaryInterceptor(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	ctx, cancel, timeout := d.apply(ctx)
+	defer cancel()
+	return d.wrapErr(invoker(ctx, method, req, reply, cc, opts...), timeout)
+}
+
+func (d *deadlines) streamInterceptor(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	ctx, cancel, timeout := d.apply(ctx)
+	stream, err := streamer(ctx, desc, cc, method, opts...)
+	if err != nil {
+		cancel()
+		return nil, d.wrapErr(err, timeout)
+	}
+	return &deadlineStream{ClientStream: stream, deadlines: d, cancel: cancel, timeout: timeout}, nil
+}
+
+// deadlineStream releases the deadline of the call once it finishes.
+type deadlineStream struct {
+	grpc.ClientStream
+	deadlines *deadlines
+	cancel    context.CancelFunc
+	timeout   time.Duration
+}
+
+func (s *deadlineStream) RecvMsg(m interface{}) error {
+	err := s.ClientStream.RecvMsg(m)
+	if err != nil {
+		s.cancel()
+	}
+	return s.deadlines.wrapErr(err, s.timeout)
+}
+
+func (s *deadlineStream) SendMsg(m interface{}) error {
+	return s.deadlines.wrapErr(s.ClientStream.SendMsg(m), s.timeout)
+}
+
+func (s *deadlineStream) Header() (metadata.MD, error) {
+	md, err := s.ClientStream.Header()
+	return md, s.deadlines.wrapErr(err, s.timeout)
+}
@@ -0,0 +1,18 @@
+package backendpool
+
+import (
+	"testing"
+
+	"github.com/golang/protobuf/ptypes/duration"
+	pb "github.com/mwitkow/kedge/_protogen/kedge/config/grpc/backends"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewDeadlinesRejectsNegativeTimeouts(t *testing.T) {
+	_, err := newDeadlines("backend", &pb.Deadlines{DefaultTimeout: &duration.Duration{Seconds: -1}})
+	assert.Error(t, err, "negative default timeouts must be rejected")
+	_, err = newDeadlines("backend", &pb.Deadlines{MaxTimeout: &duration.Duration{Seconds: -1}})
+	assert.Error(t, err, "negative max timeouts must be rejected")
+	_, err = newDeadlines("backend", &pb.Deadlines{DefaultTimeout: &duration.Duration{Seconds: 1}, MaxTimeout: &duration.Duration{Seconds: 2}})
+	assert.NoError(t, err, "positive timeouts must be accepted")
+}
@@ -0,0 +1,162 @@
+package backendpool
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"sync"
+
+	pb "github.com/mwitkow/kedge/_protogen/kedge/config/grpc/backends"
+	"github.com/mwitkow/kedge/lib/resolvers"
+	"github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/naming"
+)
+
+var (
+	failoverCallsCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "kedge",
+			Subsystem: "grpc",
+			Name:      "failover_calls_total",
+			Help:      "Count of calls to failover backends, by the backend that served them and its tier (0 is the first).",
+		}, []string{"backend_name", "tier_backend_name", "tier"})
+)
+
+func init() {
+	prometheus.MustRegister(failoverCallsCounter)
+}
+
+// failover sends calls to the first of its tiers that has healthy targets.
+type failover struct {
+	name  string
+	tiers []*backend
+}
+
+func newFailover(cnf *pb.Backend, backends map[string]*backend) (*failover, error) {
+	f := &failover{name: cnf.Name}
+	for _, name := range cnf.GetFailover().Backends {
+		be, ok := backends[name]
+		if !ok {
+			return nil, fmt.Errorf("failover backend '%v' is not a backend with a resolver", name)
+		}
+		f.tiers = append(f.tiers, be)
+	}
+	if len(f.tiers) == 0 {
+		return nil, fmt.Errorf("failover needs at least one backend")
+	}
+	return f, nil
+}
+
+// pick returns the first healthy tier, counting the call towards it.
+func (f *failover) pick() (*backend, error) {
+	for i, be := range f.tiers {
+		if be.Healthy() {
+			failoverCallsCounter.WithLabelValues(f.name, be.config.Name, strconv.Itoa(i)).Inc()
+			return be, nil
+		}
+	}
+	return nil, grpc.Errorf(codes.Unavailable, "failover backend '%v' has no healthy backends", f.name)
+}
+
+// targetHealth tracks the resolved addresses of a backend, and whether they can be connected to.
+type targetHealth struct {
+	mu sync.Mutex
+	// addrs maps the resolved addresses to their zones.
+	addrs          map[string]string
+	openConns      int
+	lastDialFailed bool
+}
+
+func newTargetHealth() *targetHealth {
+	return &targetHealth{addrs: make(map[string]string)}
+}
+
+// healthy is true if there are resolved addresses, unless none of them could be connected to.
+func (h *targetHealth) healthy() bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.addrs) > 0 && (h.openConns > 0 || !h.lastDialFailed)
+}
+
+func (h *targetHealth) update(updates []*naming.Update) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, u := range updates {
+		if u.Op == naming.Add {
+			h.addrs[u.Addr] = resolvers.AttributesOf(u.Metadata).Zone
+		} else if u.Op == naming.Delete {
+			delete(h.addrs, u.Addr)
+		}
+	}
+}
+
+// addrsInZone returns the resolved addresses in the zone.
+func (h *targetHealth) addrsInZone(zone string) map[string]bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	addrs := make(map[string]bool)
+	for addr, addrZone := range h.addrs {
+		if addrZone == zone {
+			addrs[addr] = true
+		}
+	}
+	return addrs
+}
+
+func (h *targetHealth) dialed(conn net.Conn, err error) (net.Conn, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if err != nil {
+		h.lastDialFailed = true
+		return nil, err
+	}
+	h.lastDialFailed = false
+	h.openConns++
+	return &healthConn{Conn: conn, health: h}, nil
+}
+
+func (h *targetHealth) closed() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.openConns--
+}
+
+type healthConn struct {
+	net.Conn
+	health *targetHealth
+	once   sync.Once
+}
+
+func (c *healthConn) Close() error {
+	c.once.Do(c.health.closed)
+	return c.Conn.Close()
+}
+
+// healthResolver records the addresses resolved for the balancer in the targetHealth.
+type healthResolver struct {
+	naming.Resolver
+	health *targetHealth
+}
+
+func (r *healthResolver) Resolve(target string) (naming.Watcher, error) {
+	w, err := r.Resolver.Resolve(target)
+	if err != nil {
+		return nil, err
+	}
+	return &healthWatcher{Watcher: w, health: r.health}, nil
+}
+
+type healthWatcher struct {
+	naming.Watcher
+	health *targetHealth
+}
+
+func (w *healthWatcher) Next() ([]*naming.Update, error) {
+	updates, err := w.Watcher.Next()
+	if err == nil {
+		w.health.update(updates)
+	}
+	return updates, err
+}
@@ -16,13 +16,18 @@ type Pool interface {
 	// Conn returns a dialled grpc.ClientConn for a given backend name.
 	Conn(backendName string) (*grpc.ClientConn, error)
 
+	// Select returns the name of the backend that serves calls to the given backend: the first healthy backend of a
+	// failover backend, or the backend itself.
+	Select(backendName string) (string, error)
+
 	// Close closes all the connections of the pool.
 	Close() error
 }
 
 // static is a Pool with a static configuration.
 type static struct {
-	backends map[string]*backend
+	backends  map[string]*backend
+	failovers map[string]*failover
 }
 
 func (s *static) Close() error {
@@ -34,21 +39,56 @@ func (s *static) Close() error {
 
 // NewStatic creates a backend pool that has static configuration.
 func NewStatic(backends []*pb.Backend) (Pool, error) {
-	s := &static{backends: make(map[string]*backend)}
+	s := &static{backends: make(map[string]*backend), failovers: make(map[string]*failover)}
 	for _, beCnf := range backends {
+		if beCnf.GetFailover() != nil {
+			continue
+		}
 		be, err := newBackend(beCnf)
 		if err != nil {
 			return nil, fmt.Errorf("failed creating backend '%v': %v", beCnf.Name, err)
 		}
 		s.backends[beCnf.Name] = be
 	}
+	// Failovers refer to the other backends, regardless of their order in the config.
+	for _, beCnf := range backends {
+		if beCnf.GetFailover() == nil {
+			continue
+		}
+		f, err := newFailover(beCnf, s.backends)
+		if err != nil {
+			return nil, fmt.Errorf("failed creating backend '%v': %v", beCnf.Name, err)
+		}
+		s.failovers[beCnf.Name] = f
+	}
 	return s, nil
 }
 
 func (s *static) Conn(backendName string) (*grpc.ClientConn, error) {
+	if f, ok := s.failovers[backendName]; ok {
+		be, err := f.pick()
+		if err != nil {
+			return nil, err
+		}
+		return be.Conn()
+	}
 	be, ok := s.backends[backendName]
 	if !ok {
 		return nil, ErrUnknownBackend
 	}
 	return be.Conn()
 }
+
+func (s *static) Select(backendName string) (string, error) {
+	if f, ok := s.failovers[backendName]; ok {
+		be, err := f.pick()
+		if err != nil {
+			return "", err
+		}
+		return be.config.Name, nil
+	}
+	if _, ok := s.backends[backendName]; !ok {
+		return "", ErrUnknownBackend
+	}
+	return backendName, nil
+}
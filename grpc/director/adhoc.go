@@ -0,0 +1,145 @@
+package director
+
+import (
+	"context"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/mwitkow/go-conntrack"
+	"github.com/mwitkow/grpc-proxy/proxy"
+	pb "github.com/mwitkow/kedge/_protogen/kedge/config/http/routes"
+	"github.com/mwitkow/kedge/grpc/backendpool"
+	"github.com/mwitkow/kedge/grpc/director/router"
+	"google.golang.org/grpc"
+)
+
+var (
+	// AdhocIdleTimeout is how long connections to adhoc destinations are kept without any calls on them.
+	AdhocIdleTimeout = 5 * time.Minute
+)
+
+// adhocConns caches connections to adhoc destinations, one per rule and host, and closes the ones that are idle.
+type adhocConns struct {
+	mu          sync.Mutex
+	conns       map[adhocKey]*adhocConn
+	idleTimeout time.Duration
+	dialFunc    func(ctx context.Context, network, addr string) (net.Conn, error)
+	stop        chan struct{}
+}
+
+type adhocKey struct {
+	rule *pb.Adhoc
+	host string
+}
+
+type adhocConn struct {
+	cc       *grpc.ClientConn
+	active   int
+	lastUsed time.Time
+}
+
+func newAdhocConns(idleTimeout time.Duration) *adhocConns {
+	c := &adhocConns{
+		conns:       make(map[adhocKey]*adhocConn),
+		idleTimeout: idleTimeout,
+		dialFunc: conntrack.NewDialContextFunc(
+			conntrack.DialWithName("adhoc"),
+			conntrack.DialWithDialContextFunc(backendpool.ParentDialFunc),
+			conntrack.DialWithTracing(),
+		),
+		stop: make(chan struct{}),
+	}
+	go c.expireIdle()
+	return c
+}
+
+// Conn returns the connection to the destination, dialing it if needed. The connection is in use until the context
+// of the call is done.
+//
+// Connections dial the addresses of the destination in order, resolving them again whenever they reconnect.
+func (c *adhocConns) Conn(ctx context.Context, dest *router.AdhocDestination) (*grpc.ClientConn, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	key := adhocKey{rule: dest.Rule, host: dest.Host}
+	conn, ok := c.conns[key]
+	if !ok {
+		cc, err := grpc.Dial(dest.Host,
+			grpc.WithInsecure(),
+			grpc.WithCodec(proxy.Codec()), // needed for the director to function at all.
+			grpc.WithDialer(c.dialerFor(dest)),
+		)
+		if err != nil {
+			return nil, err
+		}
+		conn = &adhocConn{cc: cc}
+		c.conns[key] = conn
+	}
+	conn.active++
+	go func() {
+		<-ctx.Done()
+		c.mu.Lock()
+		conn.active--
+		conn.lastUsed = time.Now()
+		c.mu.Unlock()
+	}()
+	return conn.cc, nil
+}
+
+func (c *adhocConns) dialerFor(dest *router.AdhocDestination) func(addr string, t time.Duration) (net.Conn, error) {
+	return func(_ string, t time.Duration) (net.Conn, error) {
+		addrs, err := dest.Addrs()
+		if err != nil {
+			return nil, err
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), t)
+		defer cancel()
+		var lastErr error
+		for _, a := range addrs {
+			conn, err := c.dialFunc(ctx, "tcp", a)
+			if err == nil {
+				return conn, nil
+			}
+			lastErr = err
+			if ctx.Err() != nil {
+				break
+			}
+		}
+		return nil, lastErr
+	}
+}
+
+func (c *adhocConns) expireIdle() {
+	ticker := time.NewTicker(c.idleTimeout / 2)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-c.stop:
+			return
+		case now := <-ticker.C:
+			c.closeIdle(now)
+		}
+	}
+}
+
+func (c *adhocConns) closeIdle(now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for key, conn := range c.conns {
+		if conn.active == 0 && now.Sub(conn.lastUsed) >= c.idleTimeout {
+			conn.cc.Close()
+			delete(c.conns, key)
+		}
+	}
+}
+
+// Close stops expiring idle connections, and closes all of them.
+func (c *adhocConns) Close() {
+	close(c.stop)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for key, conn := range c.conns {
+		conn.cc.Close()
+		delete(c.conns, key)
+	}
+}
@@ -0,0 +1,98 @@
+package director
+
+import (
+	"context"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	pb "github.com/mwitkow/kedge/_protogen/kedge/config/http/routes"
+	"github.com/mwitkow/kedge/grpc/director/router"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func staticDestination(rule *pb.Adhoc, host string, addrs ...string) *router.AdhocDestination {
+	return &router.AdhocDestination{Rule: rule, Host: host, Addrs: func() ([]string, error) { return addrs, nil }}
+}
+
+func TestAdhocConnsAreCachedPerRuleAndHostAndExpireWhenIdle(t *testing.T) {
+	c := newAdhocConns(time.Hour)
+	defer c.Close()
+	rule, otherRule := &pb.Adhoc{DnsNameMatcher: "*.pods.cluster.local"}, &pb.Adhoc{DnsNameMatcher: "*.cluster.local"}
+
+	ctx1, cancel1 := context.WithCancel(context.Background())
+	cc1, err := c.Conn(ctx1, staticDestination(rule, "a.pods.cluster.local:1", "127.0.0.1:1", "127.0.0.2:1"))
+	require.NoError(t, err)
+	ctx2, cancel2 := context.WithCancel(context.Background())
+	cc2, err := c.Conn(ctx2, staticDestination(rule, "a.pods.cluster.local:1", "127.0.0.2:1"))
+	require.NoError(t, err)
+	assert.True(t, cc1 == cc2, "calls to the same host with the same rule must share the connection")
+	ctx3, cancel3 := context.WithCancel(context.Background())
+	cc3, err := c.Conn(ctx3, staticDestination(rule, "b.pods.cluster.local:1", "127.0.0.1:1"))
+	require.NoError(t, err)
+	assert.True(t, cc1 != cc3, "calls to other hosts must use other connections, even with the same addresses")
+	ctx4, cancel4 := context.WithCancel(context.Background())
+	cc4, err := c.Conn(ctx4, staticDestination(otherRule, "a.pods.cluster.local:1", "127.0.0.1:1"))
+	require.NoError(t, err)
+	assert.True(t, cc1 != cc4, "calls allowed by other rules must use other connections")
+	cancel3()
+	cancel4()
+
+	cancel1()
+	time.Sleep(10 * time.Millisecond)
+	c.closeIdle(time.Now().Add(2 * time.Hour))
+	assert.Len(t, c.conns, 1, "idle connections must be closed")
+	assert.Contains(t, c.conns, adhocKey{rule: rule, host: "a.pods.cluster.local:1"}, "connections with calls in flight must be kept")
+
+	cancel2()
+	time.Sleep(10 * time.Millisecond)
+	c.closeIdle(time.Now().Add(30 * time.Minute))
+	assert.Len(t, c.conns, 1, "recently used connections must be kept")
+	c.closeIdle(time.Now().Add(2 * time.Hour))
+	assert.Empty(t, c.conns, "idle connections must be closed")
+}
+
+func TestAdhocConnsResolveAddressesOnEveryDial(t *testing.T) {
+	c := newAdhocConns(time.Hour)
+	defer c.Close()
+	var mu sync.Mutex
+	addrs := []string{"127.0.0.1:1"}
+	dest := &router.AdhocDestination{
+		Rule: &pb.Adhoc{DnsNameMatcher: "*.pods.cluster.local"},
+		Host: "a.pods.cluster.local:1",
+		Addrs: func() ([]string, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			return addrs, nil
+		},
+	}
+	dialed := make(chan string, 10)
+	c.dialFunc = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		select {
+		case dialed <- addr:
+		default:
+		}
+		return nil, &net.OpError{Op: "dial", Net: network, Err: assert.AnError}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	_, err := c.Conn(ctx, dest)
+	require.NoError(t, err)
+	assert.Equal(t, "127.0.0.1:1", <-dialed)
+	mu.Lock()
+	addrs = []string{"127.0.0.2:1"}
+	mu.Unlock()
+	for {
+		select {
+		case addr := <-dialed:
+			if addr == "127.0.0.2:1" {
+				return
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("reconnects must dial the addresses resolved again")
+		}
+	}
+}
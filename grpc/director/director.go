@@ -7,20 +7,43 @@ import (
 	"github.com/mwitkow/kedge/grpc/director/router"
 	"golang.org/x/net/context"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
 )
 
-// New builds a StreamDirector based off a backend pool and a router.
-func New(pool backendpool.Pool, router router.Router) proxy.StreamDirector {
+// adhocBackendLabel is logged as the backend of calls sent to adhoc destinations.
+const adhocBackendLabel = "_adhoc"
+
+// New builds a StreamDirector based off a backend pool, a router and adhoc rules.
+//
+// Calls that don't match any route are sent to the destination of their `:authority`, if the adhoc rules allow it.
+// Calls on routes with a `mirror` are only mirrored if the MirroringStreamServerInterceptor is installed.
+func New(pool backendpool.Pool, r router.Router, adhoc router.AdhocAddresser) proxy.StreamDirector {
+	adhocConns := newAdhocConns(AdhocIdleTimeout)
 	return func(ctx context.Context, fullMethodName string) (*grpc.ClientConn, error) {
-		beName, err := router.Route(ctx, fullMethodName)
+		beName, err := r.Route(ctx, fullMethodName)
+		if err == router.ErrRouteNotFound && adhoc != nil {
+			dest, err := adhoc.Address(ctx, fullMethodName)
+			if err != nil {
+				return nil, err
+			}
+			grpc_logging.ExtractMetadata(ctx).AddFieldsFromMiddleware([]string{"proxy_backend"}, []interface{}{adhocBackendLabel})
+			return adhocConns.Conn(ctx, dest)
+		} else if err != nil {
+			return nil, err
+		}
+		// Failover backends are served by one of their backends, which is the one reported.
+		beName, err = pool.Select(beName)
 		if err != nil {
 			return nil, err
 		}
 		grpc_logging.ExtractMetadata(ctx).AddFieldsFromMiddleware([]string{"proxy_backend"}, []interface{}{beName})
+		// The header is buffered, and sent together with the headers of the backend.
+		grpc.SetHeader(ctx, metadata.Pairs("x-kedge-backend-name", beName))
 		cc, err := pool.Conn(beName)
 		if err != nil {
 			return nil, err
 		}
+		maybeMirror(ctx, pool, r.Mirror(ctx, fullMethodName), beName)
 		return cc, nil
 	}
 }
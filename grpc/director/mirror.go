@@ -0,0 +1,193 @@
+package director
+
+import (
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/mwitkow/grpc-proxy/proxy"
+	pb "github.com/mwitkow/kedge/_protogen/kedge/config/grpc/routes"
+	"github.com/mwitkow/kedge/grpc/backendpool"
+	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+)
+
+var (
+	// MirrorTimeout is the maximum duration of a mirrored call.
+	MirrorTimeout = 30 * time.Second
+
+	defaultMirrorMaxMessageBytes = 64 * 1024
+
+	mirrorCallsCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "kedge",
+			Subsystem: "grpc",
+			Name:      "mirror_calls_total",
+			Help:      "Count of mirrored calls, by the codes returned by the backend and by the mirror.",
+		}, []string{"backend_name", "mirror_backend_name", "code", "mirror_code"})
+	mirrorSkippedCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "kedge",
+			Subsystem: "grpc",
+			Name:      "mirror_skipped_total",
+			Help:      "Count of calls sampled for mirroring that weren't mirrored, by reason.",
+		}, []string{"mirror_backend_name", "reason"})
+)
+
+func init() {
+	prometheus.MustRegister(mirrorCallsCounter)
+	prometheus.MustRegister(mirrorSkippedCounter)
+}
+
+type mirrorCtxKey struct{}
+
+// MirroringStreamServerInterceptor records the request message of proxied unary calls, so that calls on routes with a
+// `mirror` can be sent to the mirror backend once they finish. It must be installed on the proxying server for
+// mirroring to work.
+func MirroringStreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ms := &mirrorStream{ServerStream: stream, fullMethodName: info.FullMethod}
+		ms.ctx = context.WithValue(stream.Context(), mirrorCtxKey{}, ms)
+		err := handler(srv, ms)
+		ms.finish(err)
+		return err
+	}
+}
+
+// mirrorStream is a grpc.ServerStream that records the messages of a call once mirroring is enabled by the director.
+type mirrorStream struct {
+	grpc.ServerStream
+	ctx            context.Context
+	fullMethodName string
+
+	mu          sync.Mutex
+	enabled     bool
+	config      *pb.Mirror
+	backendName string
+	conn        *grpc.ClientConn
+	request     []byte
+	tooLarge    bool
+	received    int
+	sent        int
+}
+
+func (s *mirrorStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *mirrorStream) RecvMsg(m interface{}) error {
+	err := s.ServerStream.RecvMsg(m)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.enabled {
+		return nil
+	}
+	s.received++
+	if s.received > 1 || s.tooLarge {
+		s.request = nil
+		return nil
+	}
+	payload, mErr := proxy.Codec().Marshal(m)
+	if mErr != nil || len(payload) > s.maxMessageBytes() {
+		s.tooLarge = true
+		return nil
+	}
+	s.request = append([]byte(nil), payload...)
+	return nil
+}
+
+func (s *mirrorStream) SendMsg(m interface{}) error {
+	err := s.ServerStream.SendMsg(m)
+	s.mu.Lock()
+	s.sent++
+	s.mu.Unlock()
+	return err
+}
+
+func (s *mirrorStream) maxMessageBytes() int {
+	if s.config.MaxMessageBytes == 0 {
+		return defaultMirrorMaxMessageBytes
+	}
+	return int(s.config.MaxMessageBytes)
+}
+
+// enable starts recording the call for sending to the mirror.
+func (s *mirrorStream) enable(config *pb.Mirror, backendName string, conn *grpc.ClientConn) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.enabled = true
+	s.config = config
+	s.backendName = backendName
+	s.conn = conn
+}
+
+// finish sends the recorded call to the mirror in the background, if it was an unary call.
+func (s *mirrorStream) finish(callErr error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.enabled {
+		return
+	}
+	if s.tooLarge {
+		mirrorSkippedCounter.WithLabelValues(s.config.BackendName, "message_too_large").Inc()
+		return
+	}
+	if s.received != 1 || s.sent > 1 {
+		mirrorSkippedCounter.WithLabelValues(s.config.BackendName, "not_unary").Inc()
+		return
+	}
+	md, ok := metadata.FromContext(s.ServerStream.Context())
+	if !ok {
+		md = metadata.Pairs()
+	}
+	go sendMirror(s.conn, s.fullMethodName, md.Copy(), s.request, s.backendName, s.config.BackendName, grpc.Code(callErr))
+}
+
+func sendMirror(conn *grpc.ClientConn, fullMethodName string, md metadata.MD, request []byte, backendName string, mirrorName string, code codes.Code) {
+	ctx, cancel := context.WithTimeout(metadata.NewContext(context.Background(), md), MirrorTimeout)
+	defer cancel()
+	err := grpc.Invoke(ctx, fullMethodName, &rawMessage{payload: request}, &rawMessage{}, conn)
+	mirrorCallsCounter.WithLabelValues(backendName, mirrorName, code.String(), grpc.Code(err).String()).Inc()
+}
+
+// maybeMirror enables mirroring of the call if its route has a mirror and the call is sampled.
+func maybeMirror(ctx context.Context, pool backendpool.Pool, config *pb.Mirror, backendName string) {
+	ms, ok := ctx.Value(mirrorCtxKey{}).(*mirrorStream)
+	if !ok || config == nil || config.BackendName == "" {
+		return
+	}
+	if rand.Float32()*100 >= config.Percentage {
+		return
+	}
+	conn, err := pool.Conn(config.BackendName)
+	if err != nil {
+		mirrorSkippedCounter.WithLabelValues(config.BackendName, "backend_error").Inc()
+		return
+	}
+	ms.enable(config, backendName, conn)
+}
+
+// rawMessage passes already encoded messages through the proxy codec.
+type rawMessage struct {
+	payload []byte
+}
+
+func (m *rawMessage) Reset()         { m.payload = nil }
+func (m *rawMessage) String() string { return fmt.Sprintf("rawMessage(%d bytes)", len(m.payload)) }
+func (m *rawMessage) ProtoMessage()  {}
+
+func (m *rawMessage) Marshal() ([]byte, error) {
+	return m.payload, nil
+}
+
+func (m *rawMessage) Unmarshal(data []byte) error {
+	m.payload = data
+	return nil
+}
@@ -0,0 +1,137 @@
+package router
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	pb "github.com/mwitkow/kedge/_protogen/kedge/config/http/routes"
+	http_router "github.com/mwitkow/kedge/http/director/router"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+)
+
+// AdhocAddresser decides what "ad-hoc" ip:port to dial for calls that don't match any route, if any.
+//
+// The rules are the same as the HTTP adhoc rules, matched against the `:authority` of the call.
+type AdhocAddresser interface {
+	// Address decides the destination to send the call to, or returns an error.
+	Address(ctx context.Context, fullMethodName string) (*AdhocDestination, error)
+}
+
+// AdhocDestination is where an adhoc call is sent.
+type AdhocDestination struct {
+	// Rule is the adhoc rule that matched the call.
+	Rule *pb.Adhoc
+	// Host is the host:port of the destination, which calls with the same rule can share connections to.
+	Host string
+	// Addrs resolves the ip:ports of the destination, in the order they should be dialed. Connections call it on every
+	// dial, as they outlive the addresses of their destinations.
+	Addrs func() ([]string, error)
+}
+
+type addresser struct {
+	http http_router.AdhocAddresser
+}
+
+// NewAddresser creates an AdhocAddresser from adhoc rules, validating them.
+//
+// Calls are always sent over plain HTTP/2, on connections that live as long as they are used, so rules with
+// `security`, `timeouts` or `connection_pool` are rejected.
+func NewAddresser(rules []*pb.Adhoc) (AdhocAddresser, error) {
+	for _, r := range rules {
+		if r.Security != nil {
+			return nil, fmt.Errorf("adhoc rule '%v': security is not supported for gRPC", r.DnsNameMatcher)
+		}
+		if r.Timeouts != nil {
+			return nil, fmt.Errorf("adhoc rule '%v': timeouts are not supported for gRPC", r.DnsNameMatcher)
+		}
+		if r.ConnectionPool != nil {
+			return nil, fmt.Errorf("adhoc rule '%v': connection_pool is not supported for gRPC", r.DnsNameMatcher)
+		}
+	}
+	a, err := http_router.NewAddresser(rules, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &addresser{http: a}, nil
+}
+
+func (a *addresser) Address(ctx context.Context, fullMethodName string) (*AdhocDestination, error) {
+	md, ok := metadata.FromContext(ctx)
+	if !ok {
+		md = emptyMd
+	}
+	auth, ok := md[":authority"]
+	if !ok || len(auth) == 0 {
+		return nil, ErrRouteNotFound
+	}
+	req := requestForCall(ctx, auth[0], md)
+	dest, err := a.address(req)
+	if err != nil {
+		return nil, err
+	}
+	return &AdhocDestination{
+		Rule: dest.Rule,
+		Host: dest.Host,
+		Addrs: func() ([]string, error) {
+			dest, err := a.address(req)
+			if err != nil {
+				return nil, err
+			}
+			return dest.Addrs, nil
+		},
+	}, nil
+}
+
+func (a *addresser) address(req *http.Request) (*http_router.AdhocDestination, error) {
+	dest, err := a.http.Address(req)
+	if err == http_router.ErrRouteNotFound {
+		return nil, ErrRouteNotFound
+	} else if rErr, ok := err.(*http_router.Error); ok {
+		return nil, grpc.Errorf(codeForStatus(rErr.StatusCode()), "%v", rErr.Error())
+	} else if err != nil {
+		return nil, err
+	}
+	return dest, nil
+}
+
+// requestForCall describes the call as an HTTP request, carrying the metadata as headers and the client's TLS state,
+// for the HTTP adhoc rules and their authorization to apply.
+func requestForCall(ctx context.Context, authority string, md metadata.MD) *http.Request {
+	req := &http.Request{
+		Method: "POST",
+		URL:    &url.URL{Host: authority},
+		Host:   authority,
+		Header: make(http.Header),
+	}
+	for k, vals := range md {
+		if strings.HasPrefix(k, ":") {
+			continue
+		}
+		req.Header[http.CanonicalHeaderKey(k)] = vals
+	}
+	if p, ok := peer.FromContext(ctx); ok {
+		if tlsInfo, ok := p.AuthInfo.(credentials.TLSInfo); ok {
+			state := tlsInfo.State
+			req.TLS = &state
+		}
+	}
+	return req
+}
+
+func codeForStatus(status int) codes.Code {
+	switch status {
+	case http.StatusForbidden:
+		return codes.PermissionDenied
+	case http.StatusBadRequest:
+		return codes.InvalidArgument
+	default:
+		return codes.Unavailable
+	}
+}
@@ -0,0 +1,41 @@
+package router
+
+import (
+	"testing"
+
+	"github.com/golang/protobuf/jsonpb"
+	pb "github.com/mwitkow/kedge/_protogen/kedge/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewAddresserRejectsRulesUnsupportedForGrpc(t *testing.T) {
+	for _, tcase := range []struct {
+		name        string
+		rule        string
+		expectedErr string
+	}{
+		{
+			name:        "security",
+			rule:        `{"dnsNameMatcher": "*.pods.cluster.local", "security": {}}`,
+			expectedErr: "adhoc rule '*.pods.cluster.local': security is not supported for gRPC",
+		},
+		{
+			name:        "timeouts",
+			rule:        `{"dnsNameMatcher": "*.pods.cluster.local", "timeouts": {"connect": "1s"}}`,
+			expectedErr: "adhoc rule '*.pods.cluster.local': timeouts are not supported for gRPC",
+		},
+		{
+			name:        "connection pool",
+			rule:        `{"dnsNameMatcher": "*.pods.cluster.local", "connectionPool": {"maxIdlePerHost": 2}}`,
+			expectedErr: "adhoc rule '*.pods.cluster.local': connection_pool is not supported for gRPC",
+		},
+	} {
+		t.Run(tcase.name, func(t *testing.T) {
+			config := &pb.DirectorConfig_Grpc{}
+			require.NoError(t, jsonpb.UnmarshalString(`{"adhoc_rules": [`+tcase.rule+`]}`, config))
+			_, err := NewAddresser(config.AdhocRules)
+			assert.EqualError(t, err, tcase.expectedErr)
+		})
+	}
+}
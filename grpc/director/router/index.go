@@ -0,0 +1,172 @@
+package router
+
+import (
+	"strings"
+
+	pb "github.com/mwitkow/kedge/_protogen/kedge/config/grpc/routes"
+)
+
+// routeIndex is a compiled form of a route table that avoids scanning every route on every call.
+//
+// Routes are first bucketed by their exact `authority_matcher`, lower-cased, and then put into a trie keyed by their
+// `service_name_matcher`. Each lookup returns a small set of candidate routes (in declaration order) that still need
+// to be fully checked, which keeps the first-match-wins semantics of the linear matcher.
+type routeIndex struct {
+	byAuthority  map[string]*serviceTrie
+	anyAuthority *serviceTrie
+}
+
+func newRouteIndex(routes []*pb.Route) *routeIndex {
+	idx := &routeIndex{
+		byAuthority:  make(map[string]*serviceTrie),
+		anyAuthority: newServiceTrie(),
+	}
+	for _, route := range routes {
+		if !isAnyAuthority(route.AuthorityMatcher) {
+			if _, ok := idx.byAuthority[strings.ToLower(route.AuthorityMatcher)]; !ok {
+				idx.byAuthority[strings.ToLower(route.AuthorityMatcher)] = newServiceTrie()
+			}
+		}
+	}
+	for pos, route := range routes {
+		if isAnyAuthority(route.AuthorityMatcher) {
+			// Routes without an exact authority matcher can match any authority, they need to be present everywhere.
+			idx.anyAuthority.insert(pos, route.ServiceNameMatcher)
+			for _, t := range idx.byAuthority {
+				t.insert(pos, route.ServiceNameMatcher)
+			}
+		} else {
+			idx.byAuthority[strings.ToLower(route.AuthorityMatcher)].insert(pos, route.ServiceNameMatcher)
+		}
+	}
+	for _, t := range idx.byAuthority {
+		t.compile()
+	}
+	idx.anyAuthority.compile()
+	return idx
+}
+
+// isAnyAuthority returns true for authority matchers that can't be indexed by an exact authority.
+func isAnyAuthority(matcher string) bool {
+	return matcher == "" || matcher[0] == '*'
+}
+
+// candidates calls `fn` with positions of routes that may match, in ascending order, until fn returns false.
+func (idx *routeIndex) candidates(authority string, fullMethodName string, fn func(pos int) bool) {
+	t, ok := idx.byAuthority[strings.ToLower(authority)]
+	if !ok {
+		t = idx.anyAuthority
+	}
+	t.lookup(fullMethodName, fn)
+}
+
+// serviceTrie is a byte-wise trie of service name matchers.
+//
+// Prefix matchers (ending with `*`) are stored on the node of their prefix, exact matchers on the node of their full
+// name. After `compile` every node knows all prefix routes of its ancestors, so a lookup is a single walk.
+type serviceTrie struct {
+	root *trieNode
+}
+
+type trieNode struct {
+	children map[byte]*trieNode
+	// prefix holds positions of routes whose prefix ends at this node.
+	prefix []int
+	// inherited holds positions of all prefix routes on the path from root to this node, sorted.
+	inherited []int
+	// exact holds positions of routes that match exactly the name ending at this node.
+	exact []int
+}
+
+func newServiceTrie() *serviceTrie {
+	return &serviceTrie{root: &trieNode{}}
+}
+
+func (t *serviceTrie) insert(pos int, matcher string) {
+	isPrefix := false
+	if matcher == "" || matcher == "*" {
+		matcher = ""
+		isPrefix = true
+	} else if matcher[len(matcher)-1] == '*' {
+		matcher = matcher[:len(matcher)-1]
+		isPrefix = true
+	}
+	n := t.root
+	for i := 0; i < len(matcher); i++ {
+		if n.children == nil {
+			n.children = make(map[byte]*trieNode)
+		}
+		child, ok := n.children[matcher[i]]
+		if !ok {
+			child = &trieNode{}
+			n.children[matcher[i]] = child
+		}
+		n = child
+	}
+	if isPrefix {
+		n.prefix = append(n.prefix, pos)
+	} else {
+		n.exact = append(n.exact, pos)
+	}
+}
+
+func (t *serviceTrie) compile() {
+	t.root.compile(nil)
+}
+
+func (n *trieNode) compile(parent []int) {
+	n.inherited = mergeSorted(parent, n.prefix)
+	for _, c := range n.children {
+		c.compile(n.inherited)
+	}
+}
+
+func (t *serviceTrie) lookup(name string, fn func(pos int) bool) {
+	n := t.root
+	consumed := true
+	for i := 0; i < len(name); i++ {
+		child, ok := n.children[name[i]]
+		if !ok {
+			consumed = false
+			break
+		}
+		n = child
+	}
+	var exact []int
+	if consumed {
+		exact = n.exact
+	}
+	// Walk both sorted lists in order, without allocating.
+	inherited := n.inherited
+	i, j := 0, 0
+	for i < len(inherited) || j < len(exact) {
+		var pos int
+		if j >= len(exact) || (i < len(inherited) && inherited[i] < exact[j]) {
+			pos = inherited[i]
+			i++
+		} else {
+			pos = exact[j]
+			j++
+		}
+		if !fn(pos) {
+			return
+		}
+	}
+}
+
+// mergeSorted merges two ascending lists of positions into a new ascending list.
+func mergeSorted(a []int, b []int) []int {
+	out := make([]int, 0, len(a)+len(b))
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		if a[i] < b[j] {
+			out = append(out, a[i])
+			i++
+		} else {
+			out = append(out, b[j])
+			j++
+		}
+	}
+	out = append(out, a[i:]...)
+	return append(out, b[j:]...)
+}
@@ -0,0 +1,141 @@
+package router
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+	"testing"
+
+	pb "github.com/mwitkow/kedge/_protogen/kedge/config/grpc/routes"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	fuzzServiceParts = []string{"com", "example", "ex", "a", "b", "MyService"}
+	fuzzAuthorities  = []string{"", "authority_a.local", "authority_b.local", "authority_a.local:8443", "Authority_A.Local"}
+	fuzzWildcards    = []string{"*", "*.local", "*_a.local", "*.other"}
+	fuzzRegexps      = []string{"com\\..*", ".*/Method", "[ab]\\..*", "value(One|Two)"}
+	fuzzMetadataKeys = []string{"keyone", "keytwo"}
+	fuzzMetadataVals = []string{"valueOne", "valueTwo"}
+)
+
+func randomServiceName(rnd *rand.Rand) string {
+	parts := []string{}
+	for i := 0; i < 1+rnd.Intn(3); i++ {
+		parts = append(parts, fuzzServiceParts[rnd.Intn(len(fuzzServiceParts))])
+	}
+	return strings.Join(parts, ".")
+}
+
+func randomRoute(rnd *rand.Rand, id int) *pb.Route {
+	route := &pb.Route{BackendName: fmt.Sprintf("backend_%d", id)}
+	switch rnd.Intn(5) {
+	case 0:
+		route.ServiceNameMatcher = ""
+	case 1:
+		route.ServiceNameMatcher = "*"
+	case 2:
+		route.ServiceNameMatcher = randomServiceName(rnd) + "/Method"
+	default:
+		name := randomServiceName(rnd)
+		route.ServiceNameMatcher = name[:rnd.Intn(len(name)+1)] + "*"
+	}
+	if rnd.Intn(5) == 0 {
+		route.AuthorityMatcher = fuzzWildcards[rnd.Intn(len(fuzzWildcards))]
+	} else {
+		route.AuthorityMatcher = fuzzAuthorities[rnd.Intn(len(fuzzAuthorities))]
+	}
+	if rnd.Intn(4) == 0 {
+		route.MetadataMatcher = map[string]string{
+			fuzzMetadataKeys[rnd.Intn(len(fuzzMetadataKeys))]: fuzzMetadataVals[rnd.Intn(len(fuzzMetadataVals))],
+		}
+	}
+	if rnd.Intn(4) == 0 {
+		route.ServiceNameRegex = fuzzRegexps[rnd.Intn(len(fuzzRegexps))]
+	}
+	if rnd.Intn(4) == 0 {
+		rule := &pb.MetadataRule{Key: fuzzMetadataKeys[rnd.Intn(len(fuzzMetadataKeys))], Negate: rnd.Intn(2) == 0}
+		if rnd.Intn(2) == 0 {
+			rule.ValueRegex = fuzzRegexps[rnd.Intn(len(fuzzRegexps))]
+		}
+		route.MetadataRules = append(route.MetadataRules, rule)
+	}
+	return route
+}
+
+func randomCall(rnd *rand.Rand) (context.Context, string) {
+	md := metadata.Pairs()
+	if auth := fuzzAuthorities[rnd.Intn(len(fuzzAuthorities))]; auth != "" {
+		md[":authority"] = []string{auth}
+	}
+	for _, k := range fuzzMetadataKeys {
+		if rnd.Intn(2) == 0 {
+			md[k] = []string{fuzzMetadataVals[rnd.Intn(len(fuzzMetadataVals))]}
+		}
+	}
+	return metadata.NewContext(context.TODO(), md), "/" + randomServiceName(rnd) + "/Method"
+}
+
+func TestRouteIndex_FuzzEquivalenceWithLinear(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1337))
+	for iteration := 0; iteration < 500; iteration++ {
+		routes := []*pb.Route{}
+		for i := 0; i < rnd.Intn(30); i++ {
+			routes = append(routes, randomRoute(rnd, i))
+		}
+		linear := &router{routes: routes}
+		indexed, err := NewStatic(routes)
+		require.NoError(t, err, "fuzzed routes must be valid")
+		for call := 0; call < 50; call++ {
+			ctx, method := randomCall(rnd)
+			expectedBe, expectedErr := linear.Route(ctx, method)
+			be, err := indexed.Route(ctx, method)
+			md, _ := metadata.FromContext(ctx)
+			require.Equal(t, expectedErr, err, "error must match linear for %v %v on routes %v", method, md, routes)
+			require.Equal(t, expectedBe, be, "backend must match linear for %v %v on routes %v", method, md, routes)
+		}
+	}
+}
+
+// benchmarkRoutes builds a route table where each route has its own authority and service, as auto-generated
+// configs would.
+func benchmarkRoutes(count int) []*pb.Route {
+	routes := []*pb.Route{}
+	for i := 0; i < count; i++ {
+		routes = append(routes, &pb.Route{
+			BackendName:        fmt.Sprintf("backend_%d", i),
+			ServiceNameMatcher: fmt.Sprintf("com.example.service%d.*", i),
+			AuthorityMatcher:   fmt.Sprintf("service%d.example.com", i),
+		})
+	}
+	return routes
+}
+
+func benchmarkRoute(b *testing.B, r Router, count int) {
+	// Hit the last route, the worst case for a linear scan.
+	md := metadata.Pairs(":authority", fmt.Sprintf("service%d.example.com", count-1))
+	ctx := metadata.NewContext(context.TODO(), md)
+	method := fmt.Sprintf("/com.example.service%d.MyService/Method", count-1)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := r.Route(ctx, method); err != nil {
+			b.Fatalf("routing must not fail: %v", err)
+		}
+	}
+}
+
+func BenchmarkRoute(b *testing.B) {
+	for _, count := range []int{10, 100, 1000, 10000} {
+		routes := benchmarkRoutes(count)
+		b.Run(fmt.Sprintf("Linear_%d", count), func(b *testing.B) {
+			benchmarkRoute(b, &router{routes: routes}, count)
+		})
+		b.Run(fmt.Sprintf("Indexed_%d", count), func(b *testing.B) {
+			indexed, _ := NewStatic(routes)
+			benchmarkRoute(b, indexed, count)
+		})
+	}
+}
@@ -3,6 +3,9 @@ package router
 import (
 	pb "github.com/mwitkow/kedge/_protogen/kedge/config/grpc/routes"
 
+	"fmt"
+	"net"
+	"regexp"
 	"strings"
 
 	"golang.org/x/net/context"
@@ -12,24 +15,91 @@ import (
 )
 
 var (
-	emptyMd       = metadata.Pairs()
-	routeNotFound = grpc.Errorf(codes.Unimplemented, "unknown route to service")
+	emptyMd = metadata.Pairs()
+	// ErrRouteNotFound is returned for calls that don't match any route or adhoc rule.
+	ErrRouteNotFound = grpc.Errorf(codes.Unimplemented, "unknown route to service")
 )
 
 type Router interface {
 	// Route returns a backend name for a given call, or an error.
 	Route(ctx context.Context, fullMethodName string) (backendName string, err error)
+
+	// Mirror returns the mirroring config of the route matching the call, or nil if the call isn't mirrored.
+	Mirror(ctx context.Context, fullMethodName string) *pb.Mirror
 }
 
 type router struct {
 	routes []*pb.Route
+	// index is the compiled lookup table for routes. If nil, all routes are scanned linearly.
+	index *routeIndex
+	// regexps holds the compiled regular expressions of all routes, keyed by their source.
+	regexps map[string]*regexp.Regexp
+	// hasMirrors is false if no route is mirrored, in which case Mirror can skip matching.
+	hasMirrors bool
 }
 
-func NewStatic(routes []*pb.Route) *router {
-	return &router{routes: routes}
+// NewStatic creates a router from a static list of routes, validating their backends and matchers.
+func NewStatic(routes []*pb.Route) (*router, error) {
+	r := &router{routes: routes, index: newRouteIndex(routes), regexps: make(map[string]*regexp.Regexp)}
+	for i, route := range routes {
+		if route.BackendName != "" && len(route.Backends) > 0 {
+			return nil, fmt.Errorf("route %d for backend '%v' has both backend_name and backends", i, route.BackendName)
+		}
+		for _, be := range route.Backends {
+			if be.Name == "" {
+				return nil, fmt.Errorf("route %d has a weighted backend without a name", i)
+			}
+		}
+		if m := route.Mirror; m != nil {
+			if m.BackendName == "" {
+				return nil, fmt.Errorf("route %d for backend '%v' has a mirror without a backend", i, route.BackendName)
+			}
+			r.hasMirrors = true
+		}
+		exprs := []string{}
+		if route.ServiceNameRegex != "" {
+			exprs = append(exprs, route.ServiceNameRegex)
+		}
+		for _, rule := range route.MetadataRules {
+			if rule.Key == "" {
+				return nil, fmt.Errorf("route %d for backend '%v' has a metadata rule without a key", i, route.BackendName)
+			}
+			if rule.ValueRegex != "" {
+				exprs = append(exprs, rule.ValueRegex)
+			}
+		}
+		for _, expr := range exprs {
+			re, err := compileAnchored(expr)
+			if err != nil {
+				return nil, fmt.Errorf("route %d for backend '%v' has a bad regex '%v': %v", i, route.BackendName, expr, err)
+			}
+			r.regexps[expr] = re
+		}
+	}
+	return r, nil
 }
 
 func (r *router) Route(ctx context.Context, fullMethodName string) (backendName string, err error) {
+	route, md := r.match(ctx, fullMethodName)
+	if route == nil {
+		return "", ErrRouteNotFound
+	}
+	return backendFor(ctx, route, md), nil
+}
+
+func (r *router) Mirror(ctx context.Context, fullMethodName string) *pb.Mirror {
+	if r.index != nil && !r.hasMirrors {
+		return nil
+	}
+	route, _ := r.match(ctx, fullMethodName)
+	if route == nil {
+		return nil
+	}
+	return route.Mirror
+}
+
+// match returns the first route matching the call, or nil if none does.
+func (r *router) match(ctx context.Context, fullMethodName string) (*pb.Route, metadata.MD) {
 	md, ok := metadata.FromContext(ctx)
 	if !ok {
 		md = emptyMd
@@ -37,19 +107,46 @@ func (r *router) Route(ctx context.Context, fullMethodName string) (backendName
 	if strings.HasPrefix(fullMethodName, "/") {
 		fullMethodName = fullMethodName[1:]
 	}
-	for _, route := range r.routes {
-		if !r.serviceNameMatches(fullMethodName, route.ServiceNameMatcher) {
-			continue
-		}
-		if !r.authorityMatches(md, route.AuthorityMatcher) {
-			continue
+	if r.index == nil {
+		for _, route := range r.routes {
+			if r.routeMatches(route, fullMethodName, md) {
+				return route, md
+			}
 		}
-		if !r.metadataMatches(md, route.MetadataMatcher) {
-			continue
+		return nil, md
+	}
+	authority := ""
+	if auth, ok := md[":authority"]; ok && len(auth) > 0 {
+		authority = auth[0]
+	}
+	var matched *pb.Route
+	r.index.candidates(authority, fullMethodName, func(pos int) bool {
+		if r.routeMatches(r.routes[pos], fullMethodName, md) {
+			matched = r.routes[pos]
+			return false
 		}
-		return route.BackendName, nil
+		return true
+	})
+	return matched, md
+}
+
+func (r *router) routeMatches(route *pb.Route, fullMethodName string, md metadata.MD) bool {
+	if !r.serviceNameMatches(fullMethodName, route.ServiceNameMatcher) {
+		return false
+	}
+	if !r.authorityMatches(md, route.AuthorityMatcher) {
+		return false
+	}
+	if !r.metadataMatches(md, route.MetadataMatcher) {
+		return false
 	}
-	return "", routeNotFound
+	if !r.serviceNameRegexMatches(fullMethodName, route.ServiceNameRegex) {
+		return false
+	}
+	if !r.metadataRulesMatch(md, route.MetadataRules) {
+		return false
+	}
+	return true
 }
 
 func (r *router) serviceNameMatches(fullMethodName string, matcher string) bool {
@@ -70,7 +167,14 @@ func (r *router) authorityMatches(md metadata.MD, matcher string) bool {
 	if !ok || len(auth) == 0 {
 		return false // there was no authority header and it was expected
 	}
-	return auth[0] == matcher
+	if matcher[0] == '*' {
+		host := auth[0]
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		return strings.HasSuffix(strings.ToLower(host), strings.ToLower(matcher[1:]))
+	}
+	return strings.ToLower(auth[0]) == strings.ToLower(matcher)
 }
 
 func (r *router) metadataMatches(md metadata.MD, expectedKv map[string]string) bool {
@@ -92,3 +196,47 @@ func (r *router) metadataMatches(md metadata.MD, expectedKv map[string]string) b
 	}
 	return true
 }
+
+func (r *router) serviceNameRegexMatches(fullMethodName string, expr string) bool {
+	if expr == "" {
+		return true
+	}
+	re := r.regexp(expr)
+	return re != nil && re.MatchString(fullMethodName)
+}
+
+func (r *router) metadataRulesMatch(md metadata.MD, rules []*pb.MetadataRule) bool {
+	for _, rule := range rules {
+		vals, ok := md[strings.ToLower(rule.Key)]
+		matched := ok
+		if ok && rule.ValueRegex != "" {
+			re := r.regexp(rule.ValueRegex)
+			matched = false
+			for _, v := range vals {
+				if re != nil && re.MatchString(v) {
+					matched = true
+					break
+				}
+			}
+		}
+		if matched == rule.Negate {
+			return false
+		}
+	}
+	return true
+}
+
+// regexp returns the compiled expression, or nil if it is invalid.
+func (r *router) regexp(expr string) *regexp.Regexp {
+	if re, ok := r.regexps[expr]; ok {
+		return re
+	}
+	// Routers that weren't built through NewStatic don't have their expressions validated upfront.
+	re, _ := compileAnchored(expr)
+	return re
+}
+
+// compileAnchored compiles the expression so that it needs to match the whole string.
+func compileAnchored(expr string) (*regexp.Regexp, error) {
+	return regexp.Compile("^(?:" + expr + ")$")
+}
@@ -4,6 +4,7 @@ import "testing"
 import (
 	"github.com/golang/protobuf/jsonpb"
 	pb "github.com/mwitkow/kedge/_protogen/kedge/config"
+	pb_route "github.com/mwitkow/kedge/_protogen/kedge/config/grpc/routes"
 	"github.com/stretchr/testify/require"
 	"golang.org/x/net/context"
 	"google.golang.org/grpc/metadata"
@@ -66,6 +67,13 @@ func TestRouteMatches(t *testing.T) {
 			expectedBackend: "backendB_authorityA",
 			expectedErr:     nil,
 		},
+		{
+			name:            "MatchesAuthorityCaseInsensitively",
+			fullServiceName: "com.example.blah.MyService",
+			md:              metadata.Pairs(":authority", "Authority_A.Service.Local"),
+			expectedBackend: "backendB_authorityA",
+			expectedErr:     nil,
+		},
 		{
 			name:            "MatchesAuthorityAndServiceTakeTwo",
 			fullServiceName: "something.else.MyService",
@@ -110,3 +118,131 @@ func TestRouteMatches(t *testing.T) {
 
 	}
 }
+
+func TestRouteMatches_MethodsRegexAndRules(t *testing.T) {
+	configJson := `
+{ "routes": [
+	{
+		"backendName": "backendReplica",
+		"serviceNameMatcher": "com.example.a.MyService/Get*"
+	},
+	{
+		"backendName": "backendCanary",
+		"serviceNameMatcher": "com.example.a.MyService/Create"
+	},
+	{
+		"backendName": "backendRegex",
+		"serviceNameRegex": "com\\.example\\.(b|c)\\.[^/]+/(List|Watch).*"
+	},
+	{
+		"backendName": "backendWildcardAuthority",
+		"serviceNameMatcher": "com.example.*",
+		"authorityMatcher": "*.wildcard.local"
+	},
+	{
+		"backendName": "backendNoDebug",
+		"serviceNameMatcher": "com.example.*",
+		"metadataRules": [
+			{ "key": "x-debug", "negate": true },
+			{ "key": "x-tenant", "valueRegex": "tenant-[0-9]+" }
+		]
+	},
+	{
+		"backendName": "backendCatchAllCom",
+		"serviceNameMatcher": "com.*"
+	}
+]}`
+	config := &pb.DirectorConfig_Grpc{}
+	require.NoError(t, jsonpb.UnmarshalString(configJson, config))
+	r, err := NewStatic(config.Routes)
+	require.NoError(t, err)
+
+	for _, tcase := range []struct {
+		name            string
+		fullMethodName  string
+		md              metadata.MD
+		expectedBackend string
+	}{
+		{
+			name:            "MatchesMethodGlob",
+			fullMethodName:  "/com.example.a.MyService/GetSomething",
+			md:              metadata.Pairs(),
+			expectedBackend: "backendReplica",
+		},
+		{
+			name:            "MatchesExactMethod",
+			fullMethodName:  "/com.example.a.MyService/Create",
+			md:              metadata.Pairs(),
+			expectedBackend: "backendCanary",
+		},
+		{
+			name:            "ExactMethodDoesntMatchOtherMethods",
+			fullMethodName:  "/com.example.a.MyService/CreateMany",
+			md:              metadata.Pairs(),
+			expectedBackend: "backendCatchAllCom",
+		},
+		{
+			name:            "MatchesServiceRegex",
+			fullMethodName:  "/com.example.c.OtherService/ListThings",
+			md:              metadata.Pairs(),
+			expectedBackend: "backendRegex",
+		},
+		{
+			name:            "ServiceRegexMustMatchWholeName",
+			fullMethodName:  "/com.example.c.OtherService/DeleteThings",
+			md:              metadata.Pairs(),
+			expectedBackend: "backendCatchAllCom",
+		},
+		{
+			name:            "MatchesWildcardAuthorityIgnoringPort",
+			fullMethodName:  "/com.example.d.MyService/Method",
+			md:              metadata.Pairs(":authority", "controller.wildcard.local:8443"),
+			expectedBackend: "backendWildcardAuthority",
+		},
+		{
+			name:            "MatchesWildcardAuthorityWithoutPort",
+			fullMethodName:  "/com.example.d.MyService/Method",
+			md:              metadata.Pairs(":authority", "controller.wildcard.local"),
+			expectedBackend: "backendWildcardAuthority",
+		},
+		{
+			name:            "WildcardAuthorityDoesntMatchOtherDomains",
+			fullMethodName:  "/com.example.d.MyService/Method",
+			md:              metadata.Pairs(":authority", "controller.wildcard.other:8443"),
+			expectedBackend: "backendCatchAllCom",
+		},
+		{
+			name:            "MatchesMetadataAbsenceAndRegex",
+			fullMethodName:  "/com.example.d.MyService/Method",
+			md:              metadata.Pairs("x-tenant", "tenant-1234"),
+			expectedBackend: "backendNoDebug",
+		},
+		{
+			name:            "FailsMetadataAbsence",
+			fullMethodName:  "/com.example.d.MyService/Method",
+			md:              metadata.Pairs("x-tenant", "tenant-1234", "x-debug", "true"),
+			expectedBackend: "backendCatchAllCom",
+		},
+		{
+			name:            "FailsMetadataRegex",
+			fullMethodName:  "/com.example.d.MyService/Method",
+			md:              metadata.Pairs("x-tenant", "tenant-abc"),
+			expectedBackend: "backendCatchAllCom",
+		},
+	} {
+		t.Run(tcase.name, func(t *testing.T) {
+			ctx := metadata.NewContext(context.TODO(), tcase.md)
+			be, _ := r.Route(ctx, tcase.fullMethodName)
+			assert.Equal(t, tcase.expectedBackend, be, "must match expected backend")
+		})
+	}
+}
+
+func TestNewStaticFailsOnBadRegex(t *testing.T) {
+	_, err := NewStatic([]*pb_route.Route{{BackendName: "a", ServiceNameRegex: "com.(example"}})
+	assert.Error(t, err, "bad service regex must fail")
+	_, err = NewStatic([]*pb_route.Route{{BackendName: "a", MetadataRules: []*pb_route.MetadataRule{{Key: "k", ValueRegex: "[a-"}}}})
+	assert.Error(t, err, "bad metadata regex must fail")
+	_, err = NewStatic([]*pb_route.Route{{BackendName: "a", MetadataRules: []*pb_route.MetadataRule{{ValueRegex: "a"}}}})
+	assert.Error(t, err, "metadata rule without a key must fail")
+}
@@ -0,0 +1,68 @@
+package router
+
+import (
+	"strings"
+
+	pb "github.com/mwitkow/kedge/_protogen/kedge/config/grpc/routes"
+	"github.com/mwitkow/kedge/lib/split"
+	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+)
+
+var (
+	splitBackendCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "kedge",
+			Subsystem: "grpc",
+			Name:      "route_split_calls_total",
+			Help:      "Count of calls on routes with weighted backends, by the backend that was chosen.",
+		}, []string{"backend_name"})
+)
+
+func init() {
+	prometheus.MustRegister(splitBackendCounter)
+}
+
+// backendFor returns the backend of a matched route, choosing between weighted backends if the route has any.
+func backendFor(ctx context.Context, route *pb.Route, md metadata.MD) string {
+	if len(route.Backends) == 0 {
+		return route.BackendName
+	}
+	key, _ := stickyKey(ctx, route.Sticky, md)
+	i := split.Pick(len(route.Backends), func(i int) uint32 { return route.Backends[i].Weight }, key)
+	name := route.Backends[i].Name
+	splitBackendCounter.WithLabelValues(name).Inc()
+	return name
+}
+
+// stickyKey returns the value of the call that consistently identifies the client, if configured and present.
+func stickyKey(ctx context.Context, sticky *pb.Sticky, md metadata.MD) (string, bool) {
+	if sticky == nil {
+		return "", false
+	}
+	switch {
+	case sticky.GetMetadataKey() != "":
+		vals, ok := md[strings.ToLower(sticky.GetMetadataKey())]
+		if !ok || len(vals) == 0 || vals[0] == "" {
+			return "", false
+		}
+		return vals[0], true
+	case sticky.GetClientIdentity():
+		p, ok := peer.FromContext(ctx)
+		if !ok {
+			return "", false
+		}
+		remoteAddr := ""
+		if p.Addr != nil {
+			remoteAddr = p.Addr.String()
+		}
+		if tlsInfo, ok := p.AuthInfo.(credentials.TLSInfo); ok {
+			return split.ClientIdentity(tlsInfo.State.PeerCertificates, remoteAddr)
+		}
+		return split.ClientIdentity(nil, remoteAddr)
+	}
+	return "", false
+}
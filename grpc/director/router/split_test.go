@@ -0,0 +1,126 @@
+package router
+
+import (
+	"fmt"
+	"net"
+	"testing"
+
+	"github.com/golang/protobuf/jsonpb"
+	pb "github.com/mwitkow/kedge/_protogen/kedge/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+)
+
+const splitConfigJson = `
+{ "routes": [
+	{
+		"serviceNameMatcher": "com.example.random.*",
+		"backends": [
+			{ "name": "controller", "weight": 90 },
+			{ "name": "controller-canary", "weight": 10 }
+		]
+	},
+	{
+		"serviceNameMatcher": "com.example.metadata.*",
+		"backends": [
+			{ "name": "controller", "weight": 50 },
+			{ "name": "controller-canary", "weight": 50 }
+		],
+		"sticky": { "metadataKey": "x-user-id" }
+	},
+	{
+		"serviceNameMatcher": "com.example.identity.*",
+		"backends": [
+			{ "name": "controller", "weight": 50 },
+			{ "name": "controller-canary", "weight": 50 }
+		],
+		"sticky": { "clientIdentity": true }
+	}
+]}`
+
+func splitRouter(t *testing.T) Router {
+	config := &pb.DirectorConfig_Grpc{}
+	require.NoError(t, jsonpb.UnmarshalString(splitConfigJson, config))
+	r, err := NewStatic(config.Routes)
+	require.NoError(t, err)
+	return r
+}
+
+func TestWeightedSplit_FollowsWeights(t *testing.T) {
+	r := splitRouter(t)
+	counts := map[string]int{}
+	for i := 0; i < 10000; i++ {
+		be, err := r.Route(context.TODO(), "/com.example.random.MyService/Method")
+		require.NoError(t, err)
+		counts[be]++
+	}
+	assert.Len(t, counts, 2, "only the weighted backends must be chosen")
+	assert.InDelta(t, 9000, counts["controller"], 300, "controller must receive ~90%% of calls")
+	assert.InDelta(t, 1000, counts["controller-canary"], 300, "canary must receive ~10%% of calls")
+}
+
+func TestWeightedSplit_Sticky(t *testing.T) {
+	r := splitRouter(t)
+	for _, tcase := range []struct {
+		name           string
+		fullMethodName string
+		ctxForClient   func(client int) context.Context
+	}{
+		{
+			name:           "MetadataKey",
+			fullMethodName: "/com.example.metadata.MyService/Method",
+			ctxForClient: func(client int) context.Context {
+				return metadata.NewContext(context.TODO(), metadata.Pairs("x-user-id", fmt.Sprintf("user-%d", client)))
+			},
+		},
+		{
+			name:           "ClientIdentity",
+			fullMethodName: "/com.example.identity.MyService/Method",
+			ctxForClient: func(client int) context.Context {
+				addr := &net.TCPAddr{IP: net.IPv4(10, 0, 1, byte(client)), Port: 30000 + client}
+				return peer.NewContext(context.TODO(), &peer.Peer{Addr: addr})
+			},
+		},
+	} {
+		t.Run(tcase.name, func(t *testing.T) {
+			seen := map[string]bool{}
+			for client := 0; client < 50; client++ {
+				first, err := r.Route(tcase.ctxForClient(client), tcase.fullMethodName)
+				require.NoError(t, err)
+				seen[first] = true
+				for i := 0; i < 10; i++ {
+					be, err := r.Route(tcase.ctxForClient(client), tcase.fullMethodName)
+					require.NoError(t, err)
+					require.Equal(t, first, be, "client %d must always be sent to the same backend", client)
+				}
+			}
+			assert.Len(t, seen, 2, "different clients must be spread over both backends")
+		})
+	}
+}
+
+func TestWeightedSplit_RejectsBadBackends(t *testing.T) {
+	for _, tcase := range []struct {
+		name   string
+		config string
+	}{
+		{
+			name:   "BackendWithoutName",
+			config: `{ "routes": [ { "backends": [ { "name": "controller", "weight": 50 }, { "weight": 50 } ] } ] }`,
+		},
+		{
+			name:   "BackendNameAndBackends",
+			config: `{ "routes": [ { "backendName": "controller", "backends": [ { "name": "controller-canary" } ] } ] }`,
+		},
+	} {
+		t.Run(tcase.name, func(t *testing.T) {
+			config := &pb.DirectorConfig_Grpc{}
+			require.NoError(t, jsonpb.UnmarshalString(tcase.config, config))
+			_, err := NewStatic(config.Routes)
+			assert.Error(t, err)
+		})
+	}
+}
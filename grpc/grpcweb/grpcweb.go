@@ -0,0 +1,223 @@
+// Package grpcweb translates gRPC-Web requests from browsers into gRPC requests handled by a grpc.Server.
+//
+// See https://github.com/grpc/grpc/blob/master/doc/PROTOCOL-WEB.md for the protocol.
+package grpcweb
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"golang.org/x/net/http2"
+)
+
+const (
+	contentTypeGrpcWeb     = "application/grpc-web"
+	contentTypeGrpcWebText = "application/grpc-web-text"
+
+	// trailerFrameFlag marks a length-prefixed message of the body as the trailers of the response.
+	trailerFrameFlag = 0x80
+)
+
+// Wrapper is an http.Handler that serves gRPC-Web requests and their CORS preflights using a gRPC server.
+type Wrapper struct {
+	grpcHandler    http.Handler
+	allowedOrigins map[string]bool
+	allowAll       bool
+}
+
+// Wrap builds a Wrapper around the ServeHTTP of a gRPC server.
+//
+// Cross-origin requests are only allowed from `allowedOrigins`, with "*" allowing all origins.
+func Wrap(grpcHandler http.Handler, allowedOrigins []string) *Wrapper {
+	w := &Wrapper{grpcHandler: grpcHandler, allowedOrigins: make(map[string]bool)}
+	for _, o := range allowedOrigins {
+		if o == "*" {
+			w.allowAll = true
+		}
+		w.allowedOrigins[o] = true
+	}
+	return w
+}
+
+// IsGrpcWebRequest returns true for requests that need to be translated from gRPC-Web.
+func (w *Wrapper) IsGrpcWebRequest(req *http.Request) bool {
+	return req.Method == "POST" && strings.HasPrefix(req.Header.Get("Content-Type"), contentTypeGrpcWeb)
+}
+
+// IsCorsPreflightRequest returns true for CORS preflights of gRPC-Web requests.
+//
+// gRPC-Web clients always send the `x-grpc-web` header, which allows to tell their preflights apart from ones meant
+// for the HTTP backends.
+func (w *Wrapper) IsCorsPreflightRequest(req *http.Request) bool {
+	if req.Method != "OPTIONS" || req.Header.Get("Access-Control-Request-Method") == "" {
+		return false
+	}
+	for _, h := range strings.Split(req.Header.Get("Access-Control-Request-Headers"), ",") {
+		if strings.EqualFold(strings.TrimSpace(h), "x-grpc-web") {
+			return true
+		}
+	}
+	return false
+}
+
+func (w *Wrapper) ServeHTTP(resp http.ResponseWriter, req *http.Request) {
+	if w.IsCorsPreflightRequest(req) {
+		w.servePreflight(resp, req)
+		return
+	}
+	if !w.IsGrpcWebRequest(req) {
+		http.Error(resp, "not a gRPC-Web request", http.StatusBadRequest)
+		return
+	}
+	origin := req.Header.Get("Origin")
+	if origin != "" && !w.originAllowed(origin) {
+		http.Error(resp, fmt.Sprintf("origin '%v' is not allowed", origin), http.StatusForbidden)
+		return
+	}
+	contentType := req.Header.Get("Content-Type")
+	isText := strings.HasPrefix(contentType, contentTypeGrpcWebText)
+	grpcReq := translateRequest(req, contentType, isText)
+	grpcResp := newResponseWriter(resp, contentType, isText, origin)
+	w.grpcHandler.ServeHTTP(grpcResp, grpcReq)
+	grpcResp.finish()
+}
+
+func (w *Wrapper) originAllowed(origin string) bool {
+	return w.allowAll || w.allowedOrigins[origin]
+}
+
+func (w *Wrapper) servePreflight(resp http.ResponseWriter, req *http.Request) {
+	origin := req.Header.Get("Origin")
+	if !w.originAllowed(origin) {
+		http.Error(resp, fmt.Sprintf("origin '%v' is not allowed", origin), http.StatusForbidden)
+		return
+	}
+	h := resp.Header()
+	h.Set("Access-Control-Allow-Origin", origin)
+	h.Set("Access-Control-Allow-Credentials", "true")
+	h.Set("Access-Control-Allow-Methods", "POST")
+	h.Set("Access-Control-Allow-Headers", req.Header.Get("Access-Control-Request-Headers"))
+	h.Set("Access-Control-Max-Age", "600")
+	h.Add("Vary", "Origin")
+	resp.WriteHeader(http.StatusOK)
+}
+
+// translateRequest makes the request look like a gRPC one, which grpc.Server only accepts over HTTP/2.
+func translateRequest(req *http.Request, contentType string, isText bool) *http.Request {
+	grpcReq := req.WithContext(req.Context()) // shallow copy
+	grpcReq.ProtoMajor = 2
+	grpcReq.ProtoMinor = 0
+	grpcReq.Header = make(http.Header)
+	for k, v := range req.Header {
+		grpcReq.Header[k] = v
+	}
+	webPrefix := contentTypeGrpcWeb
+	if isText {
+		webPrefix = contentTypeGrpcWebText
+		grpcReq.Body = ioutil.NopCloser(base64.NewDecoder(base64.StdEncoding, req.Body))
+		grpcReq.ContentLength = -1
+	}
+	grpcReq.Header.Set("Content-Type", "application/grpc"+strings.TrimPrefix(contentType, webPrefix))
+	grpcReq.Header.Del("Content-Length")
+	return grpcReq
+}
+
+// responseWriter passes the gRPC response on as a gRPC-Web one, sending trailers as the last message of the body.
+type responseWriter struct {
+	resp        http.ResponseWriter
+	header      http.Header
+	contentType string
+	isText      bool
+	origin      string
+	wroteHeader bool
+}
+
+func newResponseWriter(resp http.ResponseWriter, contentType string, isText bool, origin string) *responseWriter {
+	return &responseWriter{resp: resp, header: make(http.Header), contentType: contentType, isText: isText, origin: origin}
+}
+
+func (w *responseWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *responseWriter) WriteHeader(code int) {
+	if w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
+	h := w.resp.Header()
+	exposed := []string{}
+	for k, v := range w.header {
+		if k == "Trailer" || strings.HasPrefix(k, http2.TrailerPrefix) {
+			continue
+		}
+		h[k] = v
+		exposed = append(exposed, k)
+	}
+	h.Set("Content-Type", w.contentType)
+	if w.origin != "" {
+		h.Set("Access-Control-Allow-Origin", w.origin)
+		h.Set("Access-Control-Allow-Credentials", "true")
+		h.Set("Access-Control-Expose-Headers", strings.Join(exposed, ", "))
+		h.Add("Vary", "Origin")
+	}
+	w.resp.WriteHeader(code)
+}
+
+func (w *responseWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	if w.isText {
+		if _, err := io.WriteString(w.resp, base64.StdEncoding.EncodeToString(b)); err != nil {
+			return 0, err
+		}
+		return len(b), nil
+	}
+	return w.resp.Write(b)
+}
+
+func (w *responseWriter) Flush() {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	if f, ok := w.resp.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (w *responseWriter) CloseNotify() <-chan bool {
+	if cn, ok := w.resp.(http.CloseNotifier); ok {
+		return cn.CloseNotify()
+	}
+	return make(chan bool)
+}
+
+// finish writes the trailers set by the gRPC server after the headers were sent.
+func (w *responseWriter) finish() {
+	trailers := make(http.Header)
+	for k, v := range w.header {
+		if k == "Grpc-Status" || k == "Grpc-Message" {
+			trailers[k] = v
+		} else if strings.HasPrefix(k, http2.TrailerPrefix) {
+			trailers[strings.TrimPrefix(k, http2.TrailerPrefix)] = v
+		}
+	}
+	body := &bytes.Buffer{}
+	for k, vs := range trailers {
+		for _, v := range vs {
+			fmt.Fprintf(body, "%s: %s\r\n", strings.ToLower(k), v)
+		}
+	}
+	frame := make([]byte, 5, 5+body.Len())
+	frame[0] = trailerFrameFlag
+	binary.BigEndian.PutUint32(frame[1:], uint32(body.Len()))
+	w.Write(append(frame, body.Bytes()...))
+	w.Flush()
+}
@@ -1,26 +1,35 @@
 package grpc_integration
 
 import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
 	"net"
+	"net/http"
 
 	"crypto/tls"
 	"crypto/x509"
 	"path"
 	"runtime"
+	"strconv"
 	"sync"
 	"testing"
 	"time"
 
 	"io/ioutil"
 
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes"
 	"github.com/mwitkow/go-conntrack/connhelpers"
 	"github.com/mwitkow/go-grpc-middleware/testing"
 	pb_testproto "github.com/mwitkow/go-grpc-middleware/testing/testproto"
 	"github.com/mwitkow/go-srvlb/srv"
 	"github.com/mwitkow/grpc-proxy/proxy"
+	pb_auth "github.com/mwitkow/kedge/_protogen/kedge/config/common/auth"
 	pb_res "github.com/mwitkow/kedge/_protogen/kedge/config/common/resolvers"
 	pb_be "github.com/mwitkow/kedge/_protogen/kedge/config/grpc/backends"
 	pb_route "github.com/mwitkow/kedge/_protogen/kedge/config/grpc/routes"
+	pb_adhoc "github.com/mwitkow/kedge/_protogen/kedge/config/http/routes"
 
 	"fmt"
 
@@ -29,13 +38,18 @@ import (
 	"github.com/mwitkow/kedge/grpc/backendpool"
 	"github.com/mwitkow/kedge/grpc/director"
 	"github.com/mwitkow/kedge/grpc/director/router"
+	"github.com/mwitkow/kedge/grpc/grpcweb"
+	http_router "github.com/mwitkow/kedge/http/director/router"
 	"github.com/mwitkow/kedge/lib/resolvers"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"github.com/stretchr/testify/suite"
 	"golang.org/x/net/context"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/transport"
 )
 
@@ -50,6 +64,54 @@ var backendConfigs = []*pb_be.Backend{
 			},
 		},
 	},
+	&pb_be.Backend{
+		Name: "non_secure_with_deadlines",
+		Resolver: &pb_be.Backend_Srv{
+			Srv: &pb_res.SrvResolver{
+				DnsName: "_grpc._tcp.nonsecure.backends.test.local",
+			},
+		},
+		Deadlines: &pb_be.Deadlines{
+			DefaultTimeout: ptypes.DurationProto(100 * time.Millisecond),
+			MaxTimeout:     ptypes.DurationProto(200 * time.Millisecond),
+		},
+	},
+	&pb_be.Backend{
+		Name: "mirror",
+		Resolver: &pb_be.Backend_Srv{
+			Srv: &pb_res.SrvResolver{
+				DnsName: "_grpc._tcp.mirror.backends.test.local",
+			},
+		},
+	},
+	&pb_be.Backend{
+		Name: "empty",
+		Resolver: &pb_be.Backend_Srv{
+			Srv: &pb_res.SrvResolver{
+				DnsName: "_grpc._tcp.empty.backends.test.local",
+			},
+		},
+	},
+	&pb_be.Backend{
+		Name: "dead",
+		Resolver: &pb_be.Backend_Srv{
+			Srv: &pb_res.SrvResolver{
+				DnsName: "_grpc._tcp.dead.backends.test.local",
+			},
+		},
+	},
+	&pb_be.Backend{
+		Name: "failover",
+		Resolver: &pb_be.Backend_Failover{
+			Failover: &pb_be.Failover{Backends: []string{"empty", "dead", "non_secure"}},
+		},
+	},
+	&pb_be.Backend{
+		Name: "failover_without_healthy",
+		Resolver: &pb_be.Backend_Failover{
+			Failover: &pb_be.Failover{Backends: []string{"empty", "dead"}},
+		},
+	},
 }
 
 var defaultBackendCount = 5
@@ -63,12 +125,60 @@ var routeConfigs = []*pb_route.Route{
 		BackendName:        "non_secure",
 		ServiceNameMatcher: "hand_rolled.non_secure.*", // these will be used in unknownPingBackHandler-based tests
 	},
+	&pb_route.Route{
+		BackendName:        "non_secure_with_deadlines",
+		ServiceNameMatcher: "hand_rolled.deadlines.*",
+	},
+	&pb_route.Route{
+		BackendName:        "non_secure",
+		ServiceNameMatcher: "hand_rolled.mirrored.*",
+		Mirror: &pb_route.Mirror{
+			BackendName:     "mirror",
+			Percentage:      100,
+			MaxMessageBytes: 64,
+		},
+	},
+	&pb_route.Route{
+		BackendName:        "failover",
+		ServiceNameMatcher: "hand_rolled.failover.*",
+	},
+	&pb_route.Route{
+		BackendName:        "failover_without_healthy",
+		ServiceNameMatcher: "hand_rolled.failover_without_healthy.*",
+	},
 	&pb_route.Route{
 		BackendName:        "unspecified_backend",
 		ServiceNameMatcher: "bad.backend.*", // bad.backend will match a bad tests
 	},
 }
 
+var adhocConfig = []*pb_adhoc.Adhoc{
+	{
+		DnsNameMatcher: "*.pods.test.local",
+		Port: &pb_adhoc.Adhoc_Port{
+			// gRPC clients don't send the port in the authority, so it comes from SRV.
+			Srv: &pb_adhoc.Adhoc_Port_Srv{Service: "grpc"},
+			AllowedRanges: []*pb_adhoc.Adhoc_Port_Range{
+				{
+					// This will be started on local host. God knows what port it will be.
+					From: 1024,
+					To:   65535,
+				},
+			},
+		},
+	},
+	{
+		DnsNameMatcher: "*.secure.test.local",
+		Port: &pb_adhoc.Adhoc_Port{
+			Default: 80,
+			Allowed: []uint32{80},
+		},
+		Authorization: &pb_auth.ClientAuthorization{
+			Cert: &pb_auth.CertRequirement{CommonNames: []string{"nobody"}},
+		},
+	},
+}
+
 type unknownResponse struct {
 	Addr   string `protobuf:"bytes,1,opt,name=addr,json=value"`
 	Method string `protobuf:"bytes,2,opt,name=method"`
@@ -84,6 +194,14 @@ func unknownPingbackHandler(serverAddr string) grpc.StreamHandler {
 		if !ok {
 			return fmt.Errorf("handler should have access to transport info")
 		}
+		if strings.HasSuffix(tr.Method(), "/Sleep") {
+			// Sleep until the deadline, but not for long enough to hang tests if there's none.
+			select {
+			case <-stream.Context().Done():
+				return stream.Context().Err()
+			case <-time.After(1 * time.Second):
+			}
+		}
 		return stream.SendMsg(&unknownResponse{Method: tr.Method(), Addr: serverAddr})
 	}
 }
@@ -144,12 +262,16 @@ type BackendPoolIntegrationTestSuite struct {
 
 	proxy         *grpc.Server
 	proxyListener net.Listener
+	webListener   net.Listener
 	pool          backendpool.Pool
 
 	proxyConn           *grpc.ClientConn
 	originalDialFunc    func(ctx context.Context, network, address string) (net.Conn, error)
 	originalSrvResolver srv.Resolver
+	originalALookup     func(host string) ([]string, time.Duration, error)
+	originalSrvLookup   func(service, proto, name string) ([]*net.SRV, time.Duration, error)
 	localBackends       map[string]*localBackends
+	mirroredRequests    chan string
 }
 
 func TestBackendPoolIntegrationTestSuite(t *testing.T) {
@@ -165,6 +287,16 @@ func (s *BackendPoolIntegrationTestSuite) Lookup(domainName string) ([]*srv.Targ
 	return local.targets(), nil
 }
 
+// LookupAdhocSrv resolves the ports of adhoc destinations to the first non secure backend.
+func (s *BackendPoolIntegrationTestSuite) LookupAdhocSrv(service, proto, name string) ([]*net.SRV, time.Duration, error) {
+	addr := s.localBackends["_grpc._tcp.nonsecure.backends.test.local"].targets()[0].DialAddr
+	port, err := strconv.Atoi(addr[strings.LastIndex(addr, ":")+1:])
+	if err != nil {
+		return nil, 0, err
+	}
+	return []*net.SRV{{Target: name + ".", Port: uint16(port)}}, time.Minute, nil
+}
+
 func (s *BackendPoolIntegrationTestSuite) SetupSuite() {
 	var err error
 	s.proxyListener, err = net.Listen("tcp", "localhost:0")
@@ -173,15 +305,26 @@ func (s *BackendPoolIntegrationTestSuite) SetupSuite() {
 	s.originalSrvResolver = resolvers.ParentSrvResolver
 	resolvers.ParentSrvResolver = s
 	s.buildBackends()
+	// Make adhoc rules resolve to the local backends. See LookupAdhocSrv.
+	s.originalALookup = http_router.DefaultALookup
+	http_router.DefaultALookup = func(host string) ([]string, time.Duration, error) {
+		return []string{"127.0.0.1"}, time.Minute, nil
+	}
+	s.originalSrvLookup = http_router.DefaultSrvLookup
+	http_router.DefaultSrvLookup = s.LookupAdhocSrv
 
 	s.pool, err = backendpool.NewStatic(backendConfigs)
 	require.NoError(s.T(), err, "backend pool creation must not fail")
-	router := router.NewStatic(routeConfigs)
-	dir := director.New(s.pool, router)
+	addresser, err := router.NewAddresser(adhocConfig)
+	require.NoError(s.T(), err, "addresser creation must not fail")
+	router, err := router.NewStatic(routeConfigs)
+	require.NoError(s.T(), err, "router creation must not fail")
+	dir := director.New(s.pool, router, addresser)
 
 	s.proxy = grpc.NewServer(
 		grpc.CustomCodec(proxy.Codec()),
 		grpc.UnknownServiceHandler(proxy.TransparentHandler(dir)),
+		grpc.StreamInterceptor(director.MirroringStreamServerInterceptor()),
 		grpc.Creds(credentials.NewTLS(s.tlsConfigForTest())),
 	)
 
@@ -195,6 +338,13 @@ func (s *BackendPoolIntegrationTestSuite) SetupSuite() {
 		grpc.WithBlock(),
 	)
 	require.NoError(s.T(), err, "dialing the proxy on a conn *must not* fail")
+
+	s.webListener, err = net.Listen("tcp", "localhost:0")
+	require.NoError(s.T(), err, "must be able to allocate a port for webListener")
+	s.T().Logf("starting gRPC-Web proxy at: %v", s.webListener.Addr().String())
+	go func() {
+		http.Serve(s.webListener, grpcweb.Wrap(s.proxy, []string{"https://allowed.example.com"}))
+	}()
 }
 
 func (s *BackendPoolIntegrationTestSuite) buildBackends() {
@@ -205,6 +355,27 @@ func (s *BackendPoolIntegrationTestSuite) buildBackends() {
 	}
 	nonSecure.setResolvableCount(100)
 	s.localBackends["_grpc._tcp.nonsecure.backends.test.local"] = nonSecure
+	s.mirroredRequests = make(chan string, 100)
+	mirror := &localBackends{}
+	mirror.addServer(s.T(), grpc.StreamInterceptor(s.recordMirroredRequest))
+	mirror.setResolvableCount(100)
+	s.localBackends["_grpc._tcp.mirror.backends.test.local"] = mirror
+	s.localBackends["_grpc._tcp.empty.backends.test.local"] = &localBackends{}
+	dead := &localBackends{}
+	dead.addServer(s.T())
+	dead.setResolvableCount(100)
+	dead.Close() // the target stays resolvable, but refuses connections.
+	s.localBackends["_grpc._tcp.dead.backends.test.local"] = dead
+}
+
+// recordMirroredRequest is an interceptor of the mirror backends that reads the request message before the handler.
+func (s *BackendPoolIntegrationTestSuite) recordMirroredRequest(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	req := &pb_testproto.PingRequest{}
+	if err := stream.RecvMsg(req); err != nil {
+		return err
+	}
+	s.mirroredRequests <- info.FullMethod + " " + req.Value
+	return handler(srv, stream)
 }
 
 func (s *BackendPoolIntegrationTestSuite) SimpleCtx() context.Context {
@@ -218,6 +389,65 @@ func (s *BackendPoolIntegrationTestSuite) TestCallToNonSecureBackend() {
 	require.NoError(s.T(), err, "no error on simple call")
 }
 
+func (s *BackendPoolIntegrationTestSuite) TestCallReturnsBackendNameHeader() {
+	client := pb_testproto.NewTestServiceClient(s.proxyConn)
+	header := metadata.MD{}
+	_, err := client.Ping(s.SimpleCtx(), &pb_testproto.PingRequest{}, grpc.Header(&header))
+	require.NoError(s.T(), err, "no error on simple call")
+	assert.Equal(s.T(), []string{"non_secure"}, header["x-kedge-backend-name"], "the backend name must be returned in headers")
+}
+
+func (s *BackendPoolIntegrationTestSuite) TestCallWithoutDeadlineGetsDefaultDeadline() {
+	start := time.Now()
+	err := grpc.Invoke(context.Background(), "/hand_rolled.deadlines.SomeService/Sleep", &pb_testproto.Empty{}, &unknownResponse{}, s.proxyConn)
+	require.Error(s.T(), err, "call must time out")
+	assert.Equal(s.T(), codes.DeadlineExceeded, grpc.Code(err), "call must time out")
+	assert.Contains(s.T(), grpc.ErrorDesc(err), "exceeded its deadline of 100ms", "error must explain the deadline")
+	assert.True(s.T(), time.Since(start) < 500*time.Millisecond, "call must not wait for the backend")
+}
+
+func (s *BackendPoolIntegrationTestSuite) TestCallWithLongDeadlineGetsCapped() {
+	start := time.Now()
+	err := grpc.Invoke(s.SimpleCtx(), "/hand_rolled.deadlines.SomeService/Sleep", &pb_testproto.Empty{}, &unknownResponse{}, s.proxyConn)
+	require.Error(s.T(), err, "call must time out")
+	assert.Equal(s.T(), codes.DeadlineExceeded, grpc.Code(err), "call must time out")
+	assert.Contains(s.T(), grpc.ErrorDesc(err), "exceeded its deadline of 200ms", "error must explain the deadline")
+	assert.True(s.T(), time.Since(start) < 500*time.Millisecond, "call must not wait for the backend")
+}
+
+func (s *BackendPoolIntegrationTestSuite) TestCallWithShortDeadlineIsntExtended() {
+	ctx, cancel := context.WithTimeout(context.TODO(), 50*time.Millisecond)
+	defer cancel()
+	err := grpc.Invoke(ctx, "/hand_rolled.deadlines.SomeService/Sleep", &pb_testproto.Empty{}, &unknownResponse{}, s.proxyConn)
+	require.Error(s.T(), err, "call must time out")
+	assert.Equal(s.T(), codes.DeadlineExceeded, grpc.Code(err), "call must time out")
+	assert.NotContains(s.T(), grpc.ErrorDesc(err), "kedge", "the client's own deadline was exceeded")
+}
+
+func (s *BackendPoolIntegrationTestSuite) TestMirroredCallIsSentToMirror() {
+	resp := &unknownResponse{}
+	err := grpc.Invoke(s.SimpleCtx(), "/hand_rolled.mirrored.SomeService/Method", &pb_testproto.PingRequest{Value: "mirrored"}, resp, s.proxyConn)
+	require.NoError(s.T(), err, "mirrored call must not fail")
+	assert.Equal(s.T(), "/hand_rolled.mirrored.SomeService/Method", resp.Method, "the call must be handled by the backend")
+	select {
+	case got := <-s.mirroredRequests:
+		assert.Equal(s.T(), "/hand_rolled.mirrored.SomeService/Method mirrored", got, "mirror must receive a copy of the request")
+	case <-time.After(2 * time.Second):
+		s.T().Fatalf("mirror didn't receive the call")
+	}
+}
+
+func (s *BackendPoolIntegrationTestSuite) TestMirroredCallWithLargeMessageIsNotSentToMirror() {
+	resp := &unknownResponse{}
+	err := grpc.Invoke(s.SimpleCtx(), "/hand_rolled.mirrored.SomeService/Method", &pb_testproto.PingRequest{Value: strings.Repeat("a", 128)}, resp, s.proxyConn)
+	require.NoError(s.T(), err, "mirrored call must not fail")
+	select {
+	case got := <-s.mirroredRequests:
+		s.T().Fatalf("mirror must not receive calls larger than the limit, got: %v", got)
+	case <-time.After(200 * time.Millisecond):
+	}
+}
+
 func (s *BackendPoolIntegrationTestSuite) TestCallToNonSecureBackendLoadBalancesRoundRobin() {
 	backendResponse := make(map[string]int)
 	for i := 0; i < defaultBackendCount*10; i++ {
@@ -246,6 +476,167 @@ func (s *BackendPoolIntegrationTestSuite) TestCallToUnknownBackend() {
 	require.EqualError(s.T(), err, "rpc error: code = Unimplemented desc = unknown backend", "no error on simple call")
 }
 
+func (s *BackendPoolIntegrationTestSuite) TestFailoverCallIsSentToFirstHealthyBackend() {
+	before := failoverCallCount(s.T(), "failover", "non_secure", "2")
+	header := metadata.MD{}
+	resp := &unknownResponse{}
+	err := grpc.Invoke(s.SimpleCtx(), "/hand_rolled.failover.SomeService/Method", &pb_testproto.Empty{}, resp, s.proxyConn, grpc.Header(&header))
+	require.NoError(s.T(), err, "failover call must succeed")
+	assert.Equal(s.T(), []string{"non_secure"}, header["x-kedge-backend-name"], "the backend that served the call must be returned")
+	assert.Equal(s.T(), before+1, failoverCallCount(s.T(), "failover", "non_secure", "2"), "the tier must be counted")
+}
+
+func (s *BackendPoolIntegrationTestSuite) TestFailoverCallWithoutHealthyBackendsIsUnavailable() {
+	err := grpc.Invoke(s.SimpleCtx(), "/hand_rolled.failover_without_healthy.SomeService/Method", &pb_testproto.Empty{}, &unknownResponse{}, s.proxyConn)
+	require.Error(s.T(), err, "failover call must fail")
+	assert.Equal(s.T(), codes.Unavailable, grpc.Code(err))
+	assert.Contains(s.T(), grpc.ErrorDesc(err), "has no healthy backends")
+}
+
+// adhocConn dials the proxy with the authority of an adhoc destination.
+func (s *BackendPoolIntegrationTestSuite) adhocConn(authority string) *grpc.ClientConn {
+	conn, err := grpc.Dial(authority,
+		grpc.WithDialer(func(_ string, t time.Duration) (net.Conn, error) {
+			return net.DialTimeout("tcp", s.proxyListener.Addr().String(), t)
+		}),
+		grpc.WithTransportCredentials(credentials.NewTLS(&tls.Config{InsecureSkipVerify: true})),
+		grpc.WithBlock(),
+	)
+	require.NoError(s.T(), err, "dialing the proxy on a conn *must not* fail")
+	return conn
+}
+
+func (s *BackendPoolIntegrationTestSuite) TestAdhocCallIsSentToAuthority() {
+	conn := s.adhocConn("127-0-0-1.pods.test.local")
+	defer conn.Close()
+	expectedAddr := s.localBackends["_grpc._tcp.nonsecure.backends.test.local"].targets()[0].DialAddr
+	for i := 0; i < 3; i++ {
+		resp := &unknownResponse{}
+		err := grpc.Invoke(s.SimpleCtx(), "/hand_rolled.adhoc.SomeService/Method", &pb_testproto.Empty{}, resp, conn)
+		require.NoError(s.T(), err, "adhoc call must succeed")
+		assert.Equal(s.T(), expectedAddr, resp.Addr, "adhoc call must reach the backend of the authority")
+		assert.Equal(s.T(), "/hand_rolled.adhoc.SomeService/Method", resp.Method)
+	}
+}
+
+func (s *BackendPoolIntegrationTestSuite) TestAdhocCallIsDeniedWithoutAuthorization() {
+	conn := s.adhocConn("some-pod.secure.test.local")
+	defer conn.Close()
+	err := grpc.Invoke(s.SimpleCtx(), "/hand_rolled.adhoc.SomeService/Method", &pb_testproto.Empty{}, &unknownResponse{}, conn)
+	require.Error(s.T(), err, "adhoc call must be denied")
+	assert.Equal(s.T(), codes.PermissionDenied, grpc.Code(err))
+	assert.Contains(s.T(), grpc.ErrorDesc(err), "client certificate required")
+}
+
+func (s *BackendPoolIntegrationTestSuite) TestAdhocCallToUnmatchedAuthorityCausesError() {
+	conn := s.adhocConn("some-pod.other.test.local")
+	defer conn.Close()
+	err := grpc.Invoke(s.SimpleCtx(), "/hand_rolled.adhoc.SomeService/Method", &pb_testproto.Empty{}, &unknownResponse{}, conn)
+	require.EqualError(s.T(), err, "rpc error: code = Unimplemented desc = unknown route to service")
+}
+
+func (s *BackendPoolIntegrationTestSuite) grpcWebCall(method string, msg proto.Message, isText bool) (*http.Response, [][]byte) {
+	data, err := proto.Marshal(msg)
+	require.NoError(s.T(), err, "marshaling the request must not fail")
+	frame := make([]byte, 5, 5+len(data))
+	binary.BigEndian.PutUint32(frame[1:], uint32(len(data)))
+	body := append(frame, data...)
+	contentType := "application/grpc-web+proto"
+	if isText {
+		body = []byte(base64.StdEncoding.EncodeToString(body))
+		contentType = "application/grpc-web-text+proto"
+	}
+	req, _ := http.NewRequest("POST", fmt.Sprintf("http://%s%s", s.webListener.Addr().String(), method), bytes.NewReader(body))
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("Origin", "https://allowed.example.com")
+	req.Header.Set("X-Grpc-Web", "1")
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(s.T(), err, "gRPC-Web call must not fail at the HTTP level")
+	defer resp.Body.Close()
+	respBody, err := ioutil.ReadAll(resp.Body)
+	require.NoError(s.T(), err, "reading the gRPC-Web response must not fail")
+	if isText {
+		respBody = decodeChunkedBase64(s.T(), respBody)
+	}
+	frames := [][]byte{}
+	for len(respBody) >= 5 {
+		length := int(binary.BigEndian.Uint32(respBody[1:5]))
+		require.True(s.T(), len(respBody) >= 5+length, "response frame must not be truncated")
+		frames = append(frames, respBody[:5+length])
+		respBody = respBody[5+length:]
+	}
+	require.Empty(s.T(), respBody, "response must only consist of frames")
+	return resp, frames
+}
+
+// decodeChunkedBase64 decodes base64 written in separately padded chunks, as gRPC-Web text responses are.
+func decodeChunkedBase64(t *testing.T, data []byte) []byte {
+	out := []byte{}
+	for len(data) > 0 {
+		end := bytes.IndexByte(data, '=')
+		if end < 0 {
+			end = len(data)
+		}
+		for end < len(data) && data[end] == '=' {
+			end++
+		}
+		decoded, err := base64.StdEncoding.DecodeString(string(data[:end]))
+		require.NoError(t, err, "gRPC-Web text response must be valid base64")
+		out = append(out, decoded...)
+		data = data[end:]
+	}
+	return out
+}
+
+func (s *BackendPoolIntegrationTestSuite) TestGrpcWebCall() {
+	for _, isText := range []bool{false, true} {
+		resp, frames := s.grpcWebCall("/mwitkow.testproto.TestService/Ping", &pb_testproto.PingRequest{Value: "something"}, isText)
+		assert.Equal(s.T(), http.StatusOK, resp.StatusCode, "gRPC-Web calls must return 200")
+		assert.Equal(s.T(), "https://allowed.example.com", resp.Header.Get("Access-Control-Allow-Origin"))
+		require.Len(s.T(), frames, 2, "must return a message and trailers")
+		assert.Equal(s.T(), byte(0), frames[0][0], "first frame must be a message")
+		msg := &pb_testproto.PingResponse{}
+		require.NoError(s.T(), proto.Unmarshal(frames[0][5:], msg))
+		assert.Equal(s.T(), "something", msg.Value)
+		assert.Equal(s.T(), byte(0x80), frames[1][0], "last frame must be the trailers")
+		assert.Contains(s.T(), string(frames[1][5:]), "grpc-status: 0\r\n")
+	}
+}
+
+func (s *BackendPoolIntegrationTestSuite) TestGrpcWebCallReturnsErrorInTrailers() {
+	resp, frames := s.grpcWebCall(
+		"/mwitkow.testproto.TestService/PingError",
+		&pb_testproto.PingRequest{Value: "something", ErrorCodeReturned: uint32(codes.FailedPrecondition)},
+		false)
+	assert.Equal(s.T(), http.StatusOK, resp.StatusCode, "gRPC-Web errors are returned in trailers")
+	require.Len(s.T(), frames, 1, "must return only trailers")
+	assert.Equal(s.T(), byte(0x80), frames[0][0], "frame must be the trailers")
+	assert.Contains(s.T(), string(frames[0][5:]), fmt.Sprintf("grpc-status: %d\r\n", codes.FailedPrecondition))
+}
+
+func (s *BackendPoolIntegrationTestSuite) TestGrpcWebPreflight() {
+	for _, tcase := range []struct {
+		origin       string
+		expectedCode int
+	}{
+		{origin: "https://allowed.example.com", expectedCode: http.StatusOK},
+		{origin: "https://evil.example.com", expectedCode: http.StatusForbidden},
+	} {
+		req, _ := http.NewRequest("OPTIONS", fmt.Sprintf("http://%s/mwitkow.testproto.TestService/Ping", s.webListener.Addr().String()), nil)
+		req.Header.Set("Origin", tcase.origin)
+		req.Header.Set("Access-Control-Request-Method", "POST")
+		req.Header.Set("Access-Control-Request-Headers", "content-type,x-grpc-web")
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(s.T(), err, "preflight must not fail at the HTTP level")
+		resp.Body.Close()
+		assert.Equal(s.T(), tcase.expectedCode, resp.StatusCode, "preflight from %v", tcase.origin)
+		if tcase.expectedCode == http.StatusOK {
+			assert.Equal(s.T(), tcase.origin, resp.Header.Get("Access-Control-Allow-Origin"))
+			assert.Equal(s.T(), "content-type,x-grpc-web", resp.Header.Get("Access-Control-Allow-Headers"))
+		}
+	}
+}
+
 func (s *BackendPoolIntegrationTestSuite) TearDownSuite() {
 	s.proxyConn.Close()
 	s.pool.Close()
@@ -253,11 +644,18 @@ func (s *BackendPoolIntegrationTestSuite) TearDownSuite() {
 	if s.originalSrvResolver != nil {
 		resolvers.ParentSrvResolver = s.originalSrvResolver
 	}
+	if s.originalALookup != nil {
+		http_router.DefaultALookup = s.originalALookup
+		http_router.DefaultSrvLookup = s.originalSrvLookup
+	}
 	time.Sleep(10 * time.Millisecond)
 	if s.proxy != nil {
 		s.proxy.GracefulStop()
 		s.proxyListener.Close()
 	}
+	if s.webListener != nil {
+		s.webListener.Close()
+	}
 	for _, be := range s.localBackends {
 		be.Close()
 	}
@@ -291,3 +689,24 @@ func getTestingCertsPath() string {
 	_, callerPath, _, _ := runtime.Caller(0)
 	return path.Join(path.Dir(callerPath), "..", "misc")
 }
+
+// failoverCallCount returns the count of calls to the failover backend served by the tier.
+func failoverCallCount(t *testing.T, backendName string, tierBackendName string, tier string) float64 {
+	families, err := prometheus.DefaultGatherer.Gather()
+	require.NoError(t, err, "gathering metrics must not fail")
+	for _, family := range families {
+		if family.GetName() != "kedge_grpc_failover_calls_total" {
+			continue
+		}
+		for _, m := range family.Metric {
+			labels := map[string]string{}
+			for _, l := range m.Label {
+				labels[l.GetName()] = l.GetValue()
+			}
+			if labels["backend_name"] == backendName && labels["tier_backend_name"] == tierBackendName && labels["tier"] == tier {
+				return m.GetCounter().GetValue()
+			}
+		}
+	}
+	return 0
+}
@@ -9,6 +9,8 @@ import (
 
 	"net/http"
 
+	"github.com/golang/protobuf/ptypes"
+	"github.com/golang/protobuf/ptypes/duration"
 	"github.com/mwitkow/go-conntrack"
 	pb "github.com/mwitkow/kedge/_protogen/kedge/config/http/backends"
 	"github.com/mwitkow/kedge/http/lbtransport"
@@ -18,55 +20,195 @@ import (
 )
 
 var (
+	// ParentDialFunc is used for dialing all targets. The connect timeout of the backend is set on the context.
 	ParentDialFunc = (&net.Dialer{
-		Timeout:   1 * time.Second,
 		KeepAlive: 30 * time.Second,
 	}).DialContext
+
+	// LocalZone is the availability zone kedge runs in, which backends with `locality` prefer targets in.
+	LocalZone = ""
+
+	defaultConnectTimeout   = 1 * time.Second
+	defaultIdleTimeout      = 90 * time.Second
+	defaultMaxIdlePerTarget = http.DefaultMaxIdleConnsPerHost
+	defaultMinHealthyShare  = 0.5
 )
 
 type backend struct {
 	transport *http.Transport
 	tripper   http.RoundTripper
 	config    *pb.Backend
+
+	picker           targetPicker
+	warmer           *warmer
+	dialFunc         func(ctx context.Context, network, addr string) (net.Conn, error)
+	connectTimeout   time.Duration
+	scheme           string
+	upgradeTlsConfig *tls.Config
+}
+
+// targetPicker is implemented by lbtransport RoundTrippers.
+type targetPicker interface {
+	PickTarget(req *http.Request) (*lbtransport.Target, error)
+	HasTargets() bool
+	Close() error
 }
 
 func (b *backend) Tripper() http.RoundTripper {
 	return b.tripper
 }
 
+// Dial connects to a target chosen by the backend's LBPolicy, using TLS for HTTPS backends.
+func (b *backend) Dial(ctx context.Context, req *http.Request) (net.Conn, error) {
+	target, err := b.picker.PickTarget(req)
+	if err != nil {
+		return nil, err
+	}
+	conn, _, err := b.dialTarget(ctx, target.DialAddr, b.upgradeTlsConfig)
+	return conn, err
+}
+
+// dialTarget connects to the target, and does the TLS handshake if tlsConfig isn't nil. Both share the connect timeout.
+// The raw TCP connection is returned too, which is the same as conn without TLS.
+func (b *backend) dialTarget(ctx context.Context, addr string, tlsConfig *tls.Config) (conn net.Conn, raw net.Conn, err error) {
+	ctx, cancel := context.WithTimeout(ctx, b.connectTimeout)
+	defer cancel()
+	raw, err = b.dialFunc(ctx, "tcp", addr)
+	if err != nil || tlsConfig == nil {
+		return raw, raw, err
+	}
+	tlsConn := tls.Client(raw, tlsConfig)
+	deadline, _ := ctx.Deadline()
+	raw.SetDeadline(deadline)
+	if err := tlsConn.Handshake(); err != nil {
+		raw.Close()
+		return nil, nil, err
+	}
+	raw.SetDeadline(time.Time{})
+	return tlsConn, raw, nil
+}
+
 func (b *backend) Close() error {
 	// TODO(mwitkow): Return tripper errors when stuff's closed.
+	b.picker.Close()
+	if b.warmer != nil {
+		b.warmer.Close()
+	}
 	b.transport.CloseIdleConnections()
 	return nil
 }
 
 func newBackend(cnf *pb.Backend) (*backend, error) {
-	b := &backend{}
+	b := &backend{config: cnf}
 	target, resolver, err := chooseNamingResolver(cnf)
 	if err != nil {
 		return nil, err
 	}
+	gracePeriod, err := DurationOrDefault(cnf.GetResolverGracePeriod(), resolvers.DefaultGracePeriod)
+	if err != nil {
+		return nil, fmt.Errorf("bad resolver grace period: %v", err)
+	} else if gracePeriod < 0 {
+		return nil, fmt.Errorf("negative resolver grace period")
+	}
+	resolver = resolvers.Resilient("http", cnf.Name, resolver, gracePeriod)
 	scheme, tlsConfig := buildTls(cnf)
+	connectTimeout, err := DurationOrDefault(cnf.GetTimeouts().GetConnect(), defaultConnectTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("bad connect timeout: %v", err)
+	}
+	responseHeaderTimeout, err := DurationOrDefault(cnf.GetTimeouts().GetResponseHeader(), 0)
+	if err != nil {
+		return nil, fmt.Errorf("bad response header timeout: %v", err)
+	}
+	idleTimeout, err := DurationOrDefault(cnf.GetTimeouts().GetIdle(), defaultIdleTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("bad idle timeout: %v", err)
+	}
+	maxIdlePerTarget := defaultMaxIdlePerTarget
+	if pool := cnf.GetConnectionPool(); pool != nil && pool.MaxIdlePerTarget > 0 {
+		maxIdlePerTarget = int(pool.MaxIdlePerTarget)
+	}
+	b.scheme = scheme
+	b.connectTimeout = connectTimeout
+	b.dialFunc = chooseDialFuncOpt(cnf, connectTimeout)
+	if scheme == "https" {
+		// Upgraded connections need HTTP/1.1, and the tlsConfig is changed to prefer h2 below.
+		_, b.upgradeTlsConfig = buildTls(cnf)
+		b.upgradeTlsConfig.NextProtos = []string{"http/1.1"}
+	}
 	b.transport = &http.Transport{
-		DialContext:     chooseDialFuncOpt(cnf),
-		TLSClientConfig: tlsConfig,
-		// TODO(mwitkow): add idle conn configuration.
+		DialContext:           b.dialFunc,
+		TLSClientConfig:       tlsConfig,
+		ResponseHeaderTimeout: responseHeaderTimeout,
+		IdleConnTimeout:       idleTimeout,
+		MaxIdleConnsPerHost:   maxIdlePerTarget,
+		MaxIdleConns:          int(cnf.GetConnectionPool().GetMaxIdle()),
 	}
 	// We want there to be h2 on outbound SSL connections, this mangles tlsConfig
 	if err := http2.ConfigureTransport(b.transport); err != nil {
 		return nil, err
 	}
-	b.tripper, err = lbtransport.New(target, b.transport, resolver, chooseBalancerPolicy(cnf))
+	if scheme == "https" {
+		// TLS handshakes are done by dialTarget, so that they share the connect timeout with dialing. Their TLS config
+		// negotiates h2 since http2.ConfigureTransport, which the transport picks up on the connections.
+		b.transport.DialTLS = func(network, addr string) (net.Conn, error) {
+			conn, _, err := b.dialTarget(context.Background(), addr, b.transport.TLSClientConfig)
+			return conn, err
+		}
+	}
+	policy, err := chooseBalancerPolicy(cnf)
+	if err != nil {
+		return nil, err
+	}
+	lbTripper, err := lbtransport.New(target, b.transport, resolver, policy)
 	if err != nil {
 		return nil, err
 	}
+	b.picker = lbTripper
+	if pool := cnf.GetConnectionPool(); pool != nil && pool.WarmPerTarget > 0 {
+		b.warmUp(int(pool.WarmPerTarget), idleTimeout, lbTripper.Targets)
+	}
+	b.tripper = lbTripper
 	b.tripper = buildTripperMiddlewareChain(cnf, b.tripper)
 	b.tripper = &schemeTripper{expectedScheme: scheme, parent: b.tripper}
 	return b, nil
 }
 
-func chooseDialFuncOpt(cnf *pb.Backend) func(ctx context.Context, network, addr string) (net.Conn, error) {
-	dialFunc := ParentDialFunc
+// warmUp makes the transport take connections from a warmer, which are established like the transport would.
+func (b *backend) warmUp(perTarget int, idleTimeout time.Duration, targets func() []*lbtransport.Target) {
+	var tlsConfig *tls.Config
+	if b.scheme == "https" {
+		tlsConfig = b.transport.TLSClientConfig
+	}
+	dial := func(ctx context.Context, addr string) (net.Conn, net.Conn, error) {
+		return b.dialTarget(ctx, addr, tlsConfig)
+	}
+	b.warmer = newWarmer(b.config.Name, perTarget, idleTimeout, dial, targets)
+	b.warmer.start()
+	if b.scheme == "https" {
+		b.transport.DialTLS = func(network, addr string) (net.Conn, error) {
+			if conn := b.warmer.take(addr); conn != nil {
+				return conn, nil
+			}
+			conn, _, err := dial(context.Background(), addr)
+			return conn, err
+		}
+	} else {
+		b.transport.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+			if conn := b.warmer.take(addr); conn != nil {
+				return conn, nil
+			}
+			return b.dialFunc(ctx, network, addr)
+		}
+	}
+}
+
+func chooseDialFuncOpt(cnf *pb.Backend, connectTimeout time.Duration) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	dialFunc := func(ctx context.Context, network, addr string) (net.Conn, error) {
+		ctx, cancel := context.WithTimeout(ctx, connectTimeout)
+		defer cancel()
+		return ParentDialFunc(ctx, network, addr)
+	}
 	if !cnf.DisableConntracking {
 		dialFunc = conntrack.NewDialContextFunc(
 			conntrack.DialWithName("backend_"+cnf.Name),
@@ -77,6 +219,14 @@ func chooseDialFuncOpt(cnf *pb.Backend) func(ctx context.Context, network, addr
 	return dialFunc
 }
 
+// DurationOrDefault parses the duration of a config, which is def if not set.
+func DurationOrDefault(d *duration.Duration, def time.Duration) (time.Duration, error) {
+	if d == nil {
+		return def, nil
+	}
+	return ptypes.Duration(d)
+}
+
 func buildTls(cnf *pb.Backend) (scheme string, tlsConfig *tls.Config) {
 	if sec := cnf.GetSecurity(); sec != nil {
 		tlsConfig = &tls.Config{InsecureSkipVerify: true}
@@ -97,21 +247,45 @@ func buildTripperMiddlewareChain(cnf *pb.Backend, parent http.RoundTripper) http
 func chooseNamingResolver(cnf *pb.Backend) (string, naming.Resolver, error) {
 	if s := cnf.GetSrv(); s != nil {
 		return resolvers.NewSrvFromConfig(s)
+	} else if k := cnf.GetK8S(); k != nil && k.EndpointSlices {
+		return resolvers.NewK8sFromConfig(k)
 	} else if k := cnf.GetK8S(); k != nil {
 		// TODO(mwitkow): Deal with HTTP URLs to resolver for K8s. It sets a target==kubernetes://.
 		// This needs to be done in lbtransport validation of targets.
 		return "", nil, fmt.Errorf("Kubernetes resolution is not supported at the moment for HTTP targets.")
+	} else if st := cnf.GetStatic(); st != nil {
+		return resolvers.NewStaticFromConfig(st)
+	} else if d := cnf.GetDns(); d != nil {
+		return resolvers.NewDnsFromConfig(d)
+	} else if f := cnf.GetFile(); f != nil {
+		return resolvers.NewFileFromConfig(f)
+	} else if c := cnf.GetConsul(); c != nil {
+		return resolvers.NewConsulFromConfig(c)
 	}
 	return "", nil, fmt.Errorf("unspecified naming resolver for %v", cnf.Name)
 }
 
-func chooseBalancerPolicy(cnf *pb.Backend) lbtransport.LBPolicy {
+func chooseBalancerPolicy(cnf *pb.Backend) (lbtransport.LBPolicy, error) {
+	var policy lbtransport.LBPolicy
 	switch cnf.GetBalancer() {
 	case pb.Balancer_ROUND_ROBIN:
-		return lbtransport.RoundRobinPolicy()
+		policy = lbtransport.RoundRobinPolicy()
 	default:
-		return lbtransport.RoundRobinPolicy()
+		policy = lbtransport.RoundRobinPolicy()
+	}
+	if loc := cnf.GetLocality(); loc != nil {
+		if LocalZone == "" {
+			return nil, fmt.Errorf("locality needs the zone of kedge to be set")
+		}
+		share := float64(loc.MinHealthyShare)
+		if share < 0 || share > 1 {
+			return nil, fmt.Errorf("locality min_healthy_share must be between 0 and 1")
+		} else if share == 0 {
+			share = defaultMinHealthyShare
+		}
+		policy = lbtransport.ZonePreferringPolicy(LocalZone, share, policy)
 	}
+	return policy, nil
 }
 
 // schemeTripper rewrites the request's proto scheme to enforce the backend properties
@@ -0,0 +1,19 @@
+package backendpool
+
+import (
+	"testing"
+
+	"github.com/golang/protobuf/ptypes/duration"
+	pb_res "github.com/mwitkow/kedge/_protogen/kedge/config/common/resolvers"
+	pb "github.com/mwitkow/kedge/_protogen/kedge/config/http/backends"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBackendRejectsNegativeResolverGracePeriod(t *testing.T) {
+	_, err := newBackend(&pb.Backend{
+		Name:                "negative",
+		Resolver:            &pb.Backend_Static{Static: &pb_res.StaticResolver{Addresses: []*pb_res.StaticAddress{{Address: "127.0.0.1:80"}}}},
+		ResolverGracePeriod: &duration.Duration{Seconds: -1},
+	})
+	assert.Error(t, err, "negative grace periods must be rejected")
+}
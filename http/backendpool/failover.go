@@ -0,0 +1,152 @@
+package backendpool
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+
+	pb "github.com/mwitkow/kedge/_protogen/kedge/config/http/backends"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	failoverRequestsCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "kedge",
+			Subsystem: "http",
+			Name:      "failover_requests_total",
+			Help:      "Count of requests to failover backends, by the backend that served them and its tier (0 is the first).",
+		}, []string{"backend_name", "tier_backend_name", "tier"})
+)
+
+func init() {
+	prometheus.MustRegister(failoverRequestsCounter)
+}
+
+type backendReporterKey struct{}
+
+// WithBackendReporter returns a context that makes failover backends call reportFunc with the name of the backend that
+// serves the request, before the response is returned.
+func WithBackendReporter(ctx context.Context, reportFunc func(backendName string)) context.Context {
+	return context.WithValue(ctx, backendReporterKey{}, reportFunc)
+}
+
+func reportBackend(ctx context.Context, backendName string) {
+	if reportFunc, ok := ctx.Value(backendReporterKey{}).(func(string)); ok {
+		reportFunc(backendName)
+	}
+}
+
+// failover sends requests to the first of its tiers that has targets, falling back to the next tiers when none of the
+// targets can be dialed.
+type failover struct {
+	name  string
+	tiers []*backend
+}
+
+func newFailover(cnf *pb.Backend, backends map[string]*backend) (*failover, error) {
+	f := &failover{name: cnf.Name}
+	for _, name := range cnf.GetFailover().Backends {
+		be, ok := backends[name]
+		if !ok {
+			return nil, fmt.Errorf("failover backend '%v' is not a backend with a resolver", name)
+		}
+		f.tiers = append(f.tiers, be)
+	}
+	if len(f.tiers) == 0 {
+		return nil, fmt.Errorf("failover needs at least one backend")
+	}
+	return f, nil
+}
+
+func (f *failover) RoundTrip(req *http.Request) (*http.Response, error) {
+	body := &unclosedBody{ReadCloser: req.Body}
+	lastErr := fmt.Errorf("no targets available")
+	for i, be := range f.tiers {
+		if !be.picker.HasTargets() {
+			lastErr = fmt.Errorf("backend '%v' has no targets", be.config.Name)
+			continue
+		}
+		tierReq := new(http.Request)
+		*tierReq = *req // shallow copy
+		tierUrl := *req.URL
+		tierReq.URL = &tierUrl
+		if req.Body != nil {
+			// The transport closes the body on errors, which mustn't prevent sending it to the next tier.
+			tierReq.Body = body
+		}
+		resp, err := be.Tripper().RoundTrip(tierReq)
+		if err != nil && isDialError(err) && !body.wasRead() {
+			lastErr = err
+			continue
+		}
+		if err == nil {
+			f.served(req.Context(), i, be)
+		}
+		return resp, err
+	}
+	return nil, fmt.Errorf("failover backend '%v' has no available backends, last error: %v", f.name, lastErr)
+}
+
+// Dial connects to a target of the first tier that has targets and can be dialed.
+func (f *failover) Dial(ctx context.Context, req *http.Request) (net.Conn, error) {
+	lastErr := fmt.Errorf("no targets available")
+	for i, be := range f.tiers {
+		if !be.picker.HasTargets() {
+			lastErr = fmt.Errorf("backend '%v' has no targets", be.config.Name)
+			continue
+		}
+		conn, err := be.Dial(ctx, req)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		f.served(ctx, i, be)
+		return conn, nil
+	}
+	return nil, fmt.Errorf("failover backend '%v' has no available backends, last error: %v", f.name, lastErr)
+}
+
+func (f *failover) served(ctx context.Context, tier int, be *backend) {
+	failoverRequestsCounter.WithLabelValues(f.name, be.config.Name, strconv.Itoa(tier)).Inc()
+	reportBackend(ctx, be.config.Name)
+}
+
+// isDialError returns true for errors of requests that couldn't connect to their target, and were never sent.
+func isDialError(err error) bool {
+	opErr, ok := err.(*net.OpError)
+	return ok && opErr.Op == "dial"
+}
+
+// unclosedBody ignores Close, as the server closes the request body once the handler returns, and records whether it
+// was read.
+type unclosedBody struct {
+	io.ReadCloser
+
+	mu   sync.Mutex
+	read bool
+}
+
+func (b *unclosedBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if n > 0 {
+		b.mu.Lock()
+		b.read = true
+		b.mu.Unlock()
+	}
+	return n, err
+}
+
+func (b *unclosedBody) Close() error {
+	return nil
+}
+
+func (b *unclosedBody) wasRead() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.read
+}
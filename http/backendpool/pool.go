@@ -1,7 +1,9 @@
 package backendpool
 
 import (
+	"context"
 	"fmt"
+	"net"
 
 	pb "github.com/mwitkow/kedge/_protogen/kedge/config/http/backends"
 	"google.golang.org/grpc"
@@ -15,31 +17,67 @@ var (
 
 type Pool interface {
 	// Tripper returns an already established http.RoundTripper just for this backend.
+	//
+	// The tripper of a failover backend reports the backend that served the request to the function set on the
+	// request's context with WithBackendReporter.
 	Tripper(backendName string) (http.RoundTripper, error)
+
+	// Dial returns a connection to a target of the backend chosen by its load balancing policy. The connection uses
+	// TLS for HTTPS backends. It is meant for requests that can't go through the Tripper, e.g. Upgrades.
+	Dial(ctx context.Context, backendName string, req *http.Request) (net.Conn, error)
 }
 
 // static is a Pool with a static configuration.
 type static struct {
-	backends map[string]*backend
+	backends  map[string]*backend
+	failovers map[string]*failover
 }
 
 // NewStatic creates a backend pool that has static configuration.
 func NewStatic(backends []*pb.Backend) (Pool, error) {
-	s := &static{backends: make(map[string]*backend)}
+	s := &static{backends: make(map[string]*backend), failovers: make(map[string]*failover)}
 	for _, beCnf := range backends {
+		if beCnf.GetFailover() != nil {
+			continue
+		}
 		be, err := newBackend(beCnf)
 		if err != nil {
 			return nil, fmt.Errorf("failed creating backend '%v': %v", beCnf.Name, err)
 		}
 		s.backends[beCnf.Name] = be
 	}
+	// Failovers refer to the other backends, regardless of their order in the config.
+	for _, beCnf := range backends {
+		if beCnf.GetFailover() == nil {
+			continue
+		}
+		f, err := newFailover(beCnf, s.backends)
+		if err != nil {
+			return nil, fmt.Errorf("failed creating backend '%v': %v", beCnf.Name, err)
+		}
+		s.failovers[beCnf.Name] = f
+	}
 	return s, nil
 }
 
 func (s *static) Tripper(backendName string) (http.RoundTripper, error) {
+	if f, ok := s.failovers[backendName]; ok {
+		return f, nil
+	}
 	be, ok := s.backends[backendName]
 	if !ok {
 		return nil, ErrUnknownBackend
 	}
 	return be.Tripper(), nil
 }
+
+func (s *static) Dial(ctx context.Context, backendName string, req *http.Request) (net.Conn, error) {
+	if f, ok := s.failovers[backendName]; ok {
+		return f.Dial(ctx, req)
+	}
+	be, ok := s.backends[backendName]
+	if !ok {
+		return nil, ErrUnknownBackend
+	}
+	return be.Dial(ctx, req)
+}
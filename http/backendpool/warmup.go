@@ -0,0 +1,246 @@
+package backendpool
+
+import (
+	"context"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/mwitkow/kedge/http/lbtransport"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	// WarmupRefreshInterval is how often backends replace the spare connections that were used, or that their targets
+	// closed.
+	WarmupRefreshInterval = 1 * time.Second
+
+	// WarmupDialBackoff is how long backends wait before dialing spare connections to a target again after failing
+	// to. It doubles with every consecutive failure, up to WarmupMaxDialBackoff.
+	WarmupDialBackoff    = 1 * time.Second
+	WarmupMaxDialBackoff = 1 * time.Minute
+
+	warmConnsGauge = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "kedge",
+			Subsystem: "http",
+			Name:      "backend_warm_connections",
+			Help:      "Number of spare connections kept open to the targets of backends.",
+		}, []string{"backend_name"})
+	warmConnsUsedCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "kedge",
+			Subsystem: "http",
+			Name:      "backend_warm_connections_used_total",
+			Help:      "Count of spare connections used by requests to backends, instead of dialing.",
+		}, []string{"backend_name"})
+)
+
+func init() {
+	prometheus.MustRegister(warmConnsGauge, warmConnsUsedCounter)
+}
+
+type warmConn struct {
+	net.Conn
+	// raw is the TCP connection under the TLS one of HTTPS backends, or the same as Conn.
+	raw    net.Conn
+	dialed time.Time
+}
+
+type dialBackoff struct {
+	wait  time.Duration
+	until time.Time
+}
+
+// warmer keeps spare connections open to each target of a backend, which its transport takes instead of dialing.
+// Spares are kept until the idle timeout of the backend, or until their targets close them.
+//
+// HTTP2 transports send all requests to a target over the connection they took, so with HTTP2 targets a spare is
+// only taken again once that connection is gone.
+type warmer struct {
+	name        string
+	perTarget   int
+	idleTimeout time.Duration
+	// dial establishes connections, including their TLS handshakes for HTTPS backends, returning the TCP connections
+	// under them too.
+	dial    func(ctx context.Context, addr string) (conn net.Conn, raw net.Conn, err error)
+	targets func() []*lbtransport.Target
+
+	refreshInterval time.Duration
+	backoff         time.Duration
+	maxBackoff      time.Duration
+
+	mu       sync.Mutex
+	conns    map[string][]*warmConn
+	dialing  map[string]int
+	backoffs map[string]*dialBackoff
+	refill   chan struct{}
+	ctx      context.Context
+	cancel   context.CancelFunc
+}
+
+// newWarmer creates a warmer that starts keeping spares once started.
+func newWarmer(name string, perTarget int, idleTimeout time.Duration, dial func(ctx context.Context, addr string) (net.Conn, net.Conn, error), targets func() []*lbtransport.Target) *warmer {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &warmer{
+		name:            name,
+		perTarget:       perTarget,
+		idleTimeout:     idleTimeout,
+		dial:            dial,
+		targets:         targets,
+		refreshInterval: WarmupRefreshInterval,
+		backoff:         WarmupDialBackoff,
+		maxBackoff:      WarmupMaxDialBackoff,
+		conns:           make(map[string][]*warmConn),
+		dialing:         make(map[string]int),
+		backoffs:        make(map[string]*dialBackoff),
+		refill:          make(chan struct{}, 1),
+		ctx:             ctx,
+		cancel:          cancel,
+	}
+}
+
+func (w *warmer) start() {
+	go w.run()
+}
+
+// take returns a spare connection to the target, or nil if there is none.
+func (w *warmer) take(addr string) net.Conn {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for len(w.conns[addr]) > 0 {
+		conn := w.conns[addr][0]
+		w.conns[addr] = w.conns[addr][1:]
+		select {
+		case w.refill <- struct{}{}:
+		default:
+		}
+		if !w.usable(conn, time.Now()) {
+			conn.Close()
+			continue
+		}
+		warmConnsUsedCounter.WithLabelValues(w.name).Inc()
+		w.updateGaugeLocked()
+		return conn.Conn
+	}
+	w.updateGaugeLocked()
+	return nil
+}
+
+// usable tells whether the spare connection is within the idle timeout and still open.
+func (w *warmer) usable(conn *warmConn, now time.Time) bool {
+	if w.idleTimeout > 0 && now.Sub(conn.dialed) >= w.idleTimeout {
+		return false
+	}
+	return spareUsable(conn.raw, conn.raw != conn.Conn)
+}
+
+func (w *warmer) run() {
+	for {
+		w.replenish()
+		select {
+		case <-w.ctx.Done():
+			return
+		case <-w.refill:
+		case <-time.After(w.refreshInterval):
+		}
+	}
+}
+
+// replenish closes the spare connections of targets that are gone or that can't be used anymore, and dials the
+// missing ones, unless dialing the target recently failed or the warmer is closed.
+func (w *warmer) replenish() {
+	wanted := make(map[string]bool)
+	for _, t := range w.targets() {
+		wanted[t.DialAddr] = true
+	}
+	now := time.Now()
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.ctx.Err() != nil {
+		return
+	}
+	for addr, conns := range w.conns {
+		kept := []*warmConn{}
+		for _, c := range conns {
+			if wanted[addr] && w.usable(c, now) {
+				kept = append(kept, c)
+			} else {
+				c.Close()
+			}
+		}
+		w.conns[addr] = kept
+		if len(kept) == 0 {
+			delete(w.conns, addr)
+		}
+	}
+	for addr := range w.backoffs {
+		if !wanted[addr] {
+			delete(w.backoffs, addr)
+		}
+	}
+	for addr := range wanted {
+		if b, ok := w.backoffs[addr]; ok && now.Before(b.until) {
+			continue
+		}
+		for i := len(w.conns[addr]) + w.dialing[addr]; i < w.perTarget; i++ {
+			w.dialing[addr]++
+			go w.dialSpare(addr)
+		}
+	}
+	w.updateGaugeLocked()
+}
+
+func (w *warmer) dialSpare(addr string) {
+	conn, raw, err := w.dial(w.ctx, addr)
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.dialing[addr]--
+	if w.dialing[addr] == 0 {
+		delete(w.dialing, addr)
+	}
+	if err != nil {
+		b, ok := w.backoffs[addr]
+		if !ok {
+			b = &dialBackoff{wait: w.backoff}
+			w.backoffs[addr] = b
+		} else if b.wait *= 2; b.wait > w.maxBackoff {
+			b.wait = w.maxBackoff
+		}
+		b.until = time.Now().Add(b.wait)
+		return
+	}
+	delete(w.backoffs, addr)
+	if w.ctx.Err() != nil {
+		conn.Close()
+		return
+	}
+	w.conns[addr] = append(w.conns[addr], &warmConn{Conn: conn, raw: raw, dialed: time.Now()})
+	w.updateGaugeLocked()
+}
+
+// updateGaugeLocked sets the gauge of the spares, unless the warmer is closed and deleted it.
+func (w *warmer) updateGaugeLocked() {
+	if w.ctx.Err() != nil {
+		return
+	}
+	count := 0
+	for _, conns := range w.conns {
+		count += len(conns)
+	}
+	warmConnsGauge.WithLabelValues(w.name).Set(float64(count))
+}
+
+// Close closes the spare connections, and cancels the ones being dialed.
+func (w *warmer) Close() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.cancel()
+	for _, conns := range w.conns {
+		for _, c := range conns {
+			c.Close()
+		}
+	}
+	w.conns = make(map[string][]*warmConn)
+	warmConnsGauge.DeleteLabelValues(w.name)
+}
@@ -0,0 +1,12 @@
+//go:build !unix
+// +build !unix
+
+package backendpool
+
+import "net"
+
+// spareUsable can't tell whether targets closed spare connections on this platform, which transports find out about
+// instead.
+func spareUsable(raw net.Conn, secure bool) bool {
+	return true
+}
@@ -0,0 +1,154 @@
+package backendpool
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mwitkow/kedge/http/lbtransport"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// acceptingListener keeps the connections it accepts, for tests to close them like targets do.
+type acceptingListener struct {
+	net.Listener
+
+	mu    sync.Mutex
+	conns []net.Conn
+}
+
+func newAcceptingListener(t *testing.T) *acceptingListener {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	l := &acceptingListener{Listener: listener}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			l.mu.Lock()
+			l.conns = append(l.conns, conn)
+			l.mu.Unlock()
+		}
+	}()
+	return l
+}
+
+func (l *acceptingListener) accepted() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return len(l.conns)
+}
+
+func (l *acceptingListener) closeAccepted() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, c := range l.conns {
+		c.Close()
+	}
+}
+
+func (w *warmer) spares(addr string) int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return len(w.conns[addr])
+}
+
+func eventually(condition func() bool) bool {
+	for i := 0; i < 100 && !condition(); i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	return condition()
+}
+
+func targetsOf(addrs ...string) func() []*lbtransport.Target {
+	return func() []*lbtransport.Target {
+		targets := []*lbtransport.Target{}
+		for _, addr := range addrs {
+			targets = append(targets, &lbtransport.Target{DialAddr: addr})
+		}
+		return targets
+	}
+}
+
+func TestWarmerKeepsSparesUntilTargetsCloseThem(t *testing.T) {
+	listener := newAcceptingListener(t)
+	defer listener.Close()
+	addr := listener.Addr().String()
+	dial := func(ctx context.Context, addr string) (net.Conn, net.Conn, error) {
+		conn, err := (&net.Dialer{}).DialContext(ctx, "tcp", addr)
+		return conn, conn, err
+	}
+	w := newWarmer("test_keeps", 2, time.Minute, dial, targetsOf(addr))
+	w.refreshInterval = 10 * time.Millisecond
+	w.start()
+	defer w.Close()
+
+	require.True(t, eventually(func() bool { return w.spares(addr) == 2 }), "spares must be dialed")
+	time.Sleep(10 * w.refreshInterval)
+	assert.Equal(t, 2, listener.accepted(), "open spares must not be dialed again")
+
+	listener.closeAccepted()
+	require.True(t, eventually(func() bool { return listener.accepted() == 4 }), "spares closed by the target must be dialed again")
+	require.True(t, eventually(func() bool { return w.spares(addr) == 2 }))
+	conn := w.take(addr)
+	require.NotNil(t, conn, "open spares must be taken")
+	conn.SetReadDeadline(time.Now().Add(10 * time.Millisecond))
+	_, err := conn.Read(make([]byte, 1))
+	netErr, ok := err.(net.Error)
+	assert.True(t, ok && netErr.Timeout(), "taken spares must be open, got %v", err)
+	conn.Close()
+}
+
+func TestWarmerBacksOffFailedDials(t *testing.T) {
+	var mu sync.Mutex
+	dials := 0
+	dial := func(ctx context.Context, addr string) (net.Conn, net.Conn, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		dials++
+		return nil, nil, errors.New("test dial error")
+	}
+	w := newWarmer("test_backoff", 1, time.Minute, dial, targetsOf("10.0.0.1:80"))
+	w.refreshInterval = 5 * time.Millisecond
+	w.backoff = 100 * time.Millisecond
+	w.start()
+	defer w.Close()
+
+	time.Sleep(250 * time.Millisecond)
+	mu.Lock()
+	defer mu.Unlock()
+	// Dials at 0, after 100ms, and after another 200ms.
+	assert.Equal(t, 2, dials, "failed targets must be dialed again only after a backoff")
+}
+
+func TestWarmerCloseCancelsDials(t *testing.T) {
+	dialed := make(chan error, 2)
+	dial := func(ctx context.Context, addr string) (net.Conn, net.Conn, error) {
+		<-ctx.Done()
+		dialed <- ctx.Err()
+		return nil, nil, ctx.Err()
+	}
+	w := newWarmer("test_close", 1, time.Minute, dial, targetsOf("10.0.0.1:80"))
+	w.start()
+	time.Sleep(10 * time.Millisecond)
+	w.Close()
+	select {
+	case err := <-dialed:
+		assert.Equal(t, context.Canceled, err)
+	case <-time.After(time.Second):
+		t.Fatal("closing the warmer must cancel its dials")
+	}
+
+	w.replenish()
+	select {
+	case <-dialed:
+		t.Fatal("closed warmers must not dial")
+	case <-time.After(10 * time.Millisecond):
+	}
+}
@@ -0,0 +1,37 @@
+//go:build unix
+// +build unix
+
+package backendpool
+
+import (
+	"net"
+	"syscall"
+)
+
+// spareUsable tells whether the spare connection can be handed to a transport, peeking at the socket of its raw TCP
+// connection without consuming anything. Targets that closed the connection make it unusable. So does data sent by
+// plain HTTP targets, which would be mistaken for the response, unlike TLS targets that send session tickets and HTTP2
+// settings early.
+func spareUsable(raw net.Conn, secure bool) bool {
+	sysConn, ok := raw.(syscall.Conn)
+	if !ok {
+		return true
+	}
+	rawConn, err := sysConn.SyscallConn()
+	if err != nil {
+		return false
+	}
+	usable := false
+	err = rawConn.Read(func(fd uintptr) bool {
+		var buf [1]byte
+		n, _, err := syscall.Recvfrom(int(fd), buf[:], syscall.MSG_PEEK|syscall.MSG_DONTWAIT)
+		switch {
+		case err == syscall.EAGAIN || err == syscall.EWOULDBLOCK:
+			usable = true
+		case err == nil && n > 0:
+			usable = secure
+		}
+		return true
+	})
+	return err == nil && usable
+}
@@ -0,0 +1,239 @@
+package director
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/mwitkow/go-conntrack"
+	pb "github.com/mwitkow/kedge/_protogen/kedge/config/http/routes"
+	"github.com/mwitkow/kedge/http/backendpool"
+	"github.com/mwitkow/kedge/http/director/router"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	// AdhocParentDialFunc is used for dialing all adhoc destinations. The connect timeout of the rule is set on the
+	// context.
+	AdhocParentDialFunc = (&net.Dialer{
+		KeepAlive: 30 * time.Second,
+	}).DialContext
+
+	defaultAdhocConnectTimeout = 10 * time.Second
+	defaultAdhocIdleTimeout    = 90 * time.Second
+	defaultAdhocMaxIdlePerHost = http.DefaultMaxIdleConnsPerHost
+
+	adhocRequestsCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "kedge",
+			Subsystem: "http",
+			Name:      "adhoc_requests_total",
+			Help:      "Count of requests sent to adhoc destinations, by the dns_name_matcher of the adhoc rule and response code.",
+		}, []string{"adhoc_rule", "code"})
+)
+
+func init() {
+	prometheus.MustRegister(adhocRequestsCounter)
+}
+
+type adhocDestinationKey struct{}
+
+// adhocTripper sends requests to adhoc destinations using the transport of their rule.
+//
+// The destination chosen by the AdhocAddresser is passed in the context of the request.
+type adhocTripper struct {
+	mu         sync.Mutex
+	transports map[*pb.Adhoc]*adhocTransport
+}
+
+// adhocTransport keeps the connections to the destinations of one adhoc rule.
+type adhocTransport struct {
+	transport      *http.Transport
+	dialFunc       func(ctx context.Context, network, addr string) (net.Conn, error)
+	connectTimeout time.Duration
+}
+
+func newAdhocTripper() *adhocTripper {
+	return &adhocTripper{transports: make(map[*pb.Adhoc]*adhocTransport)}
+}
+
+func (t *adhocTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	dest := req.Context().Value(adhocDestinationKey{}).(*router.AdhocDestination)
+	transport, err := t.forDestination(dest)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := transport.transport.RoundTrip(req)
+	code := "error"
+	if err == nil {
+		code = strconv.Itoa(resp.StatusCode)
+	}
+	adhocRequestsCounter.WithLabelValues(dest.Rule.DnsNameMatcher, code).Inc()
+	return resp, err
+}
+
+// Dial connects to the destination, using TLS if needed. It is meant for requests that can't go through the
+// RoundTrip, e.g. Upgrades.
+func (t *adhocTripper) Dial(ctx context.Context, dest *router.AdhocDestination) (net.Conn, error) {
+	transport, err := t.forDestination(dest)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := transport.dialFunc(context.WithValue(ctx, adhocDestinationKey{}, dest), "tcp", dest.Host)
+	if err != nil {
+		return nil, err
+	}
+	if dest.TlsConfig == nil {
+		return conn, nil
+	}
+	serverName, _, _ := net.SplitHostPort(dest.Host)
+	// Upgraded connections need HTTP/1.1.
+	tlsConn := tls.Client(conn, &tls.Config{
+		Certificates:       dest.TlsConfig.Certificates,
+		RootCAs:            dest.TlsConfig.RootCAs,
+		InsecureSkipVerify: dest.TlsConfig.InsecureSkipVerify,
+		MinVersion:         dest.TlsConfig.MinVersion,
+		ServerName:         serverName,
+		NextProtos:         []string{"http/1.1"},
+	})
+	tlsConn.SetDeadline(time.Now().Add(transport.connectTimeout))
+	if err := tlsConn.Handshake(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	tlsConn.SetDeadline(time.Time{})
+	return tlsConn, nil
+}
+
+// forDestination returns the transport of the rule of the destination, creating it on first use.
+func (t *adhocTripper) forDestination(dest *router.AdhocDestination) (*adhocTransport, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if transport, ok := t.transports[dest.Rule]; ok {
+		return transport, nil
+	}
+	transport, err := newAdhocTransport(dest.Rule, dest.TlsConfig)
+	if err != nil {
+		return nil, err
+	}
+	t.transports[dest.Rule] = transport
+	return transport, nil
+}
+
+func newAdhocTransport(rule *pb.Adhoc, tlsConfig *tls.Config) (*adhocTransport, error) {
+	connectTimeout, err := backendpool.DurationOrDefault(rule.GetTimeouts().GetConnect(), defaultAdhocConnectTimeout)
+	if err != nil {
+		return nil, err
+	}
+	responseHeaderTimeout, err := backendpool.DurationOrDefault(rule.GetTimeouts().GetResponseHeader(), 0)
+	if err != nil {
+		return nil, err
+	}
+	idleTimeout, err := backendpool.DurationOrDefault(rule.GetTimeouts().GetIdle(), defaultAdhocIdleTimeout)
+	if err != nil {
+		return nil, err
+	}
+	maxIdlePerHost := defaultAdhocMaxIdlePerHost
+	if pool := rule.GetConnectionPool(); pool != nil && pool.MaxIdlePerHost > 0 {
+		maxIdlePerHost = int(pool.MaxIdlePerHost)
+	}
+	dialFunc := func(ctx context.Context, network, addr string) (net.Conn, error) {
+		ctx, cancel := context.WithTimeout(ctx, connectTimeout)
+		defer cancel()
+		return AdhocParentDialFunc(ctx, network, addr)
+	}
+	dialFunc = conntrack.NewDialContextFunc(
+		conntrack.DialWithName("adhoc_"+rule.DnsNameMatcher),
+		conntrack.DialWithDialContextFunc(dialFunc),
+		conntrack.DialWithTracing(),
+	)
+	dialFunc = dialAdhocAddresses(dialFunc)
+	transport := &http.Transport{
+		TLSClientConfig:       tlsConfig,
+		TLSHandshakeTimeout:   connectTimeout,
+		ResponseHeaderTimeout: responseHeaderTimeout,
+		IdleConnTimeout:       idleTimeout,
+		MaxIdleConnsPerHost:   maxIdlePerHost,
+		MaxIdleConns:          int(rule.GetConnectionPool().GetMaxIdle()),
+	}
+	if maxConns := rule.GetConnectionPool().GetMaxConns(); maxConns > 0 {
+		dialFunc = limitConns(dialFunc, int(maxConns), transport.CloseIdleConnections)
+	}
+	transport.DialContext = dialFunc
+	return &adhocTransport{
+		transport:      transport,
+		dialFunc:       dialFunc,
+		connectTimeout: connectTimeout,
+	}, nil
+}
+
+// dialAdhocAddresses wraps the dial function to dial the addresses of the destination in the context, in order, as
+// the first one may be down.
+//
+// Only the addresses the AdhocAddresser checked are dialed, so dials of anything else, which would resolve host names
+// past the destination CIDRs of the rule, fail.
+func dialAdhocAddresses(dial func(ctx context.Context, network, addr string) (net.Conn, error)) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		dest, ok := ctx.Value(adhocDestinationKey{}).(*router.AdhocDestination)
+		if !ok {
+			return nil, fmt.Errorf("adhoc dial of '%v' without a destination", addr)
+		} else if dest.Host != addr {
+			return nil, fmt.Errorf("adhoc dial of '%v' for destination '%v'", addr, dest.Host)
+		}
+		var lastErr error
+		for _, a := range dest.Addrs {
+			conn, err := dial(ctx, network, a)
+			if err == nil {
+				return conn, nil
+			}
+			lastErr = err
+			if ctx.Err() != nil {
+				break
+			}
+		}
+		return nil, lastErr
+	}
+}
+
+// limitConns wraps the dial function to keep at most maxConns connections open. Dials at the limit call closeIdle
+// first, as idle keep-alive connections would otherwise hold their slots until they time out, and then wait for
+// connections in use to be closed.
+func limitConns(dial func(ctx context.Context, network, addr string) (net.Conn, error), maxConns int, closeIdle func()) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	slots := make(chan struct{}, maxConns)
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		select {
+		case slots <- struct{}{}:
+		default:
+			closeIdle()
+			select {
+			case slots <- struct{}{}:
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+		}
+		conn, err := dial(ctx, network, addr)
+		if err != nil {
+			<-slots
+			return nil, err
+		}
+		return &limitedConn{Conn: conn, release: func() { <-slots }}, nil
+	}
+}
+
+// limitedConn frees its slot of limitConns once closed.
+type limitedConn struct {
+	net.Conn
+	once    sync.Once
+	release func()
+}
+
+func (c *limitedConn) Close() error {
+	err := c.Conn.Close()
+	c.once.Do(c.release)
+	return err
+}
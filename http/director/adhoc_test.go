@@ -0,0 +1,70 @@
+package director
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/mwitkow/kedge/http/director/router"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDialAdhocAddressesOnlyDialsAddressesOfTheDestination(t *testing.T) {
+	var dialed []string
+	dial := dialAdhocAddresses(func(ctx context.Context, network, addr string) (net.Conn, error) {
+		dialed = append(dialed, addr)
+		if addr == "10.0.0.1:80" {
+			return nil, &net.OpError{Op: "dial", Net: network, Err: assert.AnError}
+		}
+		conn, _ := net.Pipe()
+		return conn, nil
+	})
+	dest := &router.AdhocDestination{Host: "a.pods.cluster.local:80", Addrs: []string{"10.0.0.1:80", "10.0.0.2:80"}}
+	ctx := context.WithValue(context.Background(), adhocDestinationKey{}, dest)
+
+	conn, err := dial(ctx, "tcp", "a.pods.cluster.local:80")
+	require.NoError(t, err, "dialing must fall back to the next address")
+	conn.Close()
+	assert.Equal(t, []string{"10.0.0.1:80", "10.0.0.2:80"}, dialed)
+
+	dialed = nil
+	_, err = dial(ctx, "tcp", "b.pods.cluster.local:80")
+	assert.Error(t, err, "dials of other hosts must fail")
+	_, err = dial(context.Background(), "tcp", "a.pods.cluster.local:80")
+	assert.Error(t, err, "dials without a destination must fail")
+	assert.Empty(t, dialed, "hosts must never be dialed past the addresses of their destination")
+}
+
+func TestLimitConnsClosesIdleConnsAtTheLimit(t *testing.T) {
+	var open []net.Conn
+	closedIdle := 0
+	dial := limitConns(func(ctx context.Context, network, addr string) (net.Conn, error) {
+		conn, _ := net.Pipe()
+		return conn, nil
+	}, 2, func() {
+		closedIdle++
+		if len(open) > 0 {
+			open[0].Close()
+			open = open[1:]
+		}
+	})
+	for i := 0; i < 2; i++ {
+		conn, err := dial(context.Background(), "tcp", "a.pods.cluster.local:80")
+		require.NoError(t, err)
+		open = append(open, conn)
+	}
+	assert.Equal(t, 0, closedIdle, "idle connections must only be closed at the limit")
+
+	conn, err := dial(context.Background(), "tcp", "a.pods.cluster.local:80")
+	require.NoError(t, err, "idle connections must make room for new ones")
+	assert.Equal(t, 1, closedIdle)
+	open = append(open, conn)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	open = nil
+	_, err = dial(ctx, "tcp", "a.pods.cluster.local:80")
+	assert.Error(t, err, "dials must wait for connections in use while there are no idle ones")
+}
@@ -0,0 +1,199 @@
+package director
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	pb "github.com/mwitkow/kedge/_protogen/kedge/config/http/routes"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	// MirrorTimeout is the maximum duration of a mirrored request.
+	MirrorTimeout = 30 * time.Second
+
+	defaultMirrorMaxBodyBytes = 64 * 1024
+
+	// hopHeaders are not copied to mirrored requests, see httputil.ReverseProxy.
+	hopHeaders = []string{
+		"Connection",
+		"Proxy-Connection",
+		"Keep-Alive",
+		"Proxy-Authenticate",
+		"Proxy-Authorization",
+		"Te",
+		"Trailer",
+		"Transfer-Encoding",
+		"Upgrade",
+	}
+
+	mirrorRequestsCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "kedge",
+			Subsystem: "http",
+			Name:      "mirror_requests_total",
+			Help:      "Count of mirrored requests, by the status codes returned by the backend and by the mirror.",
+		}, []string{"backend_name", "mirror_backend_name", "code", "mirror_code"})
+	mirrorSkippedCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "kedge",
+			Subsystem: "http",
+			Name:      "mirror_skipped_total",
+			Help:      "Count of requests sampled for mirroring that weren't mirrored, by reason.",
+		}, []string{"mirror_backend_name", "reason"})
+)
+
+func init() {
+	prometheus.MustRegister(mirrorRequestsCounter)
+	prometheus.MustRegister(mirrorSkippedCounter)
+}
+
+func shouldMirror(mirror *pb.Mirror) bool {
+	return mirror != nil && rand.Float32()*100 < mirror.Percentage
+}
+
+// serveMirrored proxies the request to the backend, and then sends a copy of it to the mirror in the background.
+//
+// The request body is copied while the backend reads it, so the original request is never delayed.
+func (p *Proxy) serveMirrored(resp http.ResponseWriter, req *http.Request, backendName string, mirror *pb.Mirror) {
+	limit := defaultMirrorMaxBodyBytes
+	if mirror.MaxBodyBytes != 0 {
+		limit = int(mirror.MaxBodyBytes)
+	}
+	var tee *teeBody
+	if req.Body != nil && req.ContentLength != 0 {
+		tee = &teeBody{ReadCloser: req.Body, limit: limit}
+		req.Body = tee
+	}
+	recorder := &statusRecorder{ResponseWriter: resp}
+	p.backendReverseProxy.ServeHTTP(recorder, req)
+
+	var body []byte
+	if tee != nil {
+		var skipReason string
+		if body, skipReason = tee.captured(); skipReason != "" {
+			mirrorSkippedCounter.WithLabelValues(mirror.BackendName, skipReason).Inc()
+			return
+		}
+	}
+	go p.sendMirror(req, body, backendName, mirror.BackendName, recorder.statusCode())
+}
+
+func (p *Proxy) sendMirror(req *http.Request, body []byte, backendName string, mirrorName string, code int) {
+	ctx, cancel := context.WithTimeout(context.Background(), MirrorTimeout)
+	defer cancel()
+	mirrorUrl := *req.URL
+	mirrorUrl.Host = mirrorName
+	mirrorReq := &http.Request{
+		Method:        req.Method,
+		URL:           &mirrorUrl,
+		Proto:         req.Proto,
+		ProtoMajor:    req.ProtoMajor,
+		ProtoMinor:    req.ProtoMinor,
+		Header:        make(http.Header),
+		Body:          ioutil.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Host:          req.Host,
+	}
+	for k, v := range req.Header {
+		mirrorReq.Header[k] = append([]string(nil), v...)
+	}
+	for _, h := range hopHeaders {
+		mirrorReq.Header.Del(h)
+	}
+	mirrorCode := "error"
+	mirrorResp, err := p.backendTripper.RoundTrip(mirrorReq.WithContext(ctx))
+	if err == nil {
+		io.Copy(ioutil.Discard, mirrorResp.Body)
+		mirrorResp.Body.Close()
+		mirrorCode = strconv.Itoa(mirrorResp.StatusCode)
+	}
+	mirrorRequestsCounter.WithLabelValues(backendName, mirrorName, strconv.Itoa(code), mirrorCode).Inc()
+}
+
+// teeBody copies the request body as it is read, unless it is larger than the limit.
+type teeBody struct {
+	io.ReadCloser
+	limit int
+
+	mu       sync.Mutex
+	buf      bytes.Buffer
+	overflow bool
+	eof      bool
+}
+
+func (t *teeBody) Read(p []byte) (int, error) {
+	n, err := t.ReadCloser.Read(p)
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if n > 0 && !t.overflow {
+		if t.buf.Len()+n > t.limit {
+			t.overflow = true
+			t.buf = bytes.Buffer{}
+		} else {
+			t.buf.Write(p[:n])
+		}
+	}
+	if err == io.EOF {
+		t.eof = true
+	}
+	return n, err
+}
+
+// captured returns the copied body, or the reason it wasn't copied: either "body_too_large", or "body_not_read" if the
+// backend didn't read all of it, e.g. as it failed early.
+func (t *teeBody) captured() (body []byte, skipReason string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.overflow {
+		return nil, "body_too_large"
+	} else if !t.eof {
+		return nil, "body_not_read"
+	}
+	return t.buf.Bytes(), ""
+}
+
+// statusRecorder remembers the status code written to the client.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(code int) {
+	if r.status == 0 {
+		r.status = code
+	}
+	r.ResponseWriter.WriteHeader(code)
+}
+
+func (r *statusRecorder) Write(b []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	return r.ResponseWriter.Write(b)
+}
+
+func (r *statusRecorder) Flush() {
+	r.ResponseWriter.(http.Flusher).Flush()
+}
+
+func (r *statusRecorder) CloseNotify() <-chan bool {
+	if cn, ok := r.ResponseWriter.(http.CloseNotifier); ok {
+		return cn.CloseNotify()
+	}
+	return nil
+}
+
+func (r *statusRecorder) statusCode() int {
+	if r.status == 0 {
+		return http.StatusOK
+	}
+	return r.status
+}
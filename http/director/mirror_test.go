@@ -0,0 +1,28 @@
+package director
+
+import (
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTeeBodySkipReasons(t *testing.T) {
+	tee := &teeBody{ReadCloser: ioutil.NopCloser(strings.NewReader("some body")), limit: 64}
+	ioutil.ReadAll(tee)
+	body, skipReason := tee.captured()
+	assert.Equal(t, "", skipReason)
+	assert.Equal(t, "some body", string(body))
+
+	tee = &teeBody{ReadCloser: ioutil.NopCloser(strings.NewReader("some body")), limit: 4}
+	ioutil.ReadAll(tee)
+	_, skipReason = tee.captured()
+	assert.Equal(t, "body_too_large", skipReason)
+
+	tee = &teeBody{ReadCloser: ioutil.NopCloser(strings.NewReader("some body")), limit: 64}
+	io.ReadFull(tee, make([]byte, 4))
+	_, skipReason = tee.captured()
+	assert.Equal(t, "body_not_read", skipReason, "bodies the backend didn't read fully must not count as too large")
+}
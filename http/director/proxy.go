@@ -1,21 +1,20 @@
 package director
 
 import (
+	"context"
+	"io/ioutil"
+	"net"
 	"net/http"
 	"net/http/httputil"
+	"strings"
 
 	"fmt"
 
-	"github.com/mwitkow/go-conntrack"
 	"github.com/mwitkow/kedge/http/backendpool"
 	"github.com/mwitkow/kedge/http/director/proxyreq"
 	"github.com/mwitkow/kedge/http/director/router"
 )
 
-var (
-	AdhocTransport = &(*(http.DefaultTransport.(*http.Transport))) // shallow copy
-)
-
 // New creates a forward/reverse proxy that is either Route+Backend and Adhoc Rules forwarding.
 //
 // The Router decides which "well-known" routes a given request matches, and which backend from the Pool it should be
@@ -23,13 +22,16 @@ var (
 //
 // If  Adhoc routing supports dialing to whitelisted DNS names either through DNS A or SRV records for undefined backends.
 func New(pool backendpool.Pool, router router.Router, adhoc router.AdhocAddresser) *Proxy {
-	adhocTripper := &(*AdhocTransport) // shallow copy
-	adhocTripper.DialContext = conntrack.NewDialContextFunc(conntrack.DialWithName("adhoc"), conntrack.DialWithTracing())
+	adhocTripper := newAdhocTripper()
+	backendTripper := &backendPoolTripper{pool: pool}
 	p := &Proxy{
+		pool:         pool,
+		adhocTripper: adhocTripper,
 		backendReverseProxy: &httputil.ReverseProxy{
 			Director:  func(r *http.Request) {},
-			Transport: &backendPoolTripper{pool: pool},
+			Transport: backendTripper,
 		},
+		backendTripper: backendTripper,
 		adhocReverseProxy: &httputil.ReverseProxy{
 			Director:  func(r *http.Request) {},
 			Transport: adhocTripper,
@@ -44,9 +46,14 @@ func New(pool backendpool.Pool, router router.Router, adhoc router.AdhocAddresse
 type Proxy struct {
 	router    router.Router
 	addresser router.AdhocAddresser
+	pool      backendpool.Pool
+	// adhocTripper is used by the adhocReverseProxy, and to dial upgraded connections to adhoc destinations.
+	adhocTripper *adhocTripper
 
 	backendReverseProxy *httputil.ReverseProxy
 	adhocReverseProxy   *httputil.ReverseProxy
+	// backendTripper is used by the backendReverseProxy, and to send mirrored requests.
+	backendTripper http.RoundTripper
 }
 
 func (p *Proxy) ServeHTTP(resp http.ResponseWriter, req *http.Request) {
@@ -55,20 +62,53 @@ func (p *Proxy) ServeHTTP(resp http.ResponseWriter, req *http.Request) {
 	}
 	// note resp needs to implement Flusher, otherwise flush intervals won't work.
 	normReq := proxyreq.NormalizeInboundRequest(req)
-	backend, err := p.router.Route(req)
+	match, err := p.router.Match(normReq)
 	if err == nil {
+		backend := match.BackendName
 		resp.Header().Set("x-kedge-backend-name", backend)
+		// Failover backends replace the name with the one of the backend that actually serves the request.
+		normReq = normReq.WithContext(backendpool.WithBackendReporter(normReq.Context(), func(servedBy string) {
+			resp.Header().Set("x-kedge-backend-name", servedBy)
+		}))
+		if isUpgrade(normReq) {
+			serveUpgrade(resp, normReq, backend, func(ctx context.Context) (net.Conn, error) {
+				return p.pool.Dial(ctx, backend, normReq)
+			})
+			return
+		}
+		resp = &streamingWriter{ResponseWriter: resp, req: req}
+		if match.Timeout > 0 {
+			ctx, cancel := context.WithTimeout(normReq.Context(), match.Timeout)
+			defer cancel()
+			normReq = normReq.WithContext(ctx)
+		}
 		normReq.URL.Host = backend
+		if shouldMirror(match.Mirror) {
+			p.serveMirrored(resp, normReq, backend, match.Mirror)
+			return
+		}
 		p.backendReverseProxy.ServeHTTP(resp, normReq)
 		return
 	} else if err != router.ErrRouteNotFound {
 		respondWithError(err, resp)
 		return
 	}
-	addr, err := p.addresser.Address(req)
+	dest, err := p.addresser.Address(req)
 	if err == nil {
-		normReq.URL.Host = addr
-		p.adhocReverseProxy.ServeHTTP(resp, normReq)
+		// The transport dials the addresses of the destination in order, falling back to the others if dialing fails.
+		normReq = normReq.WithContext(context.WithValue(normReq.Context(), adhocDestinationKey{}, dest))
+		normReq.URL.Host = dest.Host
+		normReq.URL.Scheme = "http"
+		if dest.TlsConfig != nil {
+			normReq.URL.Scheme = "https"
+		}
+		if isUpgrade(normReq) {
+			serveUpgrade(resp, normReq, adhocBackendLabel+dest.Rule.DnsNameMatcher, func(ctx context.Context) (net.Conn, error) {
+				return p.adhocTripper.Dial(ctx, dest)
+			})
+			return
+		}
+		p.adhocReverseProxy.ServeHTTP(&streamingWriter{ResponseWriter: resp, req: req}, normReq)
 		return
 	}
 	respondWithError(err, resp)
@@ -80,11 +120,43 @@ type backendPoolTripper struct {
 }
 
 func (t *backendPoolTripper) RoundTrip(req *http.Request) (*http.Response, error) {
-	tripper, err := t.pool.Tripper(req.URL.Host)
-	if err == nil {
-		return tripper.RoundTrip(req)
+	backendName := req.URL.Host
+	tripper, err := t.pool.Tripper(backendName)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := tripper.RoundTrip(req)
+	if err != nil && isTimeout(req, err) {
+		// httputil.ReverseProxy turns all errors into a 502, so a timeout needs to be returned as a response.
+		return timeoutResponse(req, fmt.Errorf("backend '%v' timed out: %v", backendName, err)), nil
+	}
+	return resp, err
+}
+
+func isTimeout(req *http.Request, err error) bool {
+	if req.Context().Err() == context.DeadlineExceeded {
+		return true
+	}
+	netErr, ok := err.(net.Error)
+	return ok && netErr.Timeout()
+}
+
+func timeoutResponse(req *http.Request, err error) *http.Response {
+	body := fmt.Sprintf("kedge error: %v", err.Error())
+	return &http.Response{
+		Status:     fmt.Sprintf("%d %s", http.StatusGatewayTimeout, http.StatusText(http.StatusGatewayTimeout)),
+		StatusCode: http.StatusGatewayTimeout,
+		Proto:      "HTTP/1.1",
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+		Header: http.Header{
+			"X-Kedge-Error": []string{err.Error()},
+			"Content-Type":  []string{"text/plain"},
+		},
+		Body:          ioutil.NopCloser(strings.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
 	}
-	return nil, err
 }
 
 func respondWithError(err error, resp http.ResponseWriter) {
@@ -1,89 +1,237 @@
 package router
 
 import (
+	"crypto/tls"
 	"fmt"
 	"net"
 	"net/http"
 	"strconv"
 	"strings"
 
+	"github.com/golang/protobuf/ptypes"
+	"github.com/golang/protobuf/ptypes/duration"
 	pb "github.com/mwitkow/kedge/_protogen/kedge/config/http/routes"
+	"github.com/mwitkow/kedge/lib/clientauth"
+	"github.com/mwitkow/kedge/lib/resolvers"
 )
 
 var (
-	// DefaultALookup is the lookup resolver for DNS A records.
-	// You can override it for caching or testing.
-	DefaultALookup = net.LookupAddr
+	// DefaultALookup is the lookup resolver for DNS A and AAAA records, returning IP addresses and their TTL.
+	// You can override it for testing. Its results are cached by the addresser.
+	DefaultALookup = resolvers.LookupHost
+
+	// DefaultSrvLookup is the lookup resolver for DNS SRV records, used for finding ports of adhoc rules, returning the
+	// records and their TTL. You can override it for testing. Its results are cached by the addresser.
+	DefaultSrvLookup = resolvers.LookupSRV
 )
 
 // AdhocAddresser implements logic that decides what "ad-hoc" ip:port to dial for a backend, if any.
 //
 // Adhoc rules are a way of forwarding requests to services that fall outside of pre-defined Routes and Backends.
 type AdhocAddresser interface {
-	// Address decides the ip:port to send the request to, if any. Errors may be returned if permission is denied.
-	// The returned string must contain contain both ip and port separated by colon.
-	Address(r *http.Request) (string, error)
+	// Address decides the destination to send the request to, if any. Errors may be returned if permission is denied.
+	Address(r *http.Request) (*AdhocDestination, error)
+}
+
+// AdhocDestination is where an adhoc request is sent.
+type AdhocDestination struct {
+	// Host is the host:port of the destination, as used for TLS verification.
+	Host string
+	// Addrs are the ip:ports of the destination, which should be dialed in order until one succeeds.
+	Addrs []string
+	// Rule is the adhoc rule that matched the request.
+	Rule *pb.Adhoc
+	// TlsConfig is the TLS config for dialing the destination, or nil for plain HTTP.
+	TlsConfig *tls.Config
 }
 
 type addresser struct {
-	rules []*pb.Adhoc
+	rules []*adhocRule
+	dns   *dnsCache
+}
+
+// adhocRule is an Adhoc rule with its authorization, CIDR lists and TLS config parsed.
+type adhocRule struct {
+	*pb.Adhoc
+	authorizer   clientauth.Authorizer
+	allowedCidrs []*net.IPNet
+	deniedCidrs  []*net.IPNet
+	tlsConfig    *tls.Config
+}
+
+// NewAddresser creates an AdhocAddresser from adhoc rules, validating them. The tlsConfigs are the named TLS client
+// configs the rules may refer to.
+func NewAddresser(rules []*pb.Adhoc, tlsConfigs map[string]*tls.Config) (AdhocAddresser, error) {
+	a := &addresser{dns: newDnsCache()}
+	for _, r := range rules {
+		rule := &adhocRule{Adhoc: r}
+		var err error
+		if sec := r.Security; sec != nil {
+			rule.tlsConfig = &tls.Config{}
+			if sec.ConfigName != "" {
+				named, ok := tlsConfigs[sec.ConfigName]
+				if !ok {
+					return nil, fmt.Errorf("adhoc rule '%v' uses unknown tls config '%v'", r.DnsNameMatcher, sec.ConfigName)
+				}
+				rule.tlsConfig = named
+			}
+			if sec.InsecureSkipVerify {
+				rule.tlsConfig = &tls.Config{
+					Certificates:       rule.tlsConfig.Certificates,
+					MinVersion:         rule.tlsConfig.MinVersion,
+					InsecureSkipVerify: true,
+				}
+			}
+		}
+		for _, d := range []*duration.Duration{r.Timeouts.GetConnect(), r.Timeouts.GetResponseHeader(), r.Timeouts.GetIdle()} {
+			if d == nil {
+				continue
+			}
+			if _, err := ptypes.Duration(d); err != nil {
+				return nil, fmt.Errorf("adhoc rule '%v' has a bad timeout: %v", r.DnsNameMatcher, err)
+			}
+		}
+		if r.Authorization != nil {
+			if rule.authorizer, err = clientauth.New(r.Authorization); err != nil {
+				return nil, fmt.Errorf("adhoc rule '%v' authorization: %v", r.DnsNameMatcher, err)
+			}
+		}
+		if rule.allowedCidrs, err = parseCidrs(r.AllowedCidrs); err != nil {
+			return nil, fmt.Errorf("adhoc rule '%v' allowed_cidrs: %v", r.DnsNameMatcher, err)
+		}
+		if rule.deniedCidrs, err = parseCidrs(r.DeniedCidrs); err != nil {
+			return nil, fmt.Errorf("adhoc rule '%v' denied_cidrs: %v", r.DnsNameMatcher, err)
+		}
+		a.rules = append(a.rules, rule)
+	}
+	return a, nil
 }
 
-func NewAddresser(rules []*pb.Adhoc) AdhocAddresser {
-	return &addresser{rules: rules}
+func parseCidrs(cidrs []string) ([]*net.IPNet, error) {
+	nets := []*net.IPNet{}
+	for _, c := range cidrs {
+		_, ipNet, err := net.ParseCIDR(c)
+		if err != nil {
+			return nil, err
+		}
+		nets = append(nets, ipNet)
+	}
+	return nets, nil
 }
 
-func (a *addresser) Address(req *http.Request) (string, error) {
+func (a *addresser) Address(req *http.Request) (*AdhocDestination, error) {
 	hostName, port, err := a.extractHostPort(req.URL.Host)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 	for _, rule := range a.rules {
 		if !a.hostMatches(hostName, rule.DnsNameMatcher) {
 			continue
 		}
+		if rule.authorizer != nil {
+			if err := rule.authorizer.Authorize(req); err != nil {
+				return nil, NewError(http.StatusForbidden, fmt.Sprintf("adhoc rule denied access: %v", err))
+			}
+		}
 		portForRule := port
+		targetHost := hostName
 		if port == 0 {
-			if defPort := rule.Port.Default; defPort != 0 {
+			if srv, ok := a.lookupSrv(hostName, rule.Port.GetSrv()); ok {
+				portForRule = int(srv.Port)
+				if rule.Port.GetSrv().UseTarget {
+					targetHost = strings.TrimSuffix(srv.Target, ".")
+				}
+			} else if defPort := rule.Port.GetDefault(); defPort != 0 {
 				portForRule = int(defPort)
 			} else {
 				portForRule = 80
 			}
 		}
 		if !a.portAllowed(portForRule, rule.Port) {
-			return "", NewError(http.StatusBadRequest, fmt.Sprintf("port %d is not allowed", portForRule))
+			return nil, NewError(http.StatusBadRequest, fmt.Sprintf("port %d is not allowed", portForRule))
 		}
-		ipAddr, err := a.resolveHost(hostName)
+		ipAddrs, err := a.resolveHost(targetHost)
 		if err != nil {
-			return "", err
+			return nil, err
+		}
+		hostPorts := make([]string, 0, len(ipAddrs))
+		for _, ipAddr := range ipAddrs {
+			if !rule.ipAllowed(net.ParseIP(ipAddr)) {
+				continue
+			}
+			hostPorts = append(hostPorts, net.JoinHostPort(ipAddr, strconv.FormatInt(int64(portForRule), 10)))
+		}
+		if len(hostPorts) == 0 {
+			return nil, NewError(http.StatusForbidden, fmt.Sprintf("destination address %v is not allowed", ipAddrs[0]))
 		}
-		return net.JoinHostPort(ipAddr, strconv.FormatInt(int64(portForRule), 10)), nil
+		return &AdhocDestination{
+			Host:      net.JoinHostPort(targetHost, strconv.FormatInt(int64(portForRule), 10)),
+			Addrs:     hostPorts,
+			Rule:      rule.Adhoc,
+			TlsConfig: rule.tlsConfig,
+		}, nil
+
+	}
+	return nil, ErrRouteNotFound
+}
 
+// lookupSrv returns the SRV record of the host for the rule, if the rule uses SRV and the lookup succeeds.
+func (a *addresser) lookupSrv(hostName string, srvRule *pb.Adhoc_Port_Srv) (*net.SRV, bool) {
+	if srvRule == nil {
+		return nil, false
+	}
+	service, protocol := srvRule.Service, srvRule.Protocol
+	if service == "" {
+		service = "http"
 	}
-	return "", ErrRouteNotFound
+	if protocol == "" {
+		protocol = "tcp"
+	}
+	srv, err := a.dns.lookupSrv(service, protocol, hostName)
+	if err != nil {
+		return nil, false
+	}
+	return srv, true
 }
 
-func (*addresser) resolveHost(hostStr string) (string, error) {
-	addrs, err := DefaultALookup(hostStr)
+func (a *addresser) resolveHost(hostStr string) ([]string, error) {
+	addrs, err := a.dns.lookup(hostStr)
 	if err != nil {
-		return "", NewError(http.StatusBadGateway, "cannot resolve host")
+		return nil, NewError(http.StatusBadGateway, "cannot resolve host")
 	}
-	return addrs[0], nil
+	return addrs, nil
 }
 
 func (*addresser) extractHostPort(hostStr string) (hostName string, port int, err error) {
-	// Using SplitHostPort is a pain due to opaque error messages. Let's assume we only do hostname matches, they fall
-	// through later anyway.
-	portOffset := strings.LastIndex(hostStr, ":")
-	if portOffset == -1 {
-		return hostStr, 0, nil
+	portPart := ""
+	if strings.HasPrefix(hostStr, "[") {
+		// IPv6 literals are bracketed, with the optional port after the brackets.
+		end := strings.Index(hostStr, "]")
+		if end == -1 {
+			return "", 0, NewError(http.StatusBadRequest, "malformed IPv6 address")
+		}
+		hostName, portPart = hostStr[1:end], hostStr[end+1:]
+		if portPart == "" {
+			return hostName, 0, nil
+		}
+		if portPart[0] != ':' {
+			return "", 0, NewError(http.StatusBadRequest, "malformed IPv6 address")
+		}
+		portPart = portPart[1:]
+	} else {
+		// Using SplitHostPort is a pain due to opaque error messages. Let's assume we only do hostname matches, they fall
+		// through later anyway.
+		portOffset := strings.LastIndex(hostStr, ":")
+		if portOffset == -1 {
+			return hostStr, 0, nil
+		}
+		hostName, portPart = hostStr[:portOffset], hostStr[portOffset+1:]
 	}
-	portPart := hostStr[portOffset+1:]
 	pNum, err := strconv.ParseInt(portPart, 10, 32)
 	if err != nil {
 		return "", 0, NewError(http.StatusBadRequest, fmt.Sprintf("malformed port number: %v", err))
 	}
-	return hostStr[:portOffset], int(pNum), nil
+	return hostName, int(pNum), nil
 }
 
 func (*addresser) hostMatches(host string, matcher string) bool {
@@ -96,6 +244,27 @@ func (*addresser) hostMatches(host string, matcher string) bool {
 	return strings.HasSuffix(host, matcher[1:])
 }
 
+// ipAllowed checks the resolved IP against the CIDR lists of the rule, so that DNS names can't point anywhere.
+func (r *adhocRule) ipAllowed(ip net.IP) bool {
+	if ip == nil {
+		return false
+	}
+	for _, n := range r.deniedCidrs {
+		if n.Contains(ip) {
+			return false
+		}
+	}
+	if len(r.allowedCidrs) == 0 {
+		return true
+	}
+	for _, n := range r.allowedCidrs {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
 func (*addresser) portAllowed(port int, portRule *pb.Adhoc_Port) bool {
 	uPort := uint32(port)
 	for _, p := range portRule.Allowed {
@@ -0,0 +1,132 @@
+package router
+
+import (
+	"math/rand"
+	"net"
+	"sync"
+	"time"
+)
+
+var (
+	// AdhocNegativeCacheTTL is how long failed lookups of adhoc hosts are cached for.
+	AdhocNegativeCacheTTL = 1 * time.Second
+
+	// maxDnsCacheEntries bounds the memory used by caches of adhoc hosts, which are free form.
+	maxDnsCacheEntries = 10000
+)
+
+type dnsCacheEntry struct {
+	addrs   []string
+	srvs    []*net.SRV
+	err     error
+	expires time.Time
+	next    int
+}
+
+// dnsCache caches the results of DefaultALookup and DefaultSrvLookup according to their TTL, and spreads requests over
+// the addresses of a host in round robin.
+type dnsCache struct {
+	mu      sync.Mutex
+	entries map[string]*dnsCacheEntry
+}
+
+func newDnsCache() *dnsCache {
+	return &dnsCache{entries: make(map[string]*dnsCacheEntry)}
+}
+
+// lookup returns all addresses of the host, rotated so that subsequent calls start with different ones.
+func (c *dnsCache) lookup(host string) ([]string, error) {
+	if ip := net.ParseIP(host); ip != nil {
+		return []string{host}, nil
+	}
+	now := time.Now()
+	entry, ok := c.cached(host, now)
+	if !ok {
+		addrs, ttl, err := DefaultALookup(host)
+		if err == nil && len(addrs) == 0 {
+			err = &net.DNSError{Err: "no addresses", Name: host}
+		}
+		entry = c.store(host, &dnsCacheEntry{addrs: addrs, err: err}, ttl, now)
+	}
+	if entry.err != nil {
+		return nil, entry.err
+	}
+	c.mu.Lock()
+	start := entry.next % len(entry.addrs)
+	entry.next++
+	c.mu.Unlock()
+	rotated := make([]string, 0, len(entry.addrs))
+	rotated = append(rotated, entry.addrs[start:]...)
+	return append(rotated, entry.addrs[:start]...), nil
+}
+
+// lookupSrv returns the SRV record of the service of the host to use, picked by priority and weight like RFC 2782
+// describes.
+func (c *dnsCache) lookupSrv(service string, protocol string, host string) (*net.SRV, error) {
+	// Spaces keep SRV entries apart from the ones of hosts.
+	key := "SRV " + service + " " + protocol + " " + host
+	now := time.Now()
+	entry, ok := c.cached(key, now)
+	if !ok {
+		srvs, ttl, err := DefaultSrvLookup(service, protocol, host)
+		if err == nil && len(srvs) == 0 {
+			err = &net.DNSError{Err: "no SRV records", Name: host}
+		}
+		entry = c.store(key, &dnsCacheEntry{srvs: srvs, err: err}, ttl, now)
+	}
+	if entry.err != nil {
+		return nil, entry.err
+	}
+	var candidates []*net.SRV
+	totalWeight := 0
+	for _, srv := range entry.srvs {
+		if len(candidates) > 0 && srv.Priority > candidates[0].Priority {
+			continue
+		}
+		if len(candidates) > 0 && srv.Priority < candidates[0].Priority {
+			candidates, totalWeight = nil, 0
+		}
+		candidates = append(candidates, srv)
+		totalWeight += int(srv.Weight)
+	}
+	if totalWeight == 0 {
+		return candidates[rand.Intn(len(candidates))], nil
+	}
+	n := rand.Intn(totalWeight)
+	for _, srv := range candidates {
+		if n < int(srv.Weight) {
+			return srv, nil
+		}
+		n -= int(srv.Weight)
+	}
+	return candidates[len(candidates)-1], nil
+}
+
+func (c *dnsCache) cached(key string, now time.Time) (*dnsCacheEntry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[key]
+	return entry, ok && !now.After(entry.expires)
+}
+
+// store caches the entry for the TTL, or for AdhocNegativeCacheTTL if it's a failure.
+func (c *dnsCache) store(key string, entry *dnsCacheEntry, ttl time.Duration, now time.Time) *dnsCacheEntry {
+	entry.expires = now.Add(ttl)
+	if entry.err != nil {
+		entry.expires = now.Add(AdhocNegativeCacheTTL)
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.entries) >= maxDnsCacheEntries {
+		for k, e := range c.entries {
+			if now.After(e.expires) {
+				delete(c.entries, k)
+			}
+		}
+		if len(c.entries) >= maxDnsCacheEntries {
+			c.entries = make(map[string]*dnsCacheEntry)
+		}
+	}
+	c.entries[key] = entry
+	return entry
+}
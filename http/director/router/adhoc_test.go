@@ -1,12 +1,20 @@
 package router
 
 import (
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
 	"errors"
+	"net"
 	"net/http"
 	"testing"
+	"time"
 
 	"github.com/golang/protobuf/jsonpb"
 	pb "github.com/mwitkow/kedge/_protogen/kedge/config"
+	pb_route "github.com/mwitkow/kedge/_protogen/kedge/config/http/routes"
+	"github.com/mwitkow/kedge/lib/resolvers"
+	"github.com/mwitkow/kedge/lib/resolvers/dnstest"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -46,61 +54,62 @@ func TestAdhocMatches(t *testing.T) {
 
 	oldLookup := DefaultALookup
 	defer func() { DefaultALookup = oldLookup }()
-	DefaultALookup = func(addr string) (names []string, err error) {
+	DefaultALookup = func(addr string) (names []string, ttl time.Duration, err error) {
 		switch addr {
 		case "1-2-3-4.namespace.pods.cluster.local":
-			return []string{"1.2.3.4"}, nil
+			return []string{"1.2.3.4"}, time.Minute, nil
 		case "somebackend.somenamespace.svc.cluster.local":
-			return []string{"2.3.4.5", "2.3.4.6"}, nil
+			return []string{"2.3.4.5", "2.3.4.6"}, time.Minute, nil
 		case "weird.cluster.local":
-			return []string{"7.6.5.4", "9.8.7.6"}, nil
+			return []string{"7.6.5.4", "9.8.7.6"}, time.Minute, nil
 		default:
-			return nil, errors.New("test lookup error")
+			return nil, 0, errors.New("test lookup error")
 		}
 	}
 
-	a := NewAddresser(config.AdhocRules)
+	a, err := NewAddresser(config.AdhocRules, nil)
+	require.NoError(t, err)
 
 	for _, tcase := range []struct {
-		name         string
-		hostPort     string
-		expectedAddr string
-		expectedErr  string
+		name          string
+		hostPort      string
+		expectedAddrs []string
+		expectedErr   string
 	}{
 		{
-			name:         "matches default port",
-			hostPort:     "1-2-3-4.namespace.pods.cluster.local",
-			expectedAddr: "1.2.3.4:80",
+			name:          "matches default port",
+			hostPort:      "1-2-3-4.namespace.pods.cluster.local",
+			expectedAddrs: []string{"1.2.3.4:80"},
 		},
 		{
-			name:         "matches lower boundary of port range",
-			hostPort:     "1-2-3-4.namespace.pods.cluster.local:11000",
-			expectedAddr: "1.2.3.4:11000",
+			name:          "matches lower boundary of port range",
+			hostPort:      "1-2-3-4.namespace.pods.cluster.local:11000",
+			expectedAddrs: []string{"1.2.3.4:11000"},
 		},
 		{
-			name:         "matches upper boundary of port range",
-			hostPort:     "1-2-3-4.namespace.pods.cluster.local:11200",
-			expectedAddr: "1.2.3.4:11200",
+			name:          "matches upper boundary of port range",
+			hostPort:      "1-2-3-4.namespace.pods.cluster.local:11200",
+			expectedAddrs: []string{"1.2.3.4:11200"},
 		},
 		{
-			name:         "fails port check outside the boundary",
-			hostPort:     "1-2-3-4.namespace.pods.cluster.local:11201",
-			expectedErr:  "port 11201 is not allowed",
+			name:        "fails port check outside the boundary",
+			hostPort:    "1-2-3-4.namespace.pods.cluster.local:11201",
+			expectedErr: "port 11201 is not allowed",
 		},
 		{
-			name:         "matches non default allowed in list",
-			hostPort:     "somebackend.somenamespace.svc.cluster.local:8081",
-			expectedAddr: "2.3.4.5:8081",
+			name:          "matches non default allowed in list",
+			hostPort:      "somebackend.somenamespace.svc.cluster.local:8081",
+			expectedAddrs: []string{"2.3.4.5:8081", "2.3.4.6:8081"},
 		},
 		{
-			name:         "fails unmatched, even though addresses resolve",
-			hostPort:     "weird.cluster.local:8081",
-			expectedErr:  "unknown route to service",
+			name:        "fails unmatched, even though addresses resolve",
+			hostPort:    "weird.cluster.local:8081",
+			expectedErr: "unknown route to service",
 		},
 		{
-			name:         "fails dial errors",
-			hostPort:     "otherbackend.somenamespace.svc.cluster.local:8081",
-			expectedErr:  "cannot resolve host",
+			name:        "fails dial errors",
+			hostPort:    "otherbackend.somenamespace.svc.cluster.local:8081",
+			expectedErr: "cannot resolve host",
 		},
 	} {
 		t.Run(tcase.name, func(t *testing.T) {
@@ -113,8 +122,483 @@ func TestAdhocMatches(t *testing.T) {
 			} else {
 				assert.NoError(t, err)
 			}
-			assert.Equal(t, be, tcase.expectedAddr, "must match expected address")
+			assert.Equal(t, tcase.expectedAddrs, addrsOf(be), "must match expected addresses")
 		})
 
 	}
 }
+
+func TestAdhocSrvPorts(t *testing.T) {
+	configJson := `
+{ "adhoc_rules": [
+	{
+		"dnsNameMatcher": "*.svc.cluster.local",
+		"port": {
+			"default": 8080,
+			"srv": {},
+			"allowed": [8080, 9090, 9091]
+		}
+	},
+	{
+		"dnsNameMatcher": "*.grpc.cluster.local",
+		"port": {
+			"srv": { "service": "grpc", "useTarget": true },
+			"allowed_ranges": [{ "from": 10000, "to": 11000 }]
+		}
+	}
+]}`
+	config := &pb.DirectorConfig_Http{}
+	require.NoError(t, jsonpb.UnmarshalString(configJson, config))
+
+	oldLookup := DefaultALookup
+	oldSrvLookup := DefaultSrvLookup
+	defer func() {
+		DefaultALookup = oldLookup
+		DefaultSrvLookup = oldSrvLookup
+	}()
+	DefaultALookup = func(addr string) (names []string, ttl time.Duration, err error) {
+		switch addr {
+		case "withsrv.svc.cluster.local", "nosrv.svc.cluster.local", "badport.svc.cluster.local":
+			return []string{"1.2.3.4"}, time.Minute, nil
+		case "pod-1.grpc.cluster.local":
+			return []string{"5.6.7.8"}, time.Minute, nil
+		default:
+			return nil, 0, errors.New("test lookup error")
+		}
+	}
+	DefaultSrvLookup = func(service, proto, name string) ([]*net.SRV, time.Duration, error) {
+		switch service + "." + proto + "." + name {
+		case "http.tcp.withsrv.svc.cluster.local":
+			return []*net.SRV{{Target: "withsrv.svc.cluster.local.", Port: 9091, Priority: 1}, {Target: "withsrv.svc.cluster.local.", Port: 9090}}, time.Minute, nil
+		case "http.tcp.badport.svc.cluster.local":
+			return []*net.SRV{{Target: "badport.svc.cluster.local.", Port: 7070}}, time.Minute, nil
+		case "grpc.tcp.service.grpc.cluster.local":
+			return []*net.SRV{{Target: "pod-1.grpc.cluster.local.", Port: 10001}}, time.Minute, nil
+		default:
+			return nil, 0, errors.New("test srv lookup error")
+		}
+	}
+
+	a, err := NewAddresser(config.AdhocRules, nil)
+	require.NoError(t, err)
+
+	for _, tcase := range []struct {
+		name          string
+		hostPort      string
+		expectedAddrs []string
+		expectedErr   string
+	}{
+		{
+			name:          "uses port of srv record with lowest priority",
+			hostPort:      "withsrv.svc.cluster.local",
+			expectedAddrs: []string{"1.2.3.4:9090"},
+		},
+		{
+			name:          "explicit port overrides srv",
+			hostPort:      "withsrv.svc.cluster.local:8080",
+			expectedAddrs: []string{"1.2.3.4:8080"},
+		},
+		{
+			name:          "falls back to default port without srv records",
+			hostPort:      "nosrv.svc.cluster.local",
+			expectedAddrs: []string{"1.2.3.4:8080"},
+		},
+		{
+			name:        "fails port check of srv port",
+			hostPort:    "badport.svc.cluster.local",
+			expectedErr: "port 7070 is not allowed",
+		},
+		{
+			name:          "uses target of named srv service",
+			hostPort:      "service.grpc.cluster.local",
+			expectedAddrs: []string{"5.6.7.8:10001"},
+		},
+	} {
+		t.Run(tcase.name, func(t *testing.T) {
+			req, err := http.NewRequest("GET", "/foo", nil)
+			require.NoError(t, err, "parsing the request shouldn't fail")
+			req.URL.Host = tcase.hostPort
+			be, err := a.Address(req)
+			if tcase.expectedErr != "" {
+				assert.EqualError(t, err, tcase.expectedErr)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tcase.expectedAddrs, addrsOf(be), "must match expected addresses")
+		})
+	}
+}
+
+func TestAdhocIPv6Literals(t *testing.T) {
+	configJson := `
+{ "adhoc_rules": [
+	{
+		"dnsNameMatcher": "*",
+		"port": { "default": 80, "allowed": [80, 8080] }
+	}
+]}`
+	config := &pb.DirectorConfig_Http{}
+	require.NoError(t, jsonpb.UnmarshalString(configJson, config))
+
+	oldLookup := DefaultALookup
+	defer func() { DefaultALookup = oldLookup }()
+	DefaultALookup = func(addr string) (names []string, ttl time.Duration, err error) {
+		return nil, 0, errors.New("ip literals must not be looked up")
+	}
+
+	a, err := NewAddresser(config.AdhocRules, nil)
+	require.NoError(t, err)
+
+	for _, tcase := range []struct {
+		name          string
+		hostPort      string
+		expectedAddrs []string
+		expectedErr   string
+	}{
+		{
+			name:          "ipv6 with port",
+			hostPort:      "[::1]:8080",
+			expectedAddrs: []string{"[::1]:8080"},
+		},
+		{
+			name:          "ipv6 without port",
+			hostPort:      "[fe80::1]",
+			expectedAddrs: []string{"[fe80::1]:80"},
+		},
+		{
+			name:          "ipv4 with port",
+			hostPort:      "10.0.0.1:8080",
+			expectedAddrs: []string{"10.0.0.1:8080"},
+		},
+		{
+			name:        "ipv6 with bad port",
+			hostPort:    "[::1]:9090",
+			expectedErr: "port 9090 is not allowed",
+		},
+		{
+			name:        "unterminated ipv6",
+			hostPort:    "[::1:8080",
+			expectedErr: "malformed IPv6 address",
+		},
+	} {
+		t.Run(tcase.name, func(t *testing.T) {
+			req, err := http.NewRequest("GET", "/foo", nil)
+			require.NoError(t, err, "parsing the request shouldn't fail")
+			req.URL.Host = tcase.hostPort
+			be, err := a.Address(req)
+			if tcase.expectedErr != "" {
+				assert.EqualError(t, err, tcase.expectedErr)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tcase.expectedAddrs, addrsOf(be), "must match expected addresses")
+		})
+	}
+}
+
+func TestAdhocDnsCache(t *testing.T) {
+	oldLookup := DefaultALookup
+	oldSrvLookup := DefaultSrvLookup
+	oldNegativeTTL := AdhocNegativeCacheTTL
+	defer func() {
+		DefaultALookup = oldLookup
+		DefaultSrvLookup = oldSrvLookup
+		AdhocNegativeCacheTTL = oldNegativeTTL
+	}()
+	AdhocNegativeCacheTTL = 50 * time.Millisecond
+	lookups := map[string]int{}
+	DefaultALookup = func(addr string) (names []string, ttl time.Duration, err error) {
+		lookups[addr]++
+		switch addr {
+		case "three.cluster.local":
+			return []string{"1.1.1.1", "2.2.2.2", "3.3.3.3"}, time.Minute, nil
+		case "short.cluster.local":
+			return []string{"1.1.1.1"}, 50 * time.Millisecond, nil
+		default:
+			return nil, 0, errors.New("test lookup error")
+		}
+	}
+
+	c := newDnsCache()
+
+	t.Run("round robins over cached addresses", func(t *testing.T) {
+		for _, expected := range [][]string{
+			{"1.1.1.1", "2.2.2.2", "3.3.3.3"},
+			{"2.2.2.2", "3.3.3.3", "1.1.1.1"},
+			{"3.3.3.3", "1.1.1.1", "2.2.2.2"},
+			{"1.1.1.1", "2.2.2.2", "3.3.3.3"},
+		} {
+			addrs, err := c.lookup("three.cluster.local")
+			require.NoError(t, err)
+			assert.Equal(t, expected, addrs)
+		}
+		assert.Equal(t, 1, lookups["three.cluster.local"], "lookup must be cached within TTL")
+	})
+
+	t.Run("refreshes after TTL", func(t *testing.T) {
+		_, err := c.lookup("short.cluster.local")
+		require.NoError(t, err)
+		_, err = c.lookup("short.cluster.local")
+		require.NoError(t, err)
+		assert.Equal(t, 1, lookups["short.cluster.local"], "lookup must be cached within TTL")
+		time.Sleep(60 * time.Millisecond)
+		_, err = c.lookup("short.cluster.local")
+		require.NoError(t, err)
+		assert.Equal(t, 2, lookups["short.cluster.local"], "lookup must be repeated after TTL")
+	})
+
+	t.Run("caches failures", func(t *testing.T) {
+		_, err := c.lookup("missing.cluster.local")
+		require.Error(t, err)
+		_, err = c.lookup("missing.cluster.local")
+		require.Error(t, err)
+		assert.Equal(t, 1, lookups["missing.cluster.local"], "failures must be cached")
+		time.Sleep(60 * time.Millisecond)
+		_, err = c.lookup("missing.cluster.local")
+		require.Error(t, err)
+		assert.Equal(t, 2, lookups["missing.cluster.local"], "failures must be retried after negative cache TTL")
+	})
+
+	DefaultSrvLookup = func(service, proto, name string) ([]*net.SRV, time.Duration, error) {
+		lookups["_"+service+"._"+proto+"."+name]++
+		return []*net.SRV{
+			{Target: "backup.cluster.local.", Port: 3, Priority: 1, Weight: 100},
+			{Target: "a.cluster.local.", Port: 1, Weight: 1},
+			{Target: "b.cluster.local.", Port: 2, Weight: 3},
+		}, 50 * time.Millisecond, nil
+	}
+
+	t.Run("caches srv records and picks by priority and weight", func(t *testing.T) {
+		ports := map[uint16]int{}
+		for i := 0; i < 400; i++ {
+			srv, err := c.lookupSrv("http", "tcp", "srv.cluster.local")
+			require.NoError(t, err)
+			ports[srv.Port]++
+		}
+		assert.Equal(t, 1, lookups["_http._tcp.srv.cluster.local"], "srv lookup must be cached within TTL")
+		assert.Zero(t, ports[3], "records of higher priorities must not be used")
+		assert.True(t, ports[2] > ports[1], "records must be picked by weight, got %v", ports)
+		time.Sleep(60 * time.Millisecond)
+		_, err := c.lookupSrv("http", "tcp", "srv.cluster.local")
+		require.NoError(t, err)
+		assert.Equal(t, 2, lookups["_http._tcp.srv.cluster.local"], "srv lookup must be repeated after TTL")
+	})
+}
+
+func TestAdhocDnsCacheExpiresOnRecordTtl(t *testing.T) {
+	oldNameservers := resolvers.DnsNameservers
+	oldFallback := resolvers.HostFallbackLookup
+	oldNegativeTTL := AdhocNegativeCacheTTL
+	defer func() {
+		resolvers.DnsNameservers = oldNameservers
+		resolvers.HostFallbackLookup = oldFallback
+		AdhocNegativeCacheTTL = oldNegativeTTL
+	}()
+	server := dnstest.NewServer(t)
+	defer server.Close()
+	server.Set("ttl.cluster.local.", dnstest.ARecord("ttl.cluster.local.", 300, "10.0.0.1"))
+	resolvers.DnsNameservers = []string{server.Addr()}
+	resolvers.HostFallbackLookup = func(hostName string) ([]string, error) {
+		return nil, &net.DNSError{Err: "no such host", Name: hostName}
+	}
+	AdhocNegativeCacheTTL = 2 * time.Second
+
+	c := newDnsCache()
+	now := time.Now()
+	addrs, err := c.lookup("ttl.cluster.local")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"10.0.0.1"}, addrs)
+	assert.WithinDuration(t, now.Add(300*time.Second), c.entries["ttl.cluster.local"].expires, time.Second, "addresses must be cached for the TTL of their records")
+
+	_, err = c.lookup("missing.cluster.local")
+	require.Error(t, err)
+	assert.WithinDuration(t, now.Add(AdhocNegativeCacheTTL), c.entries["missing.cluster.local"].expires, time.Second, "failures must be cached for the negative cache TTL")
+}
+
+func TestAdhocAuthorizationAndCidrs(t *testing.T) {
+	configJson := `
+{ "adhoc_rules": [
+	{
+		"dnsNameMatcher": "*.secure.cluster.local",
+		"port": { "default": 80, "allowed": [80] },
+		"authorization": { "cert": { "commonNames": ["alice"] } }
+	},
+	{
+		"dnsNameMatcher": "*.cluster.local",
+		"port": { "default": 80, "allowed": [80] },
+		"allowedCidrs": ["10.0.0.0/8"],
+		"deniedCidrs": ["10.0.0.1/32"]
+	},
+	{
+		"dnsNameMatcher": "*",
+		"port": { "default": 80, "allowed": [80] },
+		"deniedCidrs": ["127.0.0.0/8", "169.254.169.254/32", "::1/128"]
+	}
+]}`
+	config := &pb.DirectorConfig_Http{}
+	require.NoError(t, jsonpb.UnmarshalString(configJson, config))
+
+	oldLookup := DefaultALookup
+	defer func() { DefaultALookup = oldLookup }()
+	DefaultALookup = func(addr string) (names []string, ttl time.Duration, err error) {
+		switch addr {
+		case "pod.secure.cluster.local", "pod.cluster.local":
+			return []string{"10.1.2.3"}, time.Minute, nil
+		case "mixed.cluster.local":
+			return []string{"10.0.0.1", "10.1.1.1"}, time.Minute, nil
+		case "apiserver.cluster.local":
+			return []string{"10.0.0.1"}, time.Minute, nil
+		case "outside.cluster.local":
+			return []string{"192.168.1.1"}, time.Minute, nil
+		case "metadata.example.com":
+			return []string{"169.254.169.254"}, time.Minute, nil
+		default:
+			return nil, 0, errors.New("test lookup error")
+		}
+	}
+
+	a, err := NewAddresser(config.AdhocRules, nil)
+	require.NoError(t, err)
+
+	for _, tcase := range []struct {
+		name          string
+		hostPort      string
+		clientCN      string
+		expectedAddrs []string
+		expectedErr   string
+	}{
+		{
+			name:          "allows client with required certificate",
+			hostPort:      "pod.secure.cluster.local",
+			clientCN:      "alice",
+			expectedAddrs: []string{"10.1.2.3:80"},
+		},
+		{
+			name:        "denies client without certificate",
+			hostPort:    "pod.secure.cluster.local",
+			expectedErr: "adhoc rule denied access: client certificate required",
+		},
+		{
+			name:        "denies client with other certificate",
+			hostPort:    "pod.secure.cluster.local",
+			clientCN:    "eve",
+			expectedErr: "adhoc rule denied access: client certificate common name 'eve' is not allowed",
+		},
+		{
+			name:          "allows address in allowed cidr",
+			hostPort:      "pod.cluster.local",
+			expectedAddrs: []string{"10.1.2.3:80"},
+		},
+		{
+			name:          "skips denied addresses",
+			hostPort:      "mixed.cluster.local",
+			expectedAddrs: []string{"10.1.1.1:80"},
+		},
+		{
+			name:        "denies address in denied cidr",
+			hostPort:    "apiserver.cluster.local",
+			expectedErr: "destination address 10.0.0.1 is not allowed",
+		},
+		{
+			name:        "denies address outside of allowed cidrs",
+			hostPort:    "outside.cluster.local",
+			expectedErr: "destination address 192.168.1.1 is not allowed",
+		},
+		{
+			name:        "denies dns name pointing at metadata endpoint",
+			hostPort:    "metadata.example.com",
+			expectedErr: "destination address 169.254.169.254 is not allowed",
+		},
+		{
+			name:        "denies loopback ip literal",
+			hostPort:    "[::1]:80",
+			expectedErr: "destination address ::1 is not allowed",
+		},
+	} {
+		t.Run(tcase.name, func(t *testing.T) {
+			req, err := http.NewRequest("GET", "/foo", nil)
+			require.NoError(t, err, "parsing the request shouldn't fail")
+			req.URL.Host = tcase.hostPort
+			if tcase.clientCN != "" {
+				req.TLS = &tls.ConnectionState{
+					PeerCertificates: []*x509.Certificate{{Subject: pkix.Name{CommonName: tcase.clientCN}}},
+				}
+			}
+			be, err := a.Address(req)
+			if tcase.expectedErr != "" {
+				require.EqualError(t, err, tcase.expectedErr)
+				assert.Equal(t, http.StatusForbidden, err.(*Error).StatusCode(), "denials must be forbidden")
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tcase.expectedAddrs, addrsOf(be), "must match expected addresses")
+		})
+	}
+}
+
+func TestAdhocRejectsBadCidrs(t *testing.T) {
+	_, err := NewAddresser([]*pb_route.Adhoc{{DnsNameMatcher: "*", DeniedCidrs: []string{"10.0.0.1"}}}, nil)
+	assert.Error(t, err, "cidrs without a mask must be rejected")
+}
+
+func TestAdhocDestinationHostAndTls(t *testing.T) {
+	configJson := `
+{ "adhoc_rules": [
+	{
+		"dnsNameMatcher": "*.secure.cluster.local",
+		"port": { "default": 443, "allowed": [443] },
+		"security": { "configName": "internal" }
+	},
+	{
+		"dnsNameMatcher": "*.grpc.cluster.local",
+		"port": {
+			"srv": { "service": "grpc", "useTarget": true },
+			"allowed": [10001]
+		}
+	}
+]}`
+	config := &pb.DirectorConfig_Http{}
+	require.NoError(t, jsonpb.UnmarshalString(configJson, config))
+
+	oldLookup := DefaultALookup
+	oldSrvLookup := DefaultSrvLookup
+	defer func() {
+		DefaultALookup = oldLookup
+		DefaultSrvLookup = oldSrvLookup
+	}()
+	DefaultALookup = func(addr string) (names []string, ttl time.Duration, err error) {
+		return []string{"10.1.2.3"}, time.Minute, nil
+	}
+	DefaultSrvLookup = func(service, proto, name string) ([]*net.SRV, time.Duration, error) {
+		return []*net.SRV{{Target: "pod-1.grpc.cluster.local.", Port: 10001}}, time.Minute, nil
+	}
+
+	_, err := NewAddresser(config.AdhocRules, nil)
+	require.EqualError(t, err, "adhoc rule '*.secure.cluster.local' uses unknown tls config 'internal'")
+	internal := &tls.Config{ServerName: "internal"}
+	a, err := NewAddresser(config.AdhocRules, map[string]*tls.Config{"internal": internal})
+	require.NoError(t, err)
+
+	req, err := http.NewRequest("GET", "http://web.secure.cluster.local/foo", nil)
+	require.NoError(t, err)
+	dest, err := a.Address(req)
+	require.NoError(t, err)
+	assert.Equal(t, "web.secure.cluster.local:443", dest.Host)
+	assert.Equal(t, []string{"10.1.2.3:443"}, dest.Addrs)
+	assert.Equal(t, "*.secure.cluster.local", dest.Rule.DnsNameMatcher)
+	assert.True(t, internal == dest.TlsConfig, "the named tls config must be used")
+
+	req, err = http.NewRequest("GET", "http://service.grpc.cluster.local/foo", nil)
+	require.NoError(t, err)
+	dest, err = a.Address(req)
+	require.NoError(t, err)
+	assert.Equal(t, "pod-1.grpc.cluster.local:10001", dest.Host, "the host must be the srv target")
+	assert.Nil(t, dest.TlsConfig, "destinations without security use plain HTTP")
+}
+
+func addrsOf(dest *AdhocDestination) []string {
+	if dest == nil {
+		return nil
+	}
+	return dest.Addrs
+}
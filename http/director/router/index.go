@@ -0,0 +1,42 @@
+package router
+
+import (
+	pb "github.com/mwitkow/kedge/_protogen/kedge/config/http/routes"
+)
+
+// routeIndex is a compiled form of a route table that avoids scanning every route on every request.
+//
+// Routes are bucketed by their exact `host_matcher`. Routes without a host matcher are present in every bucket, so
+// a lookup returns the candidate routes in declaration order, keeping the first-match-wins semantics.
+type routeIndex struct {
+	byHost  map[string][]*pb.Route
+	anyHost []*pb.Route
+}
+
+func newRouteIndex(routes []*pb.Route) *routeIndex {
+	idx := &routeIndex{byHost: make(map[string][]*pb.Route)}
+	for _, route := range routes {
+		if route.HostMatcher != "" {
+			idx.byHost[route.HostMatcher] = nil
+		}
+	}
+	for _, route := range routes {
+		if route.HostMatcher == "" {
+			idx.anyHost = append(idx.anyHost, route)
+			for host, bucket := range idx.byHost {
+				idx.byHost[host] = append(bucket, route)
+			}
+		} else {
+			idx.byHost[route.HostMatcher] = append(idx.byHost[route.HostMatcher], route)
+		}
+	}
+	return idx
+}
+
+// candidates returns the routes that may match the given host, in declaration order.
+func (idx *routeIndex) candidates(host string) []*pb.Route {
+	if bucket, ok := idx.byHost[host]; ok {
+		return bucket
+	}
+	return idx.anyHost
+}
@@ -0,0 +1,121 @@
+package router
+
+import (
+	"fmt"
+	"math/rand"
+	"net/http"
+	"testing"
+
+	pb "github.com/mwitkow/kedge/_protogen/kedge/config/http/routes"
+	"github.com/mwitkow/kedge/http/director/proxyreq"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	fuzzHosts       = []string{"", "a.example.com", "b.example.com", "a.example.com:8080"}
+	fuzzPaths       = []string{"/", "/some", "/some/path", "/other", "/other/path/deeper"}
+	fuzzHeaderKeys  = []string{"X-One", "X-Two"}
+	fuzzHeaderVals  = []string{"valueOne", "valueTwo"}
+	fuzzProxyModes  = []pb.ProxyMode{pb.ProxyMode_ANY, pb.ProxyMode_REVERSE_PROXY, pb.ProxyMode_FORWARD_PROXY}
+	fuzzRequestURIs = []string{"", "http://"}
+)
+
+func randomRoute(rnd *rand.Rand, id int) *pb.Route {
+	route := &pb.Route{
+		BackendName: fmt.Sprintf("backend_%d", id),
+		HostMatcher: fuzzHosts[rnd.Intn(len(fuzzHosts))],
+		ProxyMode:   fuzzProxyModes[rnd.Intn(len(fuzzProxyModes))],
+	}
+	for i := 0; i < rnd.Intn(3); i++ {
+		path := fuzzPaths[rnd.Intn(len(fuzzPaths))]
+		if rnd.Intn(2) == 0 {
+			path = path + "*"
+		}
+		route.PathRules = append(route.PathRules, path)
+	}
+	if rnd.Intn(4) == 0 {
+		route.HeaderMatcher = map[string]string{
+			fuzzHeaderKeys[rnd.Intn(len(fuzzHeaderKeys))]: fuzzHeaderVals[rnd.Intn(len(fuzzHeaderVals))],
+		}
+	}
+	return route
+}
+
+func randomRequest(rnd *rand.Rand) *http.Request {
+	host := fuzzHosts[rnd.Intn(len(fuzzHosts))]
+	path := fuzzPaths[rnd.Intn(len(fuzzPaths))]
+	req, err := http.NewRequest("GET", "http://"+host+path, nil)
+	if err != nil {
+		panic(err)
+	}
+	req.Host = host
+	if prefix := fuzzRequestURIs[rnd.Intn(len(fuzzRequestURIs))]; prefix != "" {
+		req.RequestURI = prefix + host + path
+	} else {
+		req.RequestURI = path
+	}
+	for _, k := range fuzzHeaderKeys {
+		if rnd.Intn(2) == 0 {
+			req.Header.Set(k, fuzzHeaderVals[rnd.Intn(len(fuzzHeaderVals))])
+		}
+	}
+	return proxyreq.NormalizeInboundRequest(req)
+}
+
+func TestRouteIndex_FuzzEquivalenceWithLinear(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1337))
+	for iteration := 0; iteration < 500; iteration++ {
+		routes := []*pb.Route{}
+		for i := 0; i < rnd.Intn(30); i++ {
+			routes = append(routes, randomRoute(rnd, i))
+		}
+		linear := &router{routes: routes}
+		indexed, err := NewStatic(routes)
+		require.NoError(t, err, "fuzzed routes must be valid")
+		for call := 0; call < 50; call++ {
+			req := randomRequest(rnd)
+			expectedBe, expectedErr := linear.Route(req)
+			be, err := indexed.Route(req)
+			require.Equal(t, expectedErr, err, "error must match linear for %v on routes %v", req.URL, routes)
+			require.Equal(t, expectedBe, be, "backend must match linear for %v on routes %v", req.URL, routes)
+		}
+	}
+}
+
+// benchmarkRoutes builds a route table where each route has its own host, as auto-generated configs would.
+func benchmarkRoutes(count int) []*pb.Route {
+	routes := []*pb.Route{}
+	for i := 0; i < count; i++ {
+		routes = append(routes, &pb.Route{
+			BackendName: fmt.Sprintf("backend_%d", i),
+			HostMatcher: fmt.Sprintf("service%d.example.com", i),
+			PathRules:   []string{"/api/*"},
+		})
+	}
+	return routes
+}
+
+func benchmarkRoute(b *testing.B, r Router, count int) {
+	// Hit the last route, the worst case for a linear scan.
+	req, _ := http.NewRequest("GET", fmt.Sprintf("http://service%d.example.com/api/something", count-1), nil)
+	req = proxyreq.NormalizeInboundRequest(req)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := r.Route(req); err != nil {
+			b.Fatalf("routing must not fail: %v", err)
+		}
+	}
+}
+
+func BenchmarkRoute(b *testing.B) {
+	for _, count := range []int{10, 100, 1000, 10000} {
+		routes := benchmarkRoutes(count)
+		b.Run(fmt.Sprintf("Linear_%d", count), func(b *testing.B) {
+			benchmarkRoute(b, &router{routes: routes}, count)
+		})
+		b.Run(fmt.Sprintf("Indexed_%d", count), func(b *testing.B) {
+			indexed, _ := NewStatic(routes)
+			benchmarkRoute(b, indexed, count)
+		})
+	}
+}
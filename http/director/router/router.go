@@ -6,7 +6,10 @@ import (
 	"net/http"
 	"net/url"
 	"errors"
+	"fmt"
+	"time"
 
+	"github.com/golang/protobuf/ptypes"
 	"github.com/mwitkow/kedge/http/director/proxyreq"
 	"google.golang.org/grpc/metadata"
 
@@ -22,18 +25,138 @@ type Router interface {
 	// Route returns a backend name for a given call, or an error.
 	// Note: the request *must* be normalized.
 	Route(req *http.Request) (backendName string, err error)
+
+	// Match returns the backend, mirroring config and timeout for a given call, matching the routes only once, or an
+	// error.
+	// Note: the request *must* be normalized.
+	Match(req *http.Request) (*Match, error)
+
+	// Mirror returns the mirroring config of the route matching the request, or nil if the request isn't mirrored.
+	// Note: the request *must* be normalized.
+	Mirror(req *http.Request) *pb.Mirror
+
+	// Timeout returns the timeout of the route matching the request, or 0 if there's none.
+	// Note: the request *must* be normalized.
+	Timeout(req *http.Request) time.Duration
+}
+
+// Match is what the route matching a request decides about it.
+type Match struct {
+	// BackendName is the backend the request is sent to, chosen between weighted backends if the route has any.
+	BackendName string
+	// Mirror is the mirroring config of the route, or nil if the request isn't mirrored.
+	Mirror *pb.Mirror
+	// Timeout is the timeout of the route, or 0 if there's none.
+	Timeout time.Duration
 }
 
 type router struct {
 	routes []*pb.Route
+	// index is the compiled lookup table for routes. If nil, all routes are scanned linearly.
+	index *routeIndex
+	// hasMirrors is false if no route is mirrored, in which case Mirror can skip matching.
+	hasMirrors bool
+	// timeouts holds the parsed timeouts of routes that have them.
+	timeouts map[*pb.Route]time.Duration
 }
 
-func NewStatic(routes []*pb.Route) *router {
-	return &router{routes: routes}
+// NewStatic creates a router from a static list of routes, validating their backends and timeouts.
+func NewStatic(routes []*pb.Route) (*router, error) {
+	r := &router{routes: routes, index: newRouteIndex(routes), timeouts: make(map[*pb.Route]time.Duration)}
+	for i, route := range routes {
+		if route.BackendName != "" && len(route.Backends) > 0 {
+			return nil, fmt.Errorf("route %d for backend '%v' has both backend_name and backends", i, route.BackendName)
+		}
+		for _, be := range route.Backends {
+			if be.Name == "" {
+				return nil, fmt.Errorf("route %d has a weighted backend without a name", i)
+			}
+		}
+		if route.Mirror != nil && route.Mirror.BackendName != "" {
+			r.hasMirrors = true
+		}
+		if route.Timeout != nil {
+			d, err := ptypes.Duration(route.Timeout)
+			if err != nil {
+				return nil, fmt.Errorf("route %d for backend '%v' has a bad timeout: %v", i, route.BackendName, err)
+			}
+			if d < 0 {
+				return nil, fmt.Errorf("route %d for backend '%v' has a negative timeout", i, route.BackendName)
+			}
+			if d > 0 {
+				r.timeouts[route] = d
+			}
+		}
+	}
+	return r, nil
 }
 
 func (r *router) Route(req *http.Request) (backendName string, err error) {
-	for _, route := range r.routes {
+	route := r.match(req)
+	if route == nil {
+		return "", ErrRouteNotFound
+	}
+	return backendFor(req, route), nil
+}
+
+func (r *router) Match(req *http.Request) (*Match, error) {
+	route := r.match(req)
+	if route == nil {
+		return nil, ErrRouteNotFound
+	}
+	return &Match{BackendName: backendFor(req, route), Mirror: mirrorOf(route), Timeout: r.timeoutOf(route)}, nil
+}
+
+func (r *router) Mirror(req *http.Request) *pb.Mirror {
+	if r.index != nil && !r.hasMirrors {
+		return nil
+	}
+	route := r.match(req)
+	if route == nil {
+		return nil
+	}
+	return mirrorOf(route)
+}
+
+func (r *router) Timeout(req *http.Request) time.Duration {
+	if r.index != nil && len(r.timeouts) == 0 {
+		return 0
+	}
+	route := r.match(req)
+	if route == nil {
+		return 0
+	}
+	return r.timeoutOf(route)
+}
+
+// mirrorOf returns the mirroring config of the route, or nil if it isn't mirrored.
+func mirrorOf(route *pb.Route) *pb.Mirror {
+	if route.Mirror == nil || route.Mirror.BackendName == "" {
+		return nil
+	}
+	return route.Mirror
+}
+
+// timeoutOf returns the timeout of the route, or 0 if there's none.
+func (r *router) timeoutOf(route *pb.Route) time.Duration {
+	if route.Timeout == nil {
+		return 0
+	}
+	if d, ok := r.timeouts[route]; ok {
+		return d
+	}
+	// Routers that weren't built through NewStatic don't have their timeouts parsed upfront.
+	d, _ := ptypes.Duration(route.Timeout)
+	return d
+}
+
+// match returns the first route matching the request, or nil if none does.
+func (r *router) match(req *http.Request) *pb.Route {
+	candidates := r.routes
+	if r.index != nil {
+		candidates = r.index.candidates(req.URL.Host)
+	}
+	for _, route := range candidates {
 		if !r.urlMatches(req.URL, route.PathRules) {
 			continue
 		}
@@ -46,9 +169,9 @@ func (r *router) Route(req *http.Request) (backendName string, err error) {
 		if !r.requestTypeMatch(proxyreq.GetProxyMode(req), route.ProxyMode) {
 			continue
 		}
-		return route.BackendName, nil
+		return route
 	}
-	return "", ErrRouteNotFound
+	return nil
 }
 
 func (r *router) urlMatches(u *url.URL, matchers []string) bool {
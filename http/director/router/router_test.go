@@ -0,0 +1,49 @@
+package router
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/ptypes"
+	"github.com/golang/protobuf/ptypes/duration"
+	pb "github.com/mwitkow/kedge/_protogen/kedge/config/http/routes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewStaticParsesRouteTimeouts(t *testing.T) {
+	r, err := NewStatic([]*pb.Route{{BackendName: "a", Timeout: ptypes.DurationProto(3 * time.Second)}})
+	require.NoError(t, err)
+	req, _ := http.NewRequest("GET", "http://a.example.com/", nil)
+	assert.Equal(t, 3*time.Second, r.Timeout(req), "timeout of the route must be used")
+}
+
+func TestNewStaticFailsOnBadTimeout(t *testing.T) {
+	_, err := NewStatic([]*pb.Route{{BackendName: "a", Timeout: ptypes.DurationProto(-time.Second)}})
+	assert.Error(t, err, "negative timeout must fail")
+	_, err = NewStatic([]*pb.Route{{BackendName: "a", Timeout: &duration.Duration{Seconds: 1, Nanos: -1}}})
+	assert.Error(t, err, "invalid timeout must fail")
+}
+
+func TestMatchReturnsBackendMirrorAndTimeout(t *testing.T) {
+	mirror := &pb.Mirror{BackendName: "b", Percentage: 100}
+	r, err := NewStatic([]*pb.Route{
+		{BackendName: "a", HostMatcher: "a.example.com", Mirror: mirror, Timeout: ptypes.DurationProto(3 * time.Second)},
+		{BackendName: "c", HostMatcher: "c.example.com"},
+	})
+	require.NoError(t, err)
+	req, _ := http.NewRequest("GET", "http://a.example.com/", nil)
+	match, err := r.Match(req)
+	require.NoError(t, err)
+	assert.Equal(t, &Match{BackendName: "a", Mirror: mirror, Timeout: 3 * time.Second}, match)
+
+	req, _ = http.NewRequest("GET", "http://c.example.com/", nil)
+	match, err = r.Match(req)
+	require.NoError(t, err)
+	assert.Equal(t, &Match{BackendName: "c"}, match, "routes without mirrors and timeouts must have none")
+
+	req, _ = http.NewRequest("GET", "http://unknown.example.com/", nil)
+	_, err = r.Match(req)
+	assert.Equal(t, ErrRouteNotFound, err)
+}
@@ -0,0 +1,59 @@
+package router
+
+import (
+	"net/http"
+
+	pb "github.com/mwitkow/kedge/_protogen/kedge/config/http/routes"
+	"github.com/mwitkow/kedge/lib/split"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	splitBackendCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "kedge",
+			Subsystem: "http",
+			Name:      "route_split_requests_total",
+			Help:      "Count of requests on routes with weighted backends, by the backend that was chosen.",
+		}, []string{"backend_name"})
+)
+
+func init() {
+	prometheus.MustRegister(splitBackendCounter)
+}
+
+// backendFor returns the backend of a matched route, choosing between weighted backends if the route has any.
+func backendFor(req *http.Request, route *pb.Route) string {
+	if len(route.Backends) == 0 {
+		return route.BackendName
+	}
+	key, _ := stickyKey(req, route.Sticky)
+	i := split.Pick(len(route.Backends), func(i int) uint32 { return route.Backends[i].Weight }, key)
+	name := route.Backends[i].Name
+	splitBackendCounter.WithLabelValues(name).Inc()
+	return name
+}
+
+// stickyKey returns the value of the request that consistently identifies the client, if configured and present.
+func stickyKey(req *http.Request, sticky *pb.Sticky) (string, bool) {
+	if sticky == nil {
+		return "", false
+	}
+	switch {
+	case sticky.GetHeader() != "":
+		val := req.Header.Get(sticky.GetHeader())
+		return val, val != ""
+	case sticky.GetCookie() != "":
+		c, err := req.Cookie(sticky.GetCookie())
+		if err != nil || c.Value == "" {
+			return "", false
+		}
+		return c.Value, true
+	case sticky.GetClientIdentity():
+		if req.TLS != nil {
+			return split.ClientIdentity(req.TLS.PeerCertificates, req.RemoteAddr)
+		}
+		return split.ClientIdentity(nil, req.RemoteAddr)
+	}
+	return "", false
+}
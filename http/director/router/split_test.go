@@ -0,0 +1,167 @@
+package router
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/golang/protobuf/jsonpb"
+	pb "github.com/mwitkow/kedge/_protogen/kedge/config"
+	"github.com/mwitkow/kedge/http/director/proxyreq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const splitConfigJson = `
+{ "routes": [
+	{
+		"hostMatcher": "random.example.com",
+		"backends": [
+			{ "name": "controller", "weight": 90 },
+			{ "name": "controller-canary", "weight": 10 }
+		]
+	},
+	{
+		"hostMatcher": "header.example.com",
+		"backends": [
+			{ "name": "controller", "weight": 50 },
+			{ "name": "controller-canary", "weight": 50 }
+		],
+		"sticky": { "header": "X-User-Id" }
+	},
+	{
+		"hostMatcher": "cookie.example.com",
+		"backends": [
+			{ "name": "controller", "weight": 50 },
+			{ "name": "controller-canary", "weight": 50 }
+		],
+		"sticky": { "cookie": "session" }
+	},
+	{
+		"hostMatcher": "identity.example.com",
+		"backends": [
+			{ "name": "controller", "weight": 50 },
+			{ "name": "controller-canary", "weight": 50 }
+		],
+		"sticky": { "clientIdentity": true }
+	},
+	{
+		"hostMatcher": "zero.example.com",
+		"backends": [
+			{ "name": "controller" },
+			{ "name": "controller-canary" }
+		]
+	}
+]}`
+
+func splitRouter(t *testing.T) Router {
+	config := &pb.DirectorConfig_Http{}
+	require.NoError(t, jsonpb.UnmarshalString(splitConfigJson, config))
+	r, err := NewStatic(config.Routes)
+	require.NoError(t, err)
+	return r
+}
+
+func splitRequest(host string, decorate func(req *http.Request)) *http.Request {
+	req, _ := http.NewRequest("GET", "http://"+host+"/", nil)
+	req.RemoteAddr = "10.0.0.1:31337"
+	if decorate != nil {
+		decorate(req)
+	}
+	return proxyreq.NormalizeInboundRequest(req)
+}
+
+func TestWeightedSplit_FollowsWeights(t *testing.T) {
+	r := splitRouter(t)
+	counts := map[string]int{}
+	for i := 0; i < 10000; i++ {
+		be, err := r.Route(splitRequest("random.example.com", nil))
+		require.NoError(t, err)
+		counts[be]++
+	}
+	assert.Len(t, counts, 2, "only the weighted backends must be chosen")
+	assert.InDelta(t, 9000, counts["controller"], 300, "controller must receive ~90%% of requests")
+	assert.InDelta(t, 1000, counts["controller-canary"], 300, "canary must receive ~10%% of requests")
+}
+
+func TestWeightedSplit_ZeroWeightsSplitEqually(t *testing.T) {
+	r := splitRouter(t)
+	counts := map[string]int{}
+	for i := 0; i < 10000; i++ {
+		be, err := r.Route(splitRequest("zero.example.com", nil))
+		require.NoError(t, err)
+		counts[be]++
+	}
+	assert.InDelta(t, 5000, counts["controller"], 300)
+	assert.InDelta(t, 5000, counts["controller-canary"], 300)
+}
+
+func TestWeightedSplit_Sticky(t *testing.T) {
+	r := splitRouter(t)
+	for _, tcase := range []struct {
+		name     string
+		host     string
+		decorate func(req *http.Request, client int)
+	}{
+		{
+			name: "Header",
+			host: "header.example.com",
+			decorate: func(req *http.Request, client int) {
+				req.Header.Set("X-User-Id", fmt.Sprintf("user-%d", client))
+			},
+		},
+		{
+			name: "Cookie",
+			host: "cookie.example.com",
+			decorate: func(req *http.Request, client int) {
+				req.AddCookie(&http.Cookie{Name: "session", Value: fmt.Sprintf("session-%d", client)})
+			},
+		},
+		{
+			name: "ClientIdentity",
+			host: "identity.example.com",
+			decorate: func(req *http.Request, client int) {
+				req.RemoteAddr = fmt.Sprintf("10.0.1.%d:%d", client, 30000+client)
+			},
+		},
+	} {
+		t.Run(tcase.name, func(t *testing.T) {
+			seen := map[string]bool{}
+			for client := 0; client < 50; client++ {
+				decorate := func(req *http.Request) { tcase.decorate(req, client) }
+				first, err := r.Route(splitRequest(tcase.host, decorate))
+				require.NoError(t, err)
+				seen[first] = true
+				for i := 0; i < 10; i++ {
+					be, err := r.Route(splitRequest(tcase.host, decorate))
+					require.NoError(t, err)
+					require.Equal(t, first, be, "client %d must always be sent to the same backend", client)
+				}
+			}
+			assert.Len(t, seen, 2, "different clients must be spread over both backends")
+		})
+	}
+}
+
+func TestWeightedSplit_RejectsBadBackends(t *testing.T) {
+	for _, tcase := range []struct {
+		name   string
+		config string
+	}{
+		{
+			name:   "BackendWithoutName",
+			config: `{ "routes": [ { "backends": [ { "name": "controller", "weight": 50 }, { "weight": 50 } ] } ] }`,
+		},
+		{
+			name:   "BackendNameAndBackends",
+			config: `{ "routes": [ { "backendName": "controller", "backends": [ { "name": "controller-canary" } ] } ] }`,
+		},
+	} {
+		t.Run(tcase.name, func(t *testing.T) {
+			config := &pb.DirectorConfig_Http{}
+			require.NoError(t, jsonpb.UnmarshalString(tcase.config, config))
+			_, err := NewStatic(config.Routes)
+			assert.Error(t, err)
+		})
+	}
+}
@@ -0,0 +1,34 @@
+package director
+
+import (
+	"net/http"
+
+	"github.com/mwitkow/kedge/lib/writedeadline"
+)
+
+// streamingWriter clears the write deadline of the server for responses without a Content-Length, as these are
+// usually streamed (e.g. server-sent events) for longer than the server's write timeout. Route timeouts still apply.
+//
+// Only the write deadlines of servers listening with writedeadline.Listen are cleared.
+type streamingWriter struct {
+	http.ResponseWriter
+	req *http.Request
+}
+
+func (w *streamingWriter) WriteHeader(code int) {
+	if w.Header().Get("Content-Length") == "" {
+		writedeadline.Clear(w.req)
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *streamingWriter) Flush() {
+	w.ResponseWriter.(http.Flusher).Flush()
+}
+
+func (w *streamingWriter) CloseNotify() <-chan bool {
+	if cn, ok := w.ResponseWriter.(http.CloseNotifier); ok {
+		return cn.CloseNotify()
+	}
+	return nil
+}
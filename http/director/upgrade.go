@@ -0,0 +1,263 @@
+package director
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mwitkow/kedge/http/director/router"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	// UpgradeIdleTimeout closes upgraded connections (e.g. WebSockets) that had no traffic in either direction.
+	UpgradeIdleTimeout = 5 * time.Minute
+	// UpgradeDialTimeout is the maximum time for dialing and reading the upgrade response of the backend.
+	UpgradeDialTimeout = 10 * time.Second
+
+	// upgradeHopHeaders are not forwarded with Upgrade requests. Unlike httputil.ReverseProxy, `Connection` and
+	// `Upgrade` are kept as they are needed for the upgrade.
+	upgradeHopHeaders = []string{
+		"Proxy-Connection",
+		"Keep-Alive",
+		"Proxy-Authenticate",
+		"Proxy-Authorization",
+		"Te",
+		"Trailer",
+		"Transfer-Encoding",
+	}
+
+	upgradeConnectionsCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "kedge",
+			Subsystem: "http",
+			Name:      "upgraded_connections_total",
+			Help:      "Count of connections upgraded (e.g. to WebSockets) through the proxy, by backend and protocol.",
+		}, []string{"backend_name", "protocol"})
+	upgradeConnectionsGauge = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "kedge",
+			Subsystem: "http",
+			Name:      "upgraded_connections_open",
+			Help:      "Number of upgraded connections that are currently open, by backend.",
+		}, []string{"backend_name"})
+	upgradeBytesCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "kedge",
+			Subsystem: "http",
+			Name:      "upgraded_connection_bytes_total",
+			Help:      "Count of bytes sent over upgraded connections, by backend and direction (upstream or downstream).",
+		}, []string{"backend_name", "direction"})
+	upgradeIdleTimeoutsCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "kedge",
+			Subsystem: "http",
+			Name:      "upgraded_connection_idle_timeouts_total",
+			Help:      "Count of upgraded connections closed due to UpgradeIdleTimeout, by backend.",
+		}, []string{"backend_name"})
+)
+
+// adhocBackendLabel prefixes the dns_name_matcher of adhoc rules in metrics labelled by backend.
+const adhocBackendLabel = "_adhoc:"
+
+func init() {
+	prometheus.MustRegister(upgradeConnectionsCounter)
+	prometheus.MustRegister(upgradeConnectionsGauge)
+	prometheus.MustRegister(upgradeBytesCounter)
+	prometheus.MustRegister(upgradeIdleTimeoutsCounter)
+}
+
+// isUpgrade returns true for requests that ask to switch protocols, e.g. WebSockets.
+func isUpgrade(req *http.Request) bool {
+	if req.Header.Get("Upgrade") == "" {
+		return false
+	}
+	for _, v := range req.Header["Connection"] {
+		for _, token := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(token), "upgrade") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+type upgradeDialFunc func(ctx context.Context) (net.Conn, error)
+
+// serveUpgrade sends the Upgrade request to a connection from dialFunc. If the backend switches protocols, the
+// client connection is hijacked and bytes are copied in both directions until either side closes or goes idle.
+func serveUpgrade(resp http.ResponseWriter, req *http.Request, backendLabel string, dialFunc upgradeDialFunc) {
+	hijacker, ok := resp.(http.Hijacker)
+	if !ok {
+		respondWithError(router.NewError(http.StatusBadRequest, "upgrades are not supported over this connection"), resp)
+		return
+	}
+	ctx, cancel := context.WithTimeout(req.Context(), UpgradeDialTimeout)
+	defer cancel()
+	backendConn, err := dialFunc(ctx)
+	if err != nil {
+		respondWithError(err, resp)
+		return
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		backendConn.SetDeadline(deadline)
+	}
+	if err := upgradeRequest(req).Write(backendConn); err != nil {
+		backendConn.Close()
+		respondWithError(fmt.Errorf("failed writing upgrade request: %v", err), resp)
+		return
+	}
+	backendReader := bufio.NewReader(backendConn)
+	backendResp, err := http.ReadResponse(backendReader, req)
+	if err != nil {
+		backendConn.Close()
+		respondWithError(fmt.Errorf("failed reading upgrade response: %v", err), resp)
+		return
+	}
+	if backendResp.StatusCode != http.StatusSwitchingProtocols {
+		// The backend refused the upgrade, pass its response on as it is.
+		defer backendConn.Close()
+		defer backendResp.Body.Close()
+		for k, v := range backendResp.Header {
+			resp.Header()[k] = v
+		}
+		resp.WriteHeader(backendResp.StatusCode)
+		io.Copy(resp, backendResp.Body)
+		return
+	}
+	backendConn.SetDeadline(time.Time{})
+	clientConn, clientBuf, err := hijacker.Hijack()
+	if err != nil {
+		backendConn.Close()
+		respondWithError(fmt.Errorf("failed hijacking connection: %v", err), resp)
+		return
+	}
+	// The hijacked connection keeps the deadlines set by the http.Server, the idle timeout replaces them.
+	clientConn.SetDeadline(time.Time{})
+	if err := writeUpgradeResponse(clientBuf.Writer, resp.Header(), backendResp); err != nil {
+		clientConn.Close()
+		backendConn.Close()
+		return
+	}
+	upgradeConnectionsCounter.WithLabelValues(backendLabel, strings.ToLower(backendResp.Header.Get("Upgrade"))).Inc()
+	upgradeConnectionsGauge.WithLabelValues(backendLabel).Inc()
+	defer upgradeConnectionsGauge.WithLabelValues(backendLabel).Dec()
+	splice(backendLabel, clientConn, clientBuf.Reader, backendConn, backendReader)
+}
+
+// upgradeRequest builds the request sent to the backend.
+func upgradeRequest(req *http.Request) *http.Request {
+	outUrl := *req.URL
+	outReq := &http.Request{
+		Method:     req.Method,
+		URL:        &outUrl,
+		Proto:      "HTTP/1.1",
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+		Header:     make(http.Header),
+		Host:       req.Host,
+	}
+	for k, v := range req.Header {
+		outReq.Header[k] = append([]string(nil), v...)
+	}
+	for _, h := range upgradeHopHeaders {
+		outReq.Header.Del(h)
+	}
+	outReq.Header.Set("Connection", "Upgrade")
+	if clientIP, _, err := net.SplitHostPort(req.RemoteAddr); err == nil {
+		if prior, ok := outReq.Header["X-Forwarded-For"]; ok {
+			clientIP = strings.Join(prior, ", ") + ", " + clientIP
+		}
+		outReq.Header.Set("X-Forwarded-For", clientIP)
+	}
+	return outReq
+}
+
+// writeUpgradeResponse writes the 101 of the backend, together with headers already set by the proxy.
+func writeUpgradeResponse(w *bufio.Writer, proxyHeader http.Header, backendResp *http.Response) error {
+	header := make(http.Header)
+	for k, v := range proxyHeader {
+		header[k] = v
+	}
+	for k, v := range backendResp.Header {
+		header[k] = v
+	}
+	if _, err := fmt.Fprintf(w, "HTTP/1.1 %s\r\n", backendResp.Status); err != nil {
+		return err
+	}
+	if err := header.Write(w); err != nil {
+		return err
+	}
+	if _, err := w.WriteString("\r\n"); err != nil {
+		return err
+	}
+	return w.Flush()
+}
+
+// splice copies bytes in both directions, until one of them finishes.
+func splice(backendLabel string, clientConn net.Conn, clientReader io.Reader, backendConn net.Conn, backendReader io.Reader) {
+	idle := &idleTracker{timeout: UpgradeIdleTimeout, conns: []net.Conn{clientConn, backendConn}}
+	idle.touch()
+	done := make(chan struct{}, 2)
+	copyFunc := func(dst net.Conn, src io.Reader, direction string) {
+		n, _ := io.Copy(dst, &idleReader{Reader: src, idle: idle})
+		upgradeBytesCounter.WithLabelValues(backendLabel, direction).Add(float64(n))
+		done <- struct{}{}
+	}
+	go copyFunc(backendConn, clientReader, "upstream")
+	go copyFunc(clientConn, backendReader, "downstream")
+	<-done
+	clientConn.Close()
+	backendConn.Close()
+	<-done
+	if idle.expired() {
+		upgradeIdleTimeoutsCounter.WithLabelValues(backendLabel).Inc()
+	}
+}
+
+// idleTracker extends the deadlines of both connections whenever data goes through either of them.
+type idleTracker struct {
+	timeout time.Duration
+	conns   []net.Conn
+
+	mu       sync.Mutex
+	deadline time.Time
+}
+
+func (t *idleTracker) touch() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	deadline := time.Now().Add(t.timeout)
+	if deadline.Sub(t.deadline) < t.timeout/100 {
+		return // avoid resetting deadlines on every read of a busy connection.
+	}
+	t.deadline = deadline
+	for _, c := range t.conns {
+		c.SetDeadline(t.deadline)
+	}
+}
+
+func (t *idleTracker) expired() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return !time.Now().Before(t.deadline)
+}
+
+type idleReader struct {
+	io.Reader
+	idle *idleTracker
+}
+
+func (r *idleReader) Read(p []byte) (int, error) {
+	n, err := r.Reader.Read(p)
+	if n > 0 {
+		r.idle.touch()
+	}
+	return n, err
+}
@@ -1,6 +1,8 @@
 package http_integration
 
 import (
+	"bufio"
+	"io"
 	"net"
 
 	"crypto/tls"
@@ -13,6 +15,7 @@ import (
 
 	"io/ioutil"
 
+	"github.com/golang/protobuf/ptypes"
 	"github.com/mwitkow/go-conntrack/connhelpers"
 	"github.com/mwitkow/go-srvlb/srv"
 	pb_res "github.com/mwitkow/kedge/_protogen/kedge/config/common/resolvers"
@@ -33,6 +36,8 @@ import (
 	"github.com/mwitkow/kedge/http/director"
 	"github.com/mwitkow/kedge/http/director/router"
 	"github.com/mwitkow/kedge/lib/resolvers"
+	"github.com/mwitkow/kedge/lib/writedeadline"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"github.com/stretchr/testify/suite"
@@ -62,6 +67,64 @@ var backendConfigs = []*pb_be.Backend{
 		},
 		Balancer: pb_be.Balancer_ROUND_ROBIN,
 	},
+	&pb_be.Backend{
+		Name: "mirror",
+		Resolver: &pb_be.Backend_Srv{
+			Srv: &pb_res.SrvResolver{
+				DnsName: "_http._tcp.mirror.backends.test.local",
+			},
+		},
+		Balancer: pb_be.Balancer_ROUND_ROBIN,
+	},
+	&pb_be.Backend{
+		Name: "empty",
+		Resolver: &pb_be.Backend_Srv{
+			Srv: &pb_res.SrvResolver{
+				DnsName: "_http._tcp.empty.backends.test.local",
+			},
+		},
+	},
+	&pb_be.Backend{
+		Name: "dead",
+		Resolver: &pb_be.Backend_Srv{
+			Srv: &pb_res.SrvResolver{
+				DnsName: "_http._tcp.dead.backends.test.local",
+			},
+		},
+	},
+	&pb_be.Backend{
+		Name: "warm_non_secure",
+		Resolver: &pb_be.Backend_Srv{
+			Srv: &pb_res.SrvResolver{
+				DnsName: "_http._tcp.nonsecure.backends.test.local",
+			},
+		},
+		ConnectionPool: &pb_be.ConnectionPool{WarmPerTarget: 2},
+	},
+	&pb_be.Backend{
+		Name: "warm_secure",
+		Resolver: &pb_be.Backend_Srv{
+			Srv: &pb_res.SrvResolver{
+				DnsName: "_https._tcp.secure.backends.test.local",
+			},
+		},
+		Security: &pb_be.Security{
+			InsecureSkipVerify: true,
+		},
+		ConnectionPool: &pb_be.ConnectionPool{WarmPerTarget: 1},
+	},
+	&pb_be.Backend{
+		Name: "failover_from_empty",
+		Resolver: &pb_be.Backend_Failover{
+			Failover: &pb_be.Failover{Backends: []string{"empty", "non_secure"}},
+		},
+	},
+	&pb_be.Backend{
+		Name: "failover_from_dead",
+		Resolver: &pb_be.Backend_Failover{
+			Failover: &pb_be.Failover{Backends: []string{"empty", "dead", "non_secure"}},
+		},
+	},
 }
 
 var nonSecureBackendCount = 5
@@ -90,6 +153,42 @@ var routeConfigs = []*pb_route.Route{
 		HostMatcher: "secure.backends.test.local",
 		ProxyMode:   pb_route.ProxyMode_FORWARD_PROXY,
 	},
+	&pb_route.Route{
+		BackendName: "non_secure",
+		HostMatcher: "timeout.ext.example.com",
+		ProxyMode:   pb_route.ProxyMode_REVERSE_PROXY,
+		Timeout:     ptypes.DurationProto(100 * time.Millisecond),
+	},
+	&pb_route.Route{
+		BackendName: "non_secure",
+		HostMatcher: "mirrored.ext.example.com",
+		ProxyMode:   pb_route.ProxyMode_REVERSE_PROXY,
+		Mirror: &pb_route.Mirror{
+			BackendName:  "mirror",
+			Percentage:   100,
+			MaxBodyBytes: 64,
+		},
+	},
+	&pb_route.Route{
+		BackendName: "warm_non_secure",
+		HostMatcher: "warm-nonsecure.ext.example.com",
+		ProxyMode:   pb_route.ProxyMode_REVERSE_PROXY,
+	},
+	&pb_route.Route{
+		BackendName: "warm_secure",
+		HostMatcher: "warm-secure.ext.example.com",
+		ProxyMode:   pb_route.ProxyMode_REVERSE_PROXY,
+	},
+	&pb_route.Route{
+		BackendName: "failover_from_empty",
+		HostMatcher: "failover-empty.ext.example.com",
+		ProxyMode:   pb_route.ProxyMode_REVERSE_PROXY,
+	},
+	&pb_route.Route{
+		BackendName: "failover_from_dead",
+		HostMatcher: "failover-dead.ext.example.com",
+		ProxyMode:   pb_route.ProxyMode_REVERSE_PROXY,
+	},
 }
 
 var adhocConfig = []*pb_route.Adhoc{
@@ -105,11 +204,49 @@ var adhocConfig = []*pb_route.Adhoc{
 			},
 		},
 	},
+	{
+		DnsNameMatcher: "*.secure-pods.test.local",
+		Port: &pb_route.Adhoc_Port{
+			AllowedRanges: []*pb_route.Adhoc_Port_Range{
+				{
+					From: 1024,
+					To:   65535,
+				},
+			},
+		},
+		Security: &pb_route.Adhoc_Security{ConfigName: "test_backends"},
+		ConnectionPool: &pb_route.Adhoc_ConnectionPool{
+			MaxConns: 1,
+		},
+	},
+}
+
+// adhocTlsConfigs are the named TLS configs of adhoc rules. The testing certs don't verify.
+var adhocTlsConfigs = map[string]*tls.Config{
+	"test_backends": {InsecureSkipVerify: true},
 }
 
 func unknownPingbackHandler(serverAddr string) http.Handler {
 	return http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		if req.Header.Get("Upgrade") == "echo" {
+			serveEchoUpgrade(resp, req, serverAddr)
+			return
+		}
+		if req.URL.Path == "/sleep" {
+			time.Sleep(1 * time.Second)
+		}
+		if req.URL.Path == "/stream" {
+			// Streams a chunk every 100ms for 500ms, without a Content-Length.
+			resp.WriteHeader(http.StatusOK)
+			for i := 0; i < 5; i++ {
+				fmt.Fprintf(resp, "chunk %d\n", i)
+				resp.(http.Flusher).Flush()
+				time.Sleep(100 * time.Millisecond)
+			}
+			return
+		}
 		resp.Header().Set("x-test-req-proto", fmt.Sprintf("%d.%d", req.ProtoMajor, req.ProtoMinor))
+		resp.Header().Set("x-test-req-tls", fmt.Sprintf("%v", req.TLS != nil))
 		resp.Header().Set("x-test-req-url", req.URL.String())
 		resp.Header().Set("x-test-req-host", req.Host)
 		resp.Header().Set("x-test-backend-addr", serverAddr)
@@ -117,6 +254,27 @@ func unknownPingbackHandler(serverAddr string) http.Handler {
 	})
 }
 
+// serveEchoUpgrade switches to a protocol that echoes back everything it receives.
+func serveEchoUpgrade(resp http.ResponseWriter, req *http.Request, serverAddr string) {
+	conn, bufrw, err := resp.(http.Hijacker).Hijack()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	fmt.Fprintf(bufrw, "HTTP/1.1 101 Switching Protocols\r\nUpgrade: echo\r\nConnection: Upgrade\r\nX-Test-Backend-Addr: %s\r\n\r\n", serverAddr)
+	bufrw.Flush()
+	buf := make([]byte, 1024)
+	for {
+		n, err := bufrw.Read(buf)
+		if err != nil {
+			return
+		}
+		if _, err := conn.Write(buf[:n]); err != nil {
+			return
+		}
+	}
+}
+
 type localBackends struct {
 	mu         sync.RWMutex
 	resolvable int
@@ -124,14 +282,14 @@ type localBackends struct {
 	servers    []*http.Server
 }
 
-func buildAndStartServer(t *testing.T, config *tls.Config) (net.Listener, *http.Server) {
+func buildAndStartServer(t *testing.T, config *tls.Config, handlerFunc func(serverAddr string) http.Handler) (net.Listener, *http.Server) {
 	listener, err := net.Listen("tcp", "127.0.0.1:0")
 	require.NoError(t, err, "must be able to allocate a port for localBackend")
 	if config != nil {
 		listener = tls.NewListener(listener, config)
 	}
 	server := &http.Server{
-		Handler: unknownPingbackHandler(listener.Addr().String()),
+		Handler: handlerFunc(listener.Addr().String()),
 	}
 	go func() {
 		server.Serve(listener)
@@ -140,7 +298,11 @@ func buildAndStartServer(t *testing.T, config *tls.Config) (net.Listener, *http.
 }
 
 func (l *localBackends) addServer(t *testing.T, config *tls.Config) {
-	listener, server := buildAndStartServer(t, config)
+	l.addServerWithHandler(t, config, unknownPingbackHandler)
+}
+
+func (l *localBackends) addServerWithHandler(t *testing.T, config *tls.Config, handlerFunc func(serverAddr string) http.Handler) {
+	listener, server := buildAndStartServer(t, config, handlerFunc)
 	l.mu.Lock()
 	l.servers = append(l.servers, server)
 	l.listeners = append(l.listeners, listener)
@@ -181,9 +343,10 @@ type BackendPoolIntegrationTestSuite struct {
 	proxyListenerTls   net.Listener
 
 	originalSrvResolver srv.Resolver
-	originalAResolver   func(addr string) (names []string, err error)
+	originalAResolver   func(addr string) (names []string, ttl time.Duration, err error)
 
-	localBackends map[string]*localBackends
+	localBackends    map[string]*localBackends
+	mirroredRequests chan string
 }
 
 func TestBackendPoolIntegrationTestSuite(t *testing.T) {
@@ -200,8 +363,12 @@ func (s *BackendPoolIntegrationTestSuite) Lookup(domainName string) ([]*srv.Targ
 }
 
 // implements A resolver that always resolves local host.
-func (s *BackendPoolIntegrationTestSuite) LookupAddr(addr string) (names []string, err error) {
-	return []string{"127.0.0.1"}, nil
+func (s *BackendPoolIntegrationTestSuite) LookupAddr(addr string) (names []string, ttl time.Duration, err error) {
+	if strings.HasPrefix(addr, "failover.") {
+		// Nothing listens on 127.0.0.2, so dials to it are refused.
+		return []string{"127.0.0.2", "127.0.0.1"}, time.Minute, nil
+	}
+	return []string{"127.0.0.1"}, time.Minute, nil
 }
 
 func (s *BackendPoolIntegrationTestSuite) SetupSuite() {
@@ -223,8 +390,10 @@ func (s *BackendPoolIntegrationTestSuite) SetupSuite() {
 
 	pool, err := backendpool.NewStatic(backendConfigs)
 	require.NoError(s.T(), err, "backend pool creation must not fail")
-	staticRouter := router.NewStatic(routeConfigs)
-	addresser := router.NewAddresser(adhocConfig)
+	staticRouter, err := router.NewStatic(routeConfigs)
+	require.NoError(s.T(), err, "router creation must not fail")
+	addresser, err := router.NewAddresser(adhocConfig, adhocTlsConfigs)
+	require.NoError(s.T(), err, "addresser creation must not fail")
 	s.proxy = &http.Server{
 		Handler: director.New(pool, staticRouter, addresser),
 	}
@@ -281,6 +450,25 @@ func (s *BackendPoolIntegrationTestSuite) buildBackends() {
 	}
 	secure.setResolvableCount(100)
 	s.localBackends["_https._tcp.secure.backends.test.local"] = secure
+	s.mirroredRequests = make(chan string, 100)
+	mirror := &localBackends{}
+	mirror.addServerWithHandler(s.T(), nil /* notls */, s.recordingMirrorHandler)
+	mirror.setResolvableCount(100)
+	s.localBackends["_http._tcp.mirror.backends.test.local"] = mirror
+	s.localBackends["_http._tcp.empty.backends.test.local"] = &localBackends{}
+	dead := &localBackends{}
+	dead.addServer(s.T(), nil /* notls */)
+	dead.setResolvableCount(100)
+	dead.Close() // the target stays resolvable, but refuses connections.
+	s.localBackends["_http._tcp.dead.backends.test.local"] = dead
+}
+
+func (s *BackendPoolIntegrationTestSuite) recordingMirrorHandler(serverAddr string) http.Handler {
+	return http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		body, _ := ioutil.ReadAll(req.Body)
+		s.mirroredRequests <- fmt.Sprintf("%s %s %s", req.Method, req.URL.Path, body)
+		resp.WriteHeader(http.StatusInternalServerError) // mirror responses must never be seen by clients.
+	})
 }
 
 func (s *BackendPoolIntegrationTestSuite) SimpleCtx() context.Context {
@@ -306,6 +494,31 @@ func (s *BackendPoolIntegrationTestSuite) TestSuccessOverForwardProxy_DialUsingA
 	assert.Equal(s.T(), resp.Header.Get("x-test-req-proto"), "1.1", "non secure backends are dialed over HTTP/1.1")
 }
 
+func (s *BackendPoolIntegrationTestSuite) TestSuccessOverForwardProxy_DialUsingAddresser_FailsOverToNextAddress() {
+	addr := s.localBackends["_http._tcp.nonsecure.backends.test.local"].targets()[0].DialAddr
+	port := addr[strings.LastIndex(addr, ":")+1:]
+	// Addresses are round robined, so subsequent requests start with a different one.
+	for i := 0; i < 2; i++ {
+		req := &http.Request{Method: "GET", URL: urlMustParse(fmt.Sprintf("http://failover.pods.test.local:%s/some/strict/path", port))}
+		resp, err := s.forwardProxyClient(s.proxyListenerPlain).Do(req)
+		s.assertSuccessfulPingback(req, resp, err)
+	}
+}
+
+func (s *BackendPoolIntegrationTestSuite) TestSuccessOverForwardProxy_DialUsingAddresser_ToSecure() {
+	addr := s.localBackends["_https._tcp.secure.backends.test.local"].targets()[0].DialAddr
+	port := addr[strings.LastIndex(addr, ":")+1:]
+	before := adhocRequestCount(s.T(), "*.secure-pods.test.local", "202")
+	// The rule allows a single connection, which needs to be reused by all requests.
+	for i := 0; i < 3; i++ {
+		req := &http.Request{Method: "GET", URL: urlMustParse(fmt.Sprintf("http://127-0-0-1.secure-pods.test.local:%s/some/strict/path", port))}
+		resp, err := s.forwardProxyClient(s.proxyListenerPlain).Do(req)
+		s.assertSuccessfulPingback(req, resp, err)
+		assert.Equal(s.T(), "true", resp.Header.Get("x-test-req-tls"), "secure adhoc destinations are dialed over TLS")
+	}
+	assert.Equal(s.T(), before+3, adhocRequestCount(s.T(), "*.secure-pods.test.local", "202"), "requests must be counted by adhoc rule")
+}
+
 func (s *BackendPoolIntegrationTestSuite) TestSuccessOverReverseProxy_ToNonSecure_OverPlain() {
 	req := &http.Request{Method: "GET", URL: urlMustParse("http://nonsecure.ext.example.com/some/strict/path")}
 	resp, err := s.reverseProxyClient(s.proxyListenerPlain).Do(req)
@@ -410,7 +623,160 @@ func (s *BackendPoolIntegrationTestSuite) TestLoadbalacingToNonSecureBackend() {
 	}
 }
 
-//
+// upgradeThroughProxy sends an echo Upgrade request to the plain proxy listener and returns the connection.
+func (s *BackendPoolIntegrationTestSuite) upgradeThroughProxy(requestLine string, host string, protocol string) (net.Conn, *bufio.Reader, *http.Response) {
+	conn, err := net.Dial("tcp", s.proxyListenerPlain.Addr().String())
+	require.NoError(s.T(), err, "dialing the proxy must not fail")
+	conn.SetDeadline(time.Now().Add(2 * time.Second))
+	fmt.Fprintf(conn, "%s HTTP/1.1\r\nHost: %s\r\nConnection: Upgrade\r\nUpgrade: %s\r\n\r\n", requestLine, host, protocol)
+	reader := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(reader, nil)
+	require.NoError(s.T(), err, "reading the upgrade response must not fail")
+	return conn, reader, resp
+}
+
+func (s *BackendPoolIntegrationTestSuite) assertEchoes(conn net.Conn, reader *bufio.Reader) {
+	for _, msg := range []string{"hello", "world"} {
+		_, err := conn.Write([]byte(msg))
+		require.NoError(s.T(), err, "writing to the upgraded connection must not fail")
+		buf := make([]byte, len(msg))
+		_, err = io.ReadFull(reader, buf)
+		require.NoError(s.T(), err, "reading from the upgraded connection must not fail")
+		assert.Equal(s.T(), msg, string(buf), "upgraded connection must echo")
+	}
+}
+
+func (s *BackendPoolIntegrationTestSuite) TestUpgradeOverReverseProxy_ToNonSecure() {
+	conn, reader, resp := s.upgradeThroughProxy("GET /some/strict/path", "nonsecure.ext.example.com", "echo")
+	defer conn.Close()
+	require.Equal(s.T(), http.StatusSwitchingProtocols, resp.StatusCode, "upgrade must succeed")
+	assert.Equal(s.T(), "non_secure", resp.Header.Get("x-kedge-backend-name"))
+	assert.Equal(s.T(), "echo", resp.Header.Get("Upgrade"))
+	s.assertEchoes(conn, reader)
+}
+
+func (s *BackendPoolIntegrationTestSuite) TestUpgradeOverReverseProxy_ToSecure() {
+	conn, reader, resp := s.upgradeThroughProxy("GET /some/strict/path", "secure.ext.example.com", "echo")
+	defer conn.Close()
+	require.Equal(s.T(), http.StatusSwitchingProtocols, resp.StatusCode, "upgrade must succeed over TLS to the backend")
+	assert.Equal(s.T(), "secure", resp.Header.Get("x-kedge-backend-name"))
+	s.assertEchoes(conn, reader)
+}
+
+func (s *BackendPoolIntegrationTestSuite) TestUpgradeOverForwardProxy_DialUsingAddresser() {
+	addr := s.localBackends["_http._tcp.nonsecure.backends.test.local"].targets()[0].DialAddr
+	port := addr[strings.LastIndex(addr, ":")+1:]
+	host := fmt.Sprintf("127-0-0-1.pods.test.local:%s", port)
+	conn, reader, resp := s.upgradeThroughProxy(fmt.Sprintf("GET http://%s/some/strict/path", host), host, "echo")
+	defer conn.Close()
+	require.Equal(s.T(), http.StatusSwitchingProtocols, resp.StatusCode, "upgrade must succeed")
+	assert.Equal(s.T(), addr, resp.Header.Get("x-test-backend-addr"), "adhoc upgrade must reach the addressed backend")
+	s.assertEchoes(conn, reader)
+}
+
+func (s *BackendPoolIntegrationTestSuite) TestUpgradeRefusedByBackendIsPassedOn() {
+	conn, _, resp := s.upgradeThroughProxy("GET /some/strict/path", "nonsecure.ext.example.com", "unknown")
+	defer conn.Close()
+	assert.Equal(s.T(), http.StatusAccepted, resp.StatusCode, "the response of the backend must be passed on")
+}
+
+func (s *BackendPoolIntegrationTestSuite) TestUpgradedConnectionIsClosedWhenIdle() {
+	defer func(timeout time.Duration) {
+		director.UpgradeIdleTimeout = timeout
+	}(director.UpgradeIdleTimeout)
+	director.UpgradeIdleTimeout = 100 * time.Millisecond
+	conn, reader, resp := s.upgradeThroughProxy("GET /some/strict/path", "nonsecure.ext.example.com", "echo")
+	defer conn.Close()
+	require.Equal(s.T(), http.StatusSwitchingProtocols, resp.StatusCode, "upgrade must succeed")
+	s.assertEchoes(conn, reader)
+	time.Sleep(300 * time.Millisecond)
+	_, err := reader.ReadByte()
+	assert.Equal(s.T(), io.EOF, err, "idle connection must be closed by the proxy")
+}
+
+func (s *BackendPoolIntegrationTestSuite) TestRouteTimeoutReturnsGatewayTimeout() {
+	req := &http.Request{Method: "GET", URL: urlMustParse("http://timeout.ext.example.com/sleep")}
+	start := time.Now()
+	resp, err := s.reverseProxyClient(s.proxyListenerPlain).Do(req)
+	require.NoError(s.T(), err, "dialing should not fail")
+	assert.Equal(s.T(), http.StatusGatewayTimeout, resp.StatusCode, "request must time out")
+	assert.Contains(s.T(), resp.Header.Get("x-kedge-error"), "backend 'non_secure' timed out", "timeout must be explained in the header")
+	assert.True(s.T(), time.Since(start) < 500*time.Millisecond, "request must not wait for the backend")
+}
+
+func (s *BackendPoolIntegrationTestSuite) TestRouteTimeoutAllowsFastRequests() {
+	req := &http.Request{Method: "GET", URL: urlMustParse("http://timeout.ext.example.com/fast")}
+	resp, err := s.reverseProxyClient(s.proxyListenerPlain).Do(req)
+	s.assertSuccessfulPingback(req, resp, err)
+}
+
+func (s *BackendPoolIntegrationTestSuite) TestStreamedResponseOutlivesServerWriteTimeout() {
+	listener, err := net.Listen("tcp", "localhost:0")
+	require.NoError(s.T(), err, "must be able to allocate a port for the proxy with a write timeout")
+	proxy := &http.Server{Handler: s.proxy.Handler, WriteTimeout: 200 * time.Millisecond}
+	go proxy.Serve(writedeadline.Listen(listener))
+	defer proxy.Close()
+
+	client := s.reverseProxyClient(listener)
+	client.Transport.(*http.Transport).Proxy = http.ProxyURL(urlMustParse("http://address_overwritten_in_dialer_anyway"))
+	req := &http.Request{Method: "GET", URL: urlMustParse("http://nonsecure.backends.test.local/stream")}
+	resp, err := client.Do(req)
+	require.NoError(s.T(), err, "streamed request must not fail")
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	require.NoError(s.T(), err, "streamed response must not be cut by the write timeout")
+	assert.Equal(s.T(), "chunk 0\nchunk 1\nchunk 2\nchunk 3\nchunk 4\n", string(body))
+}
+
+func (s *BackendPoolIntegrationTestSuite) TestMirroredRequestIsSentToMirror() {
+	req, _ := http.NewRequest("POST", "http://mirrored.ext.example.com/some/path", strings.NewReader("some body"))
+	resp, err := s.reverseProxyClient(s.proxyListenerPlain).Do(req)
+	s.assertSuccessfulPingback(req, resp, err)
+	select {
+	case got := <-s.mirroredRequests:
+		assert.Equal(s.T(), "POST /some/path some body", got, "mirror must receive a copy of the request")
+	case <-time.After(2 * time.Second):
+		s.T().Fatalf("mirror didn't receive the request")
+	}
+}
+
+func (s *BackendPoolIntegrationTestSuite) TestMirroredRequestWithLargeBodyIsNotSentToMirror() {
+	req, _ := http.NewRequest("POST", "http://mirrored.ext.example.com/some/path", strings.NewReader(strings.Repeat("a", 128)))
+	resp, err := s.reverseProxyClient(s.proxyListenerPlain).Do(req)
+	s.assertSuccessfulPingback(req, resp, err)
+	select {
+	case got := <-s.mirroredRequests:
+		s.T().Fatalf("mirror must not receive requests with bodies larger than the limit, got: %v", got)
+	case <-time.After(200 * time.Millisecond):
+	}
+}
+
+func (s *BackendPoolIntegrationTestSuite) TestFailoverSkipsBackendWithoutTargets() {
+	before := failoverRequestCount(s.T(), "failover_from_empty", "non_secure", "1")
+	req := &http.Request{Method: "GET", URL: urlMustParse("http://failover-empty.ext.example.com/some/path")}
+	resp, err := s.reverseProxyClient(s.proxyListenerPlain).Do(req)
+	s.assertSuccessfulPingback(req, resp, err)
+	assert.Equal(s.T(), []string{"non_secure"}, resp.Header["X-Kedge-Backend-Name"], "the backend that served the request must be returned")
+	assert.Equal(s.T(), before+1, failoverRequestCount(s.T(), "failover_from_empty", "non_secure", "1"), "the tier must be counted")
+}
+
+func (s *BackendPoolIntegrationTestSuite) TestFailoverFallsBackOnConnectionFailure() {
+	before := failoverRequestCount(s.T(), "failover_from_dead", "non_secure", "2")
+	req := &http.Request{Method: "POST", URL: urlMustParse("http://failover-dead.ext.example.com/some/path"), Body: ioutil.NopCloser(strings.NewReader("some body"))}
+	resp, err := s.reverseProxyClient(s.proxyListenerPlain).Do(req)
+	s.assertSuccessfulPingback(req, resp, err)
+	assert.Equal(s.T(), []string{"non_secure"}, resp.Header["X-Kedge-Backend-Name"], "the backend that served the request must be returned")
+	assert.Equal(s.T(), before+1, failoverRequestCount(s.T(), "failover_from_dead", "non_secure", "2"), "the tier must be counted")
+}
+
+func (s *BackendPoolIntegrationTestSuite) TestUpgradeOverReverseProxy_ToFailover() {
+	conn, reader, resp := s.upgradeThroughProxy("GET /some/path", "failover-dead.ext.example.com", "echo")
+	defer conn.Close()
+	require.Equal(s.T(), http.StatusSwitchingProtocols, resp.StatusCode, "upgrade must succeed on the backend that can be dialed")
+	assert.Equal(s.T(), "non_secure", resp.Header.Get("x-kedge-backend-name"))
+	s.assertEchoes(conn, reader)
+}
+
 //func (s *BackendPoolIntegrationTestSuite) TestCallOverForwardProxy_Tls() {
 //	req := &http.Request{Method: "GET", URL: urlMustParse("http://nonsecure.ext.example.com/some/strict/path")}
 //	resp, err := s.forwardProxyClient(s.proxyListenerTls).Do(req)
@@ -484,3 +850,114 @@ func urlMustParse(uStr string) *url.URL {
 	}
 	return u
 }
+
+// adhocRequestCount returns the count of adhoc requests of the rule that returned the code.
+func adhocRequestCount(t *testing.T, rule string, code string) float64 {
+	families, err := prometheus.DefaultGatherer.Gather()
+	require.NoError(t, err, "gathering metrics must not fail")
+	for _, family := range families {
+		if family.GetName() != "kedge_http_adhoc_requests_total" {
+			continue
+		}
+		for _, m := range family.Metric {
+			labels := map[string]string{}
+			for _, l := range m.Label {
+				labels[l.GetName()] = l.GetValue()
+			}
+			if labels["adhoc_rule"] == rule && labels["code"] == code {
+				return m.GetCounter().GetValue()
+			}
+		}
+	}
+	return 0
+}
+
+func (s *BackendPoolIntegrationTestSuite) TestWarmConnectionsAreKeptAndUsed() {
+	for _, tc := range []struct {
+		backendName string
+		host        string
+		perTarget   int
+		targets     int
+		proto       string
+	}{
+		{backendName: "warm_non_secure", host: "warm-nonsecure.ext.example.com", perTarget: 2, targets: nonSecureBackendCount, proto: "1.1"},
+		{backendName: "warm_secure", host: "warm-secure.ext.example.com", perTarget: 1, targets: secureBackendCount, proto: "2.0"},
+	} {
+		wanted := float64(tc.perTarget * tc.targets)
+		for i := 0; i < 100 && warmBackendMetric(s.T(), "kedge_http_backend_warm_connections", tc.backendName) < wanted; i++ {
+			time.Sleep(10 * time.Millisecond)
+		}
+		require.Equal(s.T(), wanted, warmBackendMetric(s.T(), "kedge_http_backend_warm_connections", tc.backendName), "spare connections must be kept to all targets of %v", tc.backendName)
+
+		before := warmBackendMetric(s.T(), "kedge_http_backend_warm_connections_used_total", tc.backendName)
+		req := &http.Request{Method: "GET", URL: urlMustParse(fmt.Sprintf("http://%s/some/strict/path", tc.host))}
+		resp, err := s.reverseProxyClient(s.proxyListenerPlain).Do(req)
+		s.assertSuccessfulPingback(req, resp, err)
+		assert.Equal(s.T(), tc.proto, resp.Header.Get("x-test-req-proto"), "spare connections of %v must keep their protocol", tc.backendName)
+		assert.Equal(s.T(), before+1, warmBackendMetric(s.T(), "kedge_http_backend_warm_connections_used_total", tc.backendName), "requests to %v must use spare connections", tc.backendName)
+		if tc.proto != "2.0" {
+			continue
+		}
+		// HTTP2 requests share the connections taken, so only the first ones to each target take spares.
+		for batch := 0; batch < 3; batch++ {
+			var wg sync.WaitGroup
+			for i := 0; i < 2*tc.targets; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					req := &http.Request{Method: "GET", URL: urlMustParse(fmt.Sprintf("http://%s/some/strict/path", tc.host))}
+					resp, err := s.reverseProxyClient(s.proxyListenerPlain).Do(req)
+					if assert.NoError(s.T(), err, "concurrent requests to %v must not fail", tc.backendName) {
+						assert.Equal(s.T(), http.StatusAccepted, resp.StatusCode)
+						resp.Body.Close()
+					}
+				}()
+			}
+			wg.Wait()
+		}
+		taken := warmBackendMetric(s.T(), "kedge_http_backend_warm_connections_used_total", tc.backendName) - before
+		assert.True(s.T(), taken <= float64(tc.targets), "requests to %v must take at most one spare per target, took %v", tc.backendName, taken)
+	}
+}
+
+func warmBackendMetric(t *testing.T, name string, backendName string) float64 {
+	families, err := prometheus.DefaultGatherer.Gather()
+	require.NoError(t, err, "gathering metrics must not fail")
+	for _, family := range families {
+		if family.GetName() != name {
+			continue
+		}
+		for _, m := range family.Metric {
+			for _, l := range m.Label {
+				if l.GetName() == "backend_name" && l.GetValue() == backendName {
+					if m.Gauge != nil {
+						return m.GetGauge().GetValue()
+					}
+					return m.GetCounter().GetValue()
+				}
+			}
+		}
+	}
+	return 0
+}
+
+// failoverRequestCount returns the count of requests to the failover backend served by the tier.
+func failoverRequestCount(t *testing.T, backendName string, tierBackendName string, tier string) float64 {
+	families, err := prometheus.DefaultGatherer.Gather()
+	require.NoError(t, err, "gathering metrics must not fail")
+	for _, family := range families {
+		if family.GetName() != "kedge_http_failover_requests_total" {
+			continue
+		}
+		for _, m := range family.Metric {
+			labels := map[string]string{}
+			for _, l := range m.Label {
+				labels[l.GetName()] = l.GetValue()
+			}
+			if labels["backend_name"] == backendName && labels["tier_backend_name"] == tierBackendName && labels["tier"] == tier {
+				return m.GetCounter().GetValue()
+			}
+		}
+	}
+	return 0
+}
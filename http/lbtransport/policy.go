@@ -3,6 +3,9 @@ package lbtransport
 import (
 	"net/http"
 	"sync/atomic"
+	"time"
+
+	"github.com/mwitkow/kedge/lib/resolvers"
 )
 
 // LBPolicy decides which target to pick for a given call.
@@ -13,7 +16,24 @@ type LBPolicy interface {
 
 // Target represents the canonical address of a backend.
 type Target struct {
+	// unhealthyUntil is the time in UnixNano until which the target is unhealthy. It is first for atomic alignment.
+	unhealthyUntil int64
+
 	DialAddr string
+	// Zone is the availability zone of the target, if its resolver knows it.
+	Zone string
+	// Priority and Weight of the target, if its resolver knows them. See resolvers.PickByPriority.
+	Priority uint16
+	Weight   uint16
+}
+
+// Healthy returns false for targets that recently failed to be dialed, see UnhealthyTargetCooldown.
+func (t *Target) Healthy() bool {
+	return atomic.LoadInt64(&t.unhealthyUntil) < time.Now().UnixNano()
+}
+
+func (t *Target) markUnhealthy() {
+	atomic.StoreInt64(&t.unhealthyUntil, time.Now().Add(UnhealthyTargetCooldown).UnixNano())
 }
 
 type simpleRoundRobinPolicy struct {
@@ -24,9 +44,63 @@ func RoundRobinPolicy() LBPolicy {
 	return &simpleRoundRobinPolicy{}
 }
 
+// Pick chooses among the targets of the lowest priority, weighted round robin, skipping unhealthy targets unless all of
+// them are.
 func (rr *simpleRoundRobinPolicy) Pick(req *http.Request, currentTargets []*Target) (*Target, error) {
-	count := uint64(len(currentTargets))
+	candidates := healthyTargets(currentTargets)
+	if len(candidates) == 0 {
+		candidates = currentTargets
+	}
+	attrs := make([]resolvers.Attributes, len(candidates))
+	for i, t := range candidates {
+		attrs[i] = resolvers.Attributes{Priority: t.Priority, Weight: t.Weight}
+	}
 	id := atomic.AddUint64(&(rr.atomicCounter), 1)
-	targetId := int(id % count)
-	return currentTargets[targetId], nil
+	return candidates[resolvers.PickByPriority(id, attrs)], nil
+}
+
+func healthyTargets(targets []*Target) []*Target {
+	var healthy []*Target
+	for _, t := range targets {
+		if t.Healthy() {
+			healthy = append(healthy, t)
+		}
+	}
+	return healthy
+}
+
+type zonePreferringPolicy struct {
+	zone            string
+	minHealthyShare float64
+	parent          LBPolicy
+}
+
+// ZonePreferringPolicy sends requests to the healthy targets in the zone, chosen by the parent policy, as long as at
+// least minHealthyShare of the targets in the zone are healthy. Otherwise, requests spill over to healthy targets in
+// all zones.
+func ZonePreferringPolicy(zone string, minHealthyShare float64, parent LBPolicy) LBPolicy {
+	return &zonePreferringPolicy{zone: zone, minHealthyShare: minHealthyShare, parent: parent}
+}
+
+func (p *zonePreferringPolicy) Pick(req *http.Request, currentTargets []*Target) (*Target, error) {
+	var local, localHealthy, healthy []*Target
+	for _, t := range currentTargets {
+		isHealthy := t.Healthy()
+		if isHealthy {
+			healthy = append(healthy, t)
+		}
+		if t.Zone == p.zone {
+			local = append(local, t)
+			if isHealthy {
+				localHealthy = append(localHealthy, t)
+			}
+		}
+	}
+	if len(localHealthy) > 0 && float64(len(localHealthy)) >= p.minHealthyShare*float64(len(local)) {
+		return p.parent.Pick(req, localHealthy)
+	}
+	if len(healthy) > 0 {
+		return p.parent.Pick(req, healthy)
+	}
+	return p.parent.Pick(req, currentTargets)
 }
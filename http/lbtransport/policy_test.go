@@ -0,0 +1,64 @@
+package lbtransport
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func pickedAddrs(t *testing.T, policy LBPolicy, targets []*Target) map[string]bool {
+	picked := map[string]bool{}
+	for i := 0; i < 20; i++ {
+		target, err := policy.Pick(&http.Request{}, targets)
+		require.NoError(t, err, "picking must not fail")
+		picked[target.DialAddr] = true
+	}
+	return picked
+}
+
+func TestZonePreferringPolicy(t *testing.T) {
+	targets := []*Target{
+		{DialAddr: "a1:80", Zone: "zone-a"},
+		{DialAddr: "a2:80", Zone: "zone-a"},
+		{DialAddr: "b1:80", Zone: "zone-b"},
+		{DialAddr: "b2:80", Zone: "zone-b"},
+	}
+	policy := ZonePreferringPolicy("zone-a", 0.5, RoundRobinPolicy())
+	assert.Equal(t, map[string]bool{"a1:80": true, "a2:80": true}, pickedAddrs(t, policy, targets), "targets in the local zone must be preferred")
+
+	targets[0].markUnhealthy()
+	assert.Equal(t, map[string]bool{"a2:80": true}, pickedAddrs(t, policy, targets), "healthy targets in the local zone must be used while enough of them are healthy")
+
+	strictPolicy := ZonePreferringPolicy("zone-a", 0.75, RoundRobinPolicy())
+	assert.Equal(t, map[string]bool{"a2:80": true, "b1:80": true, "b2:80": true}, pickedAddrs(t, strictPolicy, targets), "requests must spill over to healthy targets in all zones")
+
+	otherZonePolicy := ZonePreferringPolicy("zone-c", 0.5, RoundRobinPolicy())
+	assert.Equal(t, map[string]bool{"a2:80": true, "b1:80": true, "b2:80": true}, pickedAddrs(t, otherZonePolicy, targets), "zones without targets must use healthy targets in all zones")
+
+	for _, target := range targets {
+		target.markUnhealthy()
+	}
+	assert.Len(t, pickedAddrs(t, policy, targets), 4, "all targets must be used if none are healthy")
+}
+
+func TestRoundRobinPolicyPicksLowestPriorityByWeight(t *testing.T) {
+	targets := []*Target{
+		{DialAddr: "backup:80", Priority: 1, Weight: 1},
+		{DialAddr: "heavy:80", Priority: 0, Weight: 3},
+		{DialAddr: "light:80", Priority: 0, Weight: 1},
+	}
+	policy := RoundRobinPolicy()
+	counts := map[string]int{}
+	for i := 0; i < 8; i++ {
+		target, err := policy.Pick(&http.Request{}, targets)
+		require.NoError(t, err, "picking must not fail")
+		counts[target.DialAddr]++
+	}
+	assert.Equal(t, map[string]int{"heavy:80": 6, "light:80": 2}, counts, "targets of the lowest priority must be picked by weight")
+
+	targets[1].markUnhealthy()
+	targets[2].markUnhealthy()
+	assert.Equal(t, map[string]bool{"backup:80": true}, pickedAddrs(t, policy, targets), "backups must be used while the primaries are unhealthy")
+}
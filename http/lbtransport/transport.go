@@ -2,10 +2,12 @@ package lbtransport
 
 import (
 	"fmt"
+	"net"
 	"net/http"
 	"sync"
 	"time"
 
+	"github.com/mwitkow/kedge/lib/resolvers"
 	"google.golang.org/grpc/naming"
 )
 
@@ -14,6 +16,8 @@ var (
 	MaximumRefreshInterval = 5 * time.Second
 	// ExpirationMargin, the margin before the TTL expiration on which we should re-resolve.
 	ExpirationMargin = 50 * time.Millisecond
+	// UnhealthyTargetCooldown is how long targets that failed to be dialed are unhealthy for, which policies can avoid.
+	UnhealthyTargetCooldown = 5 * time.Second
 )
 
 type tripper struct {
@@ -55,26 +59,28 @@ func (s *tripper) run() {
 	for {
 		updates, err := s.watcher.Next() // blocking call until new updates are there
 		if err != nil {
+			// watcher.Next errors are irrecoverable. The targets are kept, as resolvers.Resilient watchers only fail
+			// once closed, and others may just fail to refresh them.
 			s.mu.Lock()
-			s.currentTargets = []*Target{}
 			s.lastResolveError = err
 			s.mu.Unlock()
-			return // watcher.Next errors are irrecoverable.
+			return
 		}
 		s.mu.RLock()
 		targets := s.currentTargets
 		s.mu.RUnlock()
 		for _, u := range updates {
-			if u.Op == naming.Add {
-				targets = append(targets, &Target{DialAddr: u.Addr})
-			} else if u.Op == naming.Delete {
-				kept := []*Target{}
-				for _, t := range targets {
-					if u.Addr != t.DialAddr {
-						kept = append(kept, t)
-					}
+			// Targets added again replace the existing ones, e.g. with new attributes.
+			kept := []*Target{}
+			for _, t := range targets {
+				if u.Addr != t.DialAddr {
+					kept = append(kept, t)
 				}
-				targets = kept
+			}
+			targets = kept
+			if u.Op == naming.Add {
+				attrs := resolvers.AttributesOf(u.Metadata)
+				targets = append(targets, &Target{DialAddr: u.Addr, Zone: attrs.Zone, Priority: attrs.Priority, Weight: attrs.Weight})
 			}
 		}
 		s.mu.Lock()
@@ -88,11 +94,9 @@ func (s *tripper) Close() error {
 	return nil
 }
 
-func (s *tripper) RoundTrip(r *http.Request) (*http.Response, error) {
-	// TODO(mwitkow): Fixup this target name matching. Can we even do it??
-	//if r.URL.Host != s.targetName {
-	//	return nil, fmt.Errorf("lb: request Host '%v' doesn't match Target destination '%v'", r.Host, s.targetName)
-	//}
+// PickTarget chooses the target for the request using the LBPolicy, without sending it.
+// It is useful for connections that can't go through RoundTrip, e.g. Upgrades.
+func (s *tripper) PickTarget(r *http.Request) (*Target, error) {
 	s.mu.RLock()
 	targetRef := s.currentTargets
 	lastResolvErr := s.lastResolveError
@@ -104,10 +108,40 @@ func (s *tripper) RoundTrip(r *http.Request) (*http.Response, error) {
 	if err != nil {
 		return nil, fmt.Errorf("lb: failed choosing target: %v", err)
 	}
+	return target, nil
+}
+
+// Targets returns the targets the resolver returned.
+func (s *tripper) Targets() []*Target {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.currentTargets
+}
+
+// HasTargets returns true if the resolver has returned any targets to send requests to.
+func (s *tripper) HasTargets() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.currentTargets) > 0
+}
+
+func (s *tripper) RoundTrip(r *http.Request) (*http.Response, error) {
+	// TODO(mwitkow): Fixup this target name matching. Can we even do it??
+	//if r.URL.Host != s.targetName {
+	//	return nil, fmt.Errorf("lb: request Host '%v' doesn't match Target destination '%v'", r.Host, s.targetName)
+	//}
+	target, err := s.PickTarget(r)
+	if err != nil {
+		return nil, err
+	}
 	// Override the host for downstream Tripper, usually http.DefaultTransport.
 	// http.Default transport uses `URL.Host` for Dial(<host>) and relevant connection pooling.
 	// We override it to make sure it enters the appropriate dial method and hte appropriate connection pool.
 	// See http.connectMethodKey.
 	r.URL.Host = target.DialAddr
-	return s.parent.RoundTrip(r)
+	resp, err := s.parent.RoundTrip(r)
+	if opErr, ok := err.(*net.OpError); ok && opErr.Op == "dial" {
+		target.markUnhealthy()
+	}
+	return resp, err
 }
@@ -1,6 +1,7 @@
 package lbtransport_test
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
@@ -11,9 +12,11 @@ import (
 	"github.com/mwitkow/go-srvlb/grpc"
 	"github.com/mwitkow/go-srvlb/srv"
 	"github.com/mwitkow/kedge/http/lbtransport"
+	"github.com/mwitkow/kedge/lib/resolvers"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"github.com/stretchr/testify/suite"
+	"google.golang.org/grpc/naming"
 	"io"
 )
 
@@ -128,3 +131,57 @@ func (s *BalancedTransportSuite) TestRoundRobinSendsRequestsToAllBackends() {
 //	_, err := client.Get("http://not-my-magic-srv/something")
 //	require.Error(s.T(), err, "srvlb should not be able to dial targets thOat are not known")
 //}
+
+// scriptedResolver returns a watcher that returns the updates one by one, then blocks until closed.
+type scriptedResolver struct {
+	updates [][]*naming.Update
+}
+
+func (r *scriptedResolver) Resolve(target string) (naming.Watcher, error) {
+	return &scriptedWatcher{updates: r.updates, closed: make(chan struct{})}, nil
+}
+
+type scriptedWatcher struct {
+	updates [][]*naming.Update
+	closed  chan struct{}
+}
+
+func (w *scriptedWatcher) Next() ([]*naming.Update, error) {
+	if len(w.updates) > 0 {
+		next := w.updates[0]
+		w.updates = w.updates[1:]
+		return next, nil
+	}
+	<-w.closed
+	return nil, errors.New("test watcher closed")
+}
+
+func (w *scriptedWatcher) Close() {
+	close(w.closed)
+}
+
+func targetAddrs(targets []*lbtransport.Target) []string {
+	addrs := []string{}
+	for _, t := range targets {
+		addrs = append(addrs, t.DialAddr)
+	}
+	return addrs
+}
+
+func TestTransportReplacesTargetsAddedAgain(t *testing.T) {
+	resolver := &scriptedResolver{updates: [][]*naming.Update{
+		{{Op: naming.Add, Addr: "a:80"}, {Op: naming.Add, Addr: "b:80"}},
+		{{Op: naming.Add, Addr: "a:80", Metadata: resolvers.Attributes{Zone: "zone-a"}}},
+	}}
+	trans, err := lbtransport.New("my-magic-srv", http.DefaultTransport, resolver, lbtransport.RoundRobinPolicy())
+	require.NoError(t, err)
+	defer trans.Close()
+
+	for i := 0; i < 100 && !assert.ObjectsAreEqual([]string{"b:80", "a:80"}, targetAddrs(trans.Targets())); i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	targets := trans.Targets()
+	require.Len(t, targets, 2, "targets added again must not be duplicated")
+	assert.Equal(t, []string{"b:80", "a:80"}, targetAddrs(targets))
+	assert.Equal(t, "zone-a", targets[1].Zone, "targets added again must be updated")
+}
@@ -0,0 +1,82 @@
+// Package clientauth checks that requests are made by clients with a required identity, presented through TLS client
+// certificates or JSON Web Tokens.
+package clientauth
+
+import (
+	"crypto/x509"
+	"fmt"
+	"net/http"
+
+	pb "github.com/mwitkow/kedge/_protogen/kedge/config/common/auth"
+)
+
+// Authorizer decides whether the client of a request is allowed to use the resource it guards.
+type Authorizer interface {
+	// Authorize returns an error explaining why the client is not allowed, or nil.
+	Authorize(req *http.Request) error
+}
+
+type authorizer struct {
+	cert *pb.CertRequirement
+	jwt  *jwtVerifier
+}
+
+// New builds an Authorizer out of the config, reading the keys needed to verify tokens.
+//
+// A nil config allows all clients.
+func New(cnf *pb.ClientAuthorization) (Authorizer, error) {
+	a := &authorizer{cert: cnf.GetCert()}
+	if j := cnf.GetJwt(); j != nil {
+		v, err := newJwtVerifier(j)
+		if err != nil {
+			return nil, err
+		}
+		a.jwt = v
+	}
+	return a, nil
+}
+
+func (a *authorizer) Authorize(req *http.Request) error {
+	if a.cert != nil {
+		if req.TLS == nil || len(req.TLS.PeerCertificates) == 0 {
+			return fmt.Errorf("client certificate required")
+		}
+		if err := checkCert(req.TLS.PeerCertificates[0], a.cert); err != nil {
+			return err
+		}
+	}
+	if a.jwt != nil {
+		if err := a.jwt.verifyRequest(req); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func checkCert(cert *x509.Certificate, req *pb.CertRequirement) error {
+	if !anyAllowed([]string{cert.Subject.CommonName}, req.CommonNames) {
+		return fmt.Errorf("client certificate common name '%v' is not allowed", cert.Subject.CommonName)
+	}
+	if !anyAllowed(cert.Subject.Organization, req.Organizations) {
+		return fmt.Errorf("client certificate organization %v is not allowed", cert.Subject.Organization)
+	}
+	if !anyAllowed(cert.Subject.OrganizationalUnit, req.OrganizationalUnits) {
+		return fmt.Errorf("client certificate organizational unit %v is not allowed", cert.Subject.OrganizationalUnit)
+	}
+	return nil
+}
+
+// anyAllowed returns true if any of the values is allowed, or if there are no restrictions.
+func anyAllowed(values []string, allowed []string) bool {
+	if len(allowed) == 0 {
+		return true
+	}
+	for _, v := range values {
+		for _, a := range allowed {
+			if v == a {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,239 @@
+package clientauth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	pb "github.com/mwitkow/kedge/_protogen/kedge/config/common/auth"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCertRequirement(t *testing.T) {
+	a, err := New(&pb.ClientAuthorization{
+		Cert: &pb.CertRequirement{
+			CommonNames:   []string{"alice", "bob"},
+			Organizations: []string{"Engineering"},
+		},
+	})
+	require.NoError(t, err)
+
+	for _, tcase := range []struct {
+		name        string
+		subject     *pkix.Name
+		expectedErr string
+	}{
+		{
+			name:    "matching subject",
+			subject: &pkix.Name{CommonName: "bob", Organization: []string{"Sales", "Engineering"}},
+		},
+		{
+			name:        "no certificate",
+			expectedErr: "client certificate required",
+		},
+		{
+			name:        "common name not allowed",
+			subject:     &pkix.Name{CommonName: "eve", Organization: []string{"Engineering"}},
+			expectedErr: "client certificate common name 'eve' is not allowed",
+		},
+		{
+			name:        "organization not allowed",
+			subject:     &pkix.Name{CommonName: "alice", Organization: []string{"Sales"}},
+			expectedErr: "client certificate organization [Sales] is not allowed",
+		},
+	} {
+		t.Run(tcase.name, func(t *testing.T) {
+			req, err := http.NewRequest("GET", "http://example.com/", nil)
+			require.NoError(t, err)
+			if tcase.subject != nil {
+				req.TLS = &tls.ConnectionState{PeerCertificates: []*x509.Certificate{{Subject: *tcase.subject}}}
+			}
+			err = a.Authorize(req)
+			if tcase.expectedErr != "" {
+				assert.EqualError(t, err, tcase.expectedErr)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestJwtRequirement(t *testing.T) {
+	dir, err := ioutil.TempDir("", "clientauth_test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	hmacSecret := []byte("very secret")
+	rsaKeyFile := writePublicKey(t, dir, "rsa.pem", rsaKey.Public())
+	ecKeyFile := writePublicKey(t, dir, "ec.pem", ecKey.Public())
+	hmacSecretFile := filepath.Join(dir, "secret")
+	require.NoError(t, ioutil.WriteFile(hmacSecretFile, append(hmacSecret, '\n'), 0600))
+
+	sign := func(alg string, claims map[string]interface{}) string {
+		header, _ := json.Marshal(map[string]string{"alg": alg, "typ": "JWT"})
+		payload, _ := json.Marshal(claims)
+		signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+		digest := sha256.Sum256([]byte(signed))
+		var sig []byte
+		switch alg {
+		case "RS256":
+			sig, err = rsa.SignPKCS1v15(rand.Reader, rsaKey, crypto.SHA256, digest[:])
+			require.NoError(t, err)
+		case "ES256":
+			r, s, err := ecdsa.Sign(rand.Reader, ecKey, digest[:])
+			require.NoError(t, err)
+			sig = make([]byte, 64)
+			rb, sb := r.Bytes(), s.Bytes()
+			copy(sig[32-len(rb):32], rb)
+			copy(sig[64-len(sb):], sb)
+		case "HS256":
+			mac := hmac.New(sha256.New, hmacSecret)
+			mac.Write([]byte(signed))
+			sig = mac.Sum(nil)
+		}
+		return signed + "." + base64.RawURLEncoding.EncodeToString(sig)
+	}
+	validClaims := map[string]interface{}{
+		"iss":    "https://issuer.example.com",
+		"aud":    []string{"other", "kedge"},
+		"exp":    time.Now().Add(time.Hour).Unix(),
+		"groups": []string{"developers", "oncall"},
+	}
+	withClaim := func(name string, value interface{}) map[string]interface{} {
+		claims := map[string]interface{}{}
+		for k, v := range validClaims {
+			claims[k] = v
+		}
+		claims[name] = value
+		return claims
+	}
+
+	for _, tcase := range []struct {
+		name        string
+		key         *pb.JwtRequirement
+		header      string
+		value       string
+		expectedErr string
+	}{
+		{
+			name:  "valid RS256",
+			key:   &pb.JwtRequirement{Key: &pb.JwtRequirement_PublicKeyFile{PublicKeyFile: rsaKeyFile}},
+			value: "Bearer " + sign("RS256", validClaims),
+		},
+		{
+			name:  "valid ES256",
+			key:   &pb.JwtRequirement{Key: &pb.JwtRequirement_PublicKeyFile{PublicKeyFile: ecKeyFile}},
+			value: "Bearer " + sign("ES256", validClaims),
+		},
+		{
+			name:  "valid HS256 in custom header",
+			key:   &pb.JwtRequirement{Header: "Proxy-Authorization", Key: &pb.JwtRequirement_HmacSecretFile{HmacSecretFile: hmacSecretFile}},
+			value: "bearer " + sign("HS256", validClaims),
+		},
+		{
+			name:        "missing token",
+			key:         &pb.JwtRequirement{Key: &pb.JwtRequirement_PublicKeyFile{PublicKeyFile: rsaKeyFile}},
+			expectedErr: "jwt required in header Authorization",
+		},
+		{
+			name:        "not a bearer token",
+			key:         &pb.JwtRequirement{Key: &pb.JwtRequirement_PublicKeyFile{PublicKeyFile: rsaKeyFile}},
+			value:       "Basic dXNlcjpwYXNz",
+			expectedErr: "header Authorization must carry a bearer token",
+		},
+		{
+			name:        "algorithm not matching key",
+			key:         &pb.JwtRequirement{Key: &pb.JwtRequirement_PublicKeyFile{PublicKeyFile: rsaKeyFile}},
+			value:       "Bearer " + sign("HS256", validClaims),
+			expectedErr: "jwt algorithm 'HS256' is not allowed",
+		},
+		{
+			name:        "tampered claims",
+			key:         &pb.JwtRequirement{Key: &pb.JwtRequirement_PublicKeyFile{PublicKeyFile: ecKeyFile}},
+			value:       "Bearer " + tamper(sign("ES256", validClaims), sign("ES256", withClaim("groups", []string{"oncall", "admin"}))),
+			expectedErr: "jwt signature is invalid",
+		},
+		{
+			name:        "expired",
+			key:         &pb.JwtRequirement{Key: &pb.JwtRequirement_PublicKeyFile{PublicKeyFile: rsaKeyFile}},
+			value:       "Bearer " + sign("RS256", withClaim("exp", time.Now().Add(-time.Minute).Unix())),
+			expectedErr: "jwt has expired",
+		},
+		{
+			name:        "wrong issuer",
+			key:         &pb.JwtRequirement{Key: &pb.JwtRequirement_PublicKeyFile{PublicKeyFile: rsaKeyFile}},
+			value:       "Bearer " + sign("RS256", withClaim("iss", "https://evil.example.com")),
+			expectedErr: "jwt issuer is not allowed",
+		},
+		{
+			name:        "wrong audience",
+			key:         &pb.JwtRequirement{Key: &pb.JwtRequirement_PublicKeyFile{PublicKeyFile: rsaKeyFile}},
+			value:       "Bearer " + sign("RS256", withClaim("aud", "other")),
+			expectedErr: "jwt audience is not allowed",
+		},
+		{
+			name:        "missing required claim value",
+			key:         &pb.JwtRequirement{Key: &pb.JwtRequirement_PublicKeyFile{PublicKeyFile: rsaKeyFile}},
+			value:       "Bearer " + sign("RS256", withClaim("groups", []string{"sales"})),
+			expectedErr: "jwt claim 'groups' is not allowed",
+		},
+	} {
+		t.Run(tcase.name, func(t *testing.T) {
+			tcase.key.Issuer = "https://issuer.example.com"
+			tcase.key.Audience = "kedge"
+			tcase.key.Claims = map[string]string{"groups": "oncall"}
+			a, err := New(&pb.ClientAuthorization{Jwt: tcase.key})
+			require.NoError(t, err)
+			req, err := http.NewRequest("GET", "http://example.com/", nil)
+			require.NoError(t, err)
+			header := tcase.key.Header
+			if header == "" {
+				header = "Authorization"
+			}
+			if tcase.value != "" {
+				req.Header.Set(header, tcase.value)
+			}
+			err = a.Authorize(req)
+			if tcase.expectedErr != "" {
+				assert.EqualError(t, err, tcase.expectedErr)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+// tamper returns the header and claims of the other token with the signature of the token.
+func tamper(token string, other string) string {
+	return other[:strings.LastIndex(other, ".")] + token[strings.LastIndex(token, "."):]
+}
+
+func writePublicKey(t *testing.T, dir string, name string, key crypto.PublicKey) string {
+	der, err := x509.MarshalPKIXPublicKey(key)
+	require.NoError(t, err)
+	path := filepath.Join(dir, name)
+	require.NoError(t, ioutil.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0600))
+	return path
+}
@@ -0,0 +1,218 @@
+package clientauth
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"strings"
+	"time"
+
+	pb "github.com/mwitkow/kedge/_protogen/kedge/config/common/auth"
+)
+
+// jwtVerifier verifies compact serialized JSON Web Tokens signed with RS256, ES256 or HS256.
+//
+// Only the algorithm matching the configured key is accepted, so that tokens can't choose how they are verified.
+type jwtVerifier struct {
+	header     string
+	publicKey  crypto.PublicKey
+	hmacSecret []byte
+	issuer     string
+	audience   string
+	claims     map[string]string
+}
+
+func newJwtVerifier(cnf *pb.JwtRequirement) (*jwtVerifier, error) {
+	v := &jwtVerifier{header: cnf.Header, issuer: cnf.Issuer, audience: cnf.Audience, claims: cnf.Claims}
+	if v.header == "" {
+		v.header = "Authorization"
+	}
+	switch {
+	case cnf.GetPublicKeyFile() != "":
+		key, err := readPublicKey(cnf.GetPublicKeyFile())
+		if err != nil {
+			return nil, err
+		}
+		v.publicKey = key
+	case cnf.GetHmacSecretFile() != "":
+		secret, err := ioutil.ReadFile(cnf.GetHmacSecretFile())
+		if err != nil {
+			return nil, fmt.Errorf("failed reading jwt hmac secret: %v", err)
+		}
+		v.hmacSecret = bytes.TrimSpace(secret)
+	default:
+		return nil, fmt.Errorf("jwt requirement needs either public_key_file or hmac_secret_file")
+	}
+	return v, nil
+}
+
+func readPublicKey(path string) (crypto.PublicKey, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed reading jwt public key: %v", err)
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("jwt public key file '%v' is not PEM encoded", path)
+	}
+	var key crypto.PublicKey
+	if block.Type == "CERTIFICATE" {
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("failed parsing jwt certificate: %v", err)
+		}
+		key = cert.PublicKey
+	} else {
+		key, err = x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("failed parsing jwt public key: %v", err)
+		}
+	}
+	switch key.(type) {
+	case *rsa.PublicKey, *ecdsa.PublicKey:
+		return key, nil
+	default:
+		return nil, fmt.Errorf("jwt public key of type %T is not supported", key)
+	}
+}
+
+func (v *jwtVerifier) verifyRequest(req *http.Request) error {
+	value := req.Header.Get(v.header)
+	if value == "" {
+		return fmt.Errorf("jwt required in header %v", v.header)
+	}
+	const prefix = "bearer "
+	if len(value) < len(prefix) || !strings.EqualFold(value[:len(prefix)], prefix) {
+		return fmt.Errorf("header %v must carry a bearer token", v.header)
+	}
+	return v.verify(strings.TrimSpace(value[len(prefix):]), time.Now())
+}
+
+func (v *jwtVerifier) verify(token string, now time.Time) error {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return fmt.Errorf("malformed jwt")
+	}
+	header := struct {
+		Alg string `json:"alg"`
+	}{}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return err
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return fmt.Errorf("malformed jwt signature")
+	}
+	if err := v.verifySignature(header.Alg, parts[0]+"."+parts[1], signature); err != nil {
+		return err
+	}
+	claims := map[string]interface{}{}
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return err
+	}
+	return v.checkClaims(claims, now)
+}
+
+func decodeSegment(segment string, dest interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return fmt.Errorf("malformed jwt")
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(dest); err != nil {
+		return fmt.Errorf("malformed jwt")
+	}
+	return nil
+}
+
+func (v *jwtVerifier) verifySignature(alg string, signed string, signature []byte) error {
+	digest := sha256.Sum256([]byte(signed))
+	valid := false
+	switch key := v.publicKey.(type) {
+	case *rsa.PublicKey:
+		if alg != "RS256" {
+			return fmt.Errorf("jwt algorithm '%v' is not allowed", alg)
+		}
+		valid = rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], signature) == nil
+	case *ecdsa.PublicKey:
+		if alg != "ES256" {
+			return fmt.Errorf("jwt algorithm '%v' is not allowed", alg)
+		}
+		if len(signature) == 64 {
+			r := new(big.Int).SetBytes(signature[:32])
+			s := new(big.Int).SetBytes(signature[32:])
+			valid = ecdsa.Verify(key, digest[:], r, s)
+		}
+	default:
+		if alg != "HS256" {
+			return fmt.Errorf("jwt algorithm '%v' is not allowed", alg)
+		}
+		mac := hmac.New(sha256.New, v.hmacSecret)
+		mac.Write([]byte(signed))
+		valid = hmac.Equal(mac.Sum(nil), signature)
+	}
+	if !valid {
+		return fmt.Errorf("jwt signature is invalid")
+	}
+	return nil
+}
+
+func (v *jwtVerifier) checkClaims(claims map[string]interface{}, now time.Time) error {
+	if exp, ok := numericClaim(claims, "exp"); ok && now.Unix() >= exp {
+		return fmt.Errorf("jwt has expired")
+	}
+	if nbf, ok := numericClaim(claims, "nbf"); ok && now.Unix() < nbf {
+		return fmt.Errorf("jwt is not valid yet")
+	}
+	if v.issuer != "" && !claimContains(claims["iss"], v.issuer) {
+		return fmt.Errorf("jwt issuer is not allowed")
+	}
+	if v.audience != "" && !claimContains(claims["aud"], v.audience) {
+		return fmt.Errorf("jwt audience is not allowed")
+	}
+	for name, expected := range v.claims {
+		if !claimContains(claims[name], expected) {
+			return fmt.Errorf("jwt claim '%v' is not allowed", name)
+		}
+	}
+	return nil
+}
+
+func numericClaim(claims map[string]interface{}, name string) (int64, bool) {
+	n, ok := claims[name].(json.Number)
+	if !ok {
+		return 0, false
+	}
+	f, err := n.Float64()
+	if err != nil {
+		return 0, false
+	}
+	return int64(f), true
+}
+
+// claimContains matches string claims by equality, and lists of strings by containing the value.
+func claimContains(claim interface{}, value string) bool {
+	switch c := claim.(type) {
+	case string:
+		return c == value
+	case []interface{}:
+		for _, e := range c {
+			if s, ok := e.(string); ok && s == value {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,75 @@
+// Package mapper decides which kedge, if any, serves a given destination host.
+package mapper
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+
+	pb "github.com/mwitkow/kedge/_protogen/kedge/config/client"
+)
+
+var (
+	// ErrNotKedgeDestination is returned by Mappers for hosts that are not served by any kedge.
+	ErrNotKedgeDestination = errors.New("not a kedge destination")
+)
+
+// Mapper maps destination hosts onto the URLs of the kedges serving them.
+type Mapper interface {
+	// Map returns the URL of the kedge for the host (optionally with port), or ErrNotKedgeDestination.
+	Map(hostPort string) (*url.URL, error)
+}
+
+type mapping struct {
+	hostMatcher string
+	kedgeUrl    *url.URL
+}
+
+type static struct {
+	mappings []*mapping
+}
+
+// NewStatic creates a Mapper out of a list of mappings, the first matching one is used.
+func NewStatic(mappings []*pb.Mapping) (Mapper, error) {
+	s := &static{}
+	for _, m := range mappings {
+		if m.HostMatcher == "" {
+			return nil, fmt.Errorf("mapping to '%v' has no host_matcher", m.KedgeAddress)
+		}
+		if m.KedgeAddress == "" {
+			return nil, fmt.Errorf("mapping of '%v' has no kedge_address", m.HostMatcher)
+		}
+		addr := m.KedgeAddress
+		if _, _, err := net.SplitHostPort(addr); err != nil {
+			addr = net.JoinHostPort(addr, "443")
+		}
+		s.mappings = append(s.mappings, &mapping{
+			hostMatcher: m.HostMatcher,
+			kedgeUrl:    &url.URL{Scheme: "https", Host: addr},
+		})
+	}
+	return s, nil
+}
+
+func (s *static) Map(hostPort string) (*url.URL, error) {
+	host := hostPort
+	if h, _, err := net.SplitHostPort(hostPort); err == nil {
+		host = h
+	}
+	for _, m := range s.mappings {
+		if hostMatches(host, m.hostMatcher) {
+			u := *m.kedgeUrl // copy, so that callers can't modify the mapping.
+			return &u, nil
+		}
+	}
+	return nil, ErrNotKedgeDestination
+}
+
+func hostMatches(host string, matcher string) bool {
+	if matcher[0] != '*' {
+		return host == matcher
+	}
+	return strings.HasSuffix(host, matcher[1:])
+}
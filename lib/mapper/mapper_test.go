@@ -0,0 +1,87 @@
+package mapper
+
+import (
+	"testing"
+
+	"github.com/golang/protobuf/jsonpb"
+	pb "github.com/mwitkow/kedge/_protogen/kedge/config/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStaticMaps(t *testing.T) {
+	configJson := `
+{ "mappings": [
+	{
+		"hostMatcher": "*.my_cluster.cluster.local",
+		"kedgeAddress": "my_cluster.internalapi.example.com:443"
+	},
+	{
+		"hostMatcher": "special.other_cluster.cluster.local",
+		"kedgeAddress": "other_cluster.internalapi.example.com:8443"
+	},
+	{
+		"hostMatcher": "*.cluster.local",
+		"kedgeAddress": "default.internalapi.example.com"
+	}
+]}`
+	config := &pb.ClientConfig{}
+	require.NoError(t, jsonpb.UnmarshalString(configJson, config))
+	m, err := NewStatic(config.Mappings)
+	require.NoError(t, err)
+
+	for _, tcase := range []struct {
+		name        string
+		hostPort    string
+		expectedUrl string
+		expectedErr error
+	}{
+		{
+			name:        "MatchesWildcard",
+			hostPort:    "svc.my_cluster.cluster.local",
+			expectedUrl: "https://my_cluster.internalapi.example.com:443",
+		},
+		{
+			name:        "MatchesWildcardIgnoringPort",
+			hostPort:    "svc.namespace.my_cluster.cluster.local:8080",
+			expectedUrl: "https://my_cluster.internalapi.example.com:443",
+		},
+		{
+			name:        "MatchesExact",
+			hostPort:    "special.other_cluster.cluster.local",
+			expectedUrl: "https://other_cluster.internalapi.example.com:8443",
+		},
+		{
+			name:        "FallsThroughToLaterMappingsAndDefaultsPort",
+			hostPort:    "notspecial.other_cluster.cluster.local",
+			expectedUrl: "https://default.internalapi.example.com:443",
+		},
+		{
+			name:        "WildcardDoesntMatchItsSuffixAlone",
+			hostPort:    "cluster.local",
+			expectedErr: ErrNotKedgeDestination,
+		},
+		{
+			name:        "FailsForUnknownHosts",
+			hostPort:    "www.example.com:80",
+			expectedErr: ErrNotKedgeDestination,
+		},
+	} {
+		t.Run(tcase.name, func(t *testing.T) {
+			u, err := m.Map(tcase.hostPort)
+			if tcase.expectedErr != nil {
+				assert.Equal(t, tcase.expectedErr, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tcase.expectedUrl, u.String())
+		})
+	}
+}
+
+func TestNewStaticFailsOnBadMappings(t *testing.T) {
+	_, err := NewStatic([]*pb.Mapping{{KedgeAddress: "kedge.example.com"}})
+	assert.Error(t, err, "mapping without host_matcher must fail")
+	_, err = NewStatic([]*pb.Mapping{{HostMatcher: "*.cluster.local"}})
+	assert.Error(t, err, "mapping without kedge_address must fail")
+}
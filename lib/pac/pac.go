@@ -0,0 +1,105 @@
+// Package pac generates Proxy Auto-Config files pointing browsers at kedges.
+//
+// See https://developer.mozilla.org/en-US/docs/Web/HTTP/Proxy_servers_and_tunneling/Proxy_Auto-Configuration_(PAC)_file
+package pac
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+const (
+	// ContentType is the MIME type browsers expect PAC files to be served with.
+	ContentType = "application/x-ns-proxy-autoconfig"
+
+	// Direct is the PAC directive for not using a proxy.
+	Direct = "DIRECT"
+)
+
+// Rule sends requests for matching hosts to a proxy.
+type Rule struct {
+	// HostMatcher is either an exact host name or a wildcard like "*.cluster.local" matching all its subdomains.
+	HostMatcher string
+	// Proxy is the PAC directive used, e.g. "HTTPS kedge.example.com:443" or "PROXY 127.0.0.1:8070; DIRECT".
+	Proxy string
+}
+
+// File is a PAC file where the first Rule matching the host of an http:// URL decides its proxy.
+//
+// By default only http:// URLs are proxied, all others go DIRECT: kedges don't accept CONNECT requests needed for
+// https:// ones.
+type File struct {
+	Rules []Rule
+	// ProxyHttps sends https:// URLs through the Rules too, for proxies that accept CONNECT, like kedge_local.
+	ProxyHttps bool
+}
+
+// ProxyFor returns the PAC directive the generated FindProxyForURL returns for the URL.
+//
+// It is the Go equivalent of the generated JavaScript, and allows to check it without evaluating it.
+func (f *File) ProxyFor(rawUrl string) string {
+	u, err := url.Parse(rawUrl)
+	if err != nil || (u.Scheme != "http" && !(f.ProxyHttps && u.Scheme == "https")) {
+		return Direct
+	}
+	host := u.Host
+	if h, _, err := net.SplitHostPort(u.Host); err == nil {
+		host = h
+	}
+	host = strings.ToLower(host)
+	for _, r := range f.Rules {
+		if hostMatches(host, strings.ToLower(r.HostMatcher)) {
+			return r.Proxy
+		}
+	}
+	return Direct
+}
+
+func hostMatches(host string, matcher string) bool {
+	if strings.HasPrefix(matcher, "*") {
+		return strings.HasSuffix(host, matcher[1:])
+	}
+	return host == matcher
+}
+
+// String returns the JavaScript of the PAC file.
+func (f *File) String() string {
+	buf := &bytes.Buffer{}
+	fmt.Fprintf(buf, "// Generated by kedge.\n")
+	fmt.Fprintf(buf, "function FindProxyForURL(url, host) {\n")
+	if f.ProxyHttps {
+		fmt.Fprintf(buf, "    if (url.substring(0, 5) !== \"http:\" && url.substring(0, 6) !== \"https:\") {\n")
+	} else {
+		fmt.Fprintf(buf, "    if (url.substring(0, 5) !== \"http:\") {\n")
+	}
+	fmt.Fprintf(buf, "        return %s;\n", strconv.Quote(Direct))
+	fmt.Fprintf(buf, "    }\n")
+	fmt.Fprintf(buf, "    host = host.toLowerCase();\n")
+	for _, r := range f.Rules {
+		fmt.Fprintf(buf, "    if (%s) {\n", jsHostCondition(strings.ToLower(r.HostMatcher)))
+		fmt.Fprintf(buf, "        return %s;\n", strconv.Quote(r.Proxy))
+		fmt.Fprintf(buf, "    }\n")
+	}
+	fmt.Fprintf(buf, "    return %s;\n", strconv.Quote(Direct))
+	fmt.Fprintf(buf, "}\n")
+	return buf.String()
+}
+
+func jsHostCondition(matcher string) string {
+	if strings.HasPrefix(matcher, "*") {
+		return fmt.Sprintf("dnsDomainIs(host, %s)", strconv.Quote(matcher[1:]))
+	}
+	return fmt.Sprintf("host === %s", strconv.Quote(matcher))
+}
+
+// ServeHTTP serves the PAC file.
+func (f *File) ServeHTTP(resp http.ResponseWriter, req *http.Request) {
+	resp.Header().Set("Content-Type", ContentType)
+	resp.Header().Set("Cache-Control", "no-cache")
+	fmt.Fprint(resp, f.String())
+}
@@ -0,0 +1,103 @@
+package pac
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var testFile = &File{
+	Rules: []Rule{
+		{HostMatcher: "special.my_cluster.cluster.local", Proxy: "PROXY kedge.example.com:80"},
+		{HostMatcher: "*.my_cluster.cluster.local", Proxy: "HTTPS kedge.example.com:443; PROXY kedge.example.com:80"},
+		{HostMatcher: "Exact.Example.Com", Proxy: "HTTPS kedge.example.com:443"},
+	},
+}
+
+func TestProxyFor(t *testing.T) {
+	for _, tcase := range []struct {
+		name          string
+		url           string
+		expectedProxy string
+	}{
+		{
+			name:          "ExactMatchTakesPrecedence",
+			url:           "http://special.my_cluster.cluster.local/path",
+			expectedProxy: "PROXY kedge.example.com:80",
+		},
+		{
+			name:          "WildcardMatchesSubdomains",
+			url:           "http://svc.namespace.my_cluster.cluster.local:8080/path",
+			expectedProxy: "HTTPS kedge.example.com:443; PROXY kedge.example.com:80",
+		},
+		{
+			name:          "WildcardDoesntMatchItsSuffixAlone",
+			url:           "http://my_cluster.cluster.local/path",
+			expectedProxy: "DIRECT",
+		},
+		{
+			name:          "ExactMatchIsCaseInsensitive",
+			url:           "http://exact.EXAMPLE.com/",
+			expectedProxy: "HTTPS kedge.example.com:443",
+		},
+		{
+			name:          "HttpsUrlsGoDirect",
+			url:           "https://svc.my_cluster.cluster.local/path",
+			expectedProxy: "DIRECT",
+		},
+		{
+			name:          "UnmatchedUrlsGoDirect",
+			url:           "http://www.example.com/",
+			expectedProxy: "DIRECT",
+		},
+	} {
+		t.Run(tcase.name, func(t *testing.T) {
+			assert.Equal(t, tcase.expectedProxy, testFile.ProxyFor(tcase.url))
+		})
+	}
+}
+
+func TestStringMatchesProxyForLogic(t *testing.T) {
+	js := testFile.String()
+	expectedOrder := []string{
+		`function FindProxyForURL(url, host) {`,
+		`if (url.substring(0, 5) !== "http:") {`,
+		`return "DIRECT";`,
+		`host = host.toLowerCase();`,
+		`if (host === "special.my_cluster.cluster.local") {`,
+		`return "PROXY kedge.example.com:80";`,
+		`if (dnsDomainIs(host, ".my_cluster.cluster.local")) {`,
+		`return "HTTPS kedge.example.com:443; PROXY kedge.example.com:80";`,
+		`if (host === "exact.example.com") {`,
+		`return "HTTPS kedge.example.com:443";`,
+		`return "DIRECT";`,
+	}
+	rest := js
+	for _, e := range expectedOrder {
+		idx := strings.Index(rest, e)
+		if !assert.True(t, idx >= 0, "'%v' must appear in order in:\n%v", e, js) {
+			return
+		}
+		rest = rest[idx+len(e):]
+	}
+}
+
+func TestProxyHttps(t *testing.T) {
+	f := &File{Rules: testFile.Rules, ProxyHttps: true}
+	assert.Equal(t, "HTTPS kedge.example.com:443; PROXY kedge.example.com:80", f.ProxyFor("https://svc.my_cluster.cluster.local/path"))
+	assert.Equal(t, "PROXY kedge.example.com:80", f.ProxyFor("http://special.my_cluster.cluster.local/path"))
+	assert.Equal(t, "DIRECT", f.ProxyFor("https://www.example.com/"), "unmatched https:// URLs must go direct")
+	assert.Equal(t, "DIRECT", f.ProxyFor("ftp://svc.my_cluster.cluster.local/"), "other URLs must go direct")
+	assert.Contains(t, f.String(), `if (url.substring(0, 5) !== "http:" && url.substring(0, 6) !== "https:") {`)
+}
+
+func TestServeHTTP(t *testing.T) {
+	resp := httptest.NewRecorder()
+	testFile.ServeHTTP(resp, httptest.NewRequest("GET", "/proxy.pac", nil))
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(t, ContentType, resp.Header().Get("Content-Type"))
+	assert.Equal(t, testFile.String(), resp.Body.String())
+}
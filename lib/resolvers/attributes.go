@@ -0,0 +1,75 @@
+package resolvers
+
+import "google.golang.org/grpc/naming"
+
+// Attributes are set as the Metadata of naming.Updates by resolvers that know more about the targets than their
+// addresses.
+//
+// They are a value, so that the updates deleting targets carry Metadata equal to the ones that added them.
+type Attributes struct {
+	// Zone is the availability zone of the target.
+	Zone string
+	// Priority of the target, lower ones are preferred. Targets of higher priorities are backups.
+	Priority uint16
+	// Weight is the share of traffic the target gets among the targets of the same priority.
+	Weight uint16
+}
+
+// AttributesOf returns the Attributes in the Metadata of a naming.Update or a grpc.Address, which are empty if the
+// resolver didn't set any.
+func AttributesOf(metadata interface{}) Attributes {
+	if attrs, ok := metadata.(Attributes); ok {
+		return attrs
+	}
+	return Attributes{}
+}
+
+// PickByPriority returns the index of the candidate chosen by the n-th pick.
+//
+// Only candidates of the lowest priority are picked, in a weighted round robin. As in SRV records, candidates of
+// weight 0 are only picked if all the candidates of their priority have a weight of 0.
+func PickByPriority(n uint64, candidates []Attributes) int {
+	lowest := candidates[0].Priority
+	for _, c := range candidates {
+		if c.Priority < lowest {
+			lowest = c.Priority
+		}
+	}
+	var class []int
+	var totalWeight uint64
+	for i, c := range candidates {
+		if c.Priority == lowest {
+			class = append(class, i)
+			totalWeight += uint64(c.Weight)
+		}
+	}
+	if totalWeight == 0 {
+		return class[n%uint64(len(class))]
+	}
+	pos := n % totalWeight
+	for _, i := range class {
+		weight := uint64(candidates[i].Weight)
+		if pos < weight {
+			return i
+		}
+		pos -= weight
+	}
+	return class[len(class)-1]
+}
+
+// diffTargets returns the updates turning the existing targets into the fresh ones, both mapping addresses to their
+// Attributes. Targets whose Attributes changed are deleted with the old ones and added again with the new ones.
+func diffTargets(existing map[string]Attributes, fresh map[string]Attributes) []*naming.Update {
+	var updates []*naming.Update
+	for addr, attrs := range existing {
+		if freshAttrs, ok := fresh[addr]; !ok || freshAttrs != attrs {
+			updates = append(updates, &naming.Update{Op: naming.Delete, Addr: addr, Metadata: attrs})
+		}
+	}
+	for addr, attrs := range fresh {
+		if existingAttrs, ok := existing[addr]; !ok || existingAttrs != attrs {
+			updates = append(updates, &naming.Update{Op: naming.Add, Addr: addr, Metadata: attrs})
+		}
+	}
+	return updates
+}
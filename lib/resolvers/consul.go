@@ -0,0 +1,157 @@
+package resolvers
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	pb "github.com/mwitkow/kedge/_protogen/kedge/config/common/resolvers"
+	"google.golang.org/grpc/naming"
+)
+
+var (
+	// ConsulWaitTime is the longest time blocking queries to Consul wait for changes.
+	ConsulWaitTime = 5 * time.Minute
+)
+
+// NewConsulFromConfig returns a resolver of the passing instances of the service of the config.
+func NewConsulFromConfig(conf *pb.ConsulResolver) (target string, namer naming.Resolver, err error) {
+	if conf.Service == "" {
+		return "", nil, fmt.Errorf("consul resolver needs a service")
+	}
+	apiUrl, err := url.Parse(conf.Address)
+	if err != nil || apiUrl.Scheme == "" || apiUrl.Host == "" {
+		return "", nil, fmt.Errorf("consul resolver needs the URL of an agent as address, got '%v'", conf.Address)
+	}
+	query := url.Values{"passing": []string{"true"}}
+	if conf.Tag != "" {
+		query.Set("tag", conf.Tag)
+	}
+	if conf.Datacenter != "" {
+		query.Set("dc", conf.Datacenter)
+	}
+	// The API is served under the path of the address, if any, e.g. behind a proxy.
+	prefix := strings.TrimSuffix(apiUrl.EscapedPath(), "/")
+	apiUrl.Path = strings.TrimSuffix(apiUrl.Path, "/") + "/v1/health/service/" + conf.Service
+	// The service is a single path segment, so its slashes are escaped too. url.PathEscape does this, but needs Go 1.8.
+	service := strings.Replace((&url.URL{Path: conf.Service}).EscapedPath(), "/", "%2F", -1)
+	apiUrl.RawPath = prefix + "/v1/health/service/" + service
+	apiUrl.RawQuery = query.Encode()
+	// Consul adds up to 1/16th of the wait time to blocking queries, to spread out their responses.
+	client := &http.Client{Timeout: ConsulWaitTime + ConsulWaitTime/16 + 10*time.Second}
+	return conf.Service, &consulResolver{client: client, healthUrl: apiUrl}, nil
+}
+
+type consulResolver struct {
+	client    *http.Client
+	healthUrl *url.URL
+}
+
+func (r *consulResolver) Resolve(target string) (naming.Watcher, error) {
+	return &consulWatcher{resolver: r, existing: make(map[string]Attributes), closed: make(chan struct{})}, nil
+}
+
+// consulServiceEntry is the part of an entry of the Consul health API that kedge uses.
+type consulServiceEntry struct {
+	Node struct {
+		Address string
+	}
+	Service struct {
+		Address string
+		Port    int
+		Weights struct {
+			Passing int
+		}
+	}
+}
+
+type consulWatcher struct {
+	resolver *consulResolver
+	// index is the X-Consul-Index of the last response, for which the next blocking query waits to change.
+	index    uint64
+	existing map[string]Attributes
+	listed   bool
+	closed   chan struct{}
+}
+
+// Next returns the changes of the passing instances. The first call returns all of them.
+func (w *consulWatcher) Next() ([]*naming.Update, error) {
+	for {
+		fresh, err := w.query()
+		if err != nil {
+			select {
+			case <-w.closed:
+				return nil, errWatcherClosed
+			default:
+			}
+			return nil, err
+		}
+		updates := diffTargets(w.existing, fresh)
+		first := !w.listed
+		w.existing = fresh
+		w.listed = true
+		if len(updates) > 0 || first {
+			return updates, nil
+		}
+	}
+}
+
+// query returns the passing instances, blocking until they change if they were queried before.
+func (w *consulWatcher) query() (map[string]Attributes, error) {
+	u := *w.resolver.healthUrl
+	query := u.Query()
+	if w.listed {
+		query.Set("index", strconv.FormatUint(w.index, 10))
+		query.Set("wait", fmt.Sprintf("%ds", int(ConsulWaitTime.Seconds())))
+	}
+	u.RawQuery = query.Encode()
+	req, err := http.NewRequest("GET", u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Cancel = w.closed
+	resp, err := w.resolver.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("querying Consul for '%v' failed with status %v", u.Path, resp.Status)
+	}
+	entries := []*consulServiceEntry{}
+	if err := json.NewDecoder(resp.Body).Decode(&entries); err != nil {
+		return nil, fmt.Errorf("failed decoding Consul health of '%v': %v", u.Path, err)
+	}
+	index, err := strconv.ParseUint(resp.Header.Get("X-Consul-Index"), 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("bad X-Consul-Index of '%v': %v", u.Path, err)
+	}
+	if index < w.index {
+		// Indexes going backwards, e.g. after restores of Consul, make queries start over.
+		index = 0
+	}
+	w.index = index
+	addrs := make(map[string]Attributes)
+	for _, e := range entries {
+		host := e.Service.Address
+		if host == "" {
+			host = e.Node.Address
+		}
+		weight := e.Service.Weights.Passing
+		if weight > math.MaxUint16 {
+			weight = math.MaxUint16
+		}
+		addrs[net.JoinHostPort(host, strconv.Itoa(e.Service.Port))] = Attributes{Weight: uint16(weight)}
+	}
+	return addrs, nil
+}
+
+func (w *consulWatcher) Close() {
+	close(w.closed)
+}
@@ -0,0 +1,300 @@
+package resolvers
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	pb "github.com/mwitkow/kedge/_protogen/kedge/config/common/resolvers"
+	"golang.org/x/net/dns/dnsmessage"
+	"google.golang.org/grpc/naming"
+)
+
+var (
+	// DnsMinRefreshInterval and DnsMaxRefreshInterval bound the TTLs of A and AAAA records, like
+	// lbtransport.MaximumRefreshInterval does for SRV records.
+	DnsMinRefreshInterval = 1 * time.Second
+	DnsMaxRefreshInterval = 5 * time.Second
+
+	// DnsTimeout bounds every query sent to a nameserver.
+	DnsTimeout = 2 * time.Second
+
+	// DnsNameservers are the host:ports of the nameservers queried in order. If empty, the ones in /etc/resolv.conf
+	// are used.
+	DnsNameservers []string
+
+	// HostFallbackLookup looks up the host names that DnsNameservers have no addresses for.
+	HostFallbackLookup = net.LookupHost
+
+	resolvConfPath = "/etc/resolv.conf"
+)
+
+func NewDnsFromConfig(conf *pb.DnsResolver) (target string, namer naming.Resolver, err error) {
+	if conf.HostName == "" {
+		return "", nil, fmt.Errorf("dns resolver needs a host_name")
+	}
+	if conf.Port == 0 || conf.Port > 65535 {
+		return "", nil, fmt.Errorf("dns resolver needs a port between 1 and 65535")
+	}
+	port := strconv.Itoa(int(conf.Port))
+	return net.JoinHostPort(strings.TrimSuffix(conf.HostName, "."), port), &dnsResolver{hostName: conf.HostName, port: port}, nil
+}
+
+type dnsResolver struct {
+	hostName string
+	port     string
+}
+
+func (r *dnsResolver) Resolve(target string) (naming.Watcher, error) {
+	return &dnsWatcher{
+		resolver: r,
+		existing: make(map[string]Attributes),
+		closed:   make(chan struct{}),
+	}, nil
+}
+
+type dnsWatcher struct {
+	resolver *dnsResolver
+	existing map[string]Attributes
+	next     time.Time
+	listed   bool
+	closed   chan struct{}
+}
+
+// Next returns the changes of the addresses, looking them up again once their TTL expires. The first call returns all
+// of them.
+func (w *dnsWatcher) Next() ([]*naming.Update, error) {
+	for {
+		select {
+		case <-w.closed:
+			return nil, errWatcherClosed
+		case <-time.After(w.next.Sub(time.Now())):
+		}
+		ips, ttl, err := LookupHost(w.resolver.hostName)
+		if err != nil {
+			return nil, err
+		}
+		if ttl < DnsMinRefreshInterval {
+			ttl = DnsMinRefreshInterval
+		} else if ttl > DnsMaxRefreshInterval {
+			ttl = DnsMaxRefreshInterval
+		}
+		w.next = time.Now().Add(ttl)
+		fresh := make(map[string]Attributes)
+		for _, ip := range ips {
+			fresh[net.JoinHostPort(ip, w.resolver.port)] = Attributes{}
+		}
+		updates := diffTargets(w.existing, fresh)
+		first := !w.listed
+		w.existing = fresh
+		w.listed = true
+		if len(updates) > 0 || first {
+			return updates, nil
+		}
+	}
+}
+
+func (w *dnsWatcher) Close() {
+	close(w.closed)
+}
+
+// LookupHost returns the IP addresses in the A and AAAA records of the host name, and the lowest TTL of the records,
+// querying DnsNameservers. A failed query only fails the lookup if the other one found no addresses either. Host names
+// that the nameservers don't know, e.g. ones in /etc/hosts or that need the search domains of /etc/resolv.conf, are
+// looked up with HostFallbackLookup instead, and their addresses get a TTL of DnsMinRefreshInterval.
+func LookupHost(hostName string) (ips []string, ttl time.Duration, err error) {
+	name := hostName
+	if !strings.HasSuffix(name, ".") {
+		name += "."
+	}
+	var queryErr error
+	for _, qType := range []dnsmessage.Type{dnsmessage.TypeA, dnsmessage.TypeAAAA} {
+		resp, err := query(dnsmessage.Question{Name: name, Type: qType, Class: dnsmessage.ClassINET})
+		if err == nil && resp.RCode != dnsmessage.RCodeSuccess && resp.RCode != dnsmessage.RCodeNameError {
+			err = &net.DNSError{Err: fmt.Sprintf("lookup failed with rcode %d", resp.RCode), Name: hostName}
+		}
+		if err != nil {
+			queryErr = err
+			continue
+		}
+		for _, answer := range resp.Answers {
+			var ip net.IP
+			switch r := answer.(type) {
+			case *dnsmessage.AResource:
+				ip = net.IP(r.A[:])
+			case *dnsmessage.AAAAResource:
+				ip = net.IP(r.AAAA[:])
+			default:
+				continue
+			}
+			ips = append(ips, ip.String())
+			if recordTtl := time.Duration(answer.Header().TTL) * time.Second; len(ips) == 1 || recordTtl < ttl {
+				ttl = recordTtl
+			}
+		}
+	}
+	if len(ips) > 0 {
+		return ips, ttl, nil
+	} else if queryErr != nil {
+		return nil, 0, queryErr
+	}
+	ips, err = HostFallbackLookup(hostName)
+	if err != nil {
+		return nil, 0, err
+	} else if len(ips) == 0 {
+		return nil, 0, &net.DNSError{Err: "no addresses", Name: hostName}
+	}
+	return ips, DnsMinRefreshInterval, nil
+}
+
+// LookupSRV returns the SRV records of the service of the host name, and the lowest TTL of the records, querying
+// DnsNameservers. Unlike net.LookupSRV, it leaves picking by priority and weight to the caller.
+func LookupSRV(service string, proto string, hostName string) (srvs []*net.SRV, ttl time.Duration, err error) {
+	name := "_" + service + "._" + proto + "." + hostName
+	if !strings.HasSuffix(name, ".") {
+		name += "."
+	}
+	resp, err := query(dnsmessage.Question{Name: name, Type: dnsmessage.TypeSRV, Class: dnsmessage.ClassINET})
+	if err != nil {
+		return nil, 0, err
+	}
+	if resp.RCode != dnsmessage.RCodeSuccess {
+		return nil, 0, &net.DNSError{Err: fmt.Sprintf("lookup failed with rcode %d", resp.RCode), Name: name}
+	}
+	for _, answer := range resp.Answers {
+		r, ok := answer.(*dnsmessage.SRVResource)
+		if !ok {
+			continue
+		}
+		srvs = append(srvs, &net.SRV{Target: r.Target, Port: r.Port, Priority: r.Priority, Weight: r.Weight})
+		if recordTtl := time.Duration(answer.Header().TTL) * time.Second; len(srvs) == 1 || recordTtl < ttl {
+			ttl = recordTtl
+		}
+	}
+	if len(srvs) == 0 {
+		return nil, 0, &net.DNSError{Err: "no SRV records", Name: name}
+	}
+	return srvs, ttl, nil
+}
+
+// query sends the question to the nameservers in order, until one of them answers.
+func query(question dnsmessage.Question) (*dnsmessage.Message, error) {
+	servers, err := nameservers()
+	if err != nil {
+		return nil, err
+	}
+	// Unpredictable IDs keep off-path attackers from spoofing answers.
+	var id uint16
+	if err := binary.Read(rand.Reader, binary.BigEndian, &id); err != nil {
+		return nil, err
+	}
+	req := &dnsmessage.Message{
+		Header:    dnsmessage.Header{ID: id, RecursionDesired: true},
+		Questions: []dnsmessage.Question{question},
+	}
+	packed, err := req.Pack()
+	if err != nil {
+		return nil, err
+	}
+	for _, server := range servers {
+		var resp *dnsmessage.Message
+		resp, err = exchange("udp", server, packed, req)
+		if err == nil && resp.Truncated {
+			resp, err = exchange("tcp", server, packed, req)
+		}
+		if err == nil {
+			return resp, nil
+		}
+	}
+	return nil, fmt.Errorf("querying %v failed: %v", question.Name, err)
+}
+
+func exchange(network string, server string, packed []byte, req *dnsmessage.Message) (*dnsmessage.Message, error) {
+	conn, err := net.DialTimeout(network, server, DnsTimeout)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(DnsTimeout))
+	if network == "tcp" {
+		// Messages over TCP are prefixed by their length.
+		packed = append([]byte{byte(len(packed) >> 8), byte(len(packed))}, packed...)
+	}
+	if _, err := conn.Write(packed); err != nil {
+		return nil, err
+	}
+	for {
+		var buf []byte
+		if network == "tcp" {
+			var length uint16
+			if err := binary.Read(conn, binary.BigEndian, &length); err != nil {
+				return nil, err
+			}
+			buf = make([]byte, length)
+			if _, err := io.ReadFull(conn, buf); err != nil {
+				return nil, err
+			}
+		} else {
+			buf = make([]byte, 65535)
+			n, err := conn.Read(buf)
+			if err != nil {
+				return nil, err
+			}
+			buf = buf[:n]
+		}
+		resp := &dnsmessage.Message{}
+		if err := resp.Unpack(buf); err != nil {
+			return nil, err
+		}
+		// Responses to other queries are stale ones, sent to the same port before, or spoofed.
+		if resp.Response && resp.ID == req.ID && sameQuestions(resp.Questions, req.Questions) {
+			return resp, nil
+		}
+	}
+}
+
+// sameQuestions compares questions like nameservers do, ignoring the case of names.
+func sameQuestions(a []dnsmessage.Question, b []dnsmessage.Question) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].Type != b[i].Type || a[i].Class != b[i].Class || !strings.EqualFold(a[i].Name, b[i].Name) {
+			return false
+		}
+	}
+	return true
+}
+
+func nameservers() ([]string, error) {
+	if len(DnsNameservers) > 0 {
+		return DnsNameservers, nil
+	}
+	f, err := os.Open(resolvConfPath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var servers []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) >= 2 && fields[0] == "nameserver" {
+			servers = append(servers, net.JoinHostPort(fields[1], "53"))
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(servers) == 0 {
+		return nil, fmt.Errorf("no nameservers in %v", resolvConfPath)
+	}
+	return servers, nil
+}
@@ -0,0 +1,184 @@
+// Package dnstest runs nameservers for tests of code that queries resolvers.DnsNameservers.
+package dnstest
+
+import (
+	"encoding/binary"
+	"io"
+	"net"
+	"sync"
+	"testing"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// Server answers queries over UDP and TCP from its records, on the same port of 127.0.0.1.
+type Server struct {
+	udp net.PacketConn
+	tcp net.Listener
+
+	mu       sync.Mutex
+	records  map[string][]dnsmessage.Resource
+	truncate bool
+	spoof    bool
+	failType dnsmessage.Type
+}
+
+// NewServer starts a Server without records, which answers all queries with NXDOMAIN.
+func NewServer(t *testing.T) *Server {
+	var udp net.PacketConn
+	var tcp net.Listener
+	var err error
+	// The random UDP port may be taken for TCP, so it's retried until both bind.
+	for i := 0; i < 10; i++ {
+		udp, err = net.ListenPacket("udp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatalf("dnstest: failed listening: %v", err)
+		}
+		tcp, err = net.Listen("tcp", udp.LocalAddr().String())
+		if err == nil {
+			break
+		}
+		udp.Close()
+	}
+	if err != nil {
+		t.Fatalf("dnstest: failed binding the same port for UDP and TCP: %v", err)
+	}
+	s := &Server{udp: udp, tcp: tcp, records: make(map[string][]dnsmessage.Resource)}
+	go s.serveUdp()
+	go s.serveTcp()
+	return s
+}
+
+// Addr returns the host:port of the server, for resolvers.DnsNameservers.
+func (s *Server) Addr() string {
+	return s.udp.LocalAddr().String()
+}
+
+// Set replaces the records of the fully qualified name. Queries of the name are answered with the records of their
+// type, and names without records with NXDOMAIN.
+func (s *Server) Set(name string, records ...dnsmessage.Resource) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.records[name] = records
+}
+
+// SetTruncated makes answers over UDP truncated, so that clients ask again over TCP.
+func (s *Server) SetTruncated(truncate bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.truncate = truncate
+}
+
+// SetSpoofing makes answers over UDP preceded by answers to other questions with the ID of the query, like off-path
+// attackers guessing IDs send.
+func (s *Server) SetSpoofing(spoof bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.spoof = spoof
+}
+
+// SetFailing makes queries of the type fail with SERVFAIL.
+func (s *Server) SetFailing(qType dnsmessage.Type) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failType = qType
+}
+
+func (s *Server) Close() {
+	s.udp.Close()
+	s.tcp.Close()
+}
+
+func (s *Server) answer(packed []byte, overTcp bool) []byte {
+	req := &dnsmessage.Message{}
+	if err := req.Unpack(packed); err != nil || len(req.Questions) == 0 {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	resp := &dnsmessage.Message{Header: dnsmessage.Header{ID: req.ID, Response: true}, Questions: req.Questions}
+	q := req.Questions[0]
+	records, ok := s.records[q.Name]
+	if q.Type == s.failType {
+		resp.RCode = dnsmessage.RCodeServerFailure
+	} else if !ok {
+		resp.RCode = dnsmessage.RCodeNameError
+	} else if s.truncate && !overTcp {
+		resp.Truncated = true
+	} else {
+		for _, r := range records {
+			if r.Header().Type == q.Type {
+				resp.Answers = append(resp.Answers, r)
+			}
+		}
+	}
+	out, _ := resp.Pack()
+	return out
+}
+
+func (s *Server) serveUdp() {
+	buf := make([]byte, 65535)
+	for {
+		n, addr, err := s.udp.ReadFrom(buf)
+		if err != nil {
+			return
+		}
+		s.mu.Lock()
+		spoof := s.spoof
+		s.mu.Unlock()
+		if spoof {
+			s.udp.WriteTo(spoofedAnswer(buf[:n]), addr)
+		}
+		s.udp.WriteTo(s.answer(buf[:n], false), addr)
+	}
+}
+
+// spoofedAnswer answers another question with the ID of the query.
+func spoofedAnswer(packed []byte) []byte {
+	req := &dnsmessage.Message{}
+	if err := req.Unpack(packed); err != nil || len(req.Questions) == 0 {
+		return nil
+	}
+	q := req.Questions[0]
+	resp := &dnsmessage.Message{
+		Header:    dnsmessage.Header{ID: req.ID, Response: true},
+		Questions: []dnsmessage.Question{{Name: "spoofed.example.com.", Type: q.Type, Class: q.Class}},
+	}
+	if q.Type == dnsmessage.TypeA {
+		resp.Answers = []dnsmessage.Resource{ARecord(q.Name, 3600, "6.6.6.6")}
+	}
+	out, _ := resp.Pack()
+	return out
+}
+
+func (s *Server) serveTcp() {
+	for {
+		conn, err := s.tcp.Accept()
+		if err != nil {
+			return
+		}
+		var length uint16
+		if binary.Read(conn, binary.BigEndian, &length) == nil {
+			packed := make([]byte, length)
+			if _, err := io.ReadFull(conn, packed); err == nil {
+				out := s.answer(packed, true)
+				conn.Write(append([]byte{byte(len(out) >> 8), byte(len(out))}, out...))
+			}
+		}
+		conn.Close()
+	}
+}
+
+// ARecord returns an A record of the name.
+func ARecord(name string, ttl uint32, ip string) dnsmessage.Resource {
+	r := &dnsmessage.AResource{ResourceHeader: dnsmessage.ResourceHeader{Name: name, Type: dnsmessage.TypeA, Class: dnsmessage.ClassINET, TTL: ttl}}
+	copy(r.A[:], net.ParseIP(ip).To4())
+	return r
+}
+
+// AAAARecord returns an AAAA record of the name.
+func AAAARecord(name string, ttl uint32, ip string) dnsmessage.Resource {
+	r := &dnsmessage.AAAAResource{ResourceHeader: dnsmessage.ResourceHeader{Name: name, Type: dnsmessage.TypeAAAA, Class: dnsmessage.ClassINET, TTL: ttl}}
+	copy(r.AAAA[:], net.ParseIP(ip))
+	return r
+}
@@ -0,0 +1,123 @@
+package resolvers
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"time"
+
+	pb "github.com/mwitkow/kedge/_protogen/kedge/config/common/resolvers"
+	"google.golang.org/grpc/naming"
+)
+
+var (
+	// FileRefreshInterval is how often endpoint files are checked for changes. Host names in them are resolved again
+	// at least every StaticRefreshInterval.
+	FileRefreshInterval = 1 * time.Second
+)
+
+// NewFileFromConfig returns a resolver of the addresses in the file of the config, which must be readable already.
+func NewFileFromConfig(conf *pb.FileResolver) (target string, namer naming.Resolver, err error) {
+	if conf.Path == "" {
+		return "", nil, fmt.Errorf("file resolver needs a path")
+	}
+	content, err := ioutil.ReadFile(conf.Path)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed reading endpoints file: %v", err)
+	}
+	if _, err := parseEndpointsFile(conf.Path, content); err != nil {
+		return "", nil, err
+	}
+	return conf.Path, &fileResolver{path: conf.Path}, nil
+}
+
+// parseEndpointsFile parses the JSON list of StaticAddresses in an endpoints file. Other formats aren't supported.
+func parseEndpointsFile(path string, content []byte) ([]staticAddress, error) {
+	confs := []*pb.StaticAddress{}
+	if err := json.Unmarshal(content, &confs); err != nil {
+		return nil, fmt.Errorf("failed parsing endpoints file '%v' as JSON: %v", path, err)
+	}
+	return parseStaticAddresses(confs)
+}
+
+type fileResolver struct {
+	path string
+}
+
+func (r *fileResolver) Resolve(target string) (naming.Watcher, error) {
+	return &fileWatcher{
+		path:     r.path,
+		hosts:    newHostCache(),
+		existing: make(map[string]Attributes),
+		closed:   make(chan struct{}),
+	}, nil
+}
+
+type fileWatcher struct {
+	path      string
+	read      bool
+	hash      [sha256.Size]byte
+	next      time.Time
+	resolved  time.Time
+	addresses []staticAddress
+	hosts     *hostCache
+	existing  map[string]Attributes
+	listed    bool
+	closed    chan struct{}
+}
+
+// Next returns the changes of the addresses in the file. The first call returns all of them.
+func (w *fileWatcher) Next() ([]*naming.Update, error) {
+	for {
+		select {
+		case <-w.closed:
+			return nil, errWatcherClosed
+		case <-time.After(w.next.Sub(time.Now())):
+		}
+		w.next = time.Now().Add(FileRefreshInterval)
+		changed, err := w.reload()
+		if err != nil {
+			return nil, err
+		}
+		if !changed && time.Now().Sub(w.resolved) < StaticRefreshInterval {
+			continue
+		}
+		fresh := w.hosts.targets(w.addresses)
+		w.resolved = time.Now()
+		updates := diffTargets(w.existing, fresh)
+		first := !w.listed
+		w.existing = fresh
+		w.listed = true
+		if len(updates) > 0 || first {
+			return updates, nil
+		}
+	}
+}
+
+// reload reads the file, returning whether its content changed since it was last read.
+//
+// Changes are detected by the content, as rewrites within the resolution of modification times, keeping the size, are
+// only visible there.
+func (w *fileWatcher) reload() (bool, error) {
+	content, err := ioutil.ReadFile(w.path)
+	if err != nil {
+		return false, fmt.Errorf("failed reading endpoints file: %v", err)
+	}
+	hash := sha256.Sum256(content)
+	if w.read && hash == w.hash {
+		return false, nil
+	}
+	addresses, err := parseEndpointsFile(w.path, content)
+	if err != nil {
+		return false, err
+	}
+	w.read = true
+	w.hash = hash
+	w.addresses = addresses
+	return true, nil
+}
+
+func (w *fileWatcher) Close() {
+	close(w.closed)
+}
@@ -16,6 +16,13 @@ func NewK8sFromConfig(conf *pb.KubeResolver) (target string, namer naming.Resolv
 	if conf.Namespace != "" {
 		namespace = conf.Namespace
 	}
+	if conf.EndpointSlices {
+		r, err := newEndpointSlicesFromConfig(conf, namespace)
+		if err != nil {
+			return "", nil, err
+		}
+		return target, r, nil
+	}
 	b := kuberesolver.NewWithNamespace(namespace)
 	return target, b.Resolver(), nil
 }
\ No newline at end of file
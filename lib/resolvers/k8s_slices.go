@@ -0,0 +1,170 @@
+package resolvers
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strconv"
+	"time"
+
+	pb "github.com/mwitkow/kedge/_protogen/kedge/config/common/resolvers"
+	"google.golang.org/grpc/naming"
+)
+
+var (
+	// EndpointSlicesRefreshInterval is how often the EndpointSlices of a service are listed.
+	EndpointSlicesRefreshInterval = 5 * time.Second
+
+	// kubeServiceAccountDir holds the credentials of the service account of the pod.
+	kubeServiceAccountDir = "/var/run/secrets/kubernetes.io/serviceaccount"
+
+	errWatcherClosed = errors.New("resolver: watcher closed")
+)
+
+// endpointSlicesResolver resolves the ready endpoints of a Kubernetes service, and their zones, by polling the
+// EndpointSlice API.
+type endpointSlicesResolver struct {
+	client    *http.Client
+	apiUrl    string
+	tokenFile string
+	namespace string
+	service   string
+	portName  string
+}
+
+func newEndpointSlicesFromConfig(conf *pb.KubeResolver, namespace string) (*endpointSlicesResolver, error) {
+	host, port := os.Getenv("KUBERNETES_SERVICE_HOST"), os.Getenv("KUBERNETES_SERVICE_PORT")
+	if host == "" || port == "" {
+		return nil, fmt.Errorf("endpoint_slices need kedge to run in a Kubernetes pod")
+	}
+	caPem, err := ioutil.ReadFile(path.Join(kubeServiceAccountDir, "ca.crt"))
+	if err != nil {
+		return nil, fmt.Errorf("failed reading the Kubernetes CA: %v", err)
+	}
+	rootCAs := x509.NewCertPool()
+	if !rootCAs.AppendCertsFromPEM(caPem) {
+		return nil, fmt.Errorf("no certificates in the Kubernetes CA")
+	}
+	return &endpointSlicesResolver{
+		client:    &http.Client{Timeout: 10 * time.Second, Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: rootCAs}}},
+		apiUrl:    "https://" + net.JoinHostPort(host, port),
+		tokenFile: path.Join(kubeServiceAccountDir, "token"),
+		namespace: namespace,
+		service:   conf.ServiceName,
+		portName:  conf.PortName,
+	}, nil
+}
+
+func (r *endpointSlicesResolver) Resolve(target string) (naming.Watcher, error) {
+	return &endpointSlicesWatcher{resolver: r, existing: make(map[string]Attributes), closed: make(chan struct{})}, nil
+}
+
+type endpointSliceList struct {
+	Items []struct {
+		Endpoints []struct {
+			Addresses  []string `json:"addresses"`
+			Conditions struct {
+				// Ready is nil when unknown, which is meant to be interpreted as ready.
+				Ready *bool `json:"ready"`
+			} `json:"conditions"`
+			Zone string `json:"zone"`
+		} `json:"endpoints"`
+		Ports []struct {
+			Name string `json:"name"`
+			Port int    `json:"port"`
+		} `json:"ports"`
+	} `json:"items"`
+}
+
+// list returns the addresses of the ready endpoints of the service, with their zones as Attributes.
+func (r *endpointSlicesResolver) list() (map[string]Attributes, error) {
+	query := url.Values{"labelSelector": []string{"kubernetes.io/service-name=" + r.service}}
+	req, err := http.NewRequest("GET", fmt.Sprintf("%s/apis/discovery.k8s.io/v1/namespaces/%s/endpointslices?%s", r.apiUrl, r.namespace, query.Encode()), nil)
+	if err != nil {
+		return nil, err
+	}
+	if r.tokenFile != "" {
+		// Tokens of service accounts are rotated, so they are read for every request.
+		token, err := ioutil.ReadFile(r.tokenFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed reading the service account token: %v", err)
+		}
+		req.Header.Set("Authorization", "Bearer "+string(token))
+	}
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("listing EndpointSlices of '%v' failed with status %v", r.service, resp.Status)
+	}
+	slices := &endpointSliceList{}
+	if err := json.NewDecoder(resp.Body).Decode(slices); err != nil {
+		return nil, fmt.Errorf("failed decoding EndpointSlices of '%v': %v", r.service, err)
+	}
+	addrs := make(map[string]Attributes)
+	for _, slice := range slices.Items {
+		port := 0
+		for _, p := range slice.Ports {
+			if p.Name == r.portName || (r.portName == "" && len(slice.Ports) == 1) {
+				port = p.Port
+			}
+		}
+		if port == 0 {
+			continue
+		}
+		for _, e := range slice.Endpoints {
+			if e.Conditions.Ready != nil && !*e.Conditions.Ready {
+				continue
+			}
+			for _, a := range e.Addresses {
+				addrs[net.JoinHostPort(a, strconv.Itoa(port))] = Attributes{Zone: e.Zone}
+			}
+		}
+	}
+	return addrs, nil
+}
+
+type endpointSlicesWatcher struct {
+	resolver *endpointSlicesResolver
+	existing map[string]Attributes
+	listed   bool
+	closed   chan struct{}
+}
+
+// Next returns the changes of the endpoints. The first call returns all of them, even if there are none.
+func (w *endpointSlicesWatcher) Next() ([]*naming.Update, error) {
+	for {
+		if w.listed {
+			select {
+			case <-w.closed:
+				return nil, errWatcherClosed
+			case <-time.After(EndpointSlicesRefreshInterval):
+			}
+		}
+		fresh, err := w.resolver.list()
+		if err != nil {
+			return nil, err
+		}
+		updates := diffTargets(w.existing, fresh)
+		first := !w.listed
+		w.existing = fresh
+		w.listed = true
+		if len(updates) > 0 || first {
+			return updates, nil
+		}
+	}
+}
+
+func (w *endpointSlicesWatcher) Close() {
+	close(w.closed)
+}
@@ -0,0 +1,50 @@
+package resolvers
+
+import (
+	"net"
+	"regexp"
+	"strings"
+
+	"google.golang.org/grpc/naming"
+)
+
+// zonePatternResolver sets the zones of targets matched from their host names.
+type zonePatternResolver struct {
+	naming.Resolver
+	pattern *regexp.Regexp
+}
+
+func (r *zonePatternResolver) Resolve(target string) (naming.Watcher, error) {
+	w, err := r.Resolver.Resolve(target)
+	if err != nil {
+		return nil, err
+	}
+	return &zonePatternWatcher{Watcher: w, pattern: r.pattern}, nil
+}
+
+type zonePatternWatcher struct {
+	naming.Watcher
+	pattern *regexp.Regexp
+}
+
+func (w *zonePatternWatcher) Next() ([]*naming.Update, error) {
+	updates, err := w.Watcher.Next()
+	if err != nil {
+		return nil, err
+	}
+	for _, u := range updates {
+		host, _, err := net.SplitHostPort(u.Addr)
+		if err != nil {
+			host = u.Addr
+		}
+		if net.ParseIP(host) != nil {
+			continue // only host names follow naming conventions.
+		}
+		if m := w.pattern.FindStringSubmatch(strings.TrimSuffix(host, ".")); len(m) > 1 && m[1] != "" {
+			attrs := AttributesOf(u.Metadata)
+			attrs.Zone = m[1]
+			u.Metadata = attrs
+		}
+	}
+	return updates, nil
+}
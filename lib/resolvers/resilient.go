@@ -0,0 +1,333 @@
+package resolvers
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/grpc/naming"
+)
+
+var (
+	// DefaultGracePeriod is how long Resilient watchers keep the targets resolved last while their resolver fails.
+	DefaultGracePeriod = 5 * time.Minute
+
+	// WatcherRestartBackoff is how long Resilient watchers wait before restarting failed watchers. It doubles with
+	// every consecutive failure, up to WatcherMaxRestartBackoff.
+	WatcherRestartBackoff    = 100 * time.Millisecond
+	WatcherMaxRestartBackoff = 30 * time.Second
+
+	resolverErrorsCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "kedge",
+			Subsystem: "resolver",
+			Name:      "errors_total",
+			Help:      "Count of failures of the resolvers of backends, after each of which their watcher is restarted.",
+		}, []string{"pool", "backend_name"})
+	resolverTargetsGauge = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "kedge",
+			Subsystem: "resolver",
+			Name:      "targets",
+			Help:      "Number of targets of backends, including the ones kept while their resolver fails.",
+		}, []string{"pool", "backend_name"})
+	resolverFailingGauge = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "kedge",
+			Subsystem: "resolver",
+			Name:      "failing",
+			Help:      "1 while the resolver of a backend is failing, 0 otherwise.",
+		}, []string{"pool", "backend_name"})
+
+	watchersMu sync.Mutex
+	watchers   = make(map[*resilientWatcher]bool)
+)
+
+func init() {
+	prometheus.MustRegister(resolverErrorsCounter, resolverTargetsGauge, resolverFailingGauge)
+}
+
+// Resilient returns a resolver whose watchers restart the watchers of the resolver when they fail, with backoff.
+// While they fail, the targets resolved last are kept for the grace period, after which they are deleted.
+//
+// The state of the watchers is exported as metrics and by DebugHandler, labelled with the pool and the backend.
+func Resilient(pool string, backend string, resolver naming.Resolver, gracePeriod time.Duration) naming.Resolver {
+	return &resilientResolver{pool: pool, backend: backend, resolver: resolver, gracePeriod: gracePeriod}
+}
+
+type resilientResolver struct {
+	pool        string
+	backend     string
+	resolver    naming.Resolver
+	gracePeriod time.Duration
+}
+
+func (r *resilientResolver) Resolve(target string) (naming.Watcher, error) {
+	watcher, err := r.resolver.Resolve(target)
+	if err != nil {
+		return nil, err
+	}
+	w := &resilientWatcher{
+		resolver: r,
+		target:   target,
+		current:  make(map[string]interface{}),
+		backoff:  WatcherRestartBackoff,
+		watcher:  watcher,
+		state:    WatcherState{Pool: r.pool, Backend: r.backend, Target: target},
+		closed:   make(chan struct{}),
+	}
+	watchersMu.Lock()
+	watchers[w] = true
+	watchersMu.Unlock()
+	return w, nil
+}
+
+// WatcherState is the state of a Resilient watcher.
+type WatcherState struct {
+	Pool    string
+	Backend string
+	Target  string
+	// Targets is the number of targets, including the ones kept while the resolver fails.
+	Targets int
+	// FailingSince is the time the resolver started failing at, which is zero while it works.
+	FailingSince  time.Time
+	LastError     string
+	LastErrorTime time.Time
+	Restarts      int
+}
+
+type resilientWatcher struct {
+	resolver *resilientResolver
+	target   string
+	// current maps the targets returned so far to their Metadata.
+	current   map[string]interface{}
+	returned  bool
+	restarted bool
+	backoff   time.Duration
+
+	// mu guards the watcher, which Close closes while Next waits on it, and the state, which DebugHandler reads.
+	mu      sync.Mutex
+	watcher naming.Watcher
+	state   WatcherState
+	closed  chan struct{}
+}
+
+// Next returns the changes of the targets. Errors are only returned once the watcher is closed.
+func (w *resilientWatcher) Next() ([]*naming.Update, error) {
+	for {
+		w.mu.Lock()
+		watcher := w.watcher
+		w.mu.Unlock()
+		var updates []*naming.Update
+		var err error
+		if watcher == nil {
+			err = w.restart()
+		} else {
+			updates, err = watcher.Next()
+		}
+		select {
+		case <-w.closed:
+			return nil, errWatcherClosed
+		default:
+		}
+		if err != nil {
+			if expired := w.failed(err); len(expired) > 0 {
+				return expired, nil
+			}
+			continue
+		}
+		if watcher == nil {
+			continue // the restarted watcher is waited on next.
+		}
+		updates = w.succeeded(updates)
+		if len(updates) > 0 || !w.returned {
+			w.returned = true
+			return updates, nil
+		}
+	}
+}
+
+func (w *resilientWatcher) restart() error {
+	watcher, err := w.resolver.resolver.Resolve(w.target)
+	if err != nil {
+		return err
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	select {
+	case <-w.closed:
+		watcher.Close()
+		return errWatcherClosed
+	default:
+	}
+	w.watcher = watcher
+	w.state.Restarts++
+	w.restarted = true
+	return nil
+}
+
+// failed closes the failed watcher and waits before it is restarted. It returns the deletions of all targets once the
+// grace period is over.
+func (w *resilientWatcher) failed(err error) []*naming.Update {
+	now := time.Now()
+	w.mu.Lock()
+	if w.watcher != nil {
+		w.watcher.Close()
+		w.watcher = nil
+	}
+	if w.state.FailingSince.IsZero() {
+		w.state.FailingSince = now
+	}
+	w.state.LastError = err.Error()
+	w.state.LastErrorTime = now
+	graceEnd := w.state.FailingSince.Add(w.resolver.gracePeriod)
+	w.mu.Unlock()
+	resolverErrorsCounter.WithLabelValues(w.resolver.pool, w.resolver.backend).Inc()
+	resolverFailingGauge.WithLabelValues(w.resolver.pool, w.resolver.backend).Set(1)
+
+	wait := w.backoff
+	if w.backoff *= 2; w.backoff > WatcherMaxRestartBackoff {
+		w.backoff = WatcherMaxRestartBackoff
+	}
+	if len(w.current) > 0 && graceEnd.Sub(now) < wait {
+		wait = graceEnd.Sub(now)
+	}
+	select {
+	case <-w.closed:
+		return nil
+	case <-time.After(wait):
+	}
+	if len(w.current) == 0 || time.Now().Before(graceEnd) {
+		return nil
+	}
+	var deletions []*naming.Update
+	for addr, metadata := range w.current {
+		deletions = append(deletions, &naming.Update{Op: naming.Delete, Addr: addr, Metadata: metadata})
+	}
+	w.current = make(map[string]interface{})
+	w.setTargets()
+	return deletions
+}
+
+// succeeded applies the updates of the watcher to the current targets, returning the ones to pass on.
+func (w *resilientWatcher) succeeded(updates []*naming.Update) []*naming.Update {
+	if w.restarted {
+		// The first updates of restarted watchers are all of their targets, which replace the current ones.
+		fresh := make(map[string]interface{})
+		for _, u := range updates {
+			if u.Op == naming.Add {
+				fresh[u.Addr] = u.Metadata
+			}
+		}
+		updates = nil
+		for addr, metadata := range w.current {
+			if freshMetadata, ok := fresh[addr]; !ok || !reflect.DeepEqual(freshMetadata, metadata) {
+				updates = append(updates, &naming.Update{Op: naming.Delete, Addr: addr, Metadata: metadata})
+			}
+		}
+		for addr, metadata := range fresh {
+			if currentMetadata, ok := w.current[addr]; !ok || !reflect.DeepEqual(currentMetadata, metadata) {
+				updates = append(updates, &naming.Update{Op: naming.Add, Addr: addr, Metadata: metadata})
+			}
+		}
+		w.current = fresh
+		w.restarted = false
+	} else {
+		for _, u := range updates {
+			if u.Op == naming.Add {
+				w.current[u.Addr] = u.Metadata
+			} else if u.Op == naming.Delete {
+				delete(w.current, u.Addr)
+			}
+		}
+	}
+	w.backoff = WatcherRestartBackoff
+	w.mu.Lock()
+	w.state.FailingSince = time.Time{}
+	w.mu.Unlock()
+	resolverFailingGauge.WithLabelValues(w.resolver.pool, w.resolver.backend).Set(0)
+	w.setTargets()
+	return updates
+}
+
+func (w *resilientWatcher) setTargets() {
+	w.mu.Lock()
+	w.state.Targets = len(w.current)
+	w.mu.Unlock()
+	resolverTargetsGauge.WithLabelValues(w.resolver.pool, w.resolver.backend).Set(float64(len(w.current)))
+}
+
+func (w *resilientWatcher) Close() {
+	w.mu.Lock()
+	select {
+	case <-w.closed:
+		w.mu.Unlock()
+		return
+	default:
+	}
+	close(w.closed)
+	if w.watcher != nil {
+		w.watcher.Close()
+	}
+	w.mu.Unlock()
+
+	watchersMu.Lock()
+	defer watchersMu.Unlock()
+	delete(watchers, w)
+	for other := range watchers {
+		if other.resolver.pool == w.resolver.pool && other.resolver.backend == w.resolver.backend {
+			return // the metrics belong to the watcher replacing this one.
+		}
+	}
+	resolverTargetsGauge.DeleteLabelValues(w.resolver.pool, w.resolver.backend)
+	resolverFailingGauge.DeleteLabelValues(w.resolver.pool, w.resolver.backend)
+}
+
+// WatcherStates returns the states of all open Resilient watchers, ordered by pool and backend.
+func WatcherStates() []WatcherState {
+	watchersMu.Lock()
+	defer watchersMu.Unlock()
+	states := []WatcherState{}
+	for w := range watchers {
+		w.mu.Lock()
+		states = append(states, w.state)
+		w.mu.Unlock()
+	}
+	sort.Sort(statesByBackend(states))
+	return states
+}
+
+type statesByBackend []WatcherState
+
+func (s statesByBackend) Len() int      { return len(s) }
+func (s statesByBackend) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+func (s statesByBackend) Less(i, j int) bool {
+	if s[i].Pool != s[j].Pool {
+		return s[i].Pool < s[j].Pool
+	}
+	return s[i].Backend < s[j].Backend
+}
+
+// DebugHandler lists the WatcherStates as a table.
+func DebugHandler(resp http.ResponseWriter, req *http.Request) {
+	resp.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	tw := tabwriter.NewWriter(resp, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "POOL\tBACKEND\tTARGET\tTARGETS\tFAILING SINCE\tRESTARTS\tLAST ERROR")
+	for _, s := range WatcherStates() {
+		failingSince := "-"
+		if !s.FailingSince.IsZero() {
+			failingSince = s.FailingSince.Format(time.RFC3339)
+		}
+		lastError := "-"
+		if s.LastError != "" {
+			lastError = fmt.Sprintf("%v at %v", s.LastError, s.LastErrorTime.Format(time.RFC3339))
+		}
+		fmt.Fprintf(tw, "%v\t%v\t%v\t%d\t%v\t%d\t%v\n", s.Pool, s.Backend, s.Target, s.Targets, failingSince, s.Restarts, lastError)
+	}
+	tw.Flush()
+}
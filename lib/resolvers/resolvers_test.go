@@ -0,0 +1,656 @@
+package resolvers
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mwitkow/go-srvlb/srv"
+	pb "github.com/mwitkow/kedge/_protogen/kedge/config/common/resolvers"
+	"github.com/mwitkow/kedge/lib/resolvers/dnstest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/dns/dnsmessage"
+	"google.golang.org/grpc/naming"
+)
+
+type fixedWatcher struct {
+	updates []*naming.Update
+}
+
+func (w *fixedWatcher) Next() ([]*naming.Update, error) {
+	return w.updates, nil
+}
+
+func (w *fixedWatcher) Close() {}
+
+type fixedResolver struct {
+	watcher *fixedWatcher
+}
+
+func (r *fixedResolver) Resolve(target string) (naming.Watcher, error) {
+	return r.watcher, nil
+}
+
+type weightedSrvResolver struct {
+	mu      sync.Mutex
+	targets []*SrvTarget
+}
+
+func (r *weightedSrvResolver) Lookup(domainName string) ([]*srv.Target, error) {
+	panic("weighted lookups must be preferred")
+}
+
+func (r *weightedSrvResolver) LookupWeighted(domainName string) ([]*SrvTarget, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.targets, nil
+}
+
+func (r *weightedSrvResolver) set(targets ...*SrvTarget) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.targets = targets
+}
+
+type plainSrvResolver []*srv.Target
+
+func (r plainSrvResolver) Lookup(domainName string) ([]*srv.Target, error) {
+	return r, nil
+}
+
+func updatesByAddr(updates []*naming.Update) map[string]*naming.Update {
+	byAddr := map[string]*naming.Update{}
+	for _, u := range updates {
+		byAddr[fmt.Sprintf("%v %v", u.Op, u.Addr)] = u
+	}
+	return byAddr
+}
+
+func TestSrvWatcherKeepsPrioritiesAndWeights(t *testing.T) {
+	parent := &weightedSrvResolver{}
+	parent.set(
+		&SrvTarget{DialAddr: "a:80", Ttl: time.Millisecond, Priority: 0, Weight: 10},
+		&SrvTarget{DialAddr: "b:80", Ttl: time.Millisecond, Priority: 1, Weight: 5},
+	)
+	w, err := (&srvResolver{parent: parent}).Resolve("_http._tcp.example.com")
+	require.NoError(t, err)
+	defer w.Close()
+	updates, err := w.Next()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]*naming.Update{
+		"0 a:80": {Op: naming.Add, Addr: "a:80", Metadata: Attributes{Priority: 0, Weight: 10}},
+		"0 b:80": {Op: naming.Add, Addr: "b:80", Metadata: Attributes{Priority: 1, Weight: 5}},
+	}, updatesByAddr(updates))
+
+	parent.set(
+		&SrvTarget{DialAddr: "a:80", Ttl: time.Millisecond, Priority: 0, Weight: 10},
+		&SrvTarget{DialAddr: "b:80", Ttl: time.Millisecond, Priority: 0, Weight: 5},
+	)
+	updates, err = w.Next()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]*naming.Update{
+		"1 b:80": {Op: naming.Delete, Addr: "b:80", Metadata: Attributes{Priority: 1, Weight: 5}},
+		"0 b:80": {Op: naming.Add, Addr: "b:80", Metadata: Attributes{Priority: 0, Weight: 5}},
+	}, updatesByAddr(updates), "targets with changed weights must be replaced, deleting them with their old metadata")
+}
+
+func TestSrvWatcherWithoutWeights(t *testing.T) {
+	w, err := (&srvResolver{parent: plainSrvResolver{{DialAddr: "a:80", Ttl: time.Second}}}).Resolve("_http._tcp.example.com")
+	require.NoError(t, err)
+	defer w.Close()
+	updates, err := w.Next()
+	require.NoError(t, err)
+	assert.Equal(t, []*naming.Update{{Op: naming.Add, Addr: "a:80", Metadata: Attributes{}}}, updates)
+}
+
+func TestSrvWatcherClosesOnce(t *testing.T) {
+	w, err := (&srvResolver{parent: plainSrvResolver{{DialAddr: "a:80", Ttl: time.Second}}}).Resolve("_http._tcp.example.com")
+	require.NoError(t, err)
+	w.Close()
+	assert.NotPanics(t, w.Close, "closing watchers again must do nothing")
+}
+
+func TestPickByPriority(t *testing.T) {
+	candidates := []Attributes{
+		{Priority: 1, Weight: 1},
+		{Priority: 0, Weight: 3},
+		{Priority: 2, Weight: 100},
+		{Priority: 0, Weight: 1},
+		{Priority: 0, Weight: 0},
+	}
+	picks := map[int]int{}
+	for n := uint64(0); n < 40; n++ {
+		picks[PickByPriority(n, candidates)]++
+	}
+	assert.Equal(t, map[int]int{1: 30, 3: 10}, picks, "only the lowest priority must be picked, by weight")
+
+	zeroWeights := []Attributes{{Priority: 1}, {Priority: 1}, {Priority: 2, Weight: 1}}
+	picks = map[int]int{}
+	for n := uint64(0); n < 10; n++ {
+		picks[PickByPriority(n, zeroWeights)]++
+	}
+	assert.Equal(t, map[int]int{0: 5, 1: 5}, picks, "priorities with only weights of 0 must be picked evenly")
+}
+
+func TestSrvZonePattern(t *testing.T) {
+	_, _, err := NewSrvFromConfig(&pb.SrvResolver{DnsName: "_http._tcp.example.com", ZonePattern: "^[^.]+"})
+	require.Error(t, err, "zone patterns without a group must be rejected")
+
+	_, namer, err := NewSrvFromConfig(&pb.SrvResolver{DnsName: "_http._tcp.example.com", ZonePattern: `^[^.]+\.([^.]+)\.`})
+	require.NoError(t, err, "zone pattern must be valid")
+	resolver := namer.(*zonePatternResolver)
+	resolver.Resolver = &fixedResolver{&fixedWatcher{updates: []*naming.Update{
+		{Op: naming.Add, Addr: "pod-1.us-east1-b.example.com.:8080", Metadata: Attributes{Priority: 1, Weight: 5}},
+		{Op: naming.Delete, Addr: "pod-2.us-east1-c.example.com:8080"},
+		{Op: naming.Add, Addr: "10.0.0.1:8080"},
+	}}}
+	w, err := resolver.Resolve("_http._tcp.example.com")
+	require.NoError(t, err)
+	updates, err := w.Next()
+	require.NoError(t, err)
+	assert.Equal(t, Attributes{Zone: "us-east1-b", Priority: 1, Weight: 5}, updates[0].Metadata, "zones must be added to the other attributes")
+	assert.Equal(t, "us-east1-c", AttributesOf(updates[1].Metadata).Zone, "deleted targets must have the same metadata as added ones")
+	assert.Equal(t, "", AttributesOf(updates[2].Metadata).Zone, "targets not matching the pattern have no zone")
+}
+
+func TestEndpointSlicesWatcher(t *testing.T) {
+	mu := sync.Mutex{}
+	ready := true
+	server := httptest.NewServer(http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		assert.Equal(t, "/apis/discovery.k8s.io/v1/namespaces/ns/endpointslices", req.URL.Path)
+		assert.Equal(t, "kubernetes.io/service-name=svc", req.URL.Query().Get("labelSelector"))
+		mu.Lock()
+		defer mu.Unlock()
+		fmt.Fprintf(resp, `{"items": [{
+			"endpoints": [
+				{"addresses": ["10.0.0.1"], "conditions": {"ready": true}, "zone": "zone-a"},
+				{"addresses": ["10.0.0.2"], "conditions": {"ready": %v}, "zone": "zone-b"},
+				{"addresses": ["10.0.0.3"], "zone": "zone-b"}
+			],
+			"ports": [{"name": "metrics", "port": 9090}, {"name": "http", "port": 8080}]
+		}]}`, ready)
+	}))
+	defer server.Close()
+	defer func(interval time.Duration) {
+		EndpointSlicesRefreshInterval = interval
+	}(EndpointSlicesRefreshInterval)
+	EndpointSlicesRefreshInterval = 10 * time.Millisecond
+
+	r := &endpointSlicesResolver{client: http.DefaultClient, apiUrl: server.URL, namespace: "ns", service: "svc", portName: "http"}
+	w, err := r.Resolve("kubernetes://svc:http")
+	require.NoError(t, err)
+	defer w.Close()
+	updates, err := w.Next()
+	require.NoError(t, err)
+	zones := map[string]string{}
+	for _, u := range updates {
+		assert.Equal(t, naming.Add, u.Op)
+		zones[u.Addr] = AttributesOf(u.Metadata).Zone
+	}
+	assert.Equal(t, map[string]string{"10.0.0.1:8080": "zone-a", "10.0.0.2:8080": "zone-b", "10.0.0.3:8080": "zone-b"}, zones)
+
+	mu.Lock()
+	ready = false
+	mu.Unlock()
+	updates, err = w.Next()
+	require.NoError(t, err)
+	require.Len(t, updates, 1, "only the endpoint that is no longer ready must be updated")
+	assert.Equal(t, &naming.Update{Op: naming.Delete, Addr: "10.0.0.2:8080", Metadata: Attributes{Zone: "zone-b"}}, updates[0])
+}
+
+func TestStaticResolverValidation(t *testing.T) {
+	_, _, err := NewStaticFromConfig(&pb.StaticResolver{})
+	assert.Error(t, err, "static resolvers without addresses must be rejected")
+	_, _, err = NewStaticFromConfig(&pb.StaticResolver{Addresses: []*pb.StaticAddress{{Address: "api.example.com"}}})
+	assert.Error(t, err, "addresses without ports must be rejected")
+	_, _, err = NewStaticFromConfig(&pb.StaticResolver{Addresses: []*pb.StaticAddress{{Address: "api.example.com:443", Weight: 70000}}})
+	assert.Error(t, err, "weights above 65535 must be rejected")
+}
+
+func TestStaticResolverReresolvesHostNames(t *testing.T) {
+	mu := sync.Mutex{}
+	hostIps := []string{"10.0.1.1"}
+	var hostErr error
+	defer func(lookup func(string) ([]string, error), interval time.Duration) {
+		StaticHostLookup = lookup
+		StaticRefreshInterval = interval
+	}(StaticHostLookup, StaticRefreshInterval)
+	StaticHostLookup = func(host string) ([]string, error) {
+		require.Equal(t, "api.example.com", host, "only host names must be looked up")
+		mu.Lock()
+		defer mu.Unlock()
+		return hostIps, hostErr
+	}
+	StaticRefreshInterval = time.Millisecond
+
+	target, namer, err := NewStaticFromConfig(&pb.StaticResolver{Addresses: []*pb.StaticAddress{
+		{Address: "api.example.com:443", Weight: 3, Zone: "zone-a"},
+		{Address: "[::1]:8080"},
+	}})
+	require.NoError(t, err)
+	assert.Equal(t, "api.example.com:443", target, "the first address must be the target")
+	w, err := namer.Resolve(target)
+	require.NoError(t, err)
+	defer w.Close()
+	updates, err := w.Next()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]*naming.Update{
+		"0 10.0.1.1:443": {Op: naming.Add, Addr: "10.0.1.1:443", Metadata: Attributes{Zone: "zone-a", Weight: 3}},
+		"0 [::1]:8080":   {Op: naming.Add, Addr: "[::1]:8080", Metadata: Attributes{}},
+	}, updatesByAddr(updates))
+
+	mu.Lock()
+	hostIps, hostErr = nil, fmt.Errorf("temporary failure")
+	mu.Unlock()
+	time.Sleep(10 * time.Millisecond)
+	mu.Lock()
+	hostIps, hostErr = []string{"10.0.1.2"}, nil
+	mu.Unlock()
+	updates, err = w.Next()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]*naming.Update{
+		"1 10.0.1.1:443": {Op: naming.Delete, Addr: "10.0.1.1:443", Metadata: Attributes{Zone: "zone-a", Weight: 3}},
+		"0 10.0.1.2:443": {Op: naming.Add, Addr: "10.0.1.2:443", Metadata: Attributes{Zone: "zone-a", Weight: 3}},
+	}, updatesByAddr(updates), "failed lookups must keep the previous addresses until the host resolves again")
+}
+
+func TestDnsResolverValidation(t *testing.T) {
+	_, _, err := NewDnsFromConfig(&pb.DnsResolver{Port: 443})
+	assert.Error(t, err, "dns resolvers without host names must be rejected")
+	_, _, err = NewDnsFromConfig(&pb.DnsResolver{HostName: "api.example.com"})
+	assert.Error(t, err, "dns resolvers without ports must be rejected")
+}
+
+func TestDnsResolverRefreshesOnTtl(t *testing.T) {
+	server := dnstest.NewServer(t)
+	defer server.Close()
+	defer func(nameservers []string, minInterval time.Duration) {
+		DnsNameservers = nameservers
+		DnsMinRefreshInterval = minInterval
+	}(DnsNameservers, DnsMinRefreshInterval)
+	DnsNameservers = []string{server.Addr()}
+	DnsMinRefreshInterval = 10 * time.Millisecond
+
+	server.Set("api.example.com.", dnstest.ARecord("api.example.com.", 3600, "10.0.0.1"), dnstest.AAAARecord("api.example.com.", 0, "2001:db8::1"))
+	target, namer, err := NewDnsFromConfig(&pb.DnsResolver{HostName: "api.example.com", Port: 443})
+	require.NoError(t, err)
+	assert.Equal(t, "api.example.com:443", target)
+	w, err := namer.Resolve(target)
+	require.NoError(t, err)
+	defer w.Close()
+	updates, err := w.Next()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]*naming.Update{
+		"0 10.0.0.1:443":      {Op: naming.Add, Addr: "10.0.0.1:443", Metadata: Attributes{}},
+		"0 [2001:db8::1]:443": {Op: naming.Add, Addr: "[2001:db8::1]:443", Metadata: Attributes{}},
+	}, updatesByAddr(updates))
+
+	server.SetTruncated(true)
+	server.Set("api.example.com.", dnstest.ARecord("api.example.com.", 0, "10.0.0.2"))
+	updates, err = w.Next()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]*naming.Update{
+		"1 10.0.0.1:443":      {Op: naming.Delete, Addr: "10.0.0.1:443", Metadata: Attributes{}},
+		"1 [2001:db8::1]:443": {Op: naming.Delete, Addr: "[2001:db8::1]:443", Metadata: Attributes{}},
+		"0 10.0.0.2:443":      {Op: naming.Add, Addr: "10.0.0.2:443", Metadata: Attributes{}},
+	}, updatesByAddr(updates), "records must be looked up again after the lowest TTL, over TCP if truncated")
+}
+
+func TestLookupHostReturnsLowestTtl(t *testing.T) {
+	server := dnstest.NewServer(t)
+	defer server.Close()
+	defer func(nameservers []string) {
+		DnsNameservers = nameservers
+	}(DnsNameservers)
+	DnsNameservers = []string{server.Addr()}
+
+	server.Set("api.example.com.", dnstest.ARecord("api.example.com.", 3600, "10.0.0.1"), dnstest.AAAARecord("api.example.com.", 300, "2001:db8::1"))
+	ips, ttl, err := LookupHost("api.example.com")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"10.0.0.1", "2001:db8::1"}, ips)
+	assert.Equal(t, 300*time.Second, ttl, "TTLs must not be bound by the refresh intervals of dns resolvers")
+}
+
+func TestLookupHostIgnoresAnswersToOtherQuestions(t *testing.T) {
+	server := dnstest.NewServer(t)
+	defer server.Close()
+	defer func(nameservers []string) {
+		DnsNameservers = nameservers
+	}(DnsNameservers)
+	DnsNameservers = []string{server.Addr()}
+
+	server.Set("api.example.com.", dnstest.ARecord("api.example.com.", 60, "10.0.0.1"))
+	server.SetSpoofing(true)
+	ips, _, err := LookupHost("api.example.com")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"10.0.0.1"}, ips, "answers must be for the question asked")
+}
+
+func TestLookupHostKeepsAnswersOfSucceededQueries(t *testing.T) {
+	server := dnstest.NewServer(t)
+	defer server.Close()
+	defer func(nameservers []string) {
+		DnsNameservers = nameservers
+	}(DnsNameservers)
+	DnsNameservers = []string{server.Addr()}
+
+	server.Set("api.example.com.", dnstest.ARecord("api.example.com.", 60, "10.0.0.1"))
+	server.SetFailing(dnsmessage.TypeAAAA)
+	ips, ttl, err := LookupHost("api.example.com")
+	require.NoError(t, err, "failed AAAA queries must not fail lookups that found A records")
+	assert.Equal(t, []string{"10.0.0.1"}, ips)
+	assert.Equal(t, 60*time.Second, ttl)
+
+	server.SetFailing(dnsmessage.TypeA)
+	_, _, err = LookupHost("api.example.com")
+	assert.Error(t, err, "lookups must fail if no query found addresses and one failed")
+}
+
+func TestLookupHostFallsBackForUnknownHostNames(t *testing.T) {
+	server := dnstest.NewServer(t)
+	defer server.Close()
+	defer func(nameservers []string, minInterval time.Duration, fallback func(string) ([]string, error)) {
+		DnsNameservers = nameservers
+		DnsMinRefreshInterval = minInterval
+		HostFallbackLookup = fallback
+	}(DnsNameservers, DnsMinRefreshInterval, HostFallbackLookup)
+	DnsNameservers = []string{server.Addr()}
+	DnsMinRefreshInterval = 3 * time.Second
+	HostFallbackLookup = func(hostName string) ([]string, error) {
+		if hostName != "db" {
+			return nil, &net.DNSError{Err: "no such host", Name: hostName}
+		}
+		return []string{"10.0.0.5"}, nil
+	}
+
+	ips, ttl, err := LookupHost("db")
+	require.NoError(t, err, "host names unknown to the nameservers must be looked up like /etc/hosts and search domains do")
+	assert.Equal(t, []string{"10.0.0.5"}, ips)
+	assert.Equal(t, 3*time.Second, ttl)
+
+	_, _, err = LookupHost("missing")
+	assert.Error(t, err)
+}
+
+func TestLookupSrvReturnsRecordsAndLowestTtl(t *testing.T) {
+	server := dnstest.NewServer(t)
+	defer server.Close()
+	defer func(nameservers []string) {
+		DnsNameservers = nameservers
+	}(DnsNameservers)
+	DnsNameservers = []string{server.Addr()}
+
+	server.Set("_http._tcp.api.example.com.",
+		&dnsmessage.SRVResource{ResourceHeader: dnsmessage.ResourceHeader{Name: "_http._tcp.api.example.com.", Type: dnsmessage.TypeSRV, Class: dnsmessage.ClassINET, TTL: 60}, Priority: 1, Weight: 5, Port: 8080, Target: "pod-1.example.com."},
+		&dnsmessage.SRVResource{ResourceHeader: dnsmessage.ResourceHeader{Name: "_http._tcp.api.example.com.", Type: dnsmessage.TypeSRV, Class: dnsmessage.ClassINET, TTL: 30}, Priority: 0, Weight: 0, Port: 8081, Target: "pod-2.example.com."})
+	srvs, ttl, err := LookupSRV("http", "tcp", "api.example.com")
+	require.NoError(t, err)
+	assert.Equal(t, []*net.SRV{
+		{Target: "pod-1.example.com.", Port: 8080, Priority: 1, Weight: 5},
+		{Target: "pod-2.example.com.", Port: 8081, Priority: 0, Weight: 0},
+	}, srvs)
+	assert.Equal(t, 30*time.Second, ttl)
+
+	_, _, err = LookupSRV("grpc", "tcp", "api.example.com")
+	assert.Error(t, err, "unknown services must fail")
+}
+
+func TestDnsResolverFailsOnFailedLookups(t *testing.T) {
+	server := dnstest.NewServer(t)
+	defer server.Close()
+	defer func(nameservers []string, minInterval time.Duration) {
+		DnsNameservers = nameservers
+		DnsMinRefreshInterval = minInterval
+	}(DnsNameservers, DnsMinRefreshInterval)
+	DnsNameservers = []string{server.Addr()}
+	DnsMinRefreshInterval = 10 * time.Millisecond
+	defer func(fallback func(string) ([]string, error)) {
+		HostFallbackLookup = fallback
+	}(HostFallbackLookup)
+	HostFallbackLookup = func(hostName string) ([]string, error) {
+		return nil, &net.DNSError{Err: "no such host", Name: hostName}
+	}
+
+	ips, _, err := LookupHost("missing.example.com")
+	assert.Error(t, err, "unknown host names must fail")
+	assert.Empty(t, ips)
+
+	server.Set("api.example.com.", dnstest.ARecord("api.example.com.", 0, "10.0.0.1"))
+	w, err := (&dnsResolver{hostName: "api.example.com.", port: "80"}).Resolve("api.example.com:80")
+	require.NoError(t, err)
+	defer w.Close()
+	_, err = w.Next()
+	require.NoError(t, err)
+
+	server.Set("api.example.com.")
+	_, err = w.Next()
+	assert.Error(t, err, "host names without addresses must fail, for Resilient watchers to keep the addresses")
+}
+
+func TestFileResolverReloadsOnChange(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kedge-file-resolver")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "endpoints.json")
+	defer func(interval time.Duration) {
+		FileRefreshInterval = interval
+	}(FileRefreshInterval)
+	FileRefreshInterval = time.Millisecond
+
+	_, _, err = NewFileFromConfig(&pb.FileResolver{Path: path})
+	assert.Error(t, err, "missing files must be rejected")
+	require.NoError(t, ioutil.WriteFile(path, []byte(`[{"address": "10.0.0.1:8080", "weight": 2, "zone": "zone-a"}]`), 0644))
+	target, namer, err := NewFileFromConfig(&pb.FileResolver{Path: path})
+	require.NoError(t, err)
+	w, err := namer.Resolve(target)
+	require.NoError(t, err)
+	defer w.Close()
+	updates, err := w.Next()
+	require.NoError(t, err)
+	assert.Equal(t, []*naming.Update{{Op: naming.Add, Addr: "10.0.0.1:8080", Metadata: Attributes{Zone: "zone-a", Weight: 2}}}, updates)
+
+	require.NoError(t, ioutil.WriteFile(path, []byte(`[{"address": "10.0.0.1:8080", "weight": 2, "zone": "zone-a"}, {"address": "10.0.0.2:8080"}]`), 0644))
+	updates, err = w.Next()
+	require.NoError(t, err)
+	assert.Equal(t, []*naming.Update{{Op: naming.Add, Addr: "10.0.0.2:8080", Metadata: Attributes{}}}, updates, "changes of the file must be picked up")
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	require.NoError(t, ioutil.WriteFile(path, []byte(`[{"address": "10.0.0.1:8080", "weight": 2, "zone": "zone-a"}, {"address": "10.0.0.3:8080"}]`), 0644))
+	require.NoError(t, os.Chtimes(path, info.ModTime(), info.ModTime()))
+	updates, err = w.Next()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]*naming.Update{
+		"1 10.0.0.2:8080": {Op: naming.Delete, Addr: "10.0.0.2:8080", Metadata: Attributes{}},
+		"0 10.0.0.3:8080": {Op: naming.Add, Addr: "10.0.0.3:8080", Metadata: Attributes{}},
+	}, updatesByAddr(updates), "changes keeping the size and modification time of the file must be picked up")
+
+	require.NoError(t, ioutil.WriteFile(path, []byte(`not json`), 0644))
+	_, err = w.Next()
+	assert.Error(t, err, "broken files must fail, for Resilient watchers to keep the addresses")
+}
+
+func TestConsulResolverUsesBlockingQueries(t *testing.T) {
+	mu := sync.Mutex{}
+	index := 10
+	instances := `[{"Node": {"Address": "10.0.0.1"}, "Service": {"Port": 8080, "Weights": {"Passing": 3}}}]`
+	changed := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		assert.Equal(t, "/v1/health/service/svc", req.URL.Path)
+		assert.Equal(t, "true", req.URL.Query().Get("passing"))
+		assert.Equal(t, "canary", req.URL.Query().Get("tag"))
+		if req.URL.Query().Get("index") == "10" {
+			select {
+			case <-changed:
+			case <-req.Cancel:
+			case <-time.After(time.Second):
+			}
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		resp.Header().Set("X-Consul-Index", strconv.Itoa(index))
+		fmt.Fprint(resp, instances)
+	}))
+	defer server.Close()
+
+	_, _, err := NewConsulFromConfig(&pb.ConsulResolver{Address: "127.0.0.1:8500", Service: "svc"})
+	assert.Error(t, err, "addresses that aren't URLs must be rejected")
+	_, namer, err := NewConsulFromConfig(&pb.ConsulResolver{Address: "https://consul.example.com/consul/", Service: "web api/v2"})
+	require.NoError(t, err)
+	assert.Equal(t, "https://consul.example.com/consul/v1/health/service/web%20api%2Fv2?passing=true", namer.(*consulResolver).healthUrl.String(),
+		"services must be escaped once, under the path of the address")
+	target, namer, err := NewConsulFromConfig(&pb.ConsulResolver{Address: server.URL, Service: "svc", Tag: "canary"})
+	require.NoError(t, err)
+	w, err := namer.Resolve(target)
+	require.NoError(t, err)
+	defer w.Close()
+	updates, err := w.Next()
+	require.NoError(t, err)
+	assert.Equal(t, []*naming.Update{{Op: naming.Add, Addr: "10.0.0.1:8080", Metadata: Attributes{Weight: 3}}}, updates)
+
+	mu.Lock()
+	index = 11
+	instances = `[{"Node": {"Address": "10.0.0.1"}, "Service": {"Address": "10.0.1.1", "Port": 8080}}]`
+	mu.Unlock()
+	close(changed)
+	updates, err = w.Next()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]*naming.Update{
+		"1 10.0.0.1:8080": {Op: naming.Delete, Addr: "10.0.0.1:8080", Metadata: Attributes{Weight: 3}},
+		"0 10.0.1.1:8080": {Op: naming.Add, Addr: "10.0.1.1:8080", Metadata: Attributes{}},
+	}, updatesByAddr(updates), "blocking queries must return the changed instances, preferring addresses of services")
+}
+
+type scriptedResult struct {
+	updates []*naming.Update
+	err     error
+}
+
+// scriptedResolver returns watchers that return the results sent to it, one for each call of Next.
+type scriptedResolver struct {
+	results  chan scriptedResult
+	resolves int32
+}
+
+func (r *scriptedResolver) Resolve(target string) (naming.Watcher, error) {
+	atomic.AddInt32(&r.resolves, 1)
+	return &scriptedWatcher{results: r.results, closed: make(chan struct{})}, nil
+}
+
+type scriptedWatcher struct {
+	results chan scriptedResult
+	closed  chan struct{}
+}
+
+func (w *scriptedWatcher) Next() ([]*naming.Update, error) {
+	select {
+	case r := <-w.results:
+		return r.updates, r.err
+	case <-w.closed:
+		return nil, errWatcherClosed
+	}
+}
+
+func (w *scriptedWatcher) Close() {
+	close(w.closed)
+}
+
+func stateOf(backend string) (WatcherState, bool) {
+	for _, s := range WatcherStates() {
+		if s.Backend == backend {
+			return s, true
+		}
+	}
+	return WatcherState{}, false
+}
+
+func TestResilientWatcherRestartsFailedWatchers(t *testing.T) {
+	defer func(backoff time.Duration) {
+		WatcherRestartBackoff = backoff
+	}(WatcherRestartBackoff)
+	WatcherRestartBackoff = time.Millisecond
+
+	parent := &scriptedResolver{results: make(chan scriptedResult)}
+	w, err := Resilient("http", "restarting", parent, time.Hour).Resolve("target")
+	require.NoError(t, err)
+	defer w.Close()
+	go func() {
+		parent.results <- scriptedResult{updates: []*naming.Update{{Op: naming.Add, Addr: "a:80"}, {Op: naming.Add, Addr: "b:80"}}}
+		parent.results <- scriptedResult{err: fmt.Errorf("dns is down")}
+		parent.results <- scriptedResult{updates: []*naming.Update{{Op: naming.Add, Addr: "b:80"}, {Op: naming.Add, Addr: "c:80"}}}
+	}()
+	updates, err := w.Next()
+	require.NoError(t, err)
+	assert.Len(t, updates, 2)
+
+	updates, err = w.Next()
+	require.NoError(t, err, "errors of the watcher must not be passed on")
+	assert.Equal(t, map[string]*naming.Update{
+		"1 a:80": {Op: naming.Delete, Addr: "a:80"},
+		"0 c:80": {Op: naming.Add, Addr: "c:80"},
+	}, updatesByAddr(updates), "the targets of the restarted watcher must replace the ones kept")
+	assert.EqualValues(t, 2, atomic.LoadInt32(&parent.resolves), "the failed watcher must be restarted")
+
+	state, ok := stateOf("restarting")
+	require.True(t, ok, "open watchers must have a state")
+	assert.Equal(t, 2, state.Targets)
+	assert.Equal(t, 1, state.Restarts)
+	assert.Equal(t, "dns is down", state.LastError)
+	assert.True(t, state.FailingSince.IsZero(), "watchers must not be failing after a successful restart")
+}
+
+func TestResilientWatcherDeletesTargetsAfterGracePeriod(t *testing.T) {
+	defer func(backoff time.Duration) {
+		WatcherRestartBackoff = backoff
+	}(WatcherRestartBackoff)
+	WatcherRestartBackoff = time.Millisecond
+
+	parent := &scriptedResolver{results: make(chan scriptedResult)}
+	w, err := Resilient("grpc", "expiring", parent, 50*time.Millisecond).Resolve("target")
+	require.NoError(t, err)
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		parent.results <- scriptedResult{updates: []*naming.Update{{Op: naming.Add, Addr: "a:80", Metadata: Attributes{Weight: 1}}}}
+		for {
+			select {
+			case parent.results <- scriptedResult{err: fmt.Errorf("dns is down")}:
+			case <-done:
+				return
+			}
+		}
+	}()
+	_, err = w.Next()
+	require.NoError(t, err)
+
+	start := time.Now()
+	updates, err := w.Next()
+	require.NoError(t, err)
+	assert.True(t, time.Now().Sub(start) >= 50*time.Millisecond, "targets must be kept for the grace period")
+	assert.Equal(t, []*naming.Update{{Op: naming.Delete, Addr: "a:80", Metadata: Attributes{Weight: 1}}}, updates, "targets must be deleted after the grace period")
+
+	debug := httptest.NewRecorder()
+	DebugHandler(debug, &http.Request{})
+	line := ""
+	for _, l := range strings.Split(debug.Body.String(), "\n") {
+		if strings.HasPrefix(l, "grpc") && strings.Contains(l, "expiring") {
+			line = l
+		}
+	}
+	assert.Contains(t, line, "dns is down", "the debug page must list the errors of failing watchers")
+
+	w.Close()
+	_, ok := stateOf("expiring")
+	assert.False(t, ok, "closed watchers must not have a state")
+}
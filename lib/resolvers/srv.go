@@ -1,19 +1,161 @@
 package resolvers
 
 import (
+	"fmt"
+	"net"
+	"regexp"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/mwitkow/go-srvlb/srv"
 	pb "github.com/mwitkow/kedge/_protogen/kedge/config/common/resolvers"
 	"google.golang.org/grpc/naming"
-	"github.com/mwitkow/go-srvlb/grpc"
-	"github.com/mwitkow/go-srvlb/srv"
-	"time"
 )
 
 var (
-	ParentSrvResolver = srv.NewGoResolver(5 * time.Second)
+	ParentSrvResolver srv.Resolver = NewGoSrvResolver(5 * time.Second)
+
+	// srvMaxRefreshInterval bounds how long SRV answers are used before they are looked up again.
+	srvMaxRefreshInterval = 5 * time.Second
 )
 
+// SrvTarget is a srv.Target that keeps the priority and the weight of its SRV record.
+type SrvTarget struct {
+	DialAddr string
+	Ttl      time.Duration
+	Priority uint16
+	Weight   uint16
+}
+
+// WeightedSrvResolver is implemented by srv.Resolvers that also return the priorities and weights of SRV records.
+// Targets of srv.Resolvers that don't implement it all have the same priority and weight.
+type WeightedSrvResolver interface {
+	LookupWeighted(domainName string) ([]*SrvTarget, error)
+}
+
+type goSrvResolver struct {
+	ttl time.Duration
+}
+
+// NewGoSrvResolver returns a resolver that looks up SRV records with the Go resolver, using the ttl for all of them.
+func NewGoSrvResolver(ttl time.Duration) *goSrvResolver {
+	return &goSrvResolver{ttl: ttl}
+}
+
+func (r *goSrvResolver) Lookup(domainName string) ([]*srv.Target, error) {
+	weighted, err := r.LookupWeighted(domainName)
+	if err != nil {
+		return nil, err
+	}
+	var targets []*srv.Target
+	for _, t := range weighted {
+		targets = append(targets, &srv.Target{DialAddr: t.DialAddr, Ttl: t.Ttl})
+	}
+	return targets, nil
+}
+
+func (r *goSrvResolver) LookupWeighted(domainName string) ([]*SrvTarget, error) {
+	_, records, err := net.LookupSRV("", "", domainName)
+	if err != nil {
+		return nil, err
+	}
+	var targets []*SrvTarget
+	for _, rec := range records {
+		targets = append(targets, &SrvTarget{
+			DialAddr: net.JoinHostPort(rec.Target, strconv.Itoa(int(rec.Port))),
+			Ttl:      r.ttl,
+			Priority: rec.Priority,
+			Weight:   rec.Weight,
+		})
+	}
+	return targets, nil
+}
+
 func NewSrvFromConfig(conf *pb.SrvResolver) (target string, namer naming.Resolver, err error) {
-	return conf.GetDnsName(), grpcsrvlb.New(ParentSrvResolver), nil
+	namer = &srvResolver{parent: ParentSrvResolver}
+	if conf.ZonePattern != "" {
+		pattern, err := regexp.Compile(conf.ZonePattern)
+		if err != nil {
+			return "", nil, fmt.Errorf("bad zone_pattern: %v", err)
+		}
+		if pattern.NumSubexp() < 1 {
+			return "", nil, fmt.Errorf("zone_pattern '%v' needs a group matching the zone", conf.ZonePattern)
+		}
+		namer = &zonePatternResolver{Resolver: namer, pattern: pattern}
+	}
+	return conf.GetDnsName(), namer, nil
+}
+
+// srvResolver watches SRV records, setting their priorities and weights as Attributes of the updates.
+type srvResolver struct {
+	parent srv.Resolver
+}
+
+func (r *srvResolver) Resolve(target string) (naming.Watcher, error) {
+	return &srvWatcher{
+		parent:   r.parent,
+		name:     target,
+		existing: make(map[string]Attributes),
+		closed:   make(chan struct{}),
+	}, nil
+}
+
+type srvWatcher struct {
+	parent   srv.Resolver
+	name     string
+	existing map[string]Attributes
+	next     time.Time
+	closed   chan struct{}
+	once     sync.Once
 }
 
+// Next blocks until the SRV records change, looking them up again once their TTL expires.
+func (w *srvWatcher) Next() ([]*naming.Update, error) {
+	for {
+		select {
+		case <-w.closed:
+			return nil, errWatcherClosed
+		case <-time.After(w.next.Sub(time.Now())):
+		}
+		targets, err := w.lookup()
+		if err != nil {
+			return nil, err
+		}
+		refresh := srvMaxRefreshInterval
+		fresh := make(map[string]Attributes)
+		for _, t := range targets {
+			if t.Ttl < refresh {
+				refresh = t.Ttl
+			}
+			fresh[t.DialAddr] = Attributes{Priority: t.Priority, Weight: t.Weight}
+		}
+		w.next = time.Now().Add(refresh)
+		updates := diffTargets(w.existing, fresh)
+		w.existing = fresh
+		if len(updates) > 0 {
+			return updates, nil
+		}
+	}
+}
 
+func (w *srvWatcher) lookup() ([]*SrvTarget, error) {
+	if weighted, ok := w.parent.(WeightedSrvResolver); ok {
+		return weighted.LookupWeighted(w.name)
+	}
+	targets, err := w.parent.Lookup(w.name)
+	if err != nil {
+		return nil, err
+	}
+	var ret []*SrvTarget
+	for _, t := range targets {
+		ret = append(ret, &SrvTarget{DialAddr: t.DialAddr, Ttl: t.Ttl})
+	}
+	return ret, nil
+}
+
+func (w *srvWatcher) Close() {
+	w.once.Do(func() {
+		close(w.closed)
+	})
+}
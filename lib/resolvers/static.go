@@ -0,0 +1,133 @@
+package resolvers
+
+import (
+	"fmt"
+	"math"
+	"net"
+	"time"
+
+	pb "github.com/mwitkow/kedge/_protogen/kedge/config/common/resolvers"
+	"google.golang.org/grpc/naming"
+)
+
+var (
+	// StaticRefreshInterval is how often the host names of static addresses are resolved again.
+	StaticRefreshInterval = 30 * time.Second
+
+	// StaticHostLookup resolves the host names of static addresses to IP addresses, through A and AAAA records.
+	StaticHostLookup = net.LookupHost
+)
+
+type staticAddress struct {
+	host  string
+	port  string
+	attrs Attributes
+}
+
+// NewStaticFromConfig returns a resolver of the addresses of the config. The target is the first address, which gRPC
+// uses as the authority of calls.
+func NewStaticFromConfig(conf *pb.StaticResolver) (target string, namer naming.Resolver, err error) {
+	if len(conf.Addresses) == 0 {
+		return "", nil, fmt.Errorf("static resolver needs at least one address")
+	}
+	addresses, err := parseStaticAddresses(conf.Addresses)
+	if err != nil {
+		return "", nil, err
+	}
+	return conf.Addresses[0].Address, &staticResolver{addresses: addresses}, nil
+}
+
+func parseStaticAddresses(confs []*pb.StaticAddress) ([]staticAddress, error) {
+	var addresses []staticAddress
+	for _, a := range confs {
+		host, port, err := net.SplitHostPort(a.Address)
+		if err != nil {
+			return nil, fmt.Errorf("bad static address '%v': %v", a.Address, err)
+		}
+		if a.Weight > math.MaxUint16 {
+			return nil, fmt.Errorf("weight of static address '%v' is above %d", a.Address, math.MaxUint16)
+		}
+		addresses = append(addresses, staticAddress{host: host, port: port, attrs: Attributes{Zone: a.Zone, Weight: uint16(a.Weight)}})
+	}
+	return addresses, nil
+}
+
+type staticResolver struct {
+	addresses []staticAddress
+}
+
+func (r *staticResolver) Resolve(target string) (naming.Watcher, error) {
+	return &staticWatcher{
+		addresses: r.addresses,
+		hosts:     newHostCache(),
+		existing:  make(map[string]Attributes),
+		closed:    make(chan struct{}),
+	}, nil
+}
+
+type staticWatcher struct {
+	addresses []staticAddress
+	hosts     *hostCache
+	existing  map[string]Attributes
+	listed    bool
+	closed    chan struct{}
+}
+
+// Next returns the changes of the addresses. The first call returns all of them, even if no host name resolved.
+//
+// Host names that fail to resolve keep the IP addresses they were resolved to before.
+func (w *staticWatcher) Next() ([]*naming.Update, error) {
+	for {
+		if w.listed {
+			select {
+			case <-w.closed:
+				return nil, errWatcherClosed
+			case <-time.After(StaticRefreshInterval):
+			}
+		}
+		fresh := w.hosts.targets(w.addresses)
+		updates := diffTargets(w.existing, fresh)
+		first := !w.listed
+		w.existing = fresh
+		w.listed = true
+		if len(updates) > 0 || first {
+			return updates, nil
+		}
+	}
+}
+
+func (w *staticWatcher) Close() {
+	close(w.closed)
+}
+
+// hostCache resolves the host names of static addresses, keeping the IP addresses of host names that fail to resolve.
+type hostCache struct {
+	// resolved maps host names to the IP addresses they were last resolved to.
+	resolved map[string][]string
+}
+
+func newHostCache() *hostCache {
+	return &hostCache{resolved: make(map[string][]string)}
+}
+
+// targets resolves the addresses, mapping the resulting targets to their Attributes.
+func (c *hostCache) targets(addresses []staticAddress) map[string]Attributes {
+	targets := make(map[string]Attributes)
+	for _, a := range addresses {
+		for _, ip := range c.resolve(a.host) {
+			targets[net.JoinHostPort(ip, a.port)] = a.attrs
+		}
+	}
+	return targets
+}
+
+func (c *hostCache) resolve(host string) []string {
+	if net.ParseIP(host) != nil {
+		return []string{host}
+	}
+	ips, err := StaticHostLookup(host)
+	if err == nil && len(ips) > 0 {
+		c.resolved[host] = ips
+	}
+	return c.resolved[host]
+}
@@ -0,0 +1,54 @@
+// Package split chooses between the weighted backends of routes, the same way for HTTP requests and gRPC calls.
+package split
+
+import (
+	"crypto/x509"
+	"hash/fnv"
+	"math/rand"
+	"net"
+)
+
+// Pick returns the index of one of n backends, chosen by their weights, or equally if all weights are 0.
+//
+// Calls with the same non-empty sticky key get the same backend as long as the backends don't change, while others are
+// spread randomly.
+func Pick(n int, weight func(i int) uint32, stickyKey string) int {
+	total := uint64(0)
+	for i := 0; i < n; i++ {
+		total += uint64(weight(i))
+	}
+	var point uint64
+	if stickyKey != "" {
+		h := fnv.New64a()
+		h.Write([]byte(stickyKey))
+		point = h.Sum64()
+	} else {
+		point = uint64(rand.Int63())
+	}
+	if total == 0 {
+		return int(point % uint64(n))
+	}
+	point = point % total
+	for i := 0; i < n; i++ {
+		if point < uint64(weight(i)) {
+			return i
+		}
+		point -= uint64(weight(i))
+	}
+	return n - 1
+}
+
+// ClientIdentity returns the subject of the TLS client certificate, falling back to the IP of the remote address.
+func ClientIdentity(peerCertificates []*x509.Certificate, remoteAddr string) (string, bool) {
+	if len(peerCertificates) > 0 {
+		return string(peerCertificates[0].RawSubject), true
+	}
+	if remoteAddr == "" {
+		return "", false
+	}
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		return remoteAddr, true
+	}
+	return host, true
+}
@@ -0,0 +1,57 @@
+package split
+
+import (
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func weightsOf(weights ...uint32) func(i int) uint32 {
+	return func(i int) uint32 {
+		return weights[i]
+	}
+}
+
+func TestPickFollowsWeights(t *testing.T) {
+	counts := map[int]int{}
+	for i := 0; i < 10000; i++ {
+		counts[Pick(3, weightsOf(90, 0, 10), "")]++
+	}
+	assert.Equal(t, 0, counts[1], "backends of weight 0 must not be picked while others have weights")
+	assert.InDelta(t, 9000, counts[0], 300)
+	assert.InDelta(t, 1000, counts[2], 300)
+}
+
+func TestPickSplitsZeroWeightsEqually(t *testing.T) {
+	counts := map[int]int{}
+	for i := 0; i < 10000; i++ {
+		counts[Pick(2, weightsOf(0, 0), "")]++
+	}
+	assert.InDelta(t, 5000, counts[0], 300)
+	assert.InDelta(t, 5000, counts[1], 300)
+}
+
+func TestPickIsStickyForKeys(t *testing.T) {
+	picked := map[int]bool{}
+	for i := 0; i < 100; i++ {
+		assert.Equal(t, Pick(2, weightsOf(50, 50), "user-1"), Pick(2, weightsOf(50, 50), "user-1"), "keys must always get the same backend")
+		picked[Pick(2, weightsOf(50, 50), string(rune('a'+i)))] = true
+	}
+	assert.Len(t, picked, 2, "different keys must be spread over the backends")
+}
+
+func TestClientIdentity(t *testing.T) {
+	cert := &x509.Certificate{RawSubject: []byte("subject"), Subject: pkix.Name{CommonName: "someone"}}
+	id, ok := ClientIdentity([]*x509.Certificate{cert}, "10.0.0.1:31337")
+	assert.True(t, ok)
+	assert.Equal(t, "subject", id, "the client certificate must take precedence")
+
+	id, ok = ClientIdentity(nil, "10.0.0.1:31337")
+	assert.True(t, ok)
+	assert.Equal(t, "10.0.0.1", id, "the port of the remote address must be left out")
+
+	_, ok = ClientIdentity(nil, "")
+	assert.False(t, ok)
+}
@@ -0,0 +1,97 @@
+// Package tlsconfig builds the TLS configs used by clients of kedges, and by kedges dialing their destinations.
+package tlsconfig
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+
+	pb "github.com/mwitkow/kedge/_protogen/kedge/config/common/tls"
+)
+
+const (
+	// DefaultCertFile is where the client certificates of users are kept.
+	DefaultCertFile = "~/.config/kedge/client.crt"
+	// DefaultKeyFile is where the keys of client certificates of users are kept.
+	DefaultKeyFile = "~/.config/kedge/client.key"
+)
+
+// ForClient returns a TLS config presenting the client certificate to kedges.
+//
+// Kedges are verified using the rootCAFiles, or the system roots if none are given. Paths starting with "~/" are
+// relative to the home directory of the user.
+func ForClient(certFile string, keyFile string, rootCAFiles []string) (*tls.Config, error) {
+	cert, err := tls.LoadX509KeyPair(ExpandHome(certFile), ExpandHome(keyFile))
+	if err != nil {
+		return nil, fmt.Errorf("failed reading client certificate: %v", err)
+	}
+	tlsConfig := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}
+	if tlsConfig.RootCAs, err = readRootCAs(rootCAFiles); err != nil {
+		return nil, err
+	}
+	return tlsConfig, nil
+}
+
+// ForNamedClients builds the TLS configs of the kedge for dialing destinations, keyed by their names.
+func ForNamedClients(configs []*pb.TlsClientConfig) (map[string]*tls.Config, error) {
+	named := make(map[string]*tls.Config)
+	for _, cnf := range configs {
+		if cnf.Name == "" {
+			return nil, fmt.Errorf("tls client config without a name")
+		}
+		if _, ok := named[cnf.Name]; ok {
+			return nil, fmt.Errorf("tls client config '%v' is defined twice", cnf.Name)
+		}
+		tlsConfig := &tls.Config{
+			MinVersion:         tls.VersionTLS12,
+			InsecureSkipVerify: cnf.InsecureSkipVerify,
+		}
+		if cnf.CertFile != "" || cnf.KeyFile != "" {
+			cert, err := tls.LoadX509KeyPair(ExpandHome(cnf.CertFile), ExpandHome(cnf.KeyFile))
+			if err != nil {
+				return nil, fmt.Errorf("tls client config '%v': failed reading client certificate: %v", cnf.Name, err)
+			}
+			tlsConfig.Certificates = []tls.Certificate{cert}
+		}
+		rootCAs, err := readRootCAs(cnf.RootCaFiles)
+		if err != nil {
+			return nil, fmt.Errorf("tls client config '%v': %v", cnf.Name, err)
+		}
+		tlsConfig.RootCAs = rootCAs
+		named[cnf.Name] = tlsConfig
+	}
+	return named, nil
+}
+
+// readRootCAs returns the pool of CAs in the files, or nil for the system roots if there are none.
+func readRootCAs(rootCAFiles []string) (*x509.CertPool, error) {
+	if len(rootCAFiles) == 0 {
+		return nil, nil
+	}
+	pool := x509.NewCertPool()
+	for _, path := range rootCAFiles {
+		data, err := ioutil.ReadFile(ExpandHome(path))
+		if err != nil {
+			return nil, fmt.Errorf("failed reading root CA file %v: %v", path, err)
+		}
+		if ok := pool.AppendCertsFromPEM(data); !ok {
+			return nil, fmt.Errorf("failed processing root CA file %v", path)
+		}
+	}
+	return pool, nil
+}
+
+// ExpandHome replaces the leading "~/" of a path with the home directory of the user.
+func ExpandHome(filePath string) string {
+	if !strings.HasPrefix(filePath, "~/") {
+		return filePath
+	}
+	return path.Join(os.Getenv("HOME"), filePath[2:])
+}
@@ -0,0 +1,72 @@
+// Package writedeadline lets handlers of http.Servers exempt their responses from the server's WriteTimeout.
+package writedeadline
+
+import (
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+var (
+	mu    sync.Mutex
+	conns = make(map[string]net.Conn)
+)
+
+// Listen wraps the listener of an http.Server, below any TLS listener, so that Clear finds the connections it accepts.
+func Listen(l net.Listener) net.Listener {
+	return &listener{Listener: l}
+}
+
+// Clear removes the write deadline that the server set on the connection of the request, which lasts until the server
+// reads the next request on it. It does nothing for requests of connections that weren't accepted by a Listen listener.
+func Clear(req *http.Request) {
+	local, ok := req.Context().Value(http.LocalAddrContextKey).(net.Addr)
+	if !ok {
+		return
+	}
+	mu.Lock()
+	conn, ok := conns[connKey(local.String(), req.RemoteAddr)]
+	mu.Unlock()
+	if ok {
+		conn.SetWriteDeadline(time.Time{})
+	}
+}
+
+// connKey identifies connections, as requests carry their addresses.
+func connKey(local string, remote string) string {
+	return local + " " + remote
+}
+
+type listener struct {
+	net.Listener
+}
+
+func (l *listener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	c := &trackedConn{Conn: conn, key: connKey(conn.LocalAddr().String(), conn.RemoteAddr().String())}
+	mu.Lock()
+	conns[c.key] = c
+	mu.Unlock()
+	return c, nil
+}
+
+type trackedConn struct {
+	net.Conn
+	key  string
+	once sync.Once
+}
+
+func (c *trackedConn) Close() error {
+	c.once.Do(func() {
+		mu.Lock()
+		if conns[c.key] == c {
+			delete(conns, c.key)
+		}
+		mu.Unlock()
+	})
+	return c.Conn.Close()
+}
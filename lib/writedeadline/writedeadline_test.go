@@ -0,0 +1,46 @@
+package writedeadline
+
+import (
+	"io/ioutil"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func serveSlowly(t *testing.T, clear bool) (string, error) {
+	listener, err := net.Listen("tcp", "localhost:0")
+	require.NoError(t, err, "must be able to allocate a port")
+	server := &http.Server{
+		WriteTimeout: 50 * time.Millisecond,
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			if clear {
+				Clear(req)
+			}
+			time.Sleep(100 * time.Millisecond)
+			w.Write([]byte("slow"))
+		}),
+	}
+	go server.Serve(Listen(listener))
+	defer listener.Close()
+
+	resp, err := http.Get("http://" + listener.Addr().String())
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	return string(body), err
+}
+
+func TestClearExemptsResponsesFromWriteTimeout(t *testing.T) {
+	_, err := serveSlowly(t, false)
+	assert.Error(t, err, "slow responses must be cut by the write timeout")
+
+	body, err := serveSlowly(t, true)
+	require.NoError(t, err, "slow responses must not be cut once cleared")
+	assert.Equal(t, "slow", body)
+}
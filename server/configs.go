@@ -15,6 +15,7 @@ import (
 	grpc_router "github.com/mwitkow/kedge/grpc/director/router"
 	http_bp "github.com/mwitkow/kedge/http/backendpool"
 	http_router "github.com/mwitkow/kedge/http/director/router"
+	"github.com/mwitkow/kedge/lib/tlsconfig"
 )
 
 var (
@@ -28,15 +29,36 @@ var (
 		"Path to the jsonPB file configuring the backend pool.")
 )
 
-func buildRouterOrFail() (grpc_router.Router, http_router.Router, http_router.AdhocAddresser) {
+func readDirectorConfigOrFail() *pb_config.DirectorConfig {
 	cnf := &pb_config.DirectorConfig{}
 	if err := readAsJson(*flagConfigDirectorPath, cnf); err != nil {
 		log.Fatalf("failed reading director director config: %v", err)
 	}
-	grpcRouter := grpc_router.NewStatic(cnf.Grpc.Routes)
-	httpRouter := http_router.NewStatic(cnf.Http.Routes)
-	httpAddresser := http_router.NewAddresser(cnf.Http.AdhocRules)
-	return grpcRouter, httpRouter, httpAddresser
+	return cnf
+}
+
+func buildRouterOrFail(cnf *pb_config.DirectorConfig) (grpc_router.Router, grpc_router.AdhocAddresser, http_router.Router, http_router.AdhocAddresser) {
+	grpcRouter, err := grpc_router.NewStatic(cnf.Grpc.Routes)
+	if err != nil {
+		log.Fatalf("failed creating grpc router: %v", err)
+	}
+	grpcAddresser, err := grpc_router.NewAddresser(cnf.Grpc.AdhocRules)
+	if err != nil {
+		log.Fatalf("failed creating grpc adhoc addresser: %v", err)
+	}
+	httpRouter, err := http_router.NewStatic(cnf.Http.Routes)
+	if err != nil {
+		log.Fatalf("failed creating http router: %v", err)
+	}
+	tlsConfigs, err := tlsconfig.ForNamedClients(cnf.Http.TlsClientConfigs)
+	if err != nil {
+		log.Fatalf("failed reading tls client configs: %v", err)
+	}
+	httpAddresser, err := http_router.NewAddresser(cnf.Http.AdhocRules, tlsConfigs)
+	if err != nil {
+		log.Fatalf("failed creating http adhoc addresser: %v", err)
+	}
+	return grpcRouter, grpcAddresser, httpRouter, httpAddresser
 }
 
 func buildBackendPoolOrFail() (grpc_bp.Pool, http_bp.Pool) {
@@ -44,6 +66,8 @@ func buildBackendPoolOrFail() (grpc_bp.Pool, http_bp.Pool) {
 	if err := readAsJson(*flagConfigBackendPoolPath, cnf); err != nil {
 		log.Fatalf("failed reading backend pool config: %v", err)
 	}
+	grpc_bp.LocalZone = *flagZone
+	http_bp.LocalZone = *flagZone
 	grpcBePool, err := grpc_bp.NewStatic(cnf.GetGrpc().GetBackends())
 	if err != nil {
 		log.Fatalf("failed creating grpc backend pool: %v", err)
@@ -20,7 +20,10 @@ import (
 	"github.com/mwitkow/go-grpc-middleware/logging/logrus"
 	"github.com/mwitkow/grpc-proxy/proxy"
 	grpc_director "github.com/mwitkow/kedge/grpc/director"
+	"github.com/mwitkow/kedge/grpc/grpcweb"
 	http_director "github.com/mwitkow/kedge/http/director"
+	"github.com/mwitkow/kedge/lib/resolvers"
+	"github.com/mwitkow/kedge/lib/writedeadline"
 	"github.com/mwitkow/kedge/server/sharedflags"
 	"github.com/prometheus/client_golang/prometheus"
 	_ "golang.org/x/net/trace"
@@ -35,9 +38,12 @@ var (
 	flagHttpPort         = sharedflags.Set.Int("server_http_port", 8080, "TCP port to listen on for HTTP1.1/REST calls (insecure, debug). If 0, no insecure HTTP will be open.")
 	flagHttpTlsPort      = sharedflags.Set.Int("server_http_tls_port", 8443, "TCP port to listen on for HTTPS. If 0, no TLS will be open.")
 
-	flagHttpMaxWriteTimeout = sharedflags.Set.Duration("server_http_max_write_timeout", 10*time.Second, "HTTP server config, max write duration.")
+	flagHttpMaxWriteTimeout = sharedflags.Set.Duration("server_http_max_write_timeout", 10*time.Second, "HTTP server config, max write duration. Responses without a Content-Length, gRPC calls and upgraded connections are exempt, as they are often streamed. If 0, there's no limit.")
 	flagHttpMaxReadTimeout  = sharedflags.Set.Duration("server_http_max_read_timeout", 10*time.Second, "HTTP server config, max read duration.")
+	flagHttpUpgradeIdleTimeout = sharedflags.Set.Duration("server_http_upgrade_idle_timeout", 5*time.Minute, "HTTP server config, duration after which upgraded connections (e.g. WebSockets) without traffic are closed.")
+	flagGrpcWebAllowedOrigins  = sharedflags.Set.StringSlice("server_grpc_web_allowed_origins", []string{}, "Origins from which browsers can make cross-origin gRPC-Web calls. Use '*' for all origins.")
 	flagGrpcWithTracing = sharedflags.Set.Bool("server_tracing_grpc_enabled", true, "Whether enable gRPC tracing (could be expensive).")
+	flagZone            = sharedflags.Set.String("server_zone", "", "Availability zone kedge runs in. Backends with locality prefer targets in it.")
 )
 
 func main() {
@@ -50,9 +56,12 @@ func main() {
 	grpc_logrus.ReplaceGrpcLogger(logEntry)
 
 	grpcBe, httpBe := buildBackendPoolOrFail()
-	grpcRouter, httpRouter, httpAddresser := buildRouterOrFail()
-	grpcProxy := grpc_director.New(grpcBe, grpcRouter)
+	directorConfig := readDirectorConfigOrFail()
+	grpcRouter, grpcAddresser, httpRouter, httpAddresser := buildRouterOrFail(directorConfig)
+	pacServer := pacHandler(directorConfig)
+	grpcProxy := grpc_director.New(grpcBe, grpcRouter, grpcAddresser)
 	httpProxy := http_director.New(httpBe, httpRouter, httpAddresser)
+	http_director.UpgradeIdleTimeout = *flagHttpUpgradeIdleTimeout
 
 	grpcTlsCreds := newOptionalTlsCreds() // allows the server to listen both over tLS and nonTLS at the same time.
 	grpcServer := grpc.NewServer(
@@ -65,10 +74,12 @@ func main() {
 		grpc_middleware.WithStreamServerChain(
 			grpc_logrus.StreamServerInterceptor(logEntry),
 			grpc_prometheus.StreamServerInterceptor,
+			grpc_director.MirroringStreamServerInterceptor(),
 		),
 		grpc.Creds(grpcTlsCreds),
 	)
 	//grpc_prometheus.Register(grpcServer)
+	grpcWebServer := grpcweb.Wrap(grpcServer, *flagGrpcWebAllowedOrigins)
 
 	tlsConfig := buildServerTlsOrFail()
 
@@ -79,11 +90,24 @@ func main() {
 		ReadTimeout:  *flagHttpMaxReadTimeout,
 		ErrorLog:     nil, // TODO(mwitkow): Add this to log to logrus.
 		Handler: http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			if grpcWebServer.IsGrpcWebRequest(req) || grpcWebServer.IsCorsPreflightRequest(req) {
+				writedeadline.Clear(req)
+				grpcWebServer.ServeHTTP(w, req)
+				return
+			}
 			if strings.HasPrefix(req.Header.Get("content-type"), "application/grpc") {
+				// gRPC calls are limited by their deadlines instead.
+				writedeadline.Clear(req)
 				grpcServer.ServeHTTP(w, req)
+				return
 			}
 			if strings.HasPrefix(req.URL.Path, "/debug") {
 				http.DefaultServeMux.ServeHTTP(w, req)
+				return
+			}
+			if req.URL.Path == "/proxy.pac" && req.URL.Host == "" {
+				pacServer.ServeHTTP(w, req)
+				return
 			}
 			httpProxy.ServeHTTP(w, req)
 		}),
@@ -103,10 +127,11 @@ func main() {
 		grpcPlainListener = buildListenerOrFail("grpc_plain", *flagGrpcInsecurePort)
 	}
 	if *flagHttpPort != 0 {
-		httpPlainListener = buildListenerOrFail("http_plain", *flagHttpPort)
+		httpPlainListener = writedeadline.Listen(buildListenerOrFail("http_plain", *flagHttpPort))
 	}
 	if *flagHttpTlsPort != 0 {
-		httpTlsListener = buildListenerOrFail("http_tls", *flagHttpTlsPort)
+		// Handlers clear the write deadlines of streamed responses, below TLS.
+		httpTlsListener = writedeadline.Listen(buildListenerOrFail("http_tls", *flagHttpTlsPort))
 		http2TlsConfig, err := connhelpers.TlsConfigWithHttp2Enabled(tlsConfig)
 		if err != nil {
 			log.Fatalf("failed setting up HTTP2 TLS config: %v", err)
@@ -155,6 +180,7 @@ func registerDebugHandlers() {
 	// TODO(mwitkow): Add middleware for making these only visible to private IPs.
 	http.Handle("/debug/metrics", prometheus.UninstrumentedHandler())
 	http.Handle("/debug/flagz", http.HandlerFunc(flagz.NewStatusEndpoint(sharedflags.Set).ListFlags))
+	http.Handle("/debug/resolvers", http.HandlerFunc(resolvers.DebugHandler))
 	//http.Handle("/debug/pprof/", http.HandlerFunc(pprof.Index))
 	//http.Handle("/debug/pprof/cmdline", http.HandlerFunc(pprof.Cmdline))
 	//http.Handle("/debug/pprof/profile", http.HandlerFunc(pprof.Profile))
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+
+	pb_config "github.com/mwitkow/kedge/_protogen/kedge/config"
+	pb_route "github.com/mwitkow/kedge/_protogen/kedge/config/http/routes"
+	"github.com/mwitkow/kedge/lib/pac"
+)
+
+// pacHandler serves a PAC file sending browsers to this kedge for the hosts of forward proxy routes and adhoc rules.
+//
+// The kedge is addressed using the host the PAC file was fetched from.
+func pacHandler(cnf *pb_config.DirectorConfig) http.Handler {
+	return http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		host := req.Host
+		if h, _, err := net.SplitHostPort(req.Host); err == nil {
+			host = h
+		}
+		pacFileFor(cnf, host, *flagHttpTlsPort, *flagHttpPort).ServeHTTP(resp, req)
+	})
+}
+
+func pacFileFor(cnf *pb_config.DirectorConfig, kedgeHost string, tlsPort int, plainPort int) *pac.File {
+	// Over an HTTPS proxy, Chrome sends FORWARD_PROXY requests while Firefox sends REVERSE_PROXY ones, see the
+	// ProxyMode in routes.proto. Over a plain HTTP proxy both browsers send FORWARD_PROXY requests.
+	httpsProxy, plainProxy := "", ""
+	if tlsPort != 0 {
+		httpsProxy = fmt.Sprintf("HTTPS %s:%d", kedgeHost, tlsPort)
+	}
+	if plainPort != 0 {
+		plainProxy = fmt.Sprintf("PROXY %s:%d", kedgeHost, plainPort)
+	}
+	anyModeProxy := joinDirectives(httpsProxy, plainProxy)
+
+	f := &pac.File{}
+	for _, route := range cnf.GetHttp().GetRoutes() {
+		// Routes without a host matcher would make browsers send everything to kedge.
+		if route.HostMatcher == "" {
+			continue
+		}
+		proxy := ""
+		switch route.ProxyMode {
+		case pb_route.ProxyMode_ANY:
+			proxy = anyModeProxy
+		case pb_route.ProxyMode_FORWARD_PROXY:
+			proxy = plainProxy
+		}
+		if proxy != "" {
+			f.Rules = append(f.Rules, pac.Rule{HostMatcher: route.HostMatcher, Proxy: proxy})
+		}
+	}
+	// Adhoc rules don't depend on the proxy mode.
+	for _, adhoc := range cnf.GetHttp().GetAdhocRules() {
+		if adhoc.DnsNameMatcher != "" && anyModeProxy != "" {
+			f.Rules = append(f.Rules, pac.Rule{HostMatcher: adhoc.DnsNameMatcher, Proxy: anyModeProxy})
+		}
+	}
+	return f
+}
+
+func joinDirectives(directives ...string) string {
+	nonEmpty := []string{}
+	for _, d := range directives {
+		if d != "" {
+			nonEmpty = append(nonEmpty, d)
+		}
+	}
+	return strings.Join(nonEmpty, "; ")
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/golang/protobuf/jsonpb"
+	pb_config "github.com/mwitkow/kedge/_protogen/kedge/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPacFileFor(t *testing.T) {
+	configJson := `
+{ "http": {
+	"routes": [
+		{ "backendName": "any", "hostMatcher": "any.ext.example.com" },
+		{ "backendName": "forward", "hostMatcher": "forward.ext.example.com", "proxyMode": "FORWARD_PROXY" },
+		{ "backendName": "reverse", "hostMatcher": "reverse.ext.example.com", "proxyMode": "REVERSE_PROXY" },
+		{ "backendName": "catchall" }
+	],
+	"adhocRules": [
+		{ "dnsNameMatcher": "*.pods.cluster.local" }
+	]
+}}`
+	cnf := &pb_config.DirectorConfig{}
+	require.NoError(t, jsonpb.UnmarshalString(configJson, cnf))
+
+	for _, tcase := range []struct {
+		name      string
+		tlsPort   int
+		plainPort int
+		expected  map[string]string
+	}{
+		{
+			name:      "BothPorts",
+			tlsPort:   443,
+			plainPort: 80,
+			expected: map[string]string{
+				"http://any.ext.example.com/":     "HTTPS kedge.example.com:443; PROXY kedge.example.com:80",
+				"http://forward.ext.example.com/": "PROXY kedge.example.com:80",
+				"http://reverse.ext.example.com/": "DIRECT",
+				"http://a.pods.cluster.local:81/": "HTTPS kedge.example.com:443; PROXY kedge.example.com:80",
+				"https://any.ext.example.com/":    "DIRECT",
+				"http://www.example.com/":         "DIRECT",
+			},
+		},
+		{
+			name:    "OnlyTlsPortSkipsForwardProxyRoutes",
+			tlsPort: 443,
+			expected: map[string]string{
+				"http://any.ext.example.com/":     "HTTPS kedge.example.com:443",
+				"http://forward.ext.example.com/": "DIRECT",
+				"http://a.pods.cluster.local:81/": "HTTPS kedge.example.com:443",
+			},
+		},
+	} {
+		t.Run(tcase.name, func(t *testing.T) {
+			f := pacFileFor(cnf, "kedge.example.com", tcase.tlsPort, tcase.plainPort)
+			for url, expectedProxy := range tcase.expected {
+				assert.Equal(t, expectedProxy, f.ProxyFor(url), "proxy for %v", url)
+			}
+		})
+	}
+}
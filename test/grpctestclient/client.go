@@ -1,22 +1,21 @@
 package main
 
 import (
-	"net"
 	"time"
 
 	"golang.org/x/net/context"
-	"google.golang.org/grpc/credentials"
 
 	"github.com/Sirupsen/logrus"
 	google_protobuf "github.com/golang/protobuf/ptypes/empty"
 	pb_base "github.com/mwitkow/kedge/_protogen/base"
+	pb_client "github.com/mwitkow/kedge/_protogen/kedge/config/client"
+	"github.com/mwitkow/kedge/client/grpc"
+	"github.com/mwitkow/kedge/lib/mapper"
 
 	"io"
 	"os"
 
 	"crypto/tls"
-
-	"google.golang.org/grpc"
 )
 
 var (
@@ -37,10 +36,14 @@ func main() {
 	}
 	addClientCerts(tlsConfig)
 	logrus.SetOutput(os.Stdout)
-	conn, err := grpc.Dial("controller.eu1-prod.improbable.local:9999",
-		grpc.WithTransportCredentials(credentials.NewTLS(tlsConfig)),
-		grpc.WithDialer(spoofedGrpcDialer),
-	)
+	// Sends the requests to the director on localhost.
+	m, err := mapper.NewStatic([]*pb_client.Mapping{
+		{HostMatcher: "controller.eu1-prod.improbable.local", KedgeAddress: proxyHostPort},
+	})
+	if err != nil {
+		logrus.Fatalf("cannot create mapper: %v", err)
+	}
+	conn, err := kedge_grpc.Dial("controller.eu1-prod.improbable.local:9999", m, tlsConfig)
 	if err != nil {
 		logrus.Fatalf("cannot dial: %v", err)
 	}
@@ -60,15 +63,3 @@ func main() {
 		logrus.Info("Flag: ", msg)
 	}
 }
-
-// spoofedGrpcDialer pretends to dial over a remote DNS name, but resolves to localhost.
-// This is to send the requests to the director
-func spoofedGrpcDialer(addr string, t time.Duration) (net.Conn, error) {
-	host, _, _ := net.SplitHostPort(addr)
-	switch host {
-	case "controller.eu1-prod.improbable.local":
-		return net.DialTimeout("tcp", proxyHostPort, t)
-	default:
-		return net.DialTimeout("tcp", addr, t)
-	}
-}